@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/tarm/serial"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo/bridge"
+	"github.com/karlcswanson/gogpsdo/gpsdo/capture"
+	"github.com/karlcswanson/gogpsdo/gpsdo/protocol"
+	"github.com/karlcswanson/gogpsdo/internal/config"
+)
+
+// cmdCapture records the raw serial input to a capture file without running
+// the bridge
+func cmdCapture(args []string) error {
+	fs := newFlagSet("capture", "[flags] FILE")
+	port := fs.String("port", "/dev/ttyAMA0", "TOD TTY Input, or a tcp:// or rfc2217:// network serial port")
+	proto := fs.String("protocol", protocol.Default, "Input protocol whose serial settings are used")
+	baud := fs.Int("baud", 0, "Serial baud rate (0 for the protocol default)")
+	dataBits := fs.Int("data-bits", 0, "Serial data bits, 5 to 8 (0 for the protocol default)")
+	parity := fs.String("parity", "", "Serial parity, none, odd or even (default the protocol's)")
+	stopBits := fs.Int("stop-bits", 0, "Serial stop bits, 1 or 2 (0 for the protocol default)")
+	duration := fs.Duration("duration", 0, "Stop after this long (0 to run until interrupted)")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return &exitError{code: 2, err: errors.New("expected one capture file")}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if *duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *duration)
+		defer cancel()
+	}
+
+	parser, err := protocol.New(*proto, protocol.Options{})
+	if err != nil {
+		return err
+	}
+	override := config.Serial{Baud: *baud, DataBits: *dataBits, Parity: *parity, StopBits: *stopBits}
+	overrides, err := override.Settings()
+	if err != nil {
+		return err
+	}
+	settings := parser.SerialDefaults().Override(overrides)
+
+	file, err := capture.Append(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	s, err := bridge.OpenPort(&serial.Config{
+		Name:        *port,
+		Baud:        settings.Baud,
+		Size:        byte(settings.DataBits),
+		Parity:      serial.Parity(settings.Parity),
+		StopBits:    serial.StopBits(settings.StopBits),
+		ReadTimeout: time.Second,
+	})
+	if err != nil {
+		return err
+	}
+	go func() {
+		<-ctx.Done()
+		s.Close()
+	}()
+
+	slog.Info("Capturing raw input", "port", *port, "settings", settings, "file", fs.Arg(0))
+	var records, bytes int
+	defer func() {
+		slog.Info("Capture finished", "records", records, "bytes", bytes)
+	}()
+
+	buf := make([]byte, 1024)
+	for {
+		n, err := s.Read(buf)
+		if n > 0 {
+			if err := file.Write(time.Now(), buf[:n]); err != nil {
+				return err
+			}
+			records++
+			bytes += n
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		// Read timeouts are reported as EOF
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+	}
+}
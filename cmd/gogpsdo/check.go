@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo/bridge"
+	"github.com/karlcswanson/gogpsdo/gpsdo/chrony"
+	"github.com/karlcswanson/gogpsdo/gpsdo/leapsec"
+	"github.com/karlcswanson/gogpsdo/gpsdo/netserial"
+	"github.com/karlcswanson/gogpsdo/gpsdo/ntp"
+	"github.com/karlcswanson/gogpsdo/gpsdo/pps"
+	"github.com/karlcswanson/gogpsdo/gpsdo/protocol"
+	"github.com/karlcswanson/gogpsdo/internal/notify"
+	"github.com/karlcswanson/gogpsdo/internal/privilege"
+	"github.com/karlcswanson/gogpsdo/internal/state"
+)
+
+// cmdCheck validates the configuration and that the devices it names exist,
+// without opening them
+func cmdCheck(args []string) error {
+	cfg, err := parseConfig(newFlagSet("check", "[run flags]"), args)
+	if err != nil {
+		return err
+	}
+
+	var problems int
+	report := func(ok bool, format string, args ...any) {
+		status := "ok  "
+		if !ok {
+			status = "FAIL"
+			problems++
+		}
+		fmt.Printf("%s %s\n", status, fmt.Sprintf(format, args...))
+	}
+	exists := func(path string) bool {
+		_, err := os.Stat(path)
+		return err == nil
+	}
+
+	fmt.Println("ok   configuration is valid")
+	for _, dev := range cfg.DeviceList() {
+		prefix := ""
+		if dev.Name != "" {
+			prefix = dev.Name + ": "
+		}
+
+		if cfg.Replay.File != "" {
+			report(exists(cfg.Replay.File), "%sreplay file %s", prefix, cfg.Replay.File)
+		} else if dev.Serial.Port == bridge.AutoPort {
+			candidates := bridge.ProbeCandidates(nil)
+			report(len(candidates) > 0, "%sserial port auto, probing %s (%s)", prefix, strings.Join(candidates, " "), dev.Protocol)
+		} else if dev.Serial.Port == bridge.StdinPort {
+			report(true, "%sserial port stdin (%s)", prefix, dev.Protocol)
+		} else if netserial.IsAddress(dev.Serial.Port) {
+			// Not connected, ser2net may only take one client at a time.
+			// Validated by config.Parse.
+			_, addr, _ := netserial.Split(dev.Serial.Port)
+			host, _, _ := net.SplitHostPort(addr)
+			_, err := net.LookupHost(host)
+			report(err == nil, "%snetwork serial port %s (%s)%s", prefix, dev.Serial.Port, dev.Protocol, errorSuffix(err))
+		} else {
+			// Validated by config.Parse
+			parser, _ := protocol.New(dev.Protocol, protocol.Options{Generic: dev.Generic, Z3805A: dev.Z3805A})
+			overrides, _ := dev.Serial.Settings()
+			report(exists(dev.Serial.Port), "%sserial port %s (%s, %s)", prefix, dev.Serial.Port, dev.Protocol,
+				parser.SerialDefaults().Override(overrides))
+		}
+		if dev.PPS.Device != "" {
+			report(exists(dev.PPS.Device), "%sPPS device %s", prefix, dev.PPS.Device)
+		}
+		if dev.PPS.Port != "" {
+			report(exists(dev.PPS.Port), "%sPPS port %s (%s)", prefix, dev.PPS.Port, dev.PPS.ModemLine())
+		}
+		if dev.PPS.GPIO != "" {
+			// Validated by config.Parse
+			chip, line, _ := pps.ParseGPIO(dev.PPS.GPIO)
+			report(exists(chip), "%sPPS GPIO chip %s (line %d)", prefix, chip, line)
+		}
+		for _, spec := range dev.Outputs.LED.GPIO {
+			// Validated by config.Parse
+			chip, line, _ := pps.ParseGPIO(spec)
+			report(exists(chip), "%sLED GPIO chip %s (line %d)", prefix, chip, line)
+		}
+		if dev.Outputs.PHC.Device != "" {
+			report(exists(dev.Outputs.PHC.Device), "%sPHC %s", prefix, dev.Outputs.PHC.Device)
+		}
+		if dev.SCPI.Port != "" {
+			report(exists(dev.SCPI.Port), "%sSCPI port %s", prefix, dev.SCPI.Port)
+		}
+		if bus := dev.Display.I2C; bus != "" {
+			report(exists(bus), "%sdisplay I2C bus %s (%s, 0x%02x)", prefix, bus, dev.Display.Controller, dev.Display.Address)
+		}
+		if socket := dev.Outputs.Chrony.Socket; socket != "" {
+			// chronyd creates the socket, its directory must exist
+			report(exists(filepath.Dir(socket)), "%schrony socket directory %s", prefix, filepath.Dir(socket))
+			if !exists(socket) {
+				fmt.Printf("note %schrony socket %s does not exist yet, is the SOCK refclock configured?\n", prefix, socket)
+			}
+		}
+		if refid := dev.Chronyc.RefID; refid != "" {
+			t, err := chrony.Query(context.Background(), refid)
+			if err == nil && t.Source == nil {
+				err = errors.New("no such refclock in chrony.conf")
+			}
+			report(err == nil, "%schronyd refclock %s%s", prefix, refid, errorSuffix(err))
+		}
+	}
+
+	if cfg.LeapSeconds.File != "" {
+		table, err := leapsec.Load(cfg.LeapSeconds.File)
+		report(err == nil, "leap second table %s%s", cfg.LeapSeconds.File, errorSuffix(err))
+		if err == nil {
+			report(!table.Expired(time.Now()), "leap second table expires %s", table.Expires.Format(time.DateOnly))
+		}
+	}
+	if len(cfg.NTPCheck.Servers) > 0 {
+		for _, r := range ntp.QueryAll(context.Background(), cfg.NTPCheck.Servers).Replies {
+			detail := errorSuffix(r.Err)
+			if r.Err == nil {
+				detail = fmt.Sprintf(" (stratum %d, offset %s)", r.Stratum, r.Offset)
+			}
+			report(r.Err == nil, "NTP reference server %s%s", r.Server, detail)
+		}
+	}
+	if cfg.Privileges.User != "" {
+		_, err := privilege.Lookup(cfg.Privileges.User, cfg.Privileges.Group)
+		report(err == nil, "privileges user %s%s", cfg.Privileges.User, errorSuffix(err))
+	}
+	if cfg.SMTP.Server != "" {
+		_, err := notify.NewEmail(cfg.SMTP)
+		report(err == nil, "smtp templates%s", errorSuffix(err))
+	}
+	if cfg.Capture.File != "" {
+		dir := filepath.Dir(cfg.Capture.File)
+		report(exists(dir), "capture directory %s", dir)
+	}
+	if cfg.Logging.File != "" {
+		dir := filepath.Dir(cfg.Logging.File)
+		report(exists(dir), "log directory %s", dir)
+	}
+	if cfg.Daemon.PIDFile != "" {
+		dir := filepath.Dir(cfg.Daemon.PIDFile)
+		report(exists(dir), "pidfile directory %s", dir)
+	}
+	if cfg.State.File != "" {
+		_, err := state.Load(cfg.State.File)
+		report(err == nil, "state file %s%s", cfg.State.File, errorSuffix(err))
+		dir := filepath.Dir(cfg.State.File)
+		report(exists(dir), "state directory %s", dir)
+	}
+
+	if problems > 0 {
+		return fmt.Errorf("%d problem(s) found", problems)
+	}
+	return nil
+}
+
+// errorSuffix formats err for a check line
+func errorSuffix(err error) string {
+	if err == nil {
+		return ""
+	}
+	return " (" + err.Error() + ")"
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// apiClient queries the HTTP API of a running bridge
+type apiClient struct {
+	base   string
+	device string
+	client *http.Client
+}
+
+// newAPIClient creates a client for addr, an http:// URL, a host:port or
+// unix:/path as given to -http. device selects a bridge when several run.
+func newAPIClient(addr, device string) *apiClient {
+	c := &apiClient{
+		base:   addr,
+		device: device,
+		client: &http.Client{Timeout: 5 * time.Second},
+	}
+
+	if path, ok := strings.CutPrefix(addr, "unix:"); ok {
+		c.base = "http://gogpsdo"
+		c.client.Transport = &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, "unix", path)
+			},
+		}
+	} else if !strings.Contains(addr, "://") {
+		if strings.HasPrefix(addr, ":") {
+			addr = "localhost" + addr
+		}
+		c.base = "http://" + addr
+	}
+	c.base = strings.TrimSuffix(c.base, "/")
+	return c
+}
+
+// get decodes the JSON document at path into v
+func (c *apiClient) get(path string, v any) error {
+	u := c.base + path
+	if c.device != "" {
+		u += "?device=" + url.QueryEscape(c.device)
+	}
+
+	resp, err := c.client.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: %s", path, resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/karlcswanson/gogpsdo/internal/control"
+)
+
+// cmdCtl sends one command to the control socket and prints the response
+func cmdCtl(args []string) error {
+	fs := newFlagSet("ctl", "[flags] COMMAND [ARGS]")
+	socket := fs.String("socket", control.DefaultSocket, "Control socket of the bridge")
+	device := fs.String("device", "", "Apply the command to this device (default all)")
+	fs.Parse(args)
+
+	request := fs.Args()
+	if len(request) == 0 {
+		request = []string{"help"}
+	}
+	if *device != "" {
+		request = append(request, "device="+*device)
+	}
+
+	body, err := control.Request(*socket, strings.Join(request, " "))
+	if err != nil {
+		return err
+	}
+	fmt.Print(body)
+	return nil
+}
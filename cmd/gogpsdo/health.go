@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/internal/api"
+)
+
+// Health exit statuses, as used by Nagios style checks
+const (
+	healthOK       = 0
+	healthWarning  = 1
+	healthCritical = 2
+)
+
+var healthLabels = []string{"OK", "WARNING", "CRITICAL"}
+
+// cmdHealth queries a running bridge and exits 0 if every device has recent
+// locked samples, 1 if any is in holdover or not yet locked and 2 if any is
+// stale or the bridge can't be reached
+func cmdHealth(args []string) error {
+	fs := newFlagSet("health", "[flags]")
+	addr := fs.String("addr", "localhost:8080", "HTTP API address of the bridge, e.g. :8080 or unix:/run/gogpsdo/api.sock")
+	device := fs.String("device", "", "Only check this device (default all)")
+	maxAge := fs.Duration("max-age", 10*time.Second, "Samples older than this are stale")
+	fs.Parse(args)
+
+	client := newAPIClient(*addr, *device)
+	var statuses []api.Status
+	var err error
+	if *device != "" {
+		var status api.Status
+		err = client.get("/api/v1/status", &status)
+		statuses = append(statuses, status)
+	} else {
+		var devices api.Devices
+		err = client.get("/api/v1/devices", &devices)
+		statuses = devices.Devices
+	}
+	if err != nil {
+		fmt.Printf("CRITICAL bridge unreachable: %v\n", err)
+		return &exitError{code: healthCritical}
+	}
+
+	worst := healthOK
+	for _, s := range statuses {
+		code, detail := health(s, *maxAge)
+		worst = max(worst, code)
+		if s.Device != "" {
+			detail = s.Device + ": " + detail
+		}
+		fmt.Printf("%s %s\n", healthLabels[code], detail)
+	}
+	if worst != healthOK {
+		return &exitError{code: worst}
+	}
+	return nil
+}
+
+// health classifies one device status
+func health(s api.Status, maxAge time.Duration) (int, string) {
+	switch {
+	case s.SampleAge < 0:
+		return healthCritical, "no samples received"
+	case s.SampleAge > maxAge.Seconds():
+		return healthCritical, fmt.Sprintf("%s, last sample %.0fs ago", s.Status, s.SampleAge)
+	case s.Status != "LOCKED":
+		return healthWarning, fmt.Sprintf("%s, last sample %.1fs ago", s.Status, s.SampleAge)
+	}
+	return healthOK, fmt.Sprintf("%s, last sample %.1fs ago", s.Status, s.SampleAge)
+}
@@ -0,0 +1,125 @@
+// Command gogpsdo bridges the HP Z3805A time-of-day output to chronyd.
+//
+// Usage:
+//
+//	gogpsdo [command] [flags]
+//
+// The command defaults to run. See gogpsdo help for the list.
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+// command is a gogpsdo subcommand
+type command struct {
+	name    string
+	summary string
+	run     func(args []string) error
+}
+
+var commands []command
+
+func init() {
+	// Assigned here as cmdHelp refers to commands
+	commands = []command{
+		{"run", "Run the bridge (the default)", cmdRun},
+		{"monitor", "Follow the status of a running bridge", cmdMonitor},
+		{"simulate", "Emit simulated Z3805A packets to a pty", cmdSimulate},
+		{"capture", "Record raw serial input for later replay", cmdCapture},
+		{"replay", "Run the bridge on a capture file", cmdReplay},
+		{"check", "Validate the configuration and devices", cmdCheck},
+		{"protocols", "List the input protocols", cmdProtocols},
+		{"health", "Check a running bridge, for health checks and monitoring", cmdHealth},
+		{"ctl", "Send a command to the control socket of a running bridge", cmdCtl},
+		{"scpi", "Send SCPI commands to the receiver through a running bridge", cmdSCPI},
+		{"version", "Print version information", cmdVersion},
+		{"help", "Show this help", cmdHelp},
+	}
+}
+
+// exitError is returned by commands to exit with a specific status. A nil
+// err exits without a message.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	if e.err == nil {
+		return fmt.Sprintf("exit status %d", e.code)
+	}
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// fatal logs an error and exits
+func fatal(msg string, args ...any) {
+	slog.Error(msg, args...)
+	os.Exit(1)
+}
+
+func main() {
+	name, args := "run", os.Args[1:]
+	// Plain flags run the bridge, as before subcommands existed
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	for _, cmd := range commands {
+		if cmd.name != name {
+			continue
+		}
+		err := cmd.run(args)
+		if err == nil {
+			return
+		}
+
+		code := 1
+		var exit *exitError
+		if errors.As(err, &exit) {
+			code = exit.code
+		}
+		if exit == nil || exit.err != nil {
+			fmt.Fprintf(os.Stderr, "gogpsdo %s: %v\n", name, err)
+		}
+		os.Exit(code)
+	}
+
+	fmt.Fprintf(os.Stderr, "gogpsdo: unknown command %q\n\n", name)
+	usage()
+	os.Exit(2)
+}
+
+// usage prints the command list
+func usage() {
+	w := flag.CommandLine.Output()
+	fmt.Fprintf(w, "Usage: gogpsdo [command] [flags]\n\nCommands:\n")
+	for _, cmd := range commands {
+		fmt.Fprintf(w, "  %-10s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "\nRun gogpsdo <command> -h for the flags of a command.\n")
+}
+
+func cmdHelp([]string) error {
+	usage()
+	return nil
+}
+
+// newFlagSet creates the flag set of a subcommand with a usage line
+func newFlagSet(name, args string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: gogpsdo %s %s\n\n", name, args)
+		fs.PrintDefaults()
+	}
+	return fs
+}
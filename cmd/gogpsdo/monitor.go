@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/internal/api"
+)
+
+// cmdMonitor follows the status of a running bridge through its HTTP API,
+// printing a status line every interval and each new lock state transition
+func cmdMonitor(args []string) error {
+	fs := newFlagSet("monitor", "[flags]")
+	addr := fs.String("addr", "localhost:8080", "HTTP API address of the bridge, e.g. :8080 or unix:/run/gogpsdo/api.sock")
+	device := fs.String("device", "", "Device to follow when the bridge runs several")
+	interval := fs.Duration("interval", 2*time.Second, "Time between status lines")
+	fs.Parse(args)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	client := newAPIClient(*addr, *device)
+	var seen time.Time
+	first := true
+
+	ticker := time.NewTicker(*interval)
+	defer ticker.Stop()
+	for {
+		var status api.Status
+		var history api.History
+		err := client.get("/api/v1/status", &status)
+		if err == nil {
+			err = client.get("/api/v1/history", &history)
+		}
+
+		now := time.Now().Format(time.TimeOnly)
+		if err != nil {
+			fmt.Printf("%s error: %v\n", now, err)
+		} else {
+			for _, t := range history.Transitions {
+				if !t.Time.After(seen) {
+					continue
+				}
+				seen = t.Time
+				// Only the latest transition before attaching is shown
+				if !first || t.Time.Equal(history.Transitions[len(history.Transitions)-1].Time) {
+					fmt.Printf("%s transition %s -> %s at %s\n", now, t.From, t.To, t.Time.Format(time.DateTime))
+				}
+			}
+			first = false
+			fmt.Printf("%s %s\n", now, statusLine(status))
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// statusLine summarizes s on one line
+func statusLine(s api.Status) string {
+	fields := []string{s.Status}
+	if s.Timestamp != nil {
+		fields = append(fields, "gps "+s.Timestamp.UTC().Format(time.TimeOnly))
+	}
+	if s.SampleAge >= 0 {
+		fields = append(fields, fmt.Sprintf("age %.1fs", s.SampleAge))
+	}
+	fields = append(fields,
+		fmt.Sprintf("packets %d/%d", s.Packets.Valid, s.Packets.Total),
+		fmt.Sprintf("sent %d", s.Samples.Sent))
+	if s.PPS.Edges > 0 {
+		fields = append(fields, fmt.Sprintf("pps %d/%d", s.PPS.Paired, s.PPS.Edges))
+	}
+	if s.Stability != nil {
+		fields = append(fields, fmt.Sprintf("std_dev %.1fus", s.Stability.StdDev*1e6))
+	}
+	if s.Holdover.Since != nil {
+		fields = append(fields, "holdover "+time.Since(*s.Holdover.Since).Truncate(time.Second).String())
+	}
+	if !s.Input.Connected {
+		fields = append(fields, "input disconnected")
+	}
+	return strings.Join(fields, "  ")
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"net"
+	"os"
+	"strings"
+
+	"github.com/karlcswanson/gogpsdo/internal/config"
+	"github.com/karlcswanson/gogpsdo/internal/privilege"
+)
+
+// applyPermissions sets the configured owner, group and mode of path
+func applyPermissions(path string, p config.Permissions) error {
+	if err := privilege.Chown(path, p.Owner, p.Group); err != nil {
+		return err
+	}
+	// Validated by config.Parse
+	if mode, ok, _ := p.FileMode(); ok {
+		return os.Chmod(path, mode)
+	}
+	return nil
+}
+
+// listen binds an API address, a TCP address or unix:/path
+func listen(addr string, p config.Permissions) (net.Listener, error) {
+	path, ok := strings.CutPrefix(addr, "unix:")
+	if !ok {
+		return net.Listen("tcp", addr)
+	}
+	return listenUnix(path, p)
+}
+
+// listenUnix binds a unix socket with the configured permissions. A stale
+// socket left by a previous run is replaced.
+func listenUnix(path string, p config.Permissions) (net.Listener, error) {
+	if info, err := os.Lstat(path); err == nil && info.Mode().Type() == os.ModeSocket {
+		os.Remove(path)
+	}
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := applyPermissions(path, p); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
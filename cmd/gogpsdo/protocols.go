@@ -0,0 +1,18 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo/protocol"
+)
+
+// cmdProtocols lists the registered input protocols with their serial
+// settings
+func cmdProtocols(args []string) error {
+	newFlagSet("protocols", "").Parse(args)
+
+	for _, p := range protocol.Protocols() {
+		fmt.Printf("%-11s %-11s %s\n", p.Name, p.New(protocol.Options{}).SerialDefaults(), p.Summary)
+	}
+	return nil
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo/bridge"
+	"github.com/karlcswanson/gogpsdo/internal/config"
+	"github.com/karlcswanson/gogpsdo/internal/history"
+	"github.com/karlcswanson/gogpsdo/internal/logging"
+)
+
+// liveSections are the config sections applied on reload, and
+// liveDeviceSections those of each device. Other changes, such as the serial
+// port or HTTP address, need a restart.
+var (
+	liveSections       = []string{"logging.level", "influxdb", "prometheus", "mqtt", "dbus", "webhook", "smtp"}
+	liveDeviceSections = []string{"offset", "outputs"}
+)
+
+// service is a running background service
+type service struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// reloader applies config file changes on SIGHUP without reopening the
+// serial ports
+type reloader struct {
+	// mutex serializes reloads and the final stop
+	mutex    sync.Mutex
+	ctx      context.Context
+	cfg      *config.Config
+	load     func() (*config.Config, error)
+	bridges  []*bridge.Bridge
+	outputs  []*outputSet
+	history  *history.Store
+	services map[string]service
+}
+
+func newReloader(ctx context.Context, cfg *config.Config, load func() (*config.Config, error),
+	bridges []*bridge.Bridge, outputs []*outputSet, store *history.Store) *reloader {
+	return &reloader{
+		ctx:      ctx,
+		cfg:      cfg,
+		load:     load,
+		bridges:  bridges,
+		outputs:  outputs,
+		history:  store,
+		services: make(map[string]service),
+	}
+}
+
+// watch reloads the config on every SIGHUP until ctx is cancelled
+func (r *reloader) watch() {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	for {
+		select {
+		case <-r.ctx.Done():
+			return
+		case <-hup:
+			r.reload()
+		}
+	}
+}
+
+// stop stops the services and outputs once the bridges have stopped
+func (r *reloader) stop() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for name := range r.services {
+		r.stopService(name)
+	}
+	for _, set := range r.outputs {
+		set.stop()
+	}
+}
+
+func (r *reloader) startService(name string, run func(context.Context)) {
+	ctx, cancel := context.WithCancel(r.ctx)
+	s := service{cancel: cancel, done: make(chan struct{})}
+	go func() {
+		defer close(s.done)
+		run(ctx)
+	}()
+	r.services[name] = s
+}
+
+func (r *reloader) stopService(name string) {
+	if s, ok := r.services[name]; ok {
+		s.cancel()
+		<-s.done
+		delete(r.services, name)
+	}
+}
+
+// reload reads the config and applies the live changes. A config that fails
+// to load or validate leaves everything running as it was.
+func (r *reloader) reload() {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	slog.Info("Reloading configuration")
+	cfg, err := r.load()
+	if err != nil {
+		slog.Error("Reload failed, keeping the running configuration", "error", err)
+		return
+	}
+
+	changes := config.Diff(effective(r.cfg), effective(cfg))
+	if len(changes) == 0 {
+		slog.Info("Configuration unchanged")
+		return
+	}
+	// Devices are matched by position, adding or removing one needs a restart
+	sameDevices := len(cfg.Devices) == len(r.cfg.Devices)
+	for _, c := range changes {
+		if isLive(c.Key, sameDevices) {
+			slog.Info("Config changed", "key", c.Key, "old", c.Old, "new", c.New)
+		} else {
+			slog.Warn("Config change needs a restart, ignored", "key", c.Key, "old", c.Old, "new", c.New)
+		}
+	}
+
+	// applied tracks the running config, so ignored changes are reported
+	// again on the next reload
+	applied := *r.cfg
+	applied.Devices = slices.Clone(r.cfg.Devices)
+
+	if cfg.Logging.Level != r.cfg.Logging.Level {
+		// Validated by config.Parse
+		logging.SetLevel(cfg.Logging.Level)
+		applied.Logging.Level = cfg.Logging.Level
+	}
+
+	if sameDevices {
+		oldDevices, newDevices := r.cfg.DeviceList(), cfg.DeviceList()
+		for i, b := range r.bridges {
+			dev := r.applyDevice(b, i, oldDevices[i], newDevices[i])
+			if len(applied.Devices) > 0 {
+				applied.Devices[i] = dev
+			} else {
+				applied.Device = dev
+			}
+		}
+	}
+
+	r.applyServices(cfg, &applied)
+	r.cfg = &applied
+}
+
+// applyDevice applies the offset and output changes of one device and
+// returns its running config
+func (r *reloader) applyDevice(b *bridge.Bridge, i int, old, dev config.Device) config.Device {
+	if dev.Offset != old.Offset {
+		b.SetOffset(time.Duration(dev.Offset * float64(time.Second)))
+		old.Offset = dev.Offset
+	}
+	if reflect.DeepEqual(dev.Outputs, old.Outputs) {
+		return old
+	}
+
+	// Nothing may be sent to the outputs while they are replaced
+	b.SetOutputs()
+	r.outputs[i].stop()
+	leap := b.LeapStatus().Table
+	set, err := startOutputs(r.ctx, dev, r.cfg.DryRun, r.history, leap)
+	if err == nil {
+		old.Outputs = dev.Outputs
+	} else {
+		slog.Error("Outputs failed to start, restoring the previous outputs", "device", dev.Name, "error", err)
+		if set, err = startOutputs(r.ctx, old, r.cfg.DryRun, r.history, leap); err != nil {
+			slog.Error("Outputs failed to restart, samples are not sent", "device", dev.Name, "error", err)
+			set = &outputSet{cancel: func() {}}
+		}
+	}
+	r.outputs[i] = set
+	b.SetOutputs(set.outputs...)
+	return old
+}
+
+// applyServices restarts the background services whose config changed
+func (r *reloader) applyServices(cfg, applied *config.Config) {
+	services, err := newServices(cfg, r.bridges)
+	if err != nil {
+		slog.Error("Services not reloaded", "error", err)
+		return
+	}
+
+	sections := map[string][2]any{
+		"influxdb":   {r.cfg.InfluxDB, cfg.InfluxDB},
+		"prometheus": {r.cfg.Prometheus, cfg.Prometheus},
+		"mqtt":       {r.cfg.MQTT, cfg.MQTT},
+		"dbus":       {r.cfg.DBus, cfg.DBus},
+		"webhook":    {r.cfg.Webhook, cfg.Webhook},
+		"smtp":       {r.cfg.SMTP, cfg.SMTP},
+	}
+	for _, name := range serviceNames {
+		if reflect.DeepEqual(sections[name][0], sections[name][1]) {
+			continue
+		}
+		_, running := r.services[name]
+		r.stopService(name)
+		if run, ok := services[name]; ok {
+			r.startService(name, run)
+		} else if running {
+			slog.Info("Service disabled", "service", name)
+		}
+	}
+	applied.InfluxDB = cfg.InfluxDB
+	applied.Prometheus = cfg.Prometheus
+	applied.MQTT = cfg.MQTT
+	applied.DBus = cfg.DBus
+	applied.Webhook = cfg.Webhook
+	applied.SMTP = cfg.SMTP
+}
+
+// effective returns cfg without the top level device when the devices list
+// replaces it
+func effective(cfg *config.Config) *config.Config {
+	c := *cfg
+	if len(c.Devices) > 0 {
+		c.Device = config.Device{}
+	}
+	return &c
+}
+
+// isLive reports whether a change to key is applied on reload. Device
+// changes are only applied if the devices still match.
+func isLive(key string, sameDevices bool) bool {
+	if inSections(key, liveSections) {
+		return true
+	}
+	if strings.HasPrefix(key, "devices[") {
+		_, key, _ = strings.Cut(key, "].")
+	}
+	return sameDevices && inSections(key, liveDeviceSections)
+}
+
+// inSections reports whether key is one of sections or below one
+func inSections(key string, sections []string) bool {
+	for _, section := range sections {
+		if key == section || strings.HasPrefix(key, section+".") {
+			return true
+		}
+	}
+	return false
+}
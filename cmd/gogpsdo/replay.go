@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo/bridge"
+	"github.com/karlcswanson/gogpsdo/gpsdo/capture"
+	"github.com/karlcswanson/gogpsdo/internal/config"
+)
+
+// cmdReplay runs the bridge on the capture file named after the run flags
+func cmdReplay(args []string) error {
+	fs := newFlagSet("replay", "[run flags] FILE")
+	cfg, err := parseConfig(fs, args)
+	if err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return &exitError{code: 2, err: errors.New("expected one capture file")}
+	}
+
+	cfg.Replay.File = fs.Arg(0)
+	if err := cfg.Validate(); err != nil {
+		return &exitError{code: 2, err: fmt.Errorf("config: %w", err)}
+	}
+	runBridge(cfg, nil)
+	return nil
+}
+
+// replayCapture feeds a capture file through the bridge in place of the
+// serial port
+func replayCapture(ctx context.Context, b *bridge.Bridge, cfg config.Replay) error {
+	f, err := os.Open(cfg.File)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r, err := capture.NewReader(f)
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Replaying capture", "file", cfg.File, "speed", cfg.Speed)
+	return b.RunInput(ctx, capture.NewPlayer(ctx, r, cfg.Speed))
+}
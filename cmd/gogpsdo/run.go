@@ -0,0 +1,728 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/adjtimex"
+	"github.com/karlcswanson/gogpsdo/gpsdo/bridge"
+	"github.com/karlcswanson/gogpsdo/gpsdo/capture"
+	"github.com/karlcswanson/gogpsdo/gpsdo/chrony"
+	"github.com/karlcswanson/gogpsdo/gpsdo/csvlog"
+	"github.com/karlcswanson/gogpsdo/gpsdo/fifo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/gpsd"
+	"github.com/karlcswanson/gogpsdo/gpsdo/leapsec"
+	"github.com/karlcswanson/gogpsdo/gpsdo/led"
+	"github.com/karlcswanson/gogpsdo/gpsdo/netserial"
+	"github.com/karlcswanson/gogpsdo/gpsdo/ntp"
+	"github.com/karlcswanson/gogpsdo/gpsdo/phc"
+	"github.com/karlcswanson/gogpsdo/gpsdo/pps"
+	"github.com/karlcswanson/gogpsdo/gpsdo/rawtcp"
+	"github.com/karlcswanson/gogpsdo/gpsdo/scpi"
+	"github.com/karlcswanson/gogpsdo/gpsdo/shm"
+	"github.com/karlcswanson/gogpsdo/internal/api"
+	"github.com/karlcswanson/gogpsdo/internal/config"
+	"github.com/karlcswanson/gogpsdo/internal/control"
+	"github.com/karlcswanson/gogpsdo/internal/daemon"
+	"github.com/karlcswanson/gogpsdo/internal/dbus"
+	"github.com/karlcswanson/gogpsdo/internal/devwait"
+	"github.com/karlcswanson/gogpsdo/internal/display"
+	"github.com/karlcswanson/gogpsdo/internal/grpcapi"
+	"github.com/karlcswanson/gogpsdo/internal/history"
+	"github.com/karlcswanson/gogpsdo/internal/influx"
+	"github.com/karlcswanson/gogpsdo/internal/logging"
+	"github.com/karlcswanson/gogpsdo/internal/mqtt"
+	"github.com/karlcswanson/gogpsdo/internal/notify"
+	"github.com/karlcswanson/gogpsdo/internal/privilege"
+	"github.com/karlcswanson/gogpsdo/internal/prometheus"
+	"github.com/karlcswanson/gogpsdo/internal/state"
+	"github.com/karlcswanson/gogpsdo/internal/systemd"
+)
+
+// cmdRun runs the bridge with the config file and flags
+func cmdRun(args []string) error {
+	cfg, err := parseConfig(newFlagSet("run", "[flags]"), args)
+	if err != nil {
+		return err
+	}
+	reload := func() (*config.Config, error) {
+		return config.Parse(newFlagSet("run", "[flags]"), args)
+	}
+	runBridge(cfg, reload)
+	return nil
+}
+
+// parseConfig parses the run flags and config file. Config errors exit with
+// status 2.
+func parseConfig(fs *flag.FlagSet, args []string) (*config.Config, error) {
+	cfg, err := config.Parse(fs, args)
+	if err != nil {
+		return nil, &exitError{code: 2, err: fmt.Errorf("config: %w", err)}
+	}
+	return cfg, nil
+}
+
+// runBridge runs the bridges configured by cfg until interrupted. If reload
+// is not nil it is called on SIGHUP to read the changed config.
+func runBridge(cfg *config.Config, reload func() (*config.Config, error)) {
+	if cfg.Daemon.Enabled {
+		if !daemon.Child() {
+			if err := daemon.Start(); err != nil {
+				fmt.Fprintf(os.Stderr, "Daemon error: %v\n", err)
+				os.Exit(1)
+			}
+			os.Exit(0)
+		}
+		if cfg.Logging.File == "" {
+			// stderr goes nowhere once detached
+			cfg.Logging.Syslog.Enabled = true
+		}
+	}
+
+	logFile, err := logging.Setup(cfg.Logging, func(path string) error {
+		return applyPermissions(path, cfg.Logging.Permissions)
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Logging error: %v\n", err)
+		os.Exit(2)
+	}
+	defer logFile.Close()
+	if cfg.Daemon.PIDFile != "" {
+		if err := daemon.WritePIDFile(cfg.Daemon.PIDFile); err != nil {
+			fatal("Pidfile error", "error", err)
+		}
+		// May fail once privileges are dropped, a stale pidfile is replaced
+		// on the next start
+		defer daemon.RemovePIDFile(cfg.Daemon.PIDFile)
+	}
+
+	replay := cfg.Replay.File != ""
+	if replay {
+		// Never steer the system clock from recorded data
+		slog.Warn("Replay mode, chrony, SHM, NTP, PHC and kernel discipline outputs disabled", "file", cfg.Replay.File)
+		cfg.Outputs.Chrony.Socket = ""
+		cfg.Outputs.SHM.Unit = -1
+		cfg.Outputs.PHC.Device = ""
+		cfg.Outputs.Kernel.Enabled = false
+		cfg.Outputs.NTP.Listen = ""
+		cfg.PPS.Device = ""
+		cfg.PPS.DCD = false
+		cfg.PPS.Line = ""
+		cfg.PPS.Port = ""
+		cfg.PPS.GPIO = ""
+		if cfg.Replay.Speed != 1 && cfg.MaxJump > 0 {
+			// Packet times only progress in real time at speed 1
+			slog.Info("Outlier filter disabled for accelerated replay")
+			cfg.MaxJump = 0
+		}
+		if cfg.State.File != "" {
+			// Replayed counters would mix with those of the live device
+			slog.Info("State file not used for replay")
+			cfg.State.File = ""
+		}
+		if cfg.ClockAlarm > 0 {
+			// Recorded samples are compared with the clock of today
+			slog.Info("Clock alarm disabled for replay")
+			cfg.ClockAlarm = 0
+		}
+		if len(cfg.NTPCheck.Servers) > 0 {
+			slog.Info("NTP check disabled for replay")
+			cfg.NTPCheck.Servers = nil
+		}
+		if cfg.RolloverPivot == "" {
+			// Captures may be older than the build date, the default pivot
+			cfg.RolloverPivot = gpsdo.LastRollover.Format(time.DateOnly)
+			slog.Info("Rollover pivot set to the last rollover for replay", "pivot", cfg.RolloverPivot)
+		}
+	}
+
+	if cfg.DryRun {
+		slog.Warn("Dry run, chrony samples are printed instead of sent and SHM, PHC and kernel discipline are disabled")
+	}
+
+	var creds *privilege.Credentials
+	if cfg.Privileges.User != "" {
+		if os.Geteuid() != 0 {
+			fatal("Dropping privileges requires starting as root", "user", cfg.Privileges.User)
+		}
+		c, err := privilege.Lookup(cfg.Privileges.User, cfg.Privileges.Group)
+		if err != nil {
+			fatal("Privileges error", "error", err)
+		}
+		creds = &c
+	}
+
+	devices := cfg.DeviceList()
+	// claimed are the ports of the devices, which AutoPort never probes
+	var claimed []string
+	for _, dev := range devices {
+		if dev.Serial.Port != bridge.AutoPort {
+			claimed = append(claimed, dev.Serial.Port)
+		}
+	}
+	for _, dev := range devices {
+		for _, port := range []string{dev.SCPI.Port, dev.PPS.Port} {
+			if port != "" {
+				claimed = append(claimed, port)
+			}
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	go func() {
+		<-ctx.Done()
+		slog.Info("Shutdown signal received")
+		systemd.Notify(systemd.Stopping)
+	}()
+
+	if cfg.Logging.File != "" {
+		go reopenLog(ctx)
+	}
+
+	if !replay {
+		for _, dev := range devices {
+			if err := waitDevices(ctx, dev); err != nil {
+				if ctx.Err() != nil {
+					return
+				}
+				fatal("Device missing", "device", dev.Name, "error", err)
+			}
+		}
+	}
+
+	var captureWriter *capture.Writer
+	if cfg.Capture.File != "" {
+		captureFile, err := capture.Append(cfg.Capture.File)
+		if err != nil {
+			fatal("Capture error", "error", err)
+		}
+		defer captureFile.Close()
+		if err := applyPermissions(cfg.Capture.File, cfg.Capture.Permissions); err != nil {
+			fatal("Capture permissions error", "error", err)
+		}
+		captureWriter = captureFile.Writer
+		slog.Info("Capturing raw input", "file", cfg.Capture.File)
+	}
+
+	var store *history.Store
+	if cfg.History.Database != "" {
+		// Opened before privileges are dropped, like the capture file
+		store, err = history.Open(cfg.History)
+		if err != nil {
+			fatal("History error", "error", err)
+		}
+		defer store.Close()
+		if err := applyPermissions(cfg.History.Database, cfg.History.Permissions); err != nil {
+			fatal("History permissions error", "error", err)
+		}
+	}
+
+	var leapTable *leapsec.Table
+	if cfg.LeapSeconds.File != "" {
+		leapTable, err = leapsec.Load(cfg.LeapSeconds.File)
+		if err != nil {
+			fatal("Leap second table error", "error", err)
+		}
+		slog.Info("Loaded leap second table", "file", cfg.LeapSeconds.File,
+			"tai_offset", leapTable.Offset(time.Now()), "expires", leapTable.Expires.Format(time.DateOnly))
+	}
+
+	var bridges []*bridge.Bridge
+	var outputs []*outputSet
+	var displays []*display.Display
+	pollers := make(map[*bridge.Bridge]*scpi.Poller)
+	for _, dev := range devices {
+		set, err := startOutputs(ctx, dev, cfg.DryRun, store, leapTable)
+		if err != nil {
+			fatal("Output error", "device", dev.Name, "error", err)
+		}
+		outputs = append(outputs, set)
+
+		// Validated by config.Parse
+		pivot, _ := time.Parse(time.DateOnly, dev.RolloverPivot)
+		var ppsLine pps.Line
+		if line := dev.PPS.ModemLine(); line != "" {
+			ppsLine, _ = pps.ParseLine(line)
+		}
+		serial, _ := dev.Serial.Settings()
+		timescale, _ := gpsdo.ParseTimescale(dev.Timescale)
+		if timescale != gpsdo.UTC {
+			slog.Info("Samples are sent to the outputs on a non-UTC time scale", "device", dev.Name, "timescale", timescale)
+		}
+
+		var rebroadcast io.Writer
+		if dev.RawTCP.Listen != "" {
+			// Bound before privileges are dropped, the port may be privileged
+			server, err := rawtcp.Listen(dev.RawTCP.Listen)
+			if err != nil {
+				fatal("Raw TCP error", "device", dev.Name, "error", err)
+			}
+			go server.Serve(ctx)
+			rebroadcast = server
+		}
+
+		b := bridge.New(bridge.Config{
+			Name:              dev.Name,
+			Port:              dev.Serial.Port,
+			AutoExclude:       claimed,
+			Protocol:          dev.Protocol,
+			Generic:           dev.Generic,
+			Z3805A:            dev.Z3805A,
+			Serial:            serial,
+			PPSDevice:         dev.PPS.Device,
+			PPSLine:           ppsLine,
+			PPSPort:           dev.PPS.Port,
+			PPSGPIO:           dev.PPS.GPIO,
+			PPSSecondOffset:   dev.PPS.SecondOffset,
+			StatusInterval:    cfg.Logging.StatusInterval,
+			HoldoverMax:       dev.Holdover.Max,
+			RolloverPivot:     pivot,
+			MaxJump:           dev.MaxJump,
+			ClockAlarm:        dev.ClockAlarm,
+			NTPAlarm:          cfg.NTPCheck.Threshold,
+			Offset:            time.Duration(dev.Offset * float64(time.Second)),
+			SampleInterval:    dev.SampleInterval,
+			SampleAverage:     dev.SampleAverage,
+			Timescale:         timescale,
+			LeapTable:         leapTable,
+			Capture:           captureWriter,
+			Rebroadcast:       rebroadcast,
+			RecentSamples:     dev.Recent.Samples,
+			RecentTransitions: dev.Recent.Transitions,
+		}, set.outputs...)
+		bridges = append(bridges, b)
+
+		if dev.SCPI.Port != "" && !replay {
+			poller := scpi.NewPoller(dev.SCPI.Port, dev.SCPI.Interval, b.SetDiagnostics)
+			pollers[b] = poller
+			b.SetSurveyor(poller)
+			poller.SetIdentify(b.SetIdentity)
+			go poller.Run(ctx)
+		}
+		if dev.Chronyc.RefID != "" && !replay {
+			go chrony.Poll(ctx, dev.Chronyc.RefID, dev.Chronyc.Interval, b.SetChrony)
+		}
+		if dev.Display.I2C != "" {
+			// Opened before privileges are dropped
+			d, err := display.Open(dev.Display, b)
+			if err != nil {
+				fatal("Display error", "device", dev.Name, "error", err)
+			}
+			displays = append(displays, d)
+		}
+	}
+
+	if len(displays) > 0 {
+		// Stopped on return, also after a replay ends, so the displays are
+		// turned off
+		displayCtx, cancel := context.WithCancel(ctx)
+		var wg sync.WaitGroup
+		for _, d := range displays {
+			wg.Go(func() { d.Run(displayCtx) })
+		}
+		defer func() {
+			cancel()
+			wg.Wait()
+		}()
+	}
+
+	if servers := cfg.NTPCheck.Servers; len(servers) > 0 {
+		go func() {
+			// Let the first samples arrive before comparing them
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(10 * time.Second):
+			}
+			ntp.Poll(ctx, servers, cfg.NTPCheck.Interval, func(c *ntp.Check) {
+				for _, b := range bridges {
+					b.CheckNTP(c)
+				}
+			})
+		}()
+	}
+
+	if cfg.State.File != "" {
+		saved, err := state.Load(cfg.State.File)
+		if err != nil {
+			fatal("State error", "error", err)
+		}
+		saved.Restore(bridges...)
+	}
+
+	if cfg.HTTP.Listen != "" {
+		// Bind before privileges are dropped, the port may be privileged
+		listener, err := listen(cfg.HTTP.Listen, cfg.HTTP.Permissions)
+		if err != nil {
+			fatal("HTTP API error", "error", err)
+		}
+		server := api.New(bridges...)
+		if store != nil {
+			server.SetHistory(store)
+		}
+		go func() {
+			if err := server.Serve(ctx, listener); err != nil {
+				fatal("HTTP API error", "error", err)
+			}
+		}()
+	}
+
+	if cfg.Control.Socket != "" {
+		listener, err := listenUnix(cfg.Control.Socket, cfg.Control.Permissions)
+		if err != nil {
+			fatal("Control socket error", "error", err)
+		}
+		server := control.New(bridges...)
+		for b, poller := range pollers {
+			server.SetSCPI(b, poller)
+		}
+		go func() {
+			if err := server.Serve(ctx, listener); err != nil {
+				fatal("Control socket error", "error", err)
+			}
+		}()
+	}
+
+	if cfg.GRPC.Listen != "" {
+		listener, err := listen(cfg.GRPC.Listen, cfg.GRPC.Permissions)
+		if err != nil {
+			fatal("gRPC API error", "error", err)
+		}
+		server := grpcapi.New(cfg.GRPC, bridges...)
+		if store != nil {
+			server.SetHistory(store)
+		}
+		for b, poller := range pollers {
+			server.SetSCPI(b, poller)
+		}
+		go func() {
+			if err := server.Serve(ctx, listener); err != nil {
+				fatal("gRPC API error", "error", err)
+			}
+		}()
+	}
+
+	if store != nil {
+		// Stopped after the bridges so the last samples are written
+		historyCtx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			store.Run(historyCtx, bridges...)
+		}()
+		defer func() {
+			cancel()
+			<-done
+		}()
+	}
+
+	if cfg.State.File != "" {
+		// Stopped after the bridges so the final counters are saved
+		stateCtx, cancel := context.WithCancel(context.Background())
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			state.Run(stateCtx, cfg.State, bridges...)
+		}()
+		defer func() {
+			cancel()
+			<-done
+		}()
+	}
+
+	services, err := newServices(cfg, bridges)
+	if err != nil {
+		fatal("Service error", "error", err)
+	}
+	r := newReloader(ctx, cfg, reload, bridges, outputs, store)
+	defer r.stop()
+	for _, name := range serviceNames {
+		if run, ok := services[name]; ok {
+			r.startService(name, run)
+		}
+	}
+	if reload != nil {
+		go r.watch()
+	}
+
+	go func() {
+		if !waitReady(ctx, bridges) {
+			return
+		}
+		if creds != nil {
+			if err := privilege.Drop(*creds); err != nil {
+				fatal("Dropping privileges failed", "error", err)
+			}
+			slog.Info("Dropped privileges", "user", cfg.Privileges.User, "uid", creds.UID, "gid", creds.GID)
+		}
+		daemon.Ready()
+		notifySystemd(ctx, bridges)
+	}()
+	if replay {
+		err = replayCapture(ctx, bridges[0], cfg.Replay)
+	} else {
+		err = runBridges(ctx, bridges)
+	}
+	if err != nil {
+		fatal("Bridge error", "error", err)
+	}
+}
+
+// reopenLog reopens the log file on every SIGUSR1 until ctx is cancelled,
+// for rotation by an external tool such as logrotate
+func reopenLog(ctx context.Context) {
+	usr1 := make(chan os.Signal, 1)
+	signal.Notify(usr1, syscall.SIGUSR1)
+	defer signal.Stop(usr1)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-usr1:
+			if err := logging.Reopen(); err != nil {
+				fmt.Fprintf(os.Stderr, "Log file reopen error: %v\n", err)
+				continue
+			}
+			slog.Info("Log file reopened")
+		}
+	}
+}
+
+// waitDevices waits up to the serial wait of dev for its serial port and
+// PPS devices to appear. Without a wait only the serial port has to exist.
+func waitDevices(ctx context.Context, dev config.Device) error {
+	for _, path := range []string{dev.Serial.Port, dev.PPS.Device, dev.PPS.Port} {
+		if path == "" || path == bridge.AutoPort || path == bridge.StdinPort || netserial.IsAddress(path) {
+			continue
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			continue
+		}
+		if dev.Serial.Wait <= 0 {
+			if path == dev.Serial.Port {
+				return fmt.Errorf("serial port %s does not exist", path)
+			}
+			continue
+		}
+
+		slog.Info("Waiting for device to appear", "device", dev.Name, "path", path, "timeout", dev.Serial.Wait)
+		systemd.Notify(systemd.ExtendTimeout(dev.Serial.Wait))
+		systemd.Notify(systemd.Status("Waiting for " + path))
+		start := time.Now()
+		if err := devwait.Wait(ctx, path, dev.Serial.Wait); err != nil {
+			return err
+		}
+		slog.Info("Device appeared", "device", dev.Name, "path", path, "after", time.Since(start).Round(time.Millisecond))
+	}
+	return nil
+}
+
+// outputSet is the running outputs of one device
+type outputSet struct {
+	outputs []bridge.Output
+	closers []io.Closer
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+}
+
+// stop stops the outputs and waits for their goroutines to exit. They must
+// be removed from the bridge first.
+func (s *outputSet) stop() {
+	s.cancel()
+	s.wg.Wait()
+	for _, c := range s.closers {
+		c.Close()
+	}
+}
+
+// startOutputs creates the outputs configured for dev, recording its samples
+// in store if not nil and taking TAI-UTC from leap if not nil. A dry run prints the chrony samples to stdout and
+// skips SHM, PHC and kernel discipline so no clock is ever steered.
+func startOutputs(ctx context.Context, dev config.Device, dryRun bool, store *history.Store, leap *leapsec.Table) (*outputSet, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	set := &outputSet{cancel: cancel}
+
+	if dryRun {
+		set.outputs = append(set.outputs, chrony.NewDryRun(os.Stdout))
+	} else if dev.Outputs.Chrony.Socket != "" {
+		chronyClient := chrony.NewClient(dev.Outputs.Chrony.Socket)
+		set.wg.Go(func() { chronyClient.Run(ctx) })
+		set.outputs = append(set.outputs, chronyClient)
+	}
+
+	if dev.Outputs.SHM.Unit >= 0 && !dryRun {
+		segment, err := shm.Open(dev.Outputs.SHM.Unit)
+		if err != nil {
+			set.stop()
+			return nil, fmt.Errorf("NTP SHM: %w", err)
+		}
+		set.closers = append(set.closers, segment)
+		set.outputs = append(set.outputs, segment)
+	}
+
+	if dev.Outputs.PHC.Device != "" && !dryRun {
+		if dev.PPS.Device == "" && dev.PPS.ModemLine() == "" && dev.PPS.GPIO == "" {
+			slog.Warn("PHC steered without PPS is only as accurate as the serial time code", "device", dev.Name, "phc", dev.Outputs.PHC.Device)
+		}
+		clock, err := phc.Open(dev.Outputs.PHC.Device, phc.Options{TAI: dev.Outputs.PHC.TAI, Step: dev.Outputs.PHC.Step, Leap: leap})
+		if err != nil {
+			set.stop()
+			return nil, fmt.Errorf("PHC: %w", err)
+		}
+		set.closers = append(set.closers, clock)
+		set.outputs = append(set.outputs, clock)
+	}
+
+	if dev.Outputs.Kernel.Enabled && !dryRun {
+		discipline, err := adjtimex.Open(adjtimex.Options{PPSDevice: dev.PPS.Device, Step: dev.Outputs.Kernel.Step})
+		if err != nil {
+			set.stop()
+			return nil, fmt.Errorf("kernel discipline: %w", err)
+		}
+		set.closers = append(set.closers, discipline)
+		set.outputs = append(set.outputs, discipline)
+	}
+
+	if dev.Outputs.GPSD.Listen != "" {
+		server, err := gpsd.Listen(dev.Outputs.GPSD.Listen, dev.Serial.Port, dev.Protocol)
+		if err != nil {
+			set.stop()
+			return nil, fmt.Errorf("gpsd: %w", err)
+		}
+		set.wg.Go(func() { server.Serve(ctx) })
+		set.outputs = append(set.outputs, server)
+	}
+
+	if dev.Outputs.NTP.Listen != "" {
+		server, err := ntp.Listen(dev.Outputs.NTP.Listen)
+		if err != nil {
+			set.stop()
+			return nil, fmt.Errorf("NTP: %w", err)
+		}
+		set.wg.Go(func() { server.Serve(ctx) })
+		set.outputs = append(set.outputs, server)
+	}
+
+	if len(dev.Outputs.LED.GPIO) > 0 {
+		light, err := led.Open(dev.Outputs.LED.GPIO, dev.Outputs.LED.ActiveLow, dev.SampleInterval)
+		if err != nil {
+			set.stop()
+			return nil, fmt.Errorf("LED: %w", err)
+		}
+		set.wg.Go(func() { light.Run(ctx) })
+		set.closers = append(set.closers, light)
+		set.outputs = append(set.outputs, light)
+	}
+
+	if dev.Outputs.CSV.File != "" {
+		opts := csvlog.Options{
+			MaxSize:  int64(dev.Outputs.CSV.MaxSizeMB) << 20,
+			MaxFiles: dev.Outputs.CSV.MaxFiles,
+			Created: func(path string) error {
+				return privilege.Chown(path, dev.Outputs.CSV.Permissions.Owner, dev.Outputs.CSV.Permissions.Group)
+			},
+		}
+		// Validated by config.Parse
+		if mode, ok, _ := dev.Outputs.CSV.Permissions.FileMode(); ok {
+			opts.Mode = mode
+		}
+		writer, err := csvlog.Open(dev.Outputs.CSV.File, opts)
+		if err != nil {
+			set.stop()
+			return nil, fmt.Errorf("CSV: %w", err)
+		}
+		set.closers = append(set.closers, writer)
+		set.outputs = append(set.outputs, writer)
+	}
+
+	if dev.Outputs.FIFO.Path != "" {
+		opts := fifo.Options{
+			Created: func(path string) error {
+				return privilege.Chown(path, dev.Outputs.FIFO.Permissions.Owner, dev.Outputs.FIFO.Permissions.Group)
+			},
+		}
+		// Validated by config.Parse
+		if mode, ok, _ := dev.Outputs.FIFO.Permissions.FileMode(); ok {
+			opts.Mode = mode
+		}
+		writer, err := fifo.Open(dev.Outputs.FIFO.Path, opts)
+		if err != nil {
+			set.stop()
+			return nil, fmt.Errorf("FIFO: %w", err)
+		}
+		set.closers = append(set.closers, writer)
+		set.outputs = append(set.outputs, writer)
+	}
+
+	if store != nil {
+		set.outputs = append(set.outputs, store.Output(dev.Name))
+	}
+
+	return set, nil
+}
+
+// serviceNames are the config sections of the background services, in
+// start order
+var serviceNames = []string{"influxdb", "prometheus", "mqtt", "dbus", "webhook", "smtp"}
+
+// newServices creates the background services enabled by cfg, keyed by
+// config section
+func newServices(cfg *config.Config, bridges []*bridge.Bridge) (map[string]func(context.Context), error) {
+	services := make(map[string]func(context.Context))
+	if cfg.InfluxDB.URL != "" {
+		services["influxdb"] = influx.New(cfg.InfluxDB, bridges...).Run
+	}
+	if cfg.Prometheus.Textfile != "" {
+		services["prometheus"] = prometheus.NewTextfile(cfg.Prometheus, bridges...).Run
+	}
+	if cfg.MQTT.Broker != "" {
+		services["mqtt"] = mqtt.New(cfg.MQTT, bridges...).Run
+	}
+	if cfg.DBus.Bus != "" {
+		services["dbus"] = dbus.New(cfg.DBus, bridges...).Run
+	}
+	if cfg.Webhook.URL != "" {
+		services["webhook"] = notify.NewWebhook(cfg.Webhook, bridges...).Run
+	}
+	if cfg.SMTP.Server != "" {
+		email, err := notify.NewEmail(cfg.SMTP, bridges...)
+		if err != nil {
+			return nil, fmt.Errorf("email alerts: %w", err)
+		}
+		services["smtp"] = email.Run
+	}
+	return services, nil
+}
+
+// runBridges runs every bridge until ctx is cancelled, returning the first
+// error
+func runBridges(ctx context.Context, bridges []*bridge.Bridge) error {
+	errs := make(chan error, len(bridges))
+	for _, b := range bridges {
+		go func() {
+			if err := b.Run(ctx); err != nil && b.Name() != "" {
+				errs <- fmt.Errorf("%s: %w", b.Name(), err)
+			} else {
+				errs <- err
+			}
+		}()
+	}
+
+	for range bridges {
+		if err := <-errs; err != nil {
+			return err
+		}
+	}
+	return nil
+}
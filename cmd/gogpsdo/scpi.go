@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/internal/control"
+)
+
+// cmdSCPI sends SCPI commands to the receiver through the control socket of
+// a running bridge, so the port doesn't have to be freed for a terminal
+func cmdSCPI(args []string) error {
+	fs := newFlagSet("scpi", "[flags]")
+	socket := fs.String("socket", control.DefaultSocket, "Control socket of the bridge")
+	device := fs.String("device", "", "Send the commands to this device, needed with several")
+	cmd := fs.String("cmd", "", "Send this command, print the response and exit instead of reading commands from standard input")
+	logPath := fs.String("log", "", "Append the commands sent and their responses to this file")
+	fs.Parse(args)
+
+	var log io.Writer = io.Discard
+	if *logPath != "" {
+		f, err := os.OpenFile(*logPath, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		log = f
+	}
+
+	send := func(line string) error {
+		request := "scpi " + line
+		if *device != "" {
+			request += " device=" + *device
+		}
+		body, err := control.Request(*socket, request)
+		fmt.Fprintf(log, "%s > %s\n", time.Now().Format(time.RFC3339), line)
+		if err != nil {
+			fmt.Fprintf(log, "error: %v\n", err)
+			return err
+		}
+		fmt.Fprint(log, body)
+		fmt.Print(body)
+		return nil
+	}
+
+	if *cmd != "" {
+		return send(*cmd)
+	}
+
+	// Prompt only at a terminal, so commands can be piped in
+	interactive := false
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 {
+		interactive = true
+		fmt.Println("Commands are sent to the SCPI port of the running bridge, quit or end of input exits")
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	for {
+		if interactive {
+			fmt.Print("scpi > ")
+		}
+		if !scanner.Scan() {
+			break
+		}
+		line := strings.TrimSpace(scanner.Text())
+		switch line {
+		case "":
+			continue
+		case "quit", "exit":
+			return nil
+		}
+		if err := send(line); err != nil {
+			fmt.Fprintf(os.Stderr, "error: %v\n", err)
+		}
+	}
+	if interactive {
+		fmt.Println()
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/internal/pty"
+	"github.com/karlcswanson/gogpsdo/internal/simulate"
+)
+
+var simulateStatus = map[string]gpsdo.Status{
+	"locked":   gpsdo.Locked,
+	"holdover": gpsdo.Holdover,
+	"powerup":  gpsdo.PowerUp,
+	"unknown":  gpsdo.Unknown,
+}
+
+// cmdSimulate emits Z3805A TOD packets to a pty or an existing device or
+// file
+func cmdSimulate(args []string) error {
+	fs := newFlagSet("simulate", "[flags]")
+	out := fs.String("out", "", "Write packets to this device or file instead of a new pty")
+	link := fs.String("link", "", "Symlink this path to the pty, e.g. /tmp/ttyZ3805A")
+	status := fs.String("status", "locked", "Reported status (locked, holdover, powerup, unknown)")
+	leapSeconds := fs.Int("leap-seconds", 18, "Reported GPS-UTC leap seconds")
+	interval := fs.Duration("interval", 2*time.Second, "Time between packets")
+	corrupt := fs.Float64("corrupt", 0, "Probability 0-1 that a packet is corrupted")
+	fs.Parse(args)
+
+	cfg := simulate.Config{
+		Interval:    *interval,
+		LeapSeconds: *leapSeconds,
+		Corrupt:     *corrupt,
+	}
+	var ok bool
+	if cfg.Status, ok = simulateStatus[*status]; !ok {
+		return fmt.Errorf("unknown status %q", *status)
+	}
+
+	var w io.Writer
+	if *out != "" {
+		f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+		slog.Info("Simulating Z3805A", "out", *out)
+	} else {
+		master, slave, err := pty.Open()
+		if err != nil {
+			return err
+		}
+		defer master.Close()
+		// Holding the slave open keeps the pty usable between bridge restarts
+		defer slave.Close()
+		w = master
+
+		path := slave.Name()
+		if *link != "" {
+			os.Remove(*link)
+			if err := os.Symlink(path, *link); err != nil {
+				return err
+			}
+			defer os.Remove(*link)
+			path = *link
+		}
+		slog.Info("Simulating Z3805A", "pty", slave.Name(), "port", path)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	return simulate.Run(ctx, w, cfg)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo/bridge"
+	"github.com/karlcswanson/gogpsdo/internal/systemd"
+)
+
+// waitReady waits until every bridge has opened its devices, returning false
+// if ctx is cancelled first
+func waitReady(ctx context.Context, bridges []*bridge.Bridge) bool {
+	for _, b := range bridges {
+		select {
+		case <-ctx.Done():
+			return false
+		case <-b.Ready():
+		}
+	}
+	return true
+}
+
+// notifySystemd reports READY=1 and then sends WATCHDOG=1 heartbeats for as
+// long as packets keep arriving on any bridge, so a hung process lets systemd
+// restart the service. It must be called once every bridge is ready.
+func notifySystemd(ctx context.Context, bridges []*bridge.Bridge) {
+	if err := systemd.Notify(systemd.Ready); err != nil {
+		slog.Warn("systemd notify failed", "error", err)
+	}
+
+	interval := systemd.WatchdogInterval()
+	if interval == 0 {
+		return
+	}
+	slog.Info("systemd watchdog enabled", "interval", interval)
+
+	readyAt := time.Now()
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			var last time.Time
+			for _, b := range bridges {
+				if packet := b.Stats().LastPacket; packet.After(last) {
+					last = packet
+				}
+			}
+			if last.IsZero() {
+				last = readyAt
+			}
+
+			if age := time.Since(last); age >= interval {
+				slog.Warn("No packets received, withholding watchdog heartbeat", "age", age.Truncate(time.Second))
+				continue
+			}
+			if err := systemd.Notify(systemd.Watchdog); err != nil {
+				slog.Warn("systemd watchdog notify failed", "error", err)
+			}
+		}
+	}
+}
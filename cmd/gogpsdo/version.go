@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// cmdVersion prints the module version, VCS revision and Go toolchain
+func cmdVersion(args []string) error {
+	newFlagSet("version", "").Parse(args)
+
+	version, revision, built, modified, tags := "(devel)", "unknown", "", false, ""
+	if info, ok := debug.ReadBuildInfo(); ok {
+		version = info.Main.Version
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				revision = s.Value
+			case "vcs.time":
+				built = s.Value
+			case "vcs.modified":
+				modified = s.Value == "true"
+			case "-tags":
+				tags = s.Value
+			}
+		}
+	}
+
+	if modified {
+		revision += "-dirty"
+	}
+	fmt.Printf("gogpsdo %s\n", version)
+	fmt.Printf("commit  %s %s\n", revision, built)
+	fmt.Printf("go      %s %s/%s\n", runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	if tags != "" {
+		fmt.Printf("tags    %s\n", tags)
+	}
+	return nil
+}
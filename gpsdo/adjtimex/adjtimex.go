@@ -0,0 +1,147 @@
+// Package adjtimex disciplines the system clock to the GPSDO samples through
+// the kernel NTP discipline, for systems that run neither chrony nor ntpd.
+// Each sample offset is passed to the kernel PLL, and with a PPS device the
+// kernel also takes the frequency from the PPS edges (STA_PPSFREQ).
+//
+// See https://docs.kernel.org/timers/timekeeping.html and RFC 1589
+package adjtimex
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/pps"
+	"golang.org/x/sys/unix"
+)
+
+// timeConstant is the kernel PLL time constant for one sample a second
+const timeConstant = 2
+
+// Estimated error in microseconds of serial time-of-day and PPS paired
+// samples
+const (
+	errorTOD = 1000
+	errorPPS = 1
+)
+
+// Options configure the discipline
+type Options struct {
+	// PPSDevice is bound to the kernel discipline for its frequency, empty
+	// for the PLL alone
+	PPSDevice string
+	// Step is the offset above which the clock is stepped instead of
+	// slewed, 0 to never step
+	Step time.Duration
+}
+
+// Discipline steers the system clock through adjtimex
+type Discipline struct {
+	opts Options
+	pps  *pps.Source
+}
+
+// Open starts disciplining the system clock. Binding the PPS device is best
+// effort, without it the kernel PLL alone steers the clock.
+func Open(opts Options) (*Discipline, error) {
+	// Start unsynchronized, which also checks the process may adjust the
+	// clock before the first sample
+	tx := unix.Timex{Modes: unix.ADJ_STATUS, Status: unix.STA_UNSYNC}
+	if _, err := unix.Adjtimex(&tx); err != nil {
+		return nil, fmt.Errorf("adjtimex: %w", err)
+	}
+
+	d := &Discipline{opts: opts}
+	if opts.PPSDevice != "" {
+		source, err := pps.Open(opts.PPSDevice)
+		if err == nil {
+			err = source.BindKernel(true)
+			if err != nil {
+				source.Close()
+			}
+		}
+		if err != nil {
+			slog.Warn("PPS not bound to the kernel discipline, steering with the PLL alone", "device", opts.PPSDevice, "error", err)
+		} else {
+			d.pps = source
+		}
+	}
+
+	slog.Info("Disciplining the system clock through adjtimex", "pps", d.pps != nil)
+	return d, nil
+}
+
+// Name identifies the output in logs
+func (d *Discipline) Name() string {
+	return "adjtimex"
+}
+
+// Send steps the clock when it is more than Step off and otherwise passes
+// the offset of the sample to the kernel PLL
+func (d *Discipline) Send(data *gpsdo.Sample) error {
+	offset := data.SystemOffset()
+	if d.opts.Step > 0 && offset.Abs() > d.opts.Step {
+		tx := unix.Timex{Modes: unix.ADJ_SETOFFSET | unix.ADJ_NANO}
+		sec, nsec := int64(offset/time.Second), int64(offset%time.Second)
+		if nsec < 0 {
+			// The nanoseconds must be positive
+			sec, nsec = sec-1, nsec+int64(time.Second)
+		}
+		setLong(&tx.Time.Sec, sec)
+		setLong(&tx.Time.Usec, nsec)
+		if _, err := unix.Adjtimex(&tx); err != nil {
+			return fmt.Errorf("step: %w", err)
+		}
+		slog.Info("System clock stepped", "offset", offset)
+		return nil
+	}
+
+	status := unix.STA_PLL | unix.STA_NANO
+	if d.pps != nil {
+		status |= unix.STA_PPSFREQ
+	}
+	switch data.Leap {
+	case gpsdo.LeapInsert:
+		status |= unix.STA_INS
+	case gpsdo.LeapDelete:
+		status |= unix.STA_DEL
+	}
+	estError := int64(errorTOD)
+	if !data.PPS.IsZero() {
+		estError = errorPPS
+	}
+
+	// Leaving STA_UNSYNC out of the status marks the clock synchronized
+	tx := unix.Timex{
+		Modes:  unix.ADJ_OFFSET | unix.ADJ_STATUS | unix.ADJ_NANO | unix.ADJ_TIMECONST | unix.ADJ_MAXERROR | unix.ADJ_ESTERROR,
+		Status: int32(status),
+	}
+	setLong(&tx.Offset, offset.Nanoseconds())
+	setLong(&tx.Constant, timeConstant)
+	setLong(&tx.Maxerror, estError)
+	setLong(&tx.Esterror, estError)
+	if _, err := unix.Adjtimex(&tx); err != nil {
+		return fmt.Errorf("adjtimex: %w", err)
+	}
+	slog.Debug("System clock adjusted", "offset", offset, "freq_ppm", float64(tx.Freq)/65536)
+	return nil
+}
+
+// Close unbinds the PPS device and marks the clock unsynchronized. The
+// clock keeps running at its last frequency.
+func (d *Discipline) Close() error {
+	if d.pps != nil {
+		d.pps.BindKernel(false)
+		d.pps.Close()
+	}
+	tx := unix.Timex{Modes: unix.ADJ_STATUS, Status: unix.STA_UNSYNC}
+	_, err := unix.Adjtimex(&tx)
+	return err
+}
+
+// setLong sets a C long field of struct timex, which is 32 bits on 32-bit
+// platforms
+func setLong[T ~int32 | ~int64](field *T, v int64) {
+	*field = T(v)
+}
@@ -0,0 +1,28 @@
+package bridge
+
+import (
+	"strings"
+	"time"
+)
+
+// antennaFault reports whether the antenna status of the receiver, such as
+// OPEN or SHORT, is a fault. An empty status is unknown rather than a fault.
+func antennaFault(status string) bool {
+	status = strings.TrimSpace(status)
+	return status != "" && !strings.EqualFold(status, "OK")
+}
+
+// checkAntenna raises or clears the antenna alarm on a new antenna status.
+// It must be called with the mutex held.
+func (b *Bridge) checkAntenna(status string) {
+	fault := antennaFault(status)
+	switch {
+	case fault && b.stats.AntennaFaultSince.IsZero():
+		b.stats.AntennaFaultSince = time.Now()
+		b.stats.AntennaFaults++
+		b.log.Warn("Antenna fault, check the antenna, cable and lightning arrestor", "antenna", status)
+	case !fault && status != "" && !b.stats.AntennaFaultSince.IsZero():
+		b.stats.AntennaFaultSince = time.Time{}
+		b.log.Info("Antenna fault cleared", "antenna", status)
+	}
+}
@@ -0,0 +1,840 @@
+// Package bridge reads time-of-day packets from a GPSDO serial port and
+// forwards the parsed samples to chrony and other outputs.
+package bridge
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/capture"
+	"github.com/karlcswanson/gogpsdo/gpsdo/chrony"
+	"github.com/karlcswanson/gogpsdo/gpsdo/generic"
+	"github.com/karlcswanson/gogpsdo/gpsdo/leapsec"
+	"github.com/karlcswanson/gogpsdo/gpsdo/pps"
+	"github.com/karlcswanson/gogpsdo/gpsdo/protocol"
+	"github.com/karlcswanson/gogpsdo/gpsdo/stability"
+	"github.com/karlcswanson/gogpsdo/gpsdo/z3805a"
+)
+
+// Config describes the bridge input
+type Config struct {
+	// Name identifies the bridge in logs when several run in one process
+	Name string
+	// Port is the serial port, or AutoPort to probe for it
+	Port string
+	// AutoExclude are ports AutoPort never probes, such as those of other
+	// devices
+	AutoExclude []string
+	// Protocol is the name of a registered protocol.Parser
+	Protocol string
+	// Generic is the packet layout of the generic protocol
+	Generic generic.Layout
+	// Z3805A is the packet framing of the z3805a protocol
+	Z3805A z3805a.Format
+	// PPSDevice is an optional kernel PPS device paired with the TOD stream
+	PPSDevice string
+	// PPSLine pairs the TOD stream with edges of a modem status line
+	// instead of a kernel PPS device, zero for none
+	PPSLine pps.Line
+	// PPSPort is the serial port of PPSLine, Port when empty
+	PPSPort string
+	// PPSGPIO pairs the TOD stream with edges of a GPIO line, as chip:line
+	PPSGPIO string
+	// Serial overrides the port settings of Protocol that are not zero
+	Serial protocol.SerialDefaults
+	// PPSSecondOffset is added to the TOD time to get the time of the most
+	// recent PPS edge, e.g. -1 if the receiver announces the upcoming edge
+	PPSSecondOffset int
+	// StatusInterval is how often the status summary is logged
+	StatusInterval time.Duration
+	// HoldoverMax stops forwarding samples once the GPSDO has been in
+	// holdover this long, zero for no limit
+	HoldoverMax time.Duration
+	// RolloverPivot is the earliest plausible receiver time. Earlier samples
+	// are moved forward by whole GPS week rollover periods. Defaults to
+	// gpsdo.DefaultPivot.
+	RolloverPivot time.Time
+	// MaxJump rejects valid samples that differ from the time predicted by
+	// the previous sample by more than this, zero to disable
+	MaxJump time.Duration
+	// ClockAlarm raises an alarm when valid samples differ from the system
+	// clock by more than this, zero to disable
+	ClockAlarm time.Duration
+	// NTPAlarm raises an alarm when valid samples differ from the NTP
+	// reference servers passed to CheckNTP by more than this, zero to
+	// disable
+	NTPAlarm time.Duration
+	// Offset is the static calibration added to every sample
+	Offset time.Duration
+	// Timescale is the time scale of the samples sent to the outputs, UTC
+	// by default. TAI and GPS need TAI-UTC from LeapTable or the receiver.
+	Timescale gpsdo.Timescale
+	// SampleInterval sends one sample per interval of GPS time to the
+	// outputs, zero to send every sample
+	SampleInterval time.Duration
+	// SampleAverage sends the mean offset of the samples of each interval
+	// instead of its first sample
+	SampleAverage bool
+	// LeapTable is checked against the leap seconds the receiver reports,
+	// nil to trust the receiver
+	LeapTable *leapsec.Table
+	// Capture records the raw input when set
+	Capture *capture.Writer
+	// Rebroadcast receives a copy of the raw input when set. Its writes
+	// must not block.
+	Rebroadcast io.Writer
+	// RecentSamples is the number of samples kept in memory for Recent,
+	// zero to keep none
+	RecentSamples int
+	// RecentTransitions is the number of status transitions kept in memory,
+	// DefaultRecentTransitions if zero
+	RecentTransitions int
+}
+
+// Output is a destination for valid samples, such as the chrony SOCK
+// refclock or an NTP SHM segment
+type Output interface {
+	Name() string
+	Send(*gpsdo.Sample) error
+}
+
+// StatsOutput is implemented by outputs that track their own connection
+// counters, which are included in the bridge Stats
+type StatsOutput interface {
+	Output
+	OutputStats() gpsdo.OutputStats
+}
+
+// Stats holds the bridge packet counters
+type Stats struct {
+	TotalPackets  uint64
+	ValidPackets  uint64
+	FramingErrors uint64
+	// ChecksumErrors counts the packets rejected for a bad checksum,
+	// RangeErrors those with an implausible field and MalformedPackets
+	// those that couldn't be decoded otherwise
+	ChecksumErrors   uint64
+	RangeErrors      uint64
+	MalformedPackets uint64
+	SentSamples      uint64
+	DroppedSamples   uint64
+	PPSEdges         uint64
+	PPSPaired        uint64
+	LastUpdate       time.Time
+	// LastPacket is when any packet was last received, valid or not
+	LastPacket time.Time
+	// MissedPackets counts the packets expected but not received, in
+	// PacketGaps gaps
+	MissedPackets uint64
+	PacketGaps    uint64
+	// HoldoverSince is when the current holdover began, zero if locked
+	HoldoverSince     time.Time
+	LastHoldoverEntry time.Time
+	LastHoldoverExit  time.Time
+	// HoldoverRejected counts samples not forwarded due to HoldoverMax
+	HoldoverRejected uint64
+	// HoldoverEpisodes counts the holdovers entered since the bridge
+	// started. LastHoldoverDuration is the length of the last one to end,
+	// and LongestHoldover that of the longest, including the current one.
+	HoldoverEpisodes     uint64
+	LastHoldoverDuration time.Duration
+	LongestHoldover      time.Duration
+	// PowerUpSince is when the GPSDO was first seen powering up, zero once
+	// it locked. LastTimeToLock is how long the last lock after power-up
+	// took.
+	PowerUpSince   time.Time
+	LastTimeToLock time.Duration
+	// OutliersRejected counts samples dropped for jumping more than MaxJump
+	OutliersRejected uint64
+	// ClockAlarmSince is when the samples began to differ from the system
+	// clock by more than ClockAlarm, zero if they agree. ClockAlarms counts
+	// the alarms raised.
+	ClockAlarmSince time.Time
+	ClockAlarms     uint64
+	// NTPAlarmSince is when the samples began to differ from the NTP
+	// reference servers by more than NTPAlarm, zero if they agree.
+	// NTPAlarms counts the alarms raised.
+	NTPAlarmSince time.Time
+	NTPAlarms     uint64
+	// AntennaFaultSince is when the receiver began to report an antenna
+	// fault, zero if the antenna is OK or unknown. AntennaFaults counts the
+	// faults reported.
+	AntennaFaultSince time.Time
+	AntennaFaults     uint64
+	// InputConnected is false while the serial port is being reopened
+	InputConnected  bool
+	InputReconnects uint64
+	// Outputs holds the counters of each StatsOutput by name
+	Outputs map[string]gpsdo.OutputStats
+}
+
+// Bridge manages the GPSDO serial input and its outputs
+type Bridge struct {
+	config  Config
+	outputs []Output
+	mutex   sync.RWMutex
+	// sending is held while a sample is sent to the outputs
+	sending sync.Mutex
+	stats   Stats
+	current *gpsdo.Sample
+	// lastStatus is the status before the first sample, restored from the
+	// previous run or Unknown
+	lastStatus gpsdo.Status
+	// configOffset is the configured calibration offset, before changes at
+	// runtime
+	configOffset time.Duration
+
+	lastEdge   pps.Edge
+	pairedEdge uint32
+
+	leap         leapTracker
+	leapCheck    leapCheck
+	leapMismatch string
+	// unconverted is set while samples are dropped for lack of TAI-UTC
+	unconverted     bool
+	holdoverExpired bool
+	rolloverLogged  bool
+	outliers        outlierFilter
+	decimate        decimator
+	gaps            gapTracker
+	clock           clockCheck
+	stability       *stability.Tracker
+
+	ready       chan struct{}
+	transitions []Transition
+	recent      sampleRing
+	// acquisitions are the last locks after power-up, oldest first
+	acquisitions []LockAcquisition
+	diagnostics  gpsdo.Diagnostics
+	identity     gpsdo.Identity
+	// positionState is the last position mode logged by checkPosition
+	positionState positionState
+	// surveyor, or else surveyPort, controls the position survey
+	surveyor   Surveyor
+	surveyPort *surveyPort
+	chrony     *chrony.Tracking
+	ntp        *NTPCheck
+
+	subMutex    sync.Mutex
+	subscribers map[chan Event]struct{}
+
+	log *slog.Logger
+}
+
+// New creates a bridge for the given input. Valid samples are sent to every
+// output.
+func New(config Config, outputs ...Output) *Bridge {
+	if config.Protocol == "" {
+		config.Protocol = protocol.Default
+	}
+	if config.StatusInterval <= 0 {
+		config.StatusInterval = 30 * time.Second
+	}
+	if config.RolloverPivot.IsZero() {
+		config.RolloverPivot = gpsdo.DefaultPivot()
+	}
+	if config.RecentTransitions <= 0 {
+		config.RecentTransitions = DefaultRecentTransitions
+	}
+	log := slog.Default()
+	if config.Name != "" {
+		log = log.With("device", config.Name)
+	}
+	return &Bridge{
+		config:       config,
+		outputs:      outputs,
+		lastStatus:   gpsdo.Unknown,
+		configOffset: config.Offset,
+		ready:        make(chan struct{}),
+		leap:         leapTracker{log: log},
+		leapCheck:    leapCheck{table: config.LeapTable, log: log},
+		outliers:     outlierFilter{maxJump: config.MaxJump},
+		clock:        clockCheck{threshold: config.ClockAlarm},
+		decimate:     decimator{interval: config.SampleInterval, average: config.SampleAverage},
+		stability:    stability.NewTracker(stabilityWindow),
+		recent:       newSampleRing(config.RecentSamples),
+		log:          log,
+	}
+}
+
+// Name returns the configured bridge name
+func (b *Bridge) Name() string {
+	return b.config.Name
+}
+
+// Ready is closed once the serial port and PPS device are open
+func (b *Bridge) Ready() <-chan struct{} {
+	return b.ready
+}
+
+// Stats returns a snapshot of the packet and output counters
+func (b *Bridge) Stats() Stats {
+	b.mutex.RLock()
+	stats := b.stats
+	outputs := b.outputs
+	b.mutex.RUnlock()
+
+	stats.Outputs = make(map[string]gpsdo.OutputStats)
+	for _, out := range outputs {
+		if so, ok := out.(StatsOutput); ok {
+			stats.Outputs[out.Name()] = so.OutputStats()
+		}
+	}
+	return stats
+}
+
+// Current returns the most recently parsed sample, or nil
+func (b *Bridge) Current() *gpsdo.Sample {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.current
+}
+
+// SetDiagnostics stores receiver diagnostics read outside the TOD stream
+func (b *Bridge) SetDiagnostics(d gpsdo.Diagnostics) {
+	b.mutex.Lock()
+	b.diagnostics = d
+	b.checkAntenna(d.Antenna)
+	b.checkPosition(d.Position)
+	b.mutex.Unlock()
+}
+
+// Diagnostics returns the last receiver diagnostics, zero if none
+func (b *Bridge) Diagnostics() gpsdo.Diagnostics {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.diagnostics
+}
+
+// SetChrony stores chronyd's view of the refclock fed by the bridge, nil if
+// chronyd can't be queried
+func (b *Bridge) SetChrony(t *chrony.Tracking) {
+	b.mutex.Lock()
+	b.chrony = t
+	b.mutex.Unlock()
+}
+
+// Chrony returns chronyd's view of the refclock, nil if unknown
+func (b *Bridge) Chrony() *chrony.Tracking {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.chrony
+}
+
+// ResetCounters zeroes the packet, sample, PPS, rejection, reconnect, clock
+// alarm, NTP alarm and antenna fault counters. The holdover and lock times
+// and output counters are kept.
+func (b *Bridge) ResetCounters() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.stats.TotalPackets = 0
+	b.stats.ValidPackets = 0
+	b.stats.FramingErrors = 0
+	b.stats.ChecksumErrors = 0
+	b.stats.RangeErrors = 0
+	b.stats.MalformedPackets = 0
+	b.stats.SentSamples = 0
+	b.stats.DroppedSamples = 0
+	b.stats.PPSEdges = 0
+	b.stats.PPSPaired = 0
+	b.stats.HoldoverRejected = 0
+	b.stats.OutliersRejected = 0
+	b.stats.InputReconnects = 0
+	b.stats.MissedPackets = 0
+	b.stats.PacketGaps = 0
+	b.stats.ClockAlarms = 0
+	b.stats.NTPAlarms = 0
+	b.stats.AntennaFaults = 0
+}
+
+// Offset returns the calibration offset added to each sample
+func (b *Bridge) Offset() time.Duration {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.config.Offset
+}
+
+// SetOffset changes the calibration offset for the following samples
+func (b *Bridge) SetOffset(offset time.Duration) {
+	b.mutex.Lock()
+	b.config.Offset = offset
+	b.mutex.Unlock()
+}
+
+// Outputs returns the outputs samples are sent to
+func (b *Bridge) Outputs() []Output {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.outputs
+}
+
+// SetOutputs replaces the outputs for the following samples, e.g. when the
+// config is reloaded. It returns once no sample is being sent to the previous
+// outputs, so they can be closed.
+func (b *Bridge) SetOutputs(outputs ...Output) {
+	b.sending.Lock()
+	defer b.sending.Unlock()
+	b.mutex.Lock()
+	b.outputs = outputs
+	b.mutex.Unlock()
+}
+
+func (b *Bridge) sendSample(data *gpsdo.Sample) {
+	if data == nil || !data.Valid {
+		return
+	}
+
+	if b.config.Timescale != gpsdo.UTC {
+		if data = b.convert(data); data == nil {
+			return
+		}
+	}
+
+	b.sending.Lock()
+	defer b.sending.Unlock()
+	for _, out := range b.Outputs() {
+		err := out.Send(data)
+
+		b.mutex.Lock()
+		if err != nil {
+			b.stats.DroppedSamples++
+		} else {
+			b.stats.SentSamples++
+		}
+		b.mutex.Unlock()
+
+		if err != nil {
+			b.log.Warn("Sample dropped", "output", out.Name(), "error", err)
+			continue
+		}
+		if b.log.Enabled(context.Background(), slog.LevelDebug) {
+			b.log.Debug("Sample sent", "output", out.Name(), "gps_time", data.Timestamp, "status", data.Status)
+		}
+	}
+}
+
+// convert returns a copy of data on the output time scale, or nil when
+// TAI-UTC is unknown
+func (b *Bridge) convert(data *gpsdo.Sample) *gpsdo.Sample {
+	tai := b.taiOffset(data, data.Timestamp)
+	if tai == 0 {
+		if !b.unconverted {
+			b.unconverted = true
+			b.log.Warn("Samples not sent, TAI-UTC is unknown without a leap second table or receiver leap seconds",
+				"timescale", b.config.Timescale)
+		}
+		return nil
+	}
+	if b.unconverted {
+		b.unconverted = false
+		b.log.Info("TAI-UTC known, sending samples again", "timescale", b.config.Timescale, "tai_offset", tai)
+	}
+
+	converted := *data
+	converted.Timestamp = data.Timestamp.Add(b.config.Timescale.Offset(tai))
+	converted.Timescale = b.config.Timescale
+	return &converted
+}
+
+// Run opens the serial port and reads it until ctx is cancelled. The port is
+// reopened if reads keep failing, such as when a USB adapter is unplugged.
+// StdinPort reads standard input until it ends instead.
+func (b *Bridge) Run(ctx context.Context) error {
+	parser, err := b.newParser()
+	if err != nil {
+		return err
+	}
+
+	if b.config.Port == StdinPort {
+		if _, ok := parser.(protocol.Poller); ok {
+			b.log.Warn("Polled receivers can't be queried through standard input, the input must carry the responses")
+		}
+		b.mutex.Lock()
+		b.stats.InputConnected = true
+		b.mutex.Unlock()
+		return b.runInput(ctx, newStdinInput(ctx, os.Stdin), parser)
+	}
+
+	input := &serialInput{
+		b:      b,
+		ctx:    ctx,
+		config: serialConfig(b.config.Port, parser.SerialDefaults(), b.config.Serial),
+		auto:   b.config.Port == AutoPort,
+	}
+	if identifier, ok := parser.(protocol.Identifier); ok {
+		input.identify = identifier.IdentityQuery()
+	}
+	if input.auto {
+		port, err := b.probe(ctx, *input.config)
+		if err != nil {
+			// Cancelled
+			return nil
+		}
+		input.config.Name = port
+		b.config.Port = port
+	}
+	if err := input.open(); err != nil {
+		return fmt.Errorf("failed to open serial port: %w", err)
+	}
+	defer input.close()
+
+	if surveyor, ok := parser.(protocol.Surveyor); ok {
+		b.mutex.Lock()
+		b.surveyPort = &surveyPort{input: input, parser: surveyor}
+		b.mutex.Unlock()
+		defer func() {
+			b.mutex.Lock()
+			b.surveyPort = nil
+			b.mutex.Unlock()
+		}()
+	}
+
+	if poller, ok := parser.(protocol.Poller); ok {
+		if !b.hasPPS() {
+			b.log.Warn("Polled time codes carry no sub-second timing, pair them with the 1PPS output")
+		}
+		ctx, cancel := context.WithCancel(ctx)
+		var wg sync.WaitGroup
+		defer wg.Wait()
+		defer cancel()
+		wg.Go(func() { input.poll(ctx, poller.Query()) })
+	}
+
+	return b.runInput(ctx, input, parser)
+}
+
+// RunInput reads raw GPSDO output from input until ctx is cancelled or the
+// input returns an error. io.EOF ends the input cleanly and returns nil.
+func (b *Bridge) RunInput(ctx context.Context, input io.Reader) error {
+	parser, err := b.newParser()
+	if err != nil {
+		return err
+	}
+	return b.runInput(ctx, input, parser)
+}
+
+func (b *Bridge) newParser() (protocol.Parser, error) {
+	return protocol.New(b.config.Protocol, protocol.Options{Generic: b.config.Generic, Z3805A: b.config.Z3805A})
+}
+
+func (b *Bridge) runInput(ctx context.Context, input io.Reader, parser protocol.Parser) error {
+	b.log.Info("Starting GPSDO bridge", "port", b.config.Port, "protocol", b.config.Protocol)
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	// Stops the PPS and status goroutines when the input ends
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var source ppsSource
+	switch {
+	case b.config.PPSDevice != "":
+		s, err := pps.Open(b.config.PPSDevice)
+		if err != nil {
+			return fmt.Errorf("failed to open PPS device: %w", err)
+		}
+		source = s
+		b.log.Info("PPS device opened", "device", b.config.PPSDevice)
+	case b.config.PPSLine != 0:
+		port := cmp.Or(b.config.PPSPort, b.config.Port)
+		s, err := pps.OpenModemLine(port, b.config.PPSLine)
+		if err != nil {
+			return fmt.Errorf("failed to open PPS line: %w", err)
+		}
+		source = s
+		b.log.Info("PPS on modem line enabled", "port", port, "line", b.config.PPSLine)
+	case b.config.PPSGPIO != "":
+		chip, line, err := pps.ParseGPIO(b.config.PPSGPIO)
+		if err != nil {
+			return err
+		}
+		s, err := pps.OpenGPIO(chip, line)
+		if err != nil {
+			return fmt.Errorf("failed to open PPS GPIO: %w", err)
+		}
+		source = s
+		b.log.Info("PPS on GPIO enabled", "chip", chip, "line", line)
+	}
+	if source != nil {
+		defer source.Close()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			b.readPPS(ctx, source)
+		}()
+	}
+
+	close(b.ready)
+
+	// Status reporting goroutine
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		b.reportStatus(ctx)
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		b.watchSilence(ctx)
+	}()
+
+	err := b.parse(&inputReader{b: b, ctx: ctx, input: input}, parser)
+	switch {
+	case errors.Is(err, io.EOF):
+		b.log.Info("End of input")
+		return nil
+	case ctx.Err() != nil:
+		return nil
+	}
+	return fmt.Errorf("read input: %w", err)
+}
+
+// capture records a chunk of raw input to the capture file and passes it
+// to the rebroadcast, if any
+func (b *Bridge) capture(chunk []byte) {
+	if b.config.Rebroadcast != nil {
+		b.config.Rebroadcast.Write(chunk)
+	}
+	if b.config.Capture == nil {
+		return
+	}
+	if err := b.config.Capture.Write(time.Now(), chunk); err != nil {
+		b.log.Warn("Capture write failed", "error", err)
+	}
+}
+
+// hasPPS reports whether the TOD stream is paired with a PPS source
+func (b *Bridge) hasPPS() bool {
+	return b.config.PPSDevice != "" || b.config.PPSLine != 0 || b.config.PPSGPIO != ""
+}
+
+// ppsSource is a kernel PPS device, a modem status line or a GPIO line
+type ppsSource interface {
+	Fetch(timeout time.Duration) (pps.Edge, error)
+	Close() error
+}
+
+func (b *Bridge) readPPS(ctx context.Context, source ppsSource) {
+	for ctx.Err() == nil {
+		edge, err := source.Fetch(2 * time.Second)
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			continue
+		}
+		if err != nil {
+			b.log.Error("PPS fetch failed", "error", err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		b.mutex.Lock()
+		b.lastEdge = edge
+		b.stats.PPSEdges++
+		b.mutex.Unlock()
+	}
+}
+
+// pairPPS attaches the most recent PPS edge to data if it occurred within
+// the second before the TOD packet was received
+func (b *Bridge) pairPPS(data *gpsdo.Sample) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	edge := b.lastEdge
+	since := data.ParseTime.Sub(edge.Time)
+	if edge.Time.IsZero() || since < 0 || since >= time.Second || edge.Sequence == b.pairedEdge {
+		return
+	}
+
+	b.pairedEdge = edge.Sequence
+	b.stats.PPSPaired++
+	data.PPS = edge.Time
+	data.Timestamp = data.Timestamp.Add(time.Duration(b.config.PPSSecondOffset) * time.Second)
+}
+
+func (b *Bridge) countPacket() {
+	b.mutex.Lock()
+	b.stats.TotalPackets++
+	b.stats.LastPacket = time.Now()
+	b.mutex.Unlock()
+}
+
+// countReject counts a packet rejected by the parser along with the counter
+// of its class, one of the Stats
+func (b *Bridge) countReject(class *uint64) {
+	b.mutex.Lock()
+	b.stats.TotalPackets++
+	b.stats.LastPacket = time.Now()
+	*class++
+	b.mutex.Unlock()
+}
+
+func (b *Bridge) handleSample(data *gpsdo.Sample) {
+	if data == nil {
+		return
+	}
+
+	if data.FixRollover(b.config.RolloverPivot) && !b.rolloverLogged {
+		b.rolloverLogged = true
+		b.log.Warn("Correcting GPS week rollover", "pivot", b.config.RolloverPivot.Format(time.DateOnly),
+			"corrected", data.Timestamp.Format(time.DateOnly))
+	}
+
+	if b.hasPPS() {
+		b.pairPPS(data)
+	}
+	data.Offset = b.Offset()
+	if !b.outliers.check(data) {
+		b.mutex.Lock()
+		b.stats.OutliersRejected++
+		b.gaps.reset()
+		b.mutex.Unlock()
+		b.log.Warn("Outlier sample rejected", "gps_time", data.Timestamp.Format("2006-002 15:04:05"),
+			"max_jump", b.config.MaxJump)
+		return
+	}
+	if data.Valid {
+		b.leap.apply(data)
+		b.leapCheck.apply(data)
+		b.countGap(data)
+		b.checkClock(data)
+	} else {
+		b.mutex.Lock()
+		b.gaps.reset()
+		b.mutex.Unlock()
+	}
+
+	b.mutex.Lock()
+	b.stats.ValidPackets++
+	b.stats.LastUpdate = time.Now()
+	transition := b.recordTransition(data)
+	b.recordOffset(data)
+	b.current = data
+	b.recent.add(data)
+	b.leapMismatch = b.leapCheck.mismatch
+	forward := b.trackHoldover(data)
+	b.trackLock(data)
+	b.mutex.Unlock()
+
+	// The attributes are only built when logged, the bridge must not
+	// allocate per sample on a quiet log
+	if b.log.Enabled(context.Background(), slog.LevelInfo) {
+		attrs := []any{
+			"gps_time", data.Timestamp.Format("2006-002 15:04:05"),
+			"status", data.Status,
+			"valid", data.Valid,
+			"leap_seconds", data.LeapSeconds,
+			"leap", data.Leap,
+		}
+		if !data.PPS.IsZero() {
+			attrs = append(attrs, "offset", data.Timestamp.Sub(data.PPS).Seconds())
+		}
+		b.log.Info("GPSDO sample", attrs...)
+	}
+
+	b.publish(data, transition)
+
+	// Send to chrony, SHM, ...
+	if forward && data.Valid {
+		if sample := b.decimate.add(data); sample != nil {
+			b.sendSample(sample)
+		}
+	}
+}
+
+func (b *Bridge) reportStatus(ctx context.Context) {
+	ticker := time.NewTicker(b.config.StatusInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stats := b.Stats()
+			data := b.Current()
+
+			attrs := []any{
+				slog.Group("packets",
+					"total", stats.TotalPackets,
+					"valid", stats.ValidPackets,
+					"framing_errors", stats.FramingErrors,
+					"checksum_errors", stats.ChecksumErrors,
+					"range_errors", stats.RangeErrors,
+					"malformed", stats.MalformedPackets,
+					"outliers", stats.OutliersRejected,
+					"missed", stats.MissedPackets),
+				slog.Group("samples",
+					"sent", stats.SentSamples,
+					"dropped", stats.DroppedSamples),
+			}
+			if b.hasPPS() {
+				attrs = append(attrs, slog.Group("pps",
+					"edges", stats.PPSEdges,
+					"paired", stats.PPSPaired))
+			}
+			if data != nil {
+				attrs = append(attrs,
+					"current", data.Timestamp.Format("15:04:05"),
+					"status", data.Status,
+					"age", time.Since(stats.LastUpdate).Truncate(time.Second))
+			}
+			if summary := b.Stability(); summary.Samples > 1 {
+				attrs = append(attrs, slog.Group("offset",
+					"mean", summary.Mean,
+					"std_dev", summary.StdDev,
+					"mad", summary.MAD))
+			}
+			if diag := b.Diagnostics(); !diag.Updated.IsZero() {
+				receiver := []any{
+					"satellites", diag.Satellites,
+					"efc", diag.EFC,
+					"holdover_prediction", diag.HoldoverPrediction,
+					"antenna", diag.Antenna,
+					"phase_error", diag.PhaseError,
+					"discipline", diag.Discipline,
+				}
+				if diag.Signals != nil {
+					receiver = append(receiver, "signal", gpsdo.MeanStrength(diag.Signals))
+				}
+				if diag.Position != nil {
+					receiver = append(receiver, "position", diag.Position.Mode)
+				}
+				attrs = append(attrs, slog.Group("receiver", receiver...))
+			}
+			if !stats.HoldoverSince.IsZero() {
+				attrs = append(attrs, slog.Group("holdover",
+					"duration", time.Since(stats.HoldoverSince).Truncate(time.Second),
+					"episodes", stats.HoldoverEpisodes,
+					"longest", stats.LongestHoldover.Truncate(time.Second),
+					"rejected", stats.HoldoverRejected))
+			}
+			if !stats.PowerUpSince.IsZero() {
+				attrs = append(attrs, "power_up", time.Since(stats.PowerUpSince).Truncate(time.Second))
+			}
+			if !stats.ClockAlarmSince.IsZero() {
+				attrs = append(attrs, "clock_alarm", time.Since(stats.ClockAlarmSince).Truncate(time.Second))
+			}
+			if !stats.NTPAlarmSince.IsZero() {
+				attrs = append(attrs, "ntp_alarm", time.Since(stats.NTPAlarmSince).Truncate(time.Second))
+			}
+			if !stats.AntennaFaultSince.IsZero() {
+				attrs = append(attrs, "antenna_fault", time.Since(stats.AntennaFaultSince).Truncate(time.Second))
+			}
+			b.log.Info("GPSDO status", attrs...)
+
+			for name, out := range stats.Outputs {
+				b.log.Info("Output status", "output", name, "connected", out.Connected,
+					"reconnects", out.Reconnects, "write_errors", out.WriteErrors)
+			}
+		}
+	}
+}
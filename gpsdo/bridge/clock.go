@@ -0,0 +1,64 @@
+package bridge
+
+import (
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// clockConfirm is how many samples in a row must be past the threshold, or
+// back within it, before the clock alarm is raised or cleared
+const clockConfirm = 3
+
+// clockCheck compares the time of valid samples with the system clock. Once
+// chrony or ntpd have set the clock from the GPSDO the two only drift apart
+// through a fault, such as a serial framing bug that shifts the time by whole
+// seconds or a receiver reporting the wrong time.
+type clockCheck struct {
+	threshold time.Duration
+	alarm     bool
+	count     int
+}
+
+// add reports whether the alarm was raised or cleared by a sample with the
+// offset from the system clock
+func (c *clockCheck) add(offset time.Duration) bool {
+	if c.threshold <= 0 || (offset.Abs() > c.threshold) == c.alarm {
+		c.count = 0
+		return false
+	}
+	c.count++
+	if c.count < clockConfirm {
+		return false
+	}
+	c.alarm = !c.alarm
+	c.count = 0
+	return true
+}
+
+// checkClock raises or clears the clock alarm with data, a valid sample
+func (b *Bridge) checkClock(data *gpsdo.Sample) {
+	offset := data.SystemOffset()
+
+	b.mutex.Lock()
+	changed := b.clock.add(offset)
+	alarm := b.clock.alarm
+	if changed {
+		if alarm {
+			b.stats.ClockAlarmSince = time.Now()
+			b.stats.ClockAlarms++
+		} else {
+			b.stats.ClockAlarmSince = time.Time{}
+		}
+	}
+	b.mutex.Unlock()
+
+	switch {
+	case !changed:
+	case alarm:
+		b.log.Warn("Sample time disagrees with the system clock, check the serial framing and receiver",
+			"offset", offset, "threshold", b.clock.threshold)
+	default:
+		b.log.Info("Sample time agrees with the system clock again", "offset", offset)
+	}
+}
@@ -0,0 +1,46 @@
+package bridge
+
+import (
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// decimator reduces the samples sent to the outputs to one per interval of
+// GPS time. The first sample of each interval is sent, either as it is or
+// with the mean offset of the samples since the previous one sent.
+type decimator struct {
+	interval time.Duration
+	average  bool
+
+	window time.Time
+	// sum is the total of Timestamp minus ReceiveTime of the samples held
+	// back since the last one sent
+	sum time.Duration
+	n   int
+}
+
+// add takes a sample and returns the sample to send, or nil if none is due
+func (d *decimator) add(data *gpsdo.Sample) *gpsdo.Sample {
+	if d.interval <= 0 {
+		return data
+	}
+
+	d.sum += data.Timestamp.Sub(data.ReceiveTime())
+	d.n++
+	window := data.Timestamp.Truncate(d.interval)
+	if window.Equal(d.window) {
+		return nil
+	}
+	d.window = window
+
+	mean := d.sum / time.Duration(d.n)
+	d.sum, d.n = 0, 0
+	if !d.average {
+		return data
+	}
+	// Keep ReceiveTime, the time of the measurement, and move Timestamp
+	averaged := *data
+	averaged.Timestamp = data.ReceiveTime().Add(mean)
+	return &averaged
+}
@@ -0,0 +1,86 @@
+package bridge
+
+import (
+	"context"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// silenceAfter is how long without any packet before the silence is logged
+const silenceAfter = 10 * time.Second
+
+// gapTracker counts the packets missed between valid samples, such as those
+// lost to a flaky cable, from the step in GPS time. The packet interval is
+// learned as the shortest step seen, as receivers send every second or,
+// like the Z3805A, every other second.
+type gapTracker struct {
+	interval time.Duration
+	last     time.Time
+}
+
+// add returns the number of packets missed before the sample at t
+func (g *gapTracker) add(t time.Time) int {
+	last := g.last
+	g.last = t
+	step := t.Sub(last)
+	if last.IsZero() || step <= 0 {
+		return 0
+	}
+	if g.interval == 0 || step < g.interval {
+		g.interval = step
+	}
+	if step < g.interval*3/2 {
+		return 0
+	}
+	return int((step+g.interval/2)/g.interval) - 1
+}
+
+// reset forgets the last sample, so no gap is counted across samples that
+// arrived but weren't usable, such as outliers and invalid samples
+func (g *gapTracker) reset() {
+	g.last = time.Time{}
+}
+
+// countGap records the packets missed before data, an accepted valid sample
+func (b *Bridge) countGap(data *gpsdo.Sample) {
+	b.mutex.Lock()
+	missed := b.gaps.add(data.Timestamp)
+	if missed > 0 {
+		b.stats.PacketGaps++
+		b.stats.MissedPackets += uint64(missed)
+	}
+	b.mutex.Unlock()
+
+	if missed > 0 {
+		b.log.Warn("Packets missed", "missed", missed, "gps_time", data.Timestamp.Format("2006-002 15:04:05"))
+	}
+}
+
+// watchSilence logs when no packet has arrived for silenceAfter while the
+// input is connected, and when packets arrive again
+func (b *Bridge) watchSilence(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	var silent time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			stats := b.Stats()
+			switch {
+			case stats.LastPacket.IsZero() || !stats.InputConnected:
+			case time.Since(stats.LastPacket) >= silenceAfter:
+				if !silent.Equal(stats.LastPacket) {
+					silent = stats.LastPacket
+					b.log.Warn("No packets received, check the cable and receiver", "since", stats.LastPacket.Format(time.TimeOnly))
+				}
+			case !silent.IsZero():
+				b.log.Info("Packets resumed", "silence", stats.LastPacket.Sub(silent).Truncate(time.Second))
+				silent = time.Time{}
+			}
+		}
+	}
+}
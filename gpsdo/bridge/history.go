@@ -0,0 +1,45 @@
+package bridge
+
+import (
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// Transition is a change in the reported GPSDO status
+type Transition struct {
+	Time time.Time    `json:"time"`
+	From gpsdo.Status `json:"from"`
+	To   gpsdo.Status `json:"to"`
+}
+
+// recordTransition appends a transition when data changes the status, and
+// returns it. It must be called with the mutex held, before b.current is
+// updated.
+func (b *Bridge) recordTransition(data *gpsdo.Sample) *Transition {
+	from := b.lastStatus
+	if b.current != nil {
+		from = b.current.Status
+	}
+	if from == data.Status {
+		return nil
+	}
+
+	if keep := b.config.RecentTransitions; len(b.transitions) >= keep {
+		b.transitions = append(b.transitions[:0], b.transitions[len(b.transitions)-keep+1:]...)
+	}
+	t := Transition{
+		Time: data.ParseTime,
+		From: from,
+		To:   data.Status,
+	}
+	b.transitions = append(b.transitions, t)
+	return &t
+}
+
+// Transitions returns the recent status transitions, oldest first
+func (b *Bridge) Transitions() []Transition {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return append([]Transition{}, b.transitions...)
+}
@@ -0,0 +1,51 @@
+package bridge
+
+import (
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// trackHoldover records holdover entry and exit and reports whether data may
+// be forwarded under the holdover policy. It must be called with the mutex
+// held.
+func (b *Bridge) trackHoldover(data *gpsdo.Sample) bool {
+	now := data.ParseTime
+	inHoldover := data.Status == gpsdo.Holdover
+
+	switch {
+	case inHoldover && b.stats.HoldoverSince.IsZero():
+		b.stats.HoldoverSince = now
+		b.stats.LastHoldoverEntry = now
+		b.stats.HoldoverEpisodes++
+		b.log.Warn("GPSDO entered holdover", "at", now.UTC().Format(time.DateTime), "episodes", b.stats.HoldoverEpisodes)
+	case !inHoldover && !b.stats.HoldoverSince.IsZero():
+		duration := now.Sub(b.stats.HoldoverSince)
+		b.stats.LongestHoldover = max(b.stats.LongestHoldover, duration)
+		b.log.Info("GPSDO left holdover", "at", now.UTC().Format(time.DateTime),
+			"duration", duration.Truncate(time.Second), "longest", b.stats.LongestHoldover.Truncate(time.Second),
+			"status", data.Status)
+		b.stats.HoldoverSince = time.Time{}
+		b.stats.LastHoldoverExit = now
+		b.stats.LastHoldoverDuration = duration
+		b.holdoverExpired = false
+	}
+	if inHoldover {
+		b.stats.LongestHoldover = max(b.stats.LongestHoldover, now.Sub(b.stats.HoldoverSince))
+	}
+
+	if !inHoldover || b.config.HoldoverMax <= 0 {
+		return true
+	}
+
+	if now.Sub(b.stats.HoldoverSince) <= b.config.HoldoverMax {
+		return true
+	}
+
+	if !b.holdoverExpired {
+		b.holdoverExpired = true
+		b.log.Warn("Holdover limit exceeded, no longer forwarding samples", "max", b.config.HoldoverMax)
+	}
+	b.stats.HoldoverRejected++
+	return false
+}
@@ -0,0 +1,34 @@
+package bridge
+
+import (
+	"strings"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/protocol"
+)
+
+// SetIdentity stores the identity the receiver reported. A new identity is
+// logged, with a warning if the model isn't one the protocol is meant for.
+func (b *Bridge) SetIdentity(id gpsdo.Identity) {
+	b.mutex.Lock()
+	changed := id != b.identity
+	b.identity = id
+	b.mutex.Unlock()
+	if !changed {
+		return
+	}
+
+	b.log.Info("Receiver identified", "manufacturer", id.Manufacturer, "model", id.Model,
+		"serial", id.Serial, "firmware", id.Firmware)
+	if p, ok := protocol.Lookup(b.config.Protocol); ok && !p.Matches(id) {
+		b.log.Warn("Receiver model doesn't match the protocol, check the protocol setting",
+			"model", id.Model, "protocol", p.Name, "expected", strings.Join(p.Models, ", "))
+	}
+}
+
+// Identity returns the identity the receiver reported, zero if none
+func (b *Bridge) Identity() gpsdo.Identity {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.identity
+}
@@ -0,0 +1,115 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo/protocol"
+)
+
+// inputReader feeds the parser from the input. It records the raw input to
+// the capture file and rebroadcast, waits out reads that return nothing, such as serial
+// read timeouts, and ends the input once ctx is cancelled.
+type inputReader struct {
+	b     *Bridge
+	ctx   context.Context
+	input io.Reader
+	// err is the error that ended the input
+	err error
+	// read is the number of bytes read so far
+	read int64
+	// chunks are the arrival times of the reads not yet parsed
+	chunks []chunk
+}
+
+// chunk is the arrival time of the input up to a position in the stream
+type chunk struct {
+	end  int64
+	time time.Time
+}
+
+// arrival returns the time the byte at position pos of the stream arrived,
+// forgetting the chunks before it
+func (r *inputReader) arrival(pos int64) time.Time {
+	for i, c := range r.chunks {
+		if c.end > pos {
+			r.chunks = r.chunks[i:]
+			return c.time
+		}
+	}
+	r.chunks = r.chunks[:0]
+	return time.Time{}
+}
+
+func (r *inputReader) Read(p []byte) (int, error) {
+	for {
+		if err := r.ctx.Err(); err != nil {
+			r.err = err
+			return 0, err
+		}
+		n, err := r.input.Read(p)
+		if n > 0 {
+			r.read += int64(n)
+			r.chunks = append(r.chunks, chunk{end: r.read, time: time.Now()})
+			r.b.capture(p[:n])
+		}
+		if err != nil {
+			r.err = err
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+// parse passes the packets of input to handleSample until the input ends,
+// returning the error that ended it
+func (b *Bridge) parse(input *inputReader, parser protocol.Parser) error {
+	stream := protocol.NewReader(input, parser)
+	diagnostics, _ := parser.(protocol.DiagnosticsParser)
+	identifier, _ := parser.(protocol.Identifier)
+
+	for {
+		// The packet starts after the bytes already parsed
+		start := input.read - int64(stream.Buffered())
+		data, err := stream.Next()
+		arrival := input.arrival(start)
+		if err != nil && input.err != nil && errors.Is(err, input.err) {
+			return input.err
+		}
+
+		switch {
+		case errors.Is(err, protocol.ErrFraming):
+			b.countReject(&b.stats.FramingErrors)
+			b.log.Warn("Framing error, resynchronizing", "error", err)
+		case errors.Is(err, protocol.ErrChecksum):
+			b.countReject(&b.stats.ChecksumErrors)
+			b.log.Warn("Checksum error, packet discarded", "protocol", parser.Name(), "error", err)
+		case errors.Is(err, protocol.ErrRange):
+			b.countReject(&b.stats.RangeErrors)
+			b.log.Warn("Implausible packet discarded", "protocol", parser.Name(), "error", err)
+		case err != nil:
+			b.countReject(&b.stats.MalformedPackets)
+			b.log.Warn("Parse error", "protocol", parser.Name(), "error", err)
+		default:
+			if data != nil {
+				data.Arrival = arrival
+			}
+			b.countPacket()
+			if diagnostics != nil {
+				if diag, ok := diagnostics.Diagnostics(); ok {
+					diag.Updated = time.Now()
+					b.SetDiagnostics(diag)
+				}
+			}
+			if identifier != nil {
+				if id, ok := identifier.Identity(); ok {
+					b.SetIdentity(id)
+				}
+			}
+			b.handleSample(data)
+		}
+	}
+}
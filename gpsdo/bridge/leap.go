@@ -0,0 +1,195 @@
+package bridge
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/leapsec"
+)
+
+// leapWindow is how long before a leap second samples carry the Leap flag
+const leapWindow = 24 * time.Hour
+
+// leapSettle is how long after a leap second a change in the leap second
+// count is treated as the leap itself rather than a new announcement
+const leapSettle = 10 * time.Second
+
+// leapTracker detects pending leap seconds for receivers that report the
+// upcoming GPS-UTC offset as soon as it is announced instead of a dedicated
+// announcement flag. Samples that already carry a Leap from their parser are
+// left alone.
+type leapTracker struct {
+	count   int
+	seen    bool
+	pending gpsdo.Leap
+	at      time.Time
+
+	log *slog.Logger
+}
+
+// apply updates the tracker from data and sets data.Leap when data falls in
+// the window before a pending leap second
+func (t *leapTracker) apply(data *gpsdo.Sample) {
+	now := data.Timestamp
+
+	if t.pending != gpsdo.LeapNone && !now.Before(t.at) {
+		t.log.Info("Leap second has passed", "leap", t.pending, "at", t.at.Format(time.DateTime))
+		t.pending = gpsdo.LeapNone
+	}
+
+	if data.Leap == gpsdo.LeapNone && t.seen && data.LeapSeconds != t.count {
+		diff := data.LeapSeconds - t.count
+		switch {
+		case sinceLeapBoundary(now) < leapSettle:
+			// The leap second itself
+		case diff == 1:
+			t.pending = gpsdo.LeapInsert
+		case diff == -1:
+			t.pending = gpsdo.LeapDelete
+		}
+		if t.pending != gpsdo.LeapNone {
+			t.at = nextLeapBoundary(now)
+			t.log.Warn("Leap second announced", "leap", t.pending, "at", t.at.Format(time.DateTime),
+				"from", t.count, "to", data.LeapSeconds)
+		}
+	}
+	t.count = data.LeapSeconds
+	t.seen = true
+
+	if data.Leap == gpsdo.LeapNone && t.pending != gpsdo.LeapNone && t.at.Sub(now) <= leapWindow {
+		data.Leap = t.pending
+	}
+}
+
+// leapCheck compares the leap seconds reported by the receiver with a
+// leap-seconds.list table. It only warns, the samples are left alone.
+type leapCheck struct {
+	table   *leapsec.Table
+	expired bool
+	// mismatch describes the current disagreement, empty if none
+	mismatch string
+
+	log *slog.Logger
+}
+
+// apply checks data against the table and logs when the table expires and
+// when the receiver starts or stops disagreeing with it
+func (c *leapCheck) apply(data *gpsdo.Sample) {
+	if c.table == nil {
+		return
+	}
+	now := data.Timestamp
+
+	if !c.expired && c.table.Expired(now) {
+		c.expired = true
+		c.log.Warn("Leap second table has expired, update tzdata and restart", "expires", c.table.Expires.Format(time.DateOnly))
+	}
+
+	if sinceLeapBoundary(now) < leapSettle {
+		// Receivers take a moment to update their count after a leap second
+		return
+	}
+	mismatch := c.compare(data)
+	if mismatch == c.mismatch {
+		return
+	}
+	if mismatch != "" {
+		c.log.Warn("Receiver disagrees with the leap second table", "problem", mismatch)
+	} else {
+		c.log.Info("Receiver agrees with the leap second table again")
+	}
+	c.mismatch = mismatch
+}
+
+// compare describes how data disagrees with the table, empty if it agrees
+func (c *leapCheck) compare(data *gpsdo.Sample) string {
+	now := data.Timestamp
+	expected := c.table.Offset(now)
+	next, leap, scheduled := c.table.Next(now)
+	// Only the next boundary can have an announced leap second
+	scheduled = scheduled && !next.After(nextLeapBoundary(now))
+
+	if data.LeapSeconds > 0 {
+		reported := data.LeapSeconds + gpsdo.TAIGPSOffset
+		// Some receivers report the new offset as soon as the leap second
+		// is announced
+		if reported != expected && !(scheduled && reported == c.table.Offset(next)) {
+			return fmt.Sprintf("receiver TAI-UTC is %d s, table has %d s", reported, expected)
+		}
+	}
+
+	// An expired table may be missing the announcement
+	if data.Leap != gpsdo.LeapNone && !c.table.Expired(now) {
+		if !scheduled {
+			return fmt.Sprintf("receiver announces a leap second (%s) the table doesn't have", data.Leap)
+		}
+		if data.Leap != leap {
+			return fmt.Sprintf("receiver announces %s, table has %s", data.Leap, leap)
+		}
+	}
+	return ""
+}
+
+// LeapStatus is the TAI-UTC offset and the leap second table check of a
+// bridge
+type LeapStatus struct {
+	// TAIOffset is TAI-UTC in seconds, 0 if unknown
+	TAIOffset int
+	// Table is the leap second table, nil if none is loaded
+	Table *leapsec.Table
+	// Mismatch describes how the receiver disagrees with Table, empty if it
+	// agrees
+	Mismatch string
+}
+
+// LeapStatus returns TAI-UTC, from the leap second table while it is current
+// and from the leap seconds of the last sample otherwise
+func (b *Bridge) LeapStatus() LeapStatus {
+	b.mutex.RLock()
+	data, mismatch := b.current, b.leapMismatch
+	b.mutex.RUnlock()
+
+	return LeapStatus{
+		TAIOffset: b.taiOffset(data, time.Now()),
+		Table:     b.config.LeapTable,
+		Mismatch:  mismatch,
+	}
+}
+
+// taiOffset returns TAI-UTC at t, from the leap second table while it is
+// current and from the leap seconds of data otherwise, 0 if unknown. data
+// may be nil.
+func (b *Bridge) taiOffset(data *gpsdo.Sample, t time.Time) int {
+	table := b.config.LeapTable
+	switch {
+	case table != nil && !table.Expired(t):
+		return table.Offset(t)
+	case data != nil && data.LeapSeconds > 0:
+		return data.LeapSeconds + gpsdo.TAIGPSOffset
+	case table != nil:
+		return table.Offset(t)
+	}
+	return 0
+}
+
+// nextLeapBoundary returns the next 1 January or 1 July 00:00 UTC after t,
+// the only points where leap seconds are scheduled in practice
+func nextLeapBoundary(t time.Time) time.Time {
+	t = t.UTC()
+	if t.Month() < time.July {
+		return time.Date(t.Year(), time.July, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(t.Year()+1, time.January, 1, 0, 0, 0, 0, time.UTC)
+}
+
+// sinceLeapBoundary returns the time since the most recent leap boundary
+func sinceLeapBoundary(t time.Time) time.Duration {
+	t = t.UTC()
+	boundary := time.Date(t.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+	if t.Month() >= time.July {
+		boundary = time.Date(t.Year(), time.July, 1, 0, 0, 0, 0, time.UTC)
+	}
+	return t.Sub(boundary)
+}
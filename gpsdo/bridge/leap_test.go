@@ -0,0 +1,101 @@
+package bridge
+
+import (
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+func TestLeapTracker(t *testing.T) {
+	// The leap second at the end of 2016, when GPS-UTC went from 17 to 18 s
+	day := func(d, hour, minute, second int) time.Time {
+		return time.Date(2016, time.December, d, hour, minute, second, 0, time.UTC)
+	}
+	after := time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	for _, tt := range []struct {
+		name    string
+		samples []gpsdo.Sample
+		want    []gpsdo.Leap
+	}{
+		{
+			name: "announced and expired",
+			samples: []gpsdo.Sample{
+				{Timestamp: day(1, 0, 0, 0), LeapSeconds: 17},
+				// Announced a month ahead, flagged only in the last day
+				{Timestamp: day(1, 0, 0, 1), LeapSeconds: 18},
+				{Timestamp: day(30, 23, 59, 59), LeapSeconds: 18},
+				{Timestamp: day(31, 0, 0, 0), LeapSeconds: 18},
+				{Timestamp: day(31, 23, 59, 59), LeapSeconds: 18},
+				{Timestamp: after, LeapSeconds: 18},
+				{Timestamp: after.Add(time.Hour), LeapSeconds: 18},
+			},
+			want: []gpsdo.Leap{
+				gpsdo.LeapNone,
+				gpsdo.LeapNone,
+				gpsdo.LeapNone,
+				gpsdo.LeapInsert,
+				gpsdo.LeapInsert,
+				gpsdo.LeapNone,
+				gpsdo.LeapNone,
+			},
+		},
+		{
+			name: "unflagged samples keep the announcement",
+			samples: []gpsdo.Sample{
+				{Timestamp: day(30, 0, 0, 0), LeapSeconds: 17},
+				{Timestamp: day(30, 0, 0, 1), LeapSeconds: 18},
+				{Timestamp: day(30, 12, 0, 0), LeapSeconds: 18},
+				{Timestamp: day(31, 12, 0, 0), LeapSeconds: 18},
+				{Timestamp: day(31, 23, 59, 0), LeapSeconds: 18},
+				{Timestamp: day(31, 23, 59, 59), LeapSeconds: 18},
+			},
+			want: []gpsdo.Leap{
+				gpsdo.LeapNone,
+				gpsdo.LeapNone,
+				gpsdo.LeapNone,
+				gpsdo.LeapInsert,
+				gpsdo.LeapInsert,
+				gpsdo.LeapInsert,
+			},
+		},
+		{
+			name: "count changing at the leap second",
+			samples: []gpsdo.Sample{
+				{Timestamp: day(31, 23, 59, 59), LeapSeconds: 17},
+				{Timestamp: after.Add(2 * time.Second), LeapSeconds: 18},
+				{Timestamp: after.Add(time.Minute), LeapSeconds: 18},
+			},
+			want: []gpsdo.Leap{gpsdo.LeapNone, gpsdo.LeapNone, gpsdo.LeapNone},
+		},
+		{
+			name: "deletion",
+			samples: []gpsdo.Sample{
+				{Timestamp: day(31, 0, 0, 0), LeapSeconds: 18},
+				{Timestamp: day(31, 0, 0, 1), LeapSeconds: 17},
+			},
+			want: []gpsdo.Leap{gpsdo.LeapNone, gpsdo.LeapDelete},
+		},
+		{
+			name: "parser flag left alone",
+			samples: []gpsdo.Sample{
+				{Timestamp: day(31, 0, 0, 0), LeapSeconds: 17, Leap: gpsdo.LeapInsert},
+				{Timestamp: day(31, 0, 0, 1), LeapSeconds: 17, Leap: gpsdo.LeapInsert},
+				{Timestamp: day(31, 0, 0, 2), LeapSeconds: 17},
+			},
+			want: []gpsdo.Leap{gpsdo.LeapInsert, gpsdo.LeapInsert, gpsdo.LeapNone},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := leapTracker{log: slog.New(slog.DiscardHandler)}
+			for i, data := range tt.samples {
+				tracker.apply(&data)
+				if data.Leap != tt.want[i] {
+					t.Errorf("sample %d at %s: leap = %s, want %s", i, data.Timestamp.Format(time.DateTime), data.Leap, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package bridge
+
+import (
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// maxLockAcquisitions is the number of lock acquisitions kept
+const maxLockAcquisitions = 20
+
+// LockAcquisition is a lock after power-up: the first POWER_UP sample seen
+// and the first LOCKED sample after it
+type LockAcquisition struct {
+	PowerUp time.Time `json:"power_up"`
+	Locked  time.Time `json:"locked"`
+}
+
+// Duration is the time the GPSDO took to lock
+func (a LockAcquisition) Duration() time.Duration {
+	return a.Locked.Sub(a.PowerUp)
+}
+
+// trackLock measures the time from power-up to lock. A holdover or loss of
+// samples on the way doesn't restart the measurement, only a lock ends it.
+// It must be called with the mutex held.
+func (b *Bridge) trackLock(data *gpsdo.Sample) {
+	now := data.ParseTime
+
+	switch {
+	case data.Status == gpsdo.PowerUp && b.stats.PowerUpSince.IsZero():
+		b.stats.PowerUpSince = now
+		b.log.Info("GPSDO powering up", "at", now.UTC().Format(time.DateTime))
+	case data.Status == gpsdo.Locked && !b.stats.PowerUpSince.IsZero():
+		a := LockAcquisition{PowerUp: b.stats.PowerUpSince, Locked: now}
+		if len(b.acquisitions) >= maxLockAcquisitions {
+			b.acquisitions = append(b.acquisitions[:0], b.acquisitions[1:]...)
+		}
+		b.acquisitions = append(b.acquisitions, a)
+		b.stats.PowerUpSince = time.Time{}
+		b.stats.LastTimeToLock = a.Duration()
+		b.log.Info("GPSDO locked after power-up", "at", now.UTC().Format(time.DateTime),
+			"time_to_lock", a.Duration().Truncate(time.Second))
+	}
+}
+
+// LockAcquisitions returns the last lock acquisitions after power-up,
+// oldest first
+func (b *Bridge) LockAcquisitions() []LockAcquisition {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return append([]LockAcquisition{}, b.acquisitions...)
+}
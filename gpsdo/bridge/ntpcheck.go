@@ -0,0 +1,68 @@
+package bridge
+
+import (
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo/ntp"
+)
+
+// ntpSampleAge is how recent the last valid sample must be to compare it with
+// the NTP reference servers
+const ntpSampleAge = 10 * time.Second
+
+// NTPCheck compares the samples with independent NTP servers, a sanity check
+// against GPS spoofing, week rollover bugs and receivers reporting the wrong
+// time
+type NTPCheck struct {
+	*ntp.Check
+	// Divergence is the time of the last valid sample minus the consensus
+	// time of the servers. Compared is false when there was no recent valid
+	// sample or no server answered.
+	Divergence time.Duration
+	Compared   bool
+}
+
+// CheckNTP compares the last valid sample with a round of queries to the NTP
+// reference servers and raises or clears the NTP alarm
+func (b *Bridge) CheckNTP(c *ntp.Check) {
+	b.mutex.Lock()
+	check := NTPCheck{Check: c}
+	data := b.current
+	if c.Answered > 0 && data != nil && data.Valid && c.Updated.Sub(b.stats.LastUpdate) < ntpSampleAge {
+		check.Divergence = data.SystemOffset() - c.Offset
+		check.Compared = true
+	}
+	b.ntp = &check
+
+	threshold := b.config.NTPAlarm
+	alarm := !b.stats.NTPAlarmSince.IsZero()
+	changed := false
+	switch {
+	case !check.Compared || threshold <= 0:
+	case !alarm && check.Divergence.Abs() > threshold:
+		b.stats.NTPAlarmSince = time.Now()
+		b.stats.NTPAlarms++
+		changed = true
+	case alarm && check.Divergence.Abs() <= threshold:
+		b.stats.NTPAlarmSince = time.Time{}
+		changed = true
+	}
+	b.mutex.Unlock()
+
+	switch {
+	case !changed:
+	case !alarm:
+		b.log.Warn("Sample time disagrees with the NTP reference servers, check for GPS spoofing or a receiver fault",
+			"divergence", check.Divergence, "threshold", threshold, "answered", c.Answered)
+	default:
+		b.log.Info("Sample time agrees with the NTP reference servers again", "divergence", check.Divergence)
+	}
+}
+
+// NTPCheck returns the last comparison with the NTP reference servers, nil
+// if none was made
+func (b *Bridge) NTPCheck() *NTPCheck {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.ntp
+}
@@ -0,0 +1,62 @@
+package bridge
+
+import (
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// outlierConfirm is how many samples in a row must agree on a new timeline
+// before a step is accepted rather than rejected as outliers
+const outlierConfirm = 3
+
+// outlierFilter rejects valid samples whose time doesn't follow on from the
+// previous accepted sample, such as a corrupted but plausible packet. The
+// expected time is the previous sample time plus the elapsed monotonic time
+// between the two packets.
+type outlierFilter struct {
+	maxJump time.Duration
+
+	last      *gpsdo.Sample
+	candidate *gpsdo.Sample
+	confirmed int
+}
+
+// check reports whether data may be used
+func (f *outlierFilter) check(data *gpsdo.Sample) bool {
+	if f.maxJump <= 0 || !data.Valid {
+		return true
+	}
+
+	if f.last == nil || f.follows(f.last, data) {
+		f.accept(data)
+		return true
+	}
+
+	// A step that persists, such as after the receiver corrects itself, is
+	// a new timeline rather than an outlier
+	if f.candidate != nil && f.follows(f.candidate, data) {
+		f.confirmed++
+	} else {
+		f.confirmed = 1
+	}
+	f.candidate = data
+
+	if f.confirmed >= outlierConfirm {
+		f.accept(data)
+		return true
+	}
+	return false
+}
+
+func (f *outlierFilter) accept(data *gpsdo.Sample) {
+	f.last = data
+	f.candidate = nil
+	f.confirmed = 0
+}
+
+// follows reports whether next is within maxJump of where prev predicts
+func (f *outlierFilter) follows(prev, next *gpsdo.Sample) bool {
+	expected := prev.Timestamp.Add(next.ParseTime.Sub(prev.ParseTime))
+	return next.Timestamp.Sub(expected).Abs() <= f.maxJump
+}
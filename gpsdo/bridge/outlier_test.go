@@ -0,0 +1,80 @@
+package bridge
+
+import (
+	"testing"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+func TestOutlierFilter(t *testing.T) {
+	const step = time.Hour
+
+	for _, tt := range []struct {
+		name string
+		// offsets of the sample times from their packets, one per second
+		offsets []time.Duration
+		want    []bool
+	}{
+		{
+			name:    "single jump",
+			offsets: []time.Duration{0, 0, step, 0, 0},
+			want:    []bool{true, true, false, true, true},
+		},
+		{
+			name:    "within max jump",
+			offsets: []time.Duration{0, 400 * time.Millisecond, -100 * time.Millisecond},
+			want:    []bool{true, true, true},
+		},
+		{
+			name:    "persistent step",
+			offsets: []time.Duration{0, step, step, step, step},
+			want:    []bool{true, false, false, true, true},
+		},
+		{
+			name:    "window reset by a good sample",
+			offsets: []time.Duration{0, step, step, 0, step, step, step},
+			want:    []bool{true, false, false, true, false, false, true},
+		},
+		{
+			name:    "jumps that disagree with each other",
+			offsets: []time.Duration{0, step, 2 * step, 3 * step, 3 * step, 3 * step},
+			want:    []bool{true, false, false, false, false, true},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			f := outlierFilter{maxJump: 500 * time.Millisecond}
+			start := time.Date(2025, time.March, 14, 12, 0, 0, 0, time.UTC)
+			for i, offset := range tt.offsets {
+				received := start.Add(time.Duration(i) * time.Second)
+				data := &gpsdo.Sample{Timestamp: received.Add(offset), ParseTime: received, Valid: true}
+				if got := f.check(data); got != tt.want[i] {
+					t.Errorf("sample %d offset %s: check = %t, want %t", i, offset, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestOutlierFilterPassThrough(t *testing.T) {
+	start := time.Date(2025, time.March, 14, 12, 0, 0, 0, time.UTC)
+	first := &gpsdo.Sample{Timestamp: start, ParseTime: start, Valid: true}
+	jump := &gpsdo.Sample{Timestamp: start.Add(time.Hour), ParseTime: start.Add(time.Second), Valid: true}
+
+	disabled := outlierFilter{}
+	if !disabled.check(first) || !disabled.check(jump) {
+		t.Error("max jump 0 rejected a sample")
+	}
+
+	f := outlierFilter{maxJump: 500 * time.Millisecond}
+	f.check(first)
+	invalid := *jump
+	invalid.Valid = false
+	if !f.check(&invalid) {
+		t.Error("invalid sample rejected")
+	}
+	// The invalid sample is not the new reference
+	if f.check(jump) {
+		t.Error("jump accepted after an invalid sample")
+	}
+}
@@ -0,0 +1,45 @@
+package bridge
+
+import "github.com/karlcswanson/gogpsdo/gpsdo"
+
+// positionState is the position mode of the receiver as far as the timing
+// is concerned
+type positionState int
+
+const (
+	positionUnknown positionState = iota
+	positionHold
+	positionSurvey
+	positionNavigating
+)
+
+// checkPosition logs the changes of the position mode of the receiver, with a
+// warning when it is neither holding a surveyed position nor surveying one,
+// which degrades the timing. It must be called with the mutex held.
+func (b *Bridge) checkPosition(p *gpsdo.Position) {
+	if p == nil {
+		return
+	}
+	state := positionNavigating
+	switch {
+	case p.Hold:
+		state = positionHold
+	case p.Surveying:
+		state = positionSurvey
+	}
+	if state == b.positionState {
+		return
+	}
+	b.positionState = state
+
+	switch state {
+	case positionHold:
+		b.log.Info("Receiver in position hold", "latitude", p.Latitude, "longitude", p.Longitude,
+			"altitude", p.Altitude)
+	case positionSurvey:
+		b.log.Info("Receiver surveying its position", "mode", p.Mode)
+	default:
+		b.log.Warn("Receiver not in position hold, the timing is degraded until it surveys its position",
+			"mode", p.Mode)
+	}
+}
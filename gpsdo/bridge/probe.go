@@ -0,0 +1,144 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo/protocol"
+	"github.com/tarm/serial"
+)
+
+// AutoPort as the Port has the bridge probe the candidate ports for the one
+// the receiver is on
+const AutoPort = "auto"
+
+// probePatterns are the ports probed by AutoPort
+var probePatterns = []string{"/dev/ttyUSB*", "/dev/ttyACM*", "/dev/ttyAMA*"}
+
+// probeTime is how long each port is listened to, long enough for a few
+// packets of receivers that send every other second
+const probeTime = 5 * time.Second
+
+// probeRetry is how long to wait before probing again when no port answered
+const probeRetry = 30 * time.Second
+
+// ProbeCandidates returns the ports probed by AutoPort, without those in
+// exclude
+func ProbeCandidates(exclude []string) []string {
+	var ports []string
+	for _, pattern := range probePatterns {
+		matches, _ := filepath.Glob(pattern)
+		for _, port := range matches {
+			if !slices.Contains(exclude, port) {
+				ports = append(ports, port)
+			}
+		}
+	}
+	return ports
+}
+
+// probe returns the first candidate port on which a fresh parser decodes a
+// sample, trying again until one does or ctx is cancelled. config holds the
+// serial settings, its Name is ignored.
+func (b *Bridge) probe(ctx context.Context, config serial.Config) (string, error) {
+	for {
+		candidates := ProbeCandidates(b.config.AutoExclude)
+		for _, port := range candidates {
+			config.Name = port
+			found, err := b.probePort(ctx, &config)
+			if ctx.Err() != nil {
+				return "", ctx.Err()
+			}
+			if found {
+				b.log.Info("Receiver found", "port", port, "protocol", b.config.Protocol)
+				return port, nil
+			}
+			b.log.Info("No receiver on port", "port", port, "protocol", b.config.Protocol, "error", err)
+		}
+
+		b.log.Warn("No receiver found, probing again", "candidates", candidates, "protocol", b.config.Protocol, "retry", probeRetry)
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(probeRetry):
+		}
+	}
+}
+
+// probePort reports whether the receiver is on the port of config. Any
+// decoded sample counts, a receiver still acquiring satellites is found too.
+func (b *Bridge) probePort(ctx context.Context, config *serial.Config) (bool, error) {
+	parser, err := b.newParser()
+	if err != nil {
+		return false, err
+	}
+	port, err := serial.OpenPort(config)
+	if err != nil {
+		return false, err
+	}
+	defer port.Close()
+
+	b.log.Debug("Probing serial port", "port", config.Name)
+	reader := &probeReader{ctx: ctx, port: port, deadline: time.Now().Add(probeTime)}
+	if poller, ok := parser.(protocol.Poller); ok {
+		reader.query = poller.Query()
+	}
+
+	stream := protocol.NewReader(reader, parser)
+	for {
+		data, err := stream.Next()
+		switch {
+		case errors.Is(err, io.EOF):
+			return false, errors.New("no packets decoded")
+		case err == nil && data != nil:
+			return true, nil
+		case err != nil && reader.err != nil:
+			return false, reader.err
+		}
+	}
+}
+
+// probeReader reads a port until a deadline, writing the query of polled
+// receivers once a second
+type probeReader struct {
+	ctx      context.Context
+	port     *serial.Port
+	deadline time.Time
+	query    []byte
+	queried  time.Time
+	err      error
+}
+
+func (r *probeReader) Read(p []byte) (int, error) {
+	for {
+		if r.ctx.Err() != nil || time.Now().After(r.deadline) {
+			return 0, io.EOF
+		}
+		if r.query != nil && time.Since(r.queried) >= time.Second {
+			r.queried = time.Now()
+			if _, err := r.port.Write(r.query); err != nil {
+				r.err = fmt.Errorf("query: %w", err)
+				return 0, r.err
+			}
+		}
+		// Read timeouts are reported as EOF
+		start := time.Now()
+		n, err := r.port.Read(p)
+		if n > 0 {
+			return n, nil
+		}
+		if err != nil && !errors.Is(err, io.EOF) {
+			r.err = err
+			return 0, err
+		}
+		if time.Since(start) < 100*time.Millisecond {
+			// Don't spin on a port that returns at once
+			time.Sleep(100 * time.Millisecond)
+		}
+	}
+}
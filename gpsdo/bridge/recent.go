@@ -0,0 +1,70 @@
+package bridge
+
+import (
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// DefaultRecentTransitions is the number of status transitions kept when
+// Config.RecentTransitions is zero
+const DefaultRecentTransitions = 100
+
+// sampleRing keeps copies of the last samples in a fixed buffer, so
+// recording one doesn't allocate
+type sampleRing struct {
+	samples []gpsdo.Sample
+	// next is the slot of the next sample, the oldest once full
+	next int
+	full bool
+}
+
+func newSampleRing(size int) sampleRing {
+	return sampleRing{samples: make([]gpsdo.Sample, max(size, 0))}
+}
+
+// add records a copy of data, replacing the oldest sample once full
+func (r *sampleRing) add(data *gpsdo.Sample) {
+	if len(r.samples) == 0 {
+		return
+	}
+	r.samples[r.next] = *data
+	r.next++
+	if r.next == len(r.samples) {
+		r.next = 0
+		r.full = true
+	}
+}
+
+// since returns the samples parsed at or after t, oldest first
+func (r *sampleRing) since(t time.Time) []gpsdo.Sample {
+	var ordered []gpsdo.Sample
+	if r.full {
+		ordered = append(ordered, r.samples[r.next:]...)
+	}
+	ordered = append(ordered, r.samples[:r.next]...)
+
+	for i, data := range ordered {
+		if !data.ParseTime.Before(t) {
+			return ordered[i:]
+		}
+	}
+	return nil
+}
+
+// Recent returns the samples and status transitions of the last samples
+// kept in memory at or after since, oldest first. The samples are those the
+// bridge accepted, before conversion to the output time scale.
+func (b *Bridge) Recent(since time.Time) ([]gpsdo.Sample, []Transition) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	samples := b.recent.since(since)
+
+	var transitions []Transition
+	for _, t := range b.transitions {
+		if !t.Time.Before(since) {
+			transitions = append(transitions, t)
+		}
+	}
+	return samples, transitions
+}
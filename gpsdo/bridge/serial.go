@@ -0,0 +1,232 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo/netserial"
+	"github.com/karlcswanson/gogpsdo/gpsdo/protocol"
+	"github.com/tarm/serial"
+)
+
+// maxReadFailures is how many failed reads in a row close the port
+const maxReadFailures = 5
+
+// pollPhase is when in the second polled receivers are queried
+const pollPhase = 100 * time.Millisecond
+
+// Reopen backoff limits
+const (
+	minReopenBackoff = time.Second
+	maxReopenBackoff = 30 * time.Second
+)
+
+// serialConfig returns the port settings of a protocol, with the non-zero
+// settings of override taking precedence
+func serialConfig(port string, defaults, override protocol.SerialDefaults) *serial.Config {
+	settings := defaults.Override(override)
+	return &serial.Config{
+		Name:        port,
+		Baud:        settings.Baud,
+		Size:        byte(settings.DataBits),
+		Parity:      serial.Parity(settings.Parity),
+		StopBits:    serial.StopBits(settings.StopBits),
+		ReadTimeout: time.Second,
+	}
+}
+
+// settings returns the port settings of config
+func settings(config *serial.Config) protocol.SerialDefaults {
+	return protocol.SerialDefaults{
+		Baud:     config.Baud,
+		DataBits: int(config.Size),
+		Parity:   byte(config.Parity),
+		StopBits: int(config.StopBits),
+	}
+}
+
+// OpenPort opens the port of config, a device node or a tcp:// or
+// rfc2217:// network serial port. Read timeouts are reported as io.EOF.
+func OpenPort(config *serial.Config) (io.ReadWriteCloser, error) {
+	if netserial.IsAddress(config.Name) {
+		conn, err := netserial.Dial(config.Name, settings(config), config.ReadTimeout)
+		if err != nil {
+			return nil, err
+		}
+		return conn, nil
+	}
+	port, err := serial.OpenPort(config)
+	if err != nil {
+		return nil, err
+	}
+	return port, nil
+}
+
+// serialInput adapts a serial port to RunInput. The port reports a read
+// timeout, which is normal between packets, as io.EOF, so only reads that
+// fail immediately count as failures. After maxReadFailures in a row the
+// port is closed and reopened with backoff, which also picks up a device
+// node that disappears and comes back.
+type serialInput struct {
+	b      *Bridge
+	ctx    context.Context
+	config *serial.Config
+	// auto probes the candidate ports again when the port can't be reopened
+	auto bool
+	// identify asks the receiver for its identity whenever the port opens
+	identify []byte
+
+	// mutex guards port against Write, the other fields are only used by
+	// the reading goroutine
+	mutex    sync.Mutex
+	port     io.ReadWriteCloser
+	failures int
+	backoff  time.Duration
+}
+
+// Write sends p to the receiver, failing while the port is closed
+func (s *serialInput) Write(p []byte) (int, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.port == nil {
+		return 0, errors.New("serial port closed")
+	}
+	return s.port.Write(p)
+}
+
+// poll writes query to the receiver once a second, early in the second so
+// the response arrives well before the next 1PPS edge, until ctx is
+// cancelled
+func (s *serialInput) poll(ctx context.Context, query []byte) {
+	for {
+		now := time.Now()
+		next := now.Truncate(time.Second).Add(time.Second + pollPhase)
+		if next.Sub(now) > time.Second {
+			next = next.Add(-time.Second)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(next.Sub(now)):
+		}
+
+		if _, err := s.Write(query); err != nil {
+			s.b.log.Debug("Poll query failed", "error", err)
+		}
+	}
+}
+
+func (s *serialInput) Read(p []byte) (int, error) {
+	if s.port == nil {
+		s.reopen()
+		return 0, nil
+	}
+
+	start := time.Now()
+	n, err := s.port.Read(p)
+	if n > 0 {
+		s.failures = 0
+		return n, nil
+	}
+	if errors.Is(err, io.EOF) && time.Since(start) >= s.config.ReadTimeout/2 {
+		return 0, nil
+	}
+
+	s.failures++
+	if s.failures < maxReadFailures {
+		// Don't spin on a port that fails immediately
+		s.wait(100 * time.Millisecond)
+		return 0, nil
+	}
+
+	s.b.log.Warn("Serial port failing, reopening", "port", s.config.Name, "error", err)
+	s.close()
+	return 0, nil
+}
+
+// open opens the port and marks the input connected
+func (s *serialInput) open() error {
+	port, err := OpenPort(s.config)
+	if err != nil {
+		return err
+	}
+	s.mutex.Lock()
+	s.port = port
+	s.mutex.Unlock()
+	s.failures = 0
+	s.backoff = 0
+
+	s.b.mutex.Lock()
+	s.b.stats.InputConnected = true
+	s.b.mutex.Unlock()
+
+	s.b.log.Info("Serial port opened", "port", s.config.Name, "settings", settings(s.config))
+	if len(s.identify) > 0 {
+		if _, err := s.Write(s.identify); err != nil {
+			s.b.log.Debug("Identity query failed", "error", err)
+		}
+	}
+	return nil
+}
+
+// reopen waits out the backoff and tries to open the port again
+func (s *serialInput) reopen() {
+	if s.backoff == 0 {
+		s.backoff = minReopenBackoff
+	}
+	if !s.wait(s.backoff) {
+		return
+	}
+
+	err := s.open()
+	if err != nil && s.auto {
+		// The adapter may be back under another name
+		s.b.log.Warn("Serial port unavailable, probing again", "port", s.config.Name, "error", err)
+		port, perr := s.b.probe(s.ctx, *s.config)
+		if perr != nil {
+			return
+		}
+		s.config.Name = port
+		err = s.open()
+	}
+	if err != nil {
+		s.backoff = min(s.backoff*2, maxReopenBackoff)
+		s.b.log.Warn("Serial port unavailable", "port", s.config.Name, "error", err, "retry", s.backoff)
+		return
+	}
+
+	s.b.mutex.Lock()
+	s.b.stats.InputReconnects++
+	s.b.mutex.Unlock()
+}
+
+// close closes the port and marks the input disconnected
+func (s *serialInput) close() {
+	if s.port == nil {
+		return
+	}
+	s.mutex.Lock()
+	s.port.Close()
+	s.port = nil
+	s.mutex.Unlock()
+
+	s.b.mutex.Lock()
+	s.b.stats.InputConnected = false
+	s.b.mutex.Unlock()
+}
+
+// wait sleeps for d, returning false if ctx is cancelled first
+func (s *serialInput) wait(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-s.ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
@@ -0,0 +1,28 @@
+package bridge
+
+import (
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/stability"
+)
+
+// stabilityWindow is how much offset history the statistics cover
+const stabilityWindow = time.Hour
+
+// recordOffset adds the offset of a valid sample against the system clock to
+// the stability statistics: the PPS edge when paired, otherwise the time the
+// packet was parsed. It must be called with the mutex held.
+func (b *Bridge) recordOffset(data *gpsdo.Sample) {
+	if !data.Valid {
+		return
+	}
+	b.stability.Add(data.Timestamp, data.SystemOffset().Seconds())
+}
+
+// Stability returns the offset statistics over the last hour
+func (b *Bridge) Stability() stability.Summary {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.stability.Summary()
+}
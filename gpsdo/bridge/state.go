@@ -0,0 +1,148 @@
+package bridge
+
+import (
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// State is the part of the bridge kept across restarts: the cumulative
+// counters, the last known status and the calibration offset
+type State struct {
+	TotalPackets     uint64 `json:"packets_total"`
+	ValidPackets     uint64 `json:"packets_valid"`
+	FramingErrors    uint64 `json:"framing_errors"`
+	ChecksumErrors   uint64 `json:"checksum_errors"`
+	RangeErrors      uint64 `json:"range_errors"`
+	MalformedPackets uint64 `json:"malformed"`
+	OutliersRejected uint64 `json:"outliers"`
+	MissedPackets    uint64 `json:"packets_missed"`
+	PacketGaps       uint64 `json:"packet_gaps"`
+	SentSamples      uint64 `json:"samples_sent"`
+	DroppedSamples   uint64 `json:"samples_dropped"`
+	PPSEdges         uint64 `json:"pps_edges"`
+	PPSPaired        uint64 `json:"pps_paired"`
+	HoldoverRejected uint64 `json:"holdover_rejected"`
+	InputReconnects  uint64 `json:"input_reconnects"`
+	ClockAlarms      uint64 `json:"clock_alarms"`
+	NTPAlarms        uint64 `json:"ntp_alarms"`
+	AntennaFaults    uint64 `json:"antenna_faults"`
+
+	// Status is the last reported status, and HoldoverSince the start of
+	// the holdover it is in, if any
+	Status            gpsdo.Status `json:"status"`
+	HoldoverSince     time.Time    `json:"holdover_since,omitzero"`
+	LastHoldoverEntry time.Time    `json:"last_holdover_entry,omitzero"`
+	LastHoldoverExit  time.Time    `json:"last_holdover_exit,omitzero"`
+	// HoldoverEpisodes counts the holdovers entered, LastHoldoverDuration
+	// and LongestHoldover are the lengths of the last and longest
+	HoldoverEpisodes     uint64        `json:"holdover_episodes"`
+	LastHoldoverDuration time.Duration `json:"last_holdover_duration_ns,omitzero"`
+	LongestHoldover      time.Duration `json:"longest_holdover_ns,omitzero"`
+	// PowerUpSince is the start of the power-up the GPSDO is in, if any,
+	// and LockAcquisitions the last locks after power-up
+	PowerUpSince     time.Time         `json:"power_up_since,omitzero"`
+	LockAcquisitions []LockAcquisition `json:"lock_acquisitions,omitempty"`
+
+	// Offset is the calibration offset, changed at runtime if it differs
+	// from ConfiguredOffset
+	Offset           time.Duration `json:"offset_ns"`
+	ConfiguredOffset time.Duration `json:"configured_offset_ns"`
+}
+
+// State returns the state to keep across restarts
+func (b *Bridge) State() State {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	s := State{
+		TotalPackets:      b.stats.TotalPackets,
+		ValidPackets:      b.stats.ValidPackets,
+		FramingErrors:     b.stats.FramingErrors,
+		ChecksumErrors:    b.stats.ChecksumErrors,
+		RangeErrors:       b.stats.RangeErrors,
+		MalformedPackets:  b.stats.MalformedPackets,
+		OutliersRejected:  b.stats.OutliersRejected,
+		MissedPackets:     b.stats.MissedPackets,
+		PacketGaps:        b.stats.PacketGaps,
+		SentSamples:       b.stats.SentSamples,
+		DroppedSamples:    b.stats.DroppedSamples,
+		PPSEdges:          b.stats.PPSEdges,
+		PPSPaired:         b.stats.PPSPaired,
+		HoldoverRejected:  b.stats.HoldoverRejected,
+		InputReconnects:   b.stats.InputReconnects,
+		ClockAlarms:       b.stats.ClockAlarms,
+		NTPAlarms:         b.stats.NTPAlarms,
+		AntennaFaults:     b.stats.AntennaFaults,
+		Status:            b.lastStatus,
+		HoldoverSince:     b.stats.HoldoverSince,
+		LastHoldoverEntry: b.stats.LastHoldoverEntry,
+		LastHoldoverExit:  b.stats.LastHoldoverExit,
+
+		HoldoverEpisodes:     b.stats.HoldoverEpisodes,
+		LastHoldoverDuration: b.stats.LastHoldoverDuration,
+		LongestHoldover:      b.stats.LongestHoldover,
+		PowerUpSince:         b.stats.PowerUpSince,
+		LockAcquisitions:     append([]LockAcquisition(nil), b.acquisitions...),
+		Offset:               b.config.Offset,
+		ConfiguredOffset:     b.configOffset,
+	}
+	if b.current != nil {
+		s.Status = b.current.Status
+	}
+	return s
+}
+
+// Restore continues from a state saved before a restart. It must be called
+// before Run. The saved offset is only used while the configured offset is
+// the same as when it was saved, so a new calibration in the config file
+// takes effect.
+func (b *Bridge) Restore(s State) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.stats.TotalPackets = s.TotalPackets
+	b.stats.ValidPackets = s.ValidPackets
+	b.stats.FramingErrors = s.FramingErrors
+	b.stats.ChecksumErrors = s.ChecksumErrors
+	b.stats.RangeErrors = s.RangeErrors
+	b.stats.MalformedPackets = s.MalformedPackets
+	b.stats.OutliersRejected = s.OutliersRejected
+	b.stats.MissedPackets = s.MissedPackets
+	b.stats.PacketGaps = s.PacketGaps
+	b.stats.SentSamples = s.SentSamples
+	b.stats.DroppedSamples = s.DroppedSamples
+	b.stats.PPSEdges = s.PPSEdges
+	b.stats.PPSPaired = s.PPSPaired
+	b.stats.HoldoverRejected = s.HoldoverRejected
+	b.stats.InputReconnects = s.InputReconnects
+	b.stats.ClockAlarms = s.ClockAlarms
+	b.stats.NTPAlarms = s.NTPAlarms
+	b.stats.AntennaFaults = s.AntennaFaults
+	b.stats.LastHoldoverEntry = s.LastHoldoverEntry
+	b.stats.LastHoldoverExit = s.LastHoldoverExit
+	b.stats.HoldoverEpisodes = s.HoldoverEpisodes
+	b.stats.LastHoldoverDuration = s.LastHoldoverDuration
+	b.stats.LongestHoldover = s.LongestHoldover
+	b.acquisitions = append([]LockAcquisition(nil), s.LockAcquisitions...)
+	if n := len(b.acquisitions); n > maxLockAcquisitions {
+		b.acquisitions = b.acquisitions[n-maxLockAcquisitions:]
+	}
+	if n := len(b.acquisitions); n > 0 {
+		b.stats.LastTimeToLock = b.acquisitions[n-1].Duration()
+	}
+
+	// The first sample is a transition from the last known status, and a
+	// holdover or power-up that outlasts the restart keeps its start
+	b.lastStatus = s.Status
+	if s.Status == gpsdo.Holdover {
+		b.stats.HoldoverSince = s.HoldoverSince
+	}
+	if s.Status != gpsdo.Locked {
+		b.stats.PowerUpSince = s.PowerUpSince
+	}
+
+	if s.ConfiguredOffset == b.configOffset {
+		b.config.Offset = s.Offset
+	}
+}
@@ -0,0 +1,61 @@
+package bridge
+
+import (
+	"context"
+	"io"
+)
+
+// StdinPort as the Port has the bridge read the time code from standard
+// input, for pipelines and testing. The input ends at end of file.
+const StdinPort = "-"
+
+// stdinInput reads a stream that can't be interrupted, such as standard
+// input, in its own goroutine, so that a blocked read doesn't keep the
+// bridge from stopping
+type stdinInput struct {
+	ctx     context.Context
+	reads   chan stdinRead
+	pending []byte
+	err     error
+}
+
+type stdinRead struct {
+	data []byte
+	err  error
+}
+
+func newStdinInput(ctx context.Context, r io.Reader) *stdinInput {
+	s := &stdinInput{ctx: ctx, reads: make(chan stdinRead)}
+	go func() {
+		for {
+			buf := make([]byte, 1024)
+			n, err := r.Read(buf)
+			select {
+			case s.reads <- stdinRead{data: buf[:n], err: err}:
+			case <-ctx.Done():
+				return
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+	return s
+}
+
+func (s *stdinInput) Read(p []byte) (int, error) {
+	if len(s.pending) == 0 && s.err == nil {
+		select {
+		case <-s.ctx.Done():
+			return 0, s.ctx.Err()
+		case read := <-s.reads:
+			s.pending, s.err = read.data, read.err
+		}
+	}
+	n := copy(p, s.pending)
+	s.pending = s.pending[n:]
+	if n > 0 {
+		return n, nil
+	}
+	return 0, s.err
+}
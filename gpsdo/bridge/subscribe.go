@@ -0,0 +1,54 @@
+package bridge
+
+import "github.com/karlcswanson/gogpsdo/gpsdo"
+
+// subscriberBuffer is the number of events queued for a subscriber. A
+// subscriber that falls further behind misses events rather than holding up
+// the bridge.
+const subscriberBuffer = 64
+
+// Event is a sample accepted by the bridge, with the status transition it
+// caused, if any
+type Event struct {
+	Sample     *gpsdo.Sample
+	Transition *Transition
+}
+
+// Subscribe returns a channel receiving every event as it happens, and a
+// function that stops the subscription and closes the channel
+func (b *Bridge) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, subscriberBuffer)
+	b.subMutex.Lock()
+	if b.subscribers == nil {
+		b.subscribers = make(map[chan Event]struct{})
+	}
+	b.subscribers[ch] = struct{}{}
+	b.subMutex.Unlock()
+
+	return ch, func() {
+		b.subMutex.Lock()
+		defer b.subMutex.Unlock()
+		if _, ok := b.subscribers[ch]; ok {
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// publish sends a copy of data and its transition to every subscriber with
+// room for it. Nothing is copied without subscribers.
+func (b *Bridge) publish(data *gpsdo.Sample, transition *Transition) {
+	b.subMutex.Lock()
+	defer b.subMutex.Unlock()
+	if len(b.subscribers) == 0 {
+		return
+	}
+	sample := *data
+	e := Event{Sample: &sample, Transition: transition}
+	for ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
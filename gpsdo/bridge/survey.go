@@ -0,0 +1,77 @@
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/protocol"
+)
+
+// ErrNoSurvey is returned when the position survey of the receiver can't be
+// controlled
+var ErrNoSurvey = errors.New("the position survey can only be controlled over an SCPI port or with the tsip or ubx protocol")
+
+// Surveyor controls the position survey of the receiver over a port other
+// than the one it sends the time on, such as the SCPI port
+type Surveyor interface {
+	// Survey starts a new survey of the antenna position
+	Survey(ctx context.Context) error
+	// HoldPosition sets the antenna position the receiver holds
+	HoldPosition(ctx context.Context, p gpsdo.Position) error
+}
+
+// surveyPort is the serial port of a receiver whose survey is controlled
+// over it
+type surveyPort struct {
+	input  *serialInput
+	parser protocol.Surveyor
+}
+
+// SetSurveyor controls the position survey with s rather than over the
+// serial port
+func (b *Bridge) SetSurveyor(s Surveyor) {
+	b.mutex.Lock()
+	b.surveyor = s
+	b.mutex.Unlock()
+}
+
+// Survey starts a new survey of the antenna position, after which the
+// receiver holds the surveyed position
+func (b *Bridge) Survey(ctx context.Context) error {
+	b.mutex.RLock()
+	surveyor, port := b.surveyor, b.surveyPort
+	b.mutex.RUnlock()
+
+	switch {
+	case surveyor != nil:
+		return surveyor.Survey(ctx)
+	case port != nil:
+		_, err := port.input.Write(port.parser.SurveyCommand())
+		return err
+	}
+	return ErrNoSurvey
+}
+
+// HoldPosition sets the antenna position the receiver holds instead of
+// surveying one, such as the known position of a relocated unit
+func (b *Bridge) HoldPosition(ctx context.Context, p gpsdo.Position) error {
+	// Written to reject NaN
+	if !(p.Latitude >= -90 && p.Latitude <= 90 && p.Longitude >= -180 && p.Longitude <= 180) {
+		return fmt.Errorf("position %.7f, %.7f out of range", p.Latitude, p.Longitude)
+	}
+
+	b.mutex.RLock()
+	surveyor, port := b.surveyor, b.surveyPort
+	b.mutex.RUnlock()
+
+	switch {
+	case surveyor != nil:
+		return surveyor.HoldPosition(ctx, p)
+	case port != nil:
+		_, err := port.input.Write(port.parser.PositionCommand(p))
+		return err
+	}
+	return ErrNoSurvey
+}
@@ -0,0 +1,215 @@
+// Package capture records and reads raw GPSDO serial output. Each chunk of
+// bytes is stored with the time it was received so that a session can be
+// replayed through the bridge at its original pace.
+//
+// A capture file starts with the 8 byte magic "GPSDOCAP" and a little endian
+// uint16 format version, followed by records of:
+//
+//	int64  receive time, Unix nanoseconds
+//	uint32 data length
+//	[]byte data
+package capture
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// Magic identifies a capture file
+const Magic = "GPSDOCAP"
+
+// Version is the capture format version
+const Version = 1
+
+// maxRecordLen bounds the data length of a single record
+const maxRecordLen = 1 << 16
+
+// ErrFormat is returned for files that are not valid captures
+var ErrFormat = errors.New("capture: invalid format")
+
+// Record is a chunk of raw serial data and when it was received
+type Record struct {
+	Time time.Time
+	Data []byte
+}
+
+// Writer appends records to a capture file
+type Writer struct {
+	w   io.Writer
+	buf []byte
+}
+
+// NewWriter writes the capture header to w and returns a Writer for the
+// records that follow
+func NewWriter(w io.Writer) (*Writer, error) {
+	header := binary.LittleEndian.AppendUint16([]byte(Magic), Version)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w}, nil
+}
+
+// Write stores data as a record received at t. Each record is written with a
+// single Write call so an interrupted capture loses at most the last record.
+func (w *Writer) Write(t time.Time, data []byte) error {
+	if len(data) > maxRecordLen {
+		return fmt.Errorf("capture: record length %d exceeds %d", len(data), maxRecordLen)
+	}
+
+	w.buf = binary.LittleEndian.AppendUint64(w.buf[:0], uint64(t.UnixNano()))
+	w.buf = binary.LittleEndian.AppendUint32(w.buf, uint32(len(data)))
+	w.buf = append(w.buf, data...)
+	_, err := w.w.Write(w.buf)
+	return err
+}
+
+// File is a Writer backed by a capture file
+type File struct {
+	*Writer
+	f *os.File
+}
+
+// Append opens the capture file at path for appending, creating it with a
+// header if it does not exist or is empty. An existing file must have a
+// matching header.
+func Append(path string) (*File, error) {
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+
+	if info.Size() == 0 {
+		w, err := NewWriter(f)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return &File{Writer: w, f: f}, nil
+	}
+
+	if _, err := NewReader(io.NewSectionReader(f, 0, info.Size())); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &File{Writer: &Writer{w: f}, f: f}, nil
+}
+
+// Close closes the capture file
+func (f *File) Close() error {
+	return f.f.Close()
+}
+
+// Reader reads records from a capture file
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader checks the capture header and returns a Reader positioned at the
+// first record
+func NewReader(r io.Reader) (*Reader, error) {
+	br := bufio.NewReader(r)
+
+	header := make([]byte, len(Magic)+2)
+	if _, err := io.ReadFull(br, header); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrFormat, err)
+	}
+	if string(header[:len(Magic)]) != Magic {
+		return nil, fmt.Errorf("%w: bad magic", ErrFormat)
+	}
+	if v := binary.LittleEndian.Uint16(header[len(Magic):]); v != Version {
+		return nil, fmt.Errorf("%w: unsupported version %d", ErrFormat, v)
+	}
+	return &Reader{r: br}, nil
+}
+
+// Next returns the next record, or io.EOF at the end of the capture
+func (r *Reader) Next() (Record, error) {
+	var header [12]byte
+	if _, err := io.ReadFull(r.r, header[:]); err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return Record{}, fmt.Errorf("%w: truncated record", ErrFormat)
+		}
+		return Record{}, err
+	}
+
+	length := binary.LittleEndian.Uint32(header[8:])
+	if length > maxRecordLen {
+		return Record{}, fmt.Errorf("%w: record length %d", ErrFormat, length)
+	}
+
+	data := make([]byte, length)
+	if _, err := io.ReadFull(r.r, data); err != nil {
+		return Record{}, fmt.Errorf("%w: truncated record", ErrFormat)
+	}
+
+	nanos := int64(binary.LittleEndian.Uint64(header[:8]))
+	return Record{Time: time.Unix(0, nanos), Data: data}, nil
+}
+
+// Player is an io.Reader that returns the data of each record after waiting
+// out the gap since the previous record
+type Player struct {
+	ctx   context.Context
+	r     *Reader
+	speed float64
+
+	pending []byte
+	last    time.Time
+}
+
+// NewPlayer plays the records of r. Gaps between records are divided by
+// speed, so 1 replays in real time and 10 ten times faster. A speed of 0
+// replays without waiting.
+func NewPlayer(ctx context.Context, r *Reader, speed float64) *Player {
+	return &Player{ctx: ctx, r: r, speed: speed}
+}
+
+// Read implements io.Reader
+func (p *Player) Read(buf []byte) (int, error) {
+	for len(p.pending) == 0 {
+		rec, err := p.r.Next()
+		if err != nil {
+			return 0, err
+		}
+
+		if !p.last.IsZero() && p.speed > 0 {
+			gap := time.Duration(float64(rec.Time.Sub(p.last)) / p.speed)
+			if err := p.sleep(gap); err != nil {
+				return 0, err
+			}
+		}
+		p.last = rec.Time
+		p.pending = rec.Data
+	}
+
+	n := copy(buf, p.pending)
+	p.pending = p.pending[n:]
+	return n, nil
+}
+
+func (p *Player) sleep(d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-p.ctx.Done():
+		return p.ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
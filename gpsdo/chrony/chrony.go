@@ -0,0 +1,200 @@
+// Package chrony feeds GPSDO samples to chronyd through its SOCK refclock
+// driver.
+//
+// See https://gitlab.com/chrony/chrony/-/blob/master/refclock_sock.c
+package chrony
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"log/slog"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"golang.org/x/sys/unix"
+)
+
+// ErrBusy is returned by Send when the previous sample has not been written
+// yet, usually because chronyd is not running
+var ErrBusy = errors.New("chrony: channel full or chrony offline")
+
+// SockMagic identifies a sock_sample datagram to chronyd
+const SockMagic = 0x534f434b
+
+// SockSample mirrors struct sock_sample from refclock_sock.c
+type SockSample struct {
+	Tv     unix.Timeval
+	Offset float64
+	Pulse  int32
+	Leap   int32
+	Pad    int32
+	Magic  int32
+}
+
+// NewSockSample builds the chrony datagram for a parsed GPSDO sample. Samples
+// paired with a PPS edge are sent as pulses stamped with the edge time, the
+// others as the offset of the GPS time from the system time the packet
+// arrived. chronyd reads the offset of a pulse the other way round, as the
+// offset of the system time of the edge from the true second.
+func NewSockSample(data *gpsdo.Sample) SockSample {
+	pulse := int32(0)
+	offset := data.SystemOffset().Seconds()
+	if !data.PPS.IsZero() {
+		pulse = 1
+		offset = -offset
+	}
+	return SockSample{
+		Tv:     timeval(data.ReceiveTime()),
+		Offset: offset,
+		Pulse:  pulse,
+		Leap:   int32(data.Leap),
+		Magic:  SockMagic,
+	}
+}
+
+// timeval converts t with the platform's field widths
+func timeval(t time.Time) unix.Timeval {
+	return unix.NsecToTimeval(t.UnixNano())
+}
+
+// Reconnect backoff limits
+const (
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+)
+
+// Client writes samples to a chrony SOCK refclock socket over a persistent
+// connection
+type Client struct {
+	sockFile string
+	samples  chan SockSample
+
+	mutex sync.Mutex
+	stats gpsdo.OutputStats
+}
+
+func NewClient(sockFile string) *Client {
+	return &Client{
+		sockFile: sockFile,
+		samples:  make(chan SockSample),
+	}
+}
+
+// Name identifies the output in logs
+func (c *Client) Name() string {
+	return "Chrony"
+}
+
+// Send offers a sample to the Run loop without blocking
+func (c *Client) Send(data *gpsdo.Sample) error {
+	select {
+	case c.samples <- NewSockSample(data):
+		return nil
+	default:
+		return ErrBusy
+	}
+}
+
+// OutputStats returns the connection counters
+func (c *Client) OutputStats() gpsdo.OutputStats {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.stats
+}
+
+// Run keeps a connection to the chrony socket open and writes every sample
+// passed to Send, until ctx is cancelled. A failed write, usually caused by
+// chronyd restarting, closes the connection and it is re-dialled with
+// exponential backoff.
+func (c *Client) Run(ctx context.Context) {
+	var conn net.Conn
+	backoff := minBackoff
+	buf := make([]byte, 0, SockSampleSize)
+
+	defer func() {
+		if conn != nil {
+			conn.Close()
+		}
+	}()
+
+	for {
+		// Try to connect if not connected
+		for conn == nil {
+			var err error
+			conn, err = net.Dial("unixgram", c.sockFile)
+			if err == nil {
+				c.mutex.Lock()
+				if c.stats.Connects > 0 {
+					c.stats.Reconnects++
+				}
+				c.stats.Connects++
+				c.stats.Connected = true
+				c.mutex.Unlock()
+
+				slog.Info("Connected to chrony socket", "socket", c.sockFile)
+				backoff = minBackoff
+				break
+			}
+
+			slog.Warn("Chrony socket unavailable", "socket", c.sockFile, "error", err, "retry", backoff)
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(backoff):
+			}
+			backoff = min(backoff*2, maxBackoff)
+		}
+
+		// Wait for a sample and try to send it
+		var sample SockSample
+		select {
+		case <-ctx.Done():
+			return
+		case sample = <-c.samples:
+		}
+
+		if err := c.sendSample(conn, buf, sample); err != nil {
+			c.mutex.Lock()
+			c.stats.WriteErrors++
+			c.stats.Connected = false
+			c.mutex.Unlock()
+
+			slog.Warn("Chrony socket error, reconnecting", "socket", c.sockFile, "error", err)
+			conn.Close()
+			conn = nil
+		}
+	}
+}
+
+// MarshalBinary encodes the sample as chronyd reads it from the socket: the
+// host's struct sock_sample in native byte order. The width of the struct
+// timeval fields depends on the platform, see SockSampleSize.
+func (s SockSample) MarshalBinary() ([]byte, error) {
+	return s.AppendBinary(make([]byte, 0, SockSampleSize))
+}
+
+// AppendBinary appends the encoding of MarshalBinary to buf
+func (s SockSample) AppendBinary(buf []byte) ([]byte, error) {
+	buf = appendTimeval(buf, s.Tv)
+	buf = binary.NativeEndian.AppendUint64(buf, math.Float64bits(s.Offset))
+	buf = binary.NativeEndian.AppendUint32(buf, uint32(s.Pulse))
+	buf = binary.NativeEndian.AppendUint32(buf, uint32(s.Leap))
+	buf = binary.NativeEndian.AppendUint32(buf, uint32(s.Pad))
+	buf = binary.NativeEndian.AppendUint32(buf, uint32(s.Magic))
+	return buf, nil
+}
+
+// sendSample writes sample, encoded in the buffer of the Run loop
+func (c *Client) sendSample(conn net.Conn, buf []byte, sample SockSample) error {
+	buf, err := sample.AppendBinary(buf[:0])
+	if err != nil {
+		return err
+	}
+
+	_, err = conn.Write(buf)
+	return err
+}
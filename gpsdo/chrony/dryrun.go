@@ -0,0 +1,46 @@
+package chrony
+
+import (
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// DryRun is an output that prints the samples a Client would send to
+// chronyd instead of writing them to the socket
+type DryRun struct {
+	mutex sync.Mutex
+	w     io.Writer
+}
+
+// NewDryRun creates a dry run output printing to w
+func NewDryRun(w io.Writer) *DryRun {
+	return &DryRun{w: w}
+}
+
+// Name implements bridge.Output
+func (d *DryRun) Name() string {
+	return "Chrony (dry run)"
+}
+
+// Send prints the decoded sock_sample fields and the datagram bytes
+func (d *DryRun) Send(data *gpsdo.Sample) error {
+	sample := NewSockSample(data)
+	buf, err := sample.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+	_, err = fmt.Fprintf(d.w, "chrony sample: %s\n  %d bytes: % x\n", sample, len(buf), buf)
+	return err
+}
+
+// String formats the fields as chronyd decodes them
+func (s SockSample) String() string {
+	return fmt.Sprintf("tv=%d.%06d offset=%+.9f pulse=%d leap=%d pad=%d magic=%#x",
+		s.Tv.Sec, s.Tv.Usec, s.Offset, s.Pulse, s.Leap, s.Pad, s.Magic)
+}
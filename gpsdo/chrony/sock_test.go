@@ -0,0 +1,111 @@
+package chrony
+
+import (
+	"encoding/binary"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// field is the offset and width of an integer field
+type field struct {
+	offset, size int
+}
+
+// sockLayout is the expected struct sock_sample layout of a platform
+type sockLayout struct {
+	size                            int
+	sec, usec                       field
+	offset, pulse, leap, pad, magic int
+}
+
+func (f field) read(buf []byte) int64 {
+	if f.size == 8 {
+		return int64(binary.NativeEndian.Uint64(buf[f.offset:]))
+	}
+	return int64(int32(binary.NativeEndian.Uint32(buf[f.offset:])))
+}
+
+func readInt32(buf []byte, offset int) int32 {
+	return int32(binary.NativeEndian.Uint32(buf[offset:]))
+}
+
+func checkLayout(t *testing.T, want sockLayout) {
+	t.Helper()
+
+	edge := time.Unix(1757205798, 250000000)
+	sample := NewSockSample(&gpsdo.Sample{
+		Timestamp: edge.Add(-1 * time.Millisecond),
+		PPS:       edge,
+		Leap:      gpsdo.LeapInsert,
+	})
+
+	buf, err := sample.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if SockSampleSize != want.size || len(buf) != want.size {
+		t.Fatalf("size = %d (SockSampleSize %d), want %d", len(buf), SockSampleSize, want.size)
+	}
+
+	if got := want.sec.read(buf); got != 1757205798 {
+		t.Errorf("tv_sec = %d, want 1757205798", got)
+	}
+	if got := want.usec.read(buf); got != 250000 {
+		t.Errorf("tv_usec = %d, want 250000", got)
+	}
+	if got := math.Float64frombits(binary.NativeEndian.Uint64(buf[want.offset:])); got != 0.001 {
+		t.Errorf("offset = %v, want 0.001, the system time of the edge is 1ms late", got)
+	}
+	if got := readInt32(buf, want.pulse); got != 1 {
+		t.Errorf("pulse = %d, want 1", got)
+	}
+	if got := readInt32(buf, want.leap); got != int32(gpsdo.LeapInsert) {
+		t.Errorf("leap = %d, want %d", got, gpsdo.LeapInsert)
+	}
+	if got := readInt32(buf, want.pad); got != 0 {
+		t.Errorf("pad = %d, want 0", got)
+	}
+	if got := readInt32(buf, want.magic); got != SockMagic {
+		t.Errorf("magic = %#x, want %#x", got, SockMagic)
+	}
+}
+
+func TestNewSockSampleTOD(t *testing.T) {
+	second := time.Unix(1757205798, 0)
+	sample := NewSockSample(&gpsdo.Sample{
+		Timestamp: second,
+		ParseTime: second.Add(300 * time.Millisecond),
+		Arrival:   second.Add(250 * time.Millisecond),
+		Offset:    -10 * time.Millisecond,
+	})
+
+	if sample.Tv.Sec != 1757205798 || sample.Tv.Usec != 250000 {
+		t.Errorf("tv = %d.%06d, want the arrival 1757205798.250000", sample.Tv.Sec, sample.Tv.Usec)
+	}
+	if math.Abs(sample.Offset-(-0.26)) > 1e-9 {
+		t.Errorf("offset = %v, want -0.26", sample.Offset)
+	}
+	if sample.Pulse != 0 {
+		t.Errorf("pulse = %d, want 0", sample.Pulse)
+	}
+}
+
+// TestAppendBinaryAllocs checks that encoding into the buffer of the Run
+// loop doesn't allocate, the bridge sends a sample every second
+func TestAppendBinaryAllocs(t *testing.T) {
+	data := &gpsdo.Sample{Timestamp: time.Unix(1757205798, 0), Arrival: time.Unix(1757205798, 250000000)}
+	buf := make([]byte, 0, SockSampleSize)
+	allocs := testing.AllocsPerRun(100, func() {
+		buf, _ = NewSockSample(data).AppendBinary(buf[:0])
+	})
+	if allocs != 0 {
+		t.Errorf("%v allocations per sample, want 0", allocs)
+	}
+	want, _ := NewSockSample(data).MarshalBinary()
+	if string(buf) != string(want) {
+		t.Errorf("AppendBinary = %x, MarshalBinary = %x", buf, want)
+	}
+}
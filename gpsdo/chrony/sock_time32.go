@@ -0,0 +1,20 @@
+//go:build (386 || arm || mips || mipsle) && !time64
+
+package chrony
+
+import (
+	"encoding/binary"
+
+	"golang.org/x/sys/unix"
+)
+
+// SockSampleSize is the size of struct sock_sample with the traditional
+// 32-bit time_t of 32-bit platforms. The double that follows the timeval is
+// already 8 byte aligned, so there is no padding.
+const SockSampleSize = 32
+
+// appendTimeval encodes a struct timeval with 32-bit tv_sec and tv_usec
+func appendTimeval(buf []byte, tv unix.Timeval) []byte {
+	buf = binary.NativeEndian.AppendUint32(buf, uint32(int32(tv.Sec)))
+	return binary.NativeEndian.AppendUint32(buf, uint32(int32(tv.Usec)))
+}
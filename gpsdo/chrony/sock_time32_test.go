@@ -0,0 +1,14 @@
+//go:build (386 || arm || mips || mipsle) && !time64
+
+package chrony
+
+import "testing"
+
+func TestSockSampleLayout(t *testing.T) {
+	checkLayout(t, sockLayout{
+		size:   32,
+		sec:    field{0, 4},
+		usec:   field{4, 4},
+		offset: 8, pulse: 16, leap: 20, pad: 24, magic: 28,
+	})
+}
@@ -0,0 +1,21 @@
+//go:build !(386 || arm || mips || mipsle) || time64
+
+package chrony
+
+import (
+	"encoding/binary"
+
+	"golang.org/x/sys/unix"
+)
+
+// SockSampleSize is the size of struct sock_sample with a 64-bit time_t,
+// used on 64-bit platforms and on 32-bit platforms built with the time64
+// tag for a chronyd compiled with _TIME_BITS=64, such as Debian armhf
+// since trixie
+const SockSampleSize = 40
+
+// appendTimeval encodes a struct timeval with 64-bit tv_sec and tv_usec
+func appendTimeval(buf []byte, tv unix.Timeval) []byte {
+	buf = binary.NativeEndian.AppendUint64(buf, uint64(int64(tv.Sec)))
+	return binary.NativeEndian.AppendUint64(buf, uint64(int64(tv.Usec)))
+}
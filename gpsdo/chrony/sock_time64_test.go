@@ -0,0 +1,14 @@
+//go:build !(386 || arm || mips || mipsle) || time64
+
+package chrony
+
+import "testing"
+
+func TestSockSampleLayout(t *testing.T) {
+	checkLayout(t, sockLayout{
+		size:   40,
+		sec:    field{0, 8},
+		usec:   field{8, 8},
+		offset: 16, pulse: 24, leap: 28, pad: 32, magic: 36,
+	})
+}
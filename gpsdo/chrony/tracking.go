@@ -0,0 +1,326 @@
+package chrony
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// queryTimeout bounds one chronyc command
+const queryTimeout = 5 * time.Second
+
+// Tracking is chronyd's view of a refclock and of the system clock it
+// disciplines, as reported by chronyc
+type Tracking struct {
+	// RefID is the refid of the refclock in chrony.conf
+	RefID string
+	// Source is the refclock in chronyc sources, nil if chronyd has no
+	// source of that refid
+	Source *Source
+	// Stats is the refclock in chronyc sourcestats, nil if chronyd has no
+	// statistics for it
+	Stats *SourceStats
+	// System is chronyc tracking
+	System  System
+	Updated time.Time
+}
+
+// Selected reports whether chronyd synchronizes the system clock to the
+// refclock
+func (t *Tracking) Selected() bool {
+	return t.Source != nil && t.Source.State == "*"
+}
+
+// System is a chronyc tracking report. Times are in seconds and frequencies
+// in ppm.
+type System struct {
+	// RefID is the reference ID in hex and Name the refclock or server the
+	// system clock is synchronized to
+	RefID          string
+	Name           string
+	Stratum        int
+	RefTime        time.Time
+	SystemTime     float64
+	LastOffset     float64
+	RMSOffset      float64
+	Frequency      float64
+	ResidualFreq   float64
+	Skew           float64
+	RootDelay      float64
+	RootDispersion float64
+	UpdateInterval float64
+	LeapStatus     string
+}
+
+// Source is a line of chronyc sources
+type Source struct {
+	// Mode is ^ for a server, = for a peer and # for a refclock
+	Mode string
+	// State is * for the selected source, + for those combined with it, -
+	// for those not combined, ? for unusable ones, x for falsetickers and ~
+	// for those too variable
+	State   string
+	Name    string
+	Stratum int
+	// Poll is the log2 of the polling interval in seconds
+	Poll int
+	// Reach is the reachability register, one bit for each of the last 8
+	// polls
+	Reach uint8
+	// LastRx is the time since the last sample, -1 if none
+	LastRx time.Duration
+	// Offset is the last sample offset adjusted for the clock updates since,
+	// Measured the offset as measured and Error its error bound, in seconds
+	Offset   float64
+	Measured float64
+	Error    float64
+}
+
+// SourceStats is a line of chronyc sourcestats
+type SourceStats struct {
+	Name string
+	// Samples is the number of samples kept and Runs the number of runs of
+	// residuals with the same sign
+	Samples int
+	Runs    int
+	Span    time.Duration
+	// Frequency and Skew are the estimated frequency offset and its error
+	// bound in ppm
+	Frequency float64
+	Skew      float64
+	// Offset is the estimated offset and StdDev the sample standard
+	// deviation, the jitter, in seconds
+	Offset float64
+	StdDev float64
+}
+
+// fields splits a line of chronyc -c output, checking the field count
+func fields(line string, n int, report string) ([]string, error) {
+	f := strings.Split(strings.TrimSpace(line), ",")
+	if len(f) != n {
+		return nil, fmt.Errorf("chronyc %s: %d fields, expected %d: %q", report, len(f), n, line)
+	}
+	return f, nil
+}
+
+// numbers parses the fields of a report, keeping the first error
+type numbers struct {
+	report string
+	err    error
+}
+
+func (p *numbers) float(s string) float64 {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil && p.err == nil {
+		p.err = fmt.Errorf("chronyc %s: %w", p.report, err)
+	}
+	return v
+}
+
+func (p *numbers) int(s string) int {
+	return int(p.float(s))
+}
+
+// ParseTracking parses the output of chronyc -c tracking
+func ParseTracking(out string) (System, error) {
+	f, err := fields(out, 14, "tracking")
+	if err != nil {
+		return System{}, err
+	}
+	p := numbers{report: "tracking"}
+	s := System{
+		RefID:          f[0],
+		Name:           f[1],
+		Stratum:        p.int(f[2]),
+		SystemTime:     p.float(f[4]),
+		LastOffset:     p.float(f[5]),
+		RMSOffset:      p.float(f[6]),
+		Frequency:      p.float(f[7]),
+		ResidualFreq:   p.float(f[8]),
+		Skew:           p.float(f[9]),
+		RootDelay:      p.float(f[10]),
+		RootDispersion: p.float(f[11]),
+		UpdateInterval: p.float(f[12]),
+		LeapStatus:     f[13],
+	}
+	// Zero until chronyd first updates the clock
+	if ref := p.float(f[3]); ref > 0 {
+		sec := int64(ref)
+		s.RefTime = time.Unix(sec, int64((ref-float64(sec))*1e9))
+	}
+	return s, p.err
+}
+
+// ParseSources parses the output of chronyc -c sources
+func ParseSources(out string) ([]Source, error) {
+	var sources []Source
+	for line := range strings.Lines(out) {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		f, err := fields(line, 10, "sources")
+		if err != nil {
+			return nil, err
+		}
+		reach, err := strconv.ParseUint(f[5], 8, 8)
+		if err != nil {
+			return nil, fmt.Errorf("chronyc sources: reach %q: %w", f[5], err)
+		}
+		p := numbers{report: "sources"}
+		s := Source{
+			Mode:     f[0],
+			State:    f[1],
+			Name:     f[2],
+			Stratum:  p.int(f[3]),
+			Poll:     p.int(f[4]),
+			Reach:    uint8(reach),
+			LastRx:   -1,
+			Offset:   p.float(f[7]),
+			Measured: p.float(f[8]),
+			Error:    p.float(f[9]),
+		}
+		// A source never heard from has no last sample
+		if rx, err := strconv.ParseInt(f[6], 10, 64); err == nil {
+			s.LastRx = time.Duration(rx) * time.Second
+		}
+		if p.err != nil {
+			return nil, p.err
+		}
+		sources = append(sources, s)
+	}
+	return sources, nil
+}
+
+// ParseSourceStats parses the output of chronyc -c sourcestats
+func ParseSourceStats(out string) ([]SourceStats, error) {
+	var stats []SourceStats
+	for line := range strings.Lines(out) {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		f, err := fields(line, 8, "sourcestats")
+		if err != nil {
+			return nil, err
+		}
+		p := numbers{report: "sourcestats"}
+		s := SourceStats{
+			Name:      f[0],
+			Samples:   p.int(f[1]),
+			Runs:      p.int(f[2]),
+			Span:      time.Duration(p.int(f[3])) * time.Second,
+			Frequency: p.float(f[4]),
+			Skew:      p.float(f[5]),
+			Offset:    p.float(f[6]),
+			StdDev:    p.float(f[7]),
+		}
+		if p.err != nil {
+			return nil, p.err
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+// chronyc runs a chronyc command with machine readable output and without
+// resolving server addresses
+func chronyc(ctx context.Context, command string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "chronyc", "-c", "-n", command)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("chronyc %s: %w: %s", command, err, msg)
+		}
+		return "", fmt.Errorf("chronyc %s: %w", command, err)
+	}
+	return stdout.String(), nil
+}
+
+// Query asks chronyd about the refclock with the refid and the system clock
+func Query(ctx context.Context, refid string) (*Tracking, error) {
+	out, err := chronyc(ctx, "tracking")
+	if err != nil {
+		return nil, err
+	}
+	system, err := ParseTracking(out)
+	if err != nil {
+		return nil, err
+	}
+	t := &Tracking{RefID: refid, System: system, Updated: time.Now()}
+
+	if out, err = chronyc(ctx, "sources"); err != nil {
+		return nil, err
+	}
+	sources, err := ParseSources(out)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range sources {
+		if s.Name == refid {
+			t.Source = &s
+		}
+	}
+
+	if out, err = chronyc(ctx, "sourcestats"); err != nil {
+		return nil, err
+	}
+	stats, err := ParseSourceStats(out)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range stats {
+		if s.Name == refid {
+			t.Stats = &s
+		}
+	}
+	return t, nil
+}
+
+// Poll queries chronyd about the refclock with the refid every interval
+// until ctx is cancelled, passing the result to update, or nil when chronyd
+// can't be queried
+func Poll(ctx context.Context, refid string, interval time.Duration, update func(*Tracking)) {
+	log := slog.With("refid", refid)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// failed and missing keep repeated problems out of the log
+	var failed, missing bool
+	for {
+		t, err := Query(ctx, refid)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case err != nil && !failed:
+			failed = true
+			log.Warn("chronyd query failed", "error", err)
+		case err != nil:
+			log.Debug("chronyd query failed", "error", err)
+		case failed:
+			failed = false
+			log.Info("chronyd query succeeded")
+		}
+		if t != nil {
+			if t.Source == nil && !missing {
+				log.Warn("chronyd has no refclock with the refid, check refid in chrony.conf")
+			}
+			missing = t.Source == nil
+		}
+		update(t)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
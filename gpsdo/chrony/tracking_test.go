@@ -0,0 +1,88 @@
+package chrony
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTracking(t *testing.T) {
+	s, err := ParseTracking("47505344,GPSD,1,1757205798.250000000,0.000000012,-0.000000034,0.000000210,-12.345,0.001,0.015,0.000000001,0.000010500,16.0,Normal\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.RefID != "47505344" || s.Name != "GPSD" || s.Stratum != 1 || s.LeapStatus != "Normal" {
+		t.Errorf("got %+v", s)
+	}
+	if !s.RefTime.Equal(time.Unix(1757205798, 250000000)) {
+		t.Errorf("ref time = %v", s.RefTime)
+	}
+	if s.SystemTime != 12e-9 || s.LastOffset != -34e-9 || s.Frequency != -12.345 || s.UpdateInterval != 16 {
+		t.Errorf("got %+v", s)
+	}
+
+	s, err = ParseTracking("7F7F0101,,10,0.000000000,0.000000000,0.000000000,0.000000000,0.000,0.000,0.000,0.000000000,0.000000000,0.0,Not synchronised")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.RefTime.IsZero() || s.LeapStatus != "Not synchronised" {
+		t.Errorf("unsynchronized: got %+v", s)
+	}
+
+	for _, out := range []string{"", "47505344,GPSD,1", "47505344,GPSD,x,0,0,0,0,0,0,0,0,0,0,Normal"} {
+		if _, err := ParseTracking(out); err == nil {
+			t.Errorf("ParseTracking(%q) succeeded", out)
+		}
+	}
+}
+
+func TestParseSources(t *testing.T) {
+	sources, err := ParseSources(`#,*,GPSD,0,4,377,13,-0.000000120,-0.000000100,0.000000200
+#,?,PPSG,0,4,0,-,0.000000000,0.000000000,0.000000000
+^,-,192.0.2.1,2,6,17,45,0.001234000,0.001230000,0.025000000
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sources) != 3 {
+		t.Fatalf("got %d sources, want 3", len(sources))
+	}
+
+	gpsd := sources[0]
+	if gpsd.Mode != "#" || gpsd.State != "*" || gpsd.Name != "GPSD" || gpsd.Poll != 4 {
+		t.Errorf("got %+v", gpsd)
+	}
+	if gpsd.Reach != 0377 || gpsd.LastRx != 13*time.Second || gpsd.Offset != -120e-9 || gpsd.Error != 200e-9 {
+		t.Errorf("got %+v", gpsd)
+	}
+	if sources[1].Reach != 0 || sources[1].LastRx != -1 {
+		t.Errorf("unreached source: got %+v", sources[1])
+	}
+	if sources[2].Reach != 017 || sources[2].Stratum != 2 {
+		t.Errorf("server: got %+v", sources[2])
+	}
+
+	if _, err := ParseSources("#,*,GPSD,0,4,999,13,0,0,0\n"); err == nil {
+		t.Error("reach 999 accepted")
+	}
+}
+
+func TestParseSourceStats(t *testing.T) {
+	stats, err := ParseSourceStats("GPSD,64,32,1008,0.000,0.001,-0.000000001,0.000000100\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 {
+		t.Fatalf("got %d lines, want 1", len(stats))
+	}
+	s := stats[0]
+	if s.Name != "GPSD" || s.Samples != 64 || s.Runs != 32 || s.Span != 1008*time.Second {
+		t.Errorf("got %+v", s)
+	}
+	if s.Skew != 0.001 || s.Offset != -1e-9 || s.StdDev != 100e-9 {
+		t.Errorf("got %+v", s)
+	}
+
+	if _, err := ParseSourceStats("GPSD,64,32,1008\n"); err == nil {
+		t.Error("short line accepted")
+	}
+}
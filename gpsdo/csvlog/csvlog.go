@@ -0,0 +1,176 @@
+// Package csvlog appends accepted samples to a CSV file, rotated by size, so
+// long term data can be analyzed in a spreadsheet or pandas without a
+// database.
+package csvlog
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// header names the columns of every file
+var header = []string{"gps_time", "receive_time", "offset", "status", "leap_seconds"}
+
+// Options control rotation and the files created
+type Options struct {
+	// MaxSize rotates the file before it would exceed this many bytes, 0 to
+	// never rotate
+	MaxSize int64
+	// MaxFiles is the number of rotated files kept, named FILE.1 (newest)
+	// to FILE.MaxFiles
+	MaxFiles int
+	// Mode is the mode of created files, 0644 if zero
+	Mode os.FileMode
+	// Created, if set, is called with the path of every file created, such
+	// as to set its owner
+	Created func(path string) error
+}
+
+// Writer is a bridge output writing one CSV row per sample
+type Writer struct {
+	path  string
+	opts  Options
+	mutex sync.Mutex
+	file  *os.File
+	csv   *csv.Writer
+	size  int64
+}
+
+// Open opens path for appending, writing the header if the file is new
+func Open(path string, opts Options) (*Writer, error) {
+	if opts.Mode == 0 {
+		opts.Mode = 0o644
+	}
+	w := &Writer{path: path, opts: opts}
+	if err := w.open(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) open() error {
+	_, err := os.Stat(w.path)
+	created := os.IsNotExist(err)
+
+	f, err := os.OpenFile(w.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, w.opts.Mode)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if created {
+		// The mode passed to OpenFile is reduced by the umask
+		if err := f.Chmod(w.opts.Mode); err != nil {
+			f.Close()
+			return err
+		}
+		if w.opts.Created != nil {
+			if err := w.opts.Created(w.path); err != nil {
+				f.Close()
+				return err
+			}
+		}
+	}
+
+	w.file = f
+	w.csv = csv.NewWriter(f)
+	w.size = info.Size()
+	if w.size == 0 {
+		return w.write(header)
+	}
+	return nil
+}
+
+// Name implements bridge.Output
+func (w *Writer) Name() string {
+	return "CSV"
+}
+
+// Send appends a row for data
+func (w *Writer) Send(data *gpsdo.Sample) error {
+	row := []string{
+		data.Timestamp.UTC().Format(time.RFC3339Nano),
+		data.ReceiveTime().UTC().Format(time.RFC3339Nano),
+		strconv.FormatFloat(data.SystemOffset().Seconds(), 'f', 9, 64),
+		data.Status.String(),
+		strconv.Itoa(data.LeapSeconds),
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.file == nil {
+		return fmt.Errorf("csv file %s is closed", w.path)
+	}
+	if w.opts.MaxSize > 0 && w.size+rowSize(row) > w.opts.MaxSize {
+		if err := w.rotate(); err != nil {
+			return fmt.Errorf("rotate %s: %w", w.path, err)
+		}
+	}
+	return w.write(row)
+}
+
+// write writes and flushes one row
+func (w *Writer) write(row []string) error {
+	if err := w.csv.Write(row); err != nil {
+		return err
+	}
+	w.csv.Flush()
+	if err := w.csv.Error(); err != nil {
+		return err
+	}
+	w.size += rowSize(row)
+	return nil
+}
+
+// rowSize is the encoded length of a row without quoting, which the
+// columns never need
+func rowSize(row []string) int64 {
+	n := len(row)
+	for _, field := range row {
+		n += len(field)
+	}
+	return int64(n)
+}
+
+// rotate renames the file to FILE.1, shifting older files up and removing
+// the oldest, and starts a new file
+func (w *Writer) rotate() error {
+	if err := w.file.Close(); err != nil {
+		return err
+	}
+	w.file = nil
+
+	if w.opts.MaxFiles <= 0 {
+		os.Remove(w.path)
+	} else {
+		os.Remove(fmt.Sprintf("%s.%d", w.path, w.opts.MaxFiles))
+		for i := w.opts.MaxFiles - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", w.path, i), fmt.Sprintf("%s.%d", w.path, i+1))
+		}
+		if err := os.Rename(w.path, w.path+".1"); err != nil {
+			return err
+		}
+	}
+	return w.open()
+}
+
+// Close closes the file
+func (w *Writer) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.file == nil {
+		return nil
+	}
+	err := w.file.Close()
+	w.file = nil
+	return err
+}
@@ -0,0 +1,183 @@
+// Package fifo writes every sample as a line of JSON to a named pipe, so
+// scripts on the same host can read the decoded time code with cat or a
+// shell loop, without linking the library or speaking HTTP.
+//
+// The pipe is opened without blocking. Samples are discarded while no
+// reader has it open, and lines a slow reader leaves no room for are
+// dropped rather than delaying the other outputs.
+package fifo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// Line is the JSON written for each sample
+type Line struct {
+	Timestamp   time.Time `json:"timestamp"`
+	Status      string    `json:"status"`
+	Valid       bool      `json:"valid"`
+	LeapSeconds int       `json:"leap_seconds"`
+	Leap        string    `json:"leap"`
+	// Offset is the sample time minus the system clock in seconds, null for
+	// an invalid sample
+	Offset *float64 `json:"offset"`
+	// PPS is set when the sample was paired with a PPS edge
+	PPS bool `json:"pps"`
+	// Received is the system time the sample refers to
+	Received time.Time `json:"received"`
+}
+
+// NewLine converts a sample
+func NewLine(data *gpsdo.Sample) Line {
+	line := Line{
+		Timestamp:   data.Timestamp,
+		Status:      data.Status.String(),
+		Valid:       data.Valid,
+		LeapSeconds: data.LeapSeconds,
+		Leap:        data.Leap.String(),
+		PPS:         !data.PPS.IsZero(),
+		Received:    data.ReceiveTime(),
+	}
+	if data.Valid {
+		offset := data.SystemOffset().Seconds()
+		line.Offset = &offset
+	}
+	return line
+}
+
+// Options control the pipe created
+type Options struct {
+	// Mode is the mode of a created pipe, 0644 if zero
+	Mode os.FileMode
+	// Created, if set, is called with the path of a created pipe, such as
+	// to set its owner
+	Created func(path string) error
+}
+
+// Writer is a bridge output writing one line per sample
+type Writer struct {
+	path   string
+	mutex  sync.Mutex
+	fd     int
+	closed bool
+	stats  gpsdo.OutputStats
+}
+
+// Open creates the named pipe at path unless it already exists. The pipe is
+// opened for writing by Send once a reader is there.
+func Open(path string, opts Options) (*Writer, error) {
+	if opts.Mode == 0 {
+		opts.Mode = 0o644
+	}
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		if err := unix.Mkfifo(path, uint32(opts.Mode.Perm())); err != nil {
+			return nil, &os.PathError{Op: "mkfifo", Path: path, Err: err}
+		}
+		// The mode passed to mkfifo is reduced by the umask
+		if err := os.Chmod(path, opts.Mode); err != nil {
+			return nil, err
+		}
+		if opts.Created != nil {
+			if err := opts.Created(path); err != nil {
+				return nil, err
+			}
+		}
+	case err != nil:
+		return nil, err
+	case info.Mode().Type() != os.ModeNamedPipe:
+		return nil, fmt.Errorf("%s exists and is not a named pipe", path)
+	}
+	return &Writer{path: path, fd: -1}, nil
+}
+
+// Name implements bridge.Output
+func (w *Writer) Name() string {
+	return "FIFO"
+}
+
+// Send writes a line for data if a reader has the pipe open
+func (w *Writer) Send(data *gpsdo.Sample) error {
+	line, err := json.Marshal(NewLine(data))
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.closed {
+		return fmt.Errorf("fifo %s is closed", w.path)
+	}
+	if w.fd < 0 {
+		// ENXIO until a reader opens the pipe
+		fd, err := unix.Open(w.path, unix.O_WRONLY|unix.O_NONBLOCK|unix.O_CLOEXEC, 0)
+		if err == unix.ENXIO {
+			return nil
+		}
+		if err != nil {
+			return &os.PathError{Op: "open", Path: w.path, Err: err}
+		}
+		w.fd = fd
+		if w.stats.Connects > 0 {
+			w.stats.Reconnects++
+		}
+		w.stats.Connects++
+		w.stats.Connected = true
+		slog.Debug("FIFO reader connected", "path", w.path)
+	}
+
+	// Writes of at most PIPE_BUF bytes are atomic, a line is either written
+	// whole or not at all
+	_, err = unix.Write(w.fd, line)
+	switch err {
+	case nil:
+	case unix.EAGAIN:
+		w.stats.WriteErrors++
+	case unix.EPIPE:
+		w.disconnect()
+		slog.Debug("FIFO reader disconnected", "path", w.path)
+	default:
+		w.stats.WriteErrors++
+		w.disconnect()
+		return &os.PathError{Op: "write", Path: w.path, Err: err}
+	}
+	return nil
+}
+
+// disconnect closes the pipe until the next reader
+func (w *Writer) disconnect() {
+	unix.Close(w.fd)
+	w.fd = -1
+	w.stats.Connected = false
+}
+
+// OutputStats returns the reader counters, a connect for every time a
+// reader opened the pipe and a write error for every line dropped
+func (w *Writer) OutputStats() gpsdo.OutputStats {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	return w.stats
+}
+
+// Close closes the pipe, leaving it in place for the next run
+func (w *Writer) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	w.closed = true
+	if w.fd >= 0 {
+		w.disconnect()
+	}
+	return nil
+}
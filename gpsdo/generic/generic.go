@@ -0,0 +1,258 @@
+// Package generic parses fixed-width time-of-day packets whose layout is
+// described by configuration rather than code, so obscure receivers can be
+// supported without writing a parser. A packet has a fixed length and ends
+// in a terminator that never occurs inside it. Its fields are runs of
+// decimal digits, either one BCD digit (0x00-0x09) or one ASCII digit per
+// byte.
+package generic
+
+import (
+	"cmp"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// Encodings of the digits
+const (
+	// EncodingBCD has one binary digit 0x00-0x09 per byte, like the Z3805A
+	EncodingBCD = "bcd"
+	// EncodingASCII has one ASCII digit '0'-'9' per byte
+	EncodingASCII = "ascii"
+)
+
+var (
+	// ErrFraming is returned when a terminator arrives before a full packet
+	ErrFraming = errors.New("generic: short packet")
+	// ErrFormat is returned for packets that cannot be decoded
+	ErrFormat = errors.New("generic: malformed packet")
+	// ErrRange is returned for packets with a time field out of range or a
+	// byte that isn't a digit
+	ErrRange = errors.New("generic: field out of range")
+)
+
+// Field is the position of a field in the packet, absent if Width is 0
+type Field struct {
+	Offset int `yaml:"offset"`
+	Width  int `yaml:"width"`
+}
+
+// Fields are the positions of the time and status fields
+type Fields struct {
+	// Year has 2 digits, 20yy, or 4. Without it the year is taken from the
+	// system clock.
+	Year      Field `yaml:"year"`
+	DayOfYear Field `yaml:"day_of_year"`
+	Hour      Field `yaml:"hour"`
+	Minute    Field `yaml:"minute"`
+	Second    Field `yaml:"second"`
+	// LeapSeconds is the optional GPS-UTC offset
+	LeapSeconds Field `yaml:"leap_seconds"`
+	// Status is compared with the StatusValues, without it every packet is
+	// locked
+	Status Field `yaml:"status"`
+}
+
+// StatusValues lists the status field values of each status, as hex bytes
+// for BCD packets (e.g. "1000") and as text for ASCII packets. Values not
+// listed are UNKNOWN.
+type StatusValues struct {
+	Locked   []string `yaml:"locked"`
+	Holdover []string `yaml:"holdover"`
+	PowerUp  []string `yaml:"power_up"`
+}
+
+// Layout describes a packet format
+type Layout struct {
+	// Length is the packet length including the terminator
+	Length     int          `yaml:"length"`
+	Terminator byte         `yaml:"terminator"`
+	Encoding   string       `yaml:"encoding"`
+	Fields     Fields       `yaml:"fields"`
+	Status     StatusValues `yaml:"status"`
+}
+
+// Validate checks that the layout can be decoded
+func (l *Layout) Validate() error {
+	if l.Length < 2 {
+		return errors.New("length must be at least 2")
+	}
+	if l.Encoding != EncodingBCD && l.Encoding != EncodingASCII {
+		return fmt.Errorf("encoding must be %s or %s", EncodingBCD, EncodingASCII)
+	}
+	f := l.Fields
+	for _, field := range []struct {
+		name     string
+		field    Field
+		required bool
+	}{
+		{"year", f.Year, false},
+		{"day_of_year", f.DayOfYear, true},
+		{"hour", f.Hour, true},
+		{"minute", f.Minute, true},
+		{"second", f.Second, true},
+		{"leap_seconds", f.LeapSeconds, false},
+		{"status", f.Status, false},
+	} {
+		if field.field.Width == 0 {
+			if field.required {
+				return fmt.Errorf("field %s is required", field.name)
+			}
+			continue
+		}
+		if field.field.Offset < 0 || field.field.Width < 0 || field.field.Offset+field.field.Width > l.Length-1 {
+			return fmt.Errorf("field %s is outside the packet", field.name)
+		}
+	}
+	if f.Year.Width != 0 && f.Year.Width != 2 && f.Year.Width != 4 {
+		return errors.New("field year must have 2 or 4 digits")
+	}
+	return nil
+}
+
+// Decoder reframes packets of a layout from a byte stream regardless of how
+// reads are chunked
+type Decoder struct {
+	layout *Layout
+	buf    []byte
+}
+
+// NewDecoder returns a decoder of layout
+func NewDecoder(layout *Layout) *Decoder {
+	return &Decoder{layout: layout}
+}
+
+// Feed consumes one byte of the stream. It returns the packet when the byte
+// is a terminator completing one, and ErrFraming when a terminator follows a
+// partial packet. The returned packet is only valid until the next call.
+func (d *Decoder) Feed(c byte) ([]byte, error) {
+	length := d.layout.Length
+	if len(d.buf) == length {
+		// Slide the window, the oldest byte cannot start a packet
+		copy(d.buf, d.buf[1:])
+		d.buf = d.buf[:length-1]
+	}
+	d.buf = append(d.buf, c)
+
+	if c != d.layout.Terminator {
+		return nil, nil
+	}
+
+	packet := d.buf
+	d.buf = d.buf[:0]
+	if len(packet) != length {
+		return nil, ErrFraming
+	}
+	return packet, nil
+}
+
+// Parse decodes a packet of the layout
+func (l *Layout) Parse(data []byte) (*gpsdo.Sample, error) {
+	if len(data) != l.Length || data[l.Length-1] != l.Terminator {
+		return nil, fmt.Errorf("%w: %q", ErrFormat, data)
+	}
+	f := l.Fields
+	day, err1 := l.number(data, f.DayOfYear)
+	hour, err2 := l.number(data, f.Hour)
+	minute, err3 := l.number(data, f.Minute)
+	second, err4 := l.number(data, f.Second)
+	leapSeconds, err5 := l.number(data, f.LeapSeconds)
+	if err := cmp.Or(err1, err2, err3, err4, err5); err != nil {
+		return nil, fmt.Errorf("%w: %q", err, data)
+	}
+	if hour > 23 || minute > 59 || second > 59 {
+		return nil, fmt.Errorf("%w: time %02d:%02d:%02d", ErrRange, hour, minute, second)
+	}
+
+	received := time.Now()
+	var years []int
+	if f.Year.Width == 0 {
+		year := received.UTC().Year()
+		years = []int{year - 1, year, year + 1}
+	} else {
+		year, err := l.number(data, f.Year)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %q", err, data)
+		}
+		if f.Year.Width == 2 {
+			year += 2000
+		}
+		years = []int{year}
+	}
+
+	// Without a year field, the year that puts the day closest to now
+	best := time.Duration(-1)
+	var timestamp time.Time
+	for _, year := range years {
+		start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		if day < 1 || day > start.AddDate(1, 0, -1).YearDay() {
+			continue
+		}
+		ts := start.AddDate(0, 0, day-1).Add(time.Duration(hour)*time.Hour +
+			time.Duration(minute)*time.Minute + time.Duration(second)*time.Second)
+		if d := ts.Sub(received).Abs(); best < 0 || d < best {
+			best, timestamp = d, ts
+		}
+	}
+	if best < 0 {
+		return nil, fmt.Errorf("%w: day %d", ErrRange, day)
+	}
+
+	status := gpsdo.Locked
+	if f.Status.Width != 0 {
+		status = l.status(data[f.Status.Offset : f.Status.Offset+f.Status.Width])
+	}
+
+	return &gpsdo.Sample{
+		Year:        timestamp.Year(),
+		DayOfYear:   timestamp.YearDay(),
+		Hour:        hour,
+		Minute:      minute,
+		Second:      second,
+		LeapSeconds: leapSeconds,
+		Status:      status,
+		Valid:       status == gpsdo.Locked || status == gpsdo.Holdover,
+		Timestamp:   timestamp,
+		ParseTime:   received,
+	}, nil
+}
+
+// number decodes the digits of field, 0 for an absent field. A byte that
+// isn't a digit is an ErrRange error.
+func (l *Layout) number(data []byte, field Field) (int, error) {
+	n := 0
+	for _, b := range data[field.Offset : field.Offset+field.Width] {
+		c := b
+		if l.Encoding == EncodingASCII {
+			c -= '0'
+		}
+		if c > 9 {
+			return 0, fmt.Errorf("%w: digit 0x%02X", ErrRange, b)
+		}
+		n = n*10 + int(c)
+	}
+	return n, nil
+}
+
+// status looks up the status field value
+func (l *Layout) status(value []byte) gpsdo.Status {
+	match := func(v string) bool { return v == string(value) }
+	if l.Encoding == EncodingBCD {
+		key := hex.EncodeToString(value)
+		match = func(v string) bool { return strings.EqualFold(v, key) }
+	}
+	switch {
+	case slices.ContainsFunc(l.Status.Locked, match):
+		return gpsdo.Locked
+	case slices.ContainsFunc(l.Status.Holdover, match):
+		return gpsdo.Holdover
+	case slices.ContainsFunc(l.Status.PowerUp, match):
+		return gpsdo.PowerUp
+	}
+	return gpsdo.Unknown
+}
@@ -0,0 +1,330 @@
+// Package gpsd serves GPSDO samples over a subset of the gpsd JSON protocol
+// (VERSION, DEVICES, WATCH, POLL, TPV and PPS) so existing gpsd clients can
+// watch the bridge.
+//
+// See https://gpsd.gitlab.io/gpsd/gpsd_json.html
+package gpsd
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// DefaultAddr is the standard gpsd port
+const DefaultAddr = "127.0.0.1:2947"
+
+// Protocol version reported in the VERSION object
+const (
+	protoMajor = 3
+	protoMinor = 14
+	release    = "gogpsdo"
+)
+
+// clientQueue is the number of reports buffered for a slow client
+const clientQueue = 16
+
+// Version is the VERSION object sent on connect
+type Version struct {
+	Class      string `json:"class"`
+	Release    string `json:"release"`
+	Rev        string `json:"rev"`
+	ProtoMajor int    `json:"proto_major"`
+	ProtoMinor int    `json:"proto_minor"`
+}
+
+// Device is a DEVICE object
+type Device struct {
+	Class     string `json:"class"`
+	Path      string `json:"path"`
+	Driver    string `json:"driver"`
+	Activated string `json:"activated"`
+}
+
+// Devices is the DEVICES object
+type Devices struct {
+	Class   string   `json:"class"`
+	Devices []Device `json:"devices"`
+}
+
+// Watch is the WATCH object, used both for requests and responses
+type Watch struct {
+	Class  string `json:"class"`
+	Enable bool   `json:"enable"`
+	JSON   bool   `json:"json"`
+	PPS    bool   `json:"pps"`
+}
+
+// TPV is a time-position-velocity report. Only the time fields are filled.
+type TPV struct {
+	Class       string `json:"class"`
+	Device      string `json:"device"`
+	Mode        int    `json:"mode"`
+	Time        string `json:"time,omitempty"`
+	LeapSeconds int    `json:"leapseconds,omitempty"`
+}
+
+// PPS reports a paired PPS edge
+type PPS struct {
+	Class     string `json:"class"`
+	Device    string `json:"device"`
+	RealSec   int64  `json:"real_sec"`
+	RealNsec  int    `json:"real_nsec"`
+	ClockSec  int64  `json:"clock_sec"`
+	ClockNsec int    `json:"clock_nsec"`
+	Precision int    `json:"precision"`
+}
+
+// Poll is the POLL response
+type Poll struct {
+	Class  string `json:"class"`
+	Time   string `json:"time"`
+	Active int    `json:"active"`
+	TPV    []TPV  `json:"tpv"`
+}
+
+// Error is sent for requests the server does not understand
+type Error struct {
+	Class   string `json:"class"`
+	Message string `json:"message"`
+}
+
+// TPV fix modes
+const (
+	modeNoFix = 1
+	mode3D    = 3
+)
+
+// Server is a gpsd JSON protocol listener and bridge output
+type Server struct {
+	listener  net.Listener
+	device    string
+	driver    string
+	activated time.Time
+
+	mutex   sync.Mutex
+	clients map[*client]struct{}
+	lastTPV *TPV
+}
+
+type client struct {
+	conn  net.Conn
+	queue chan []byte
+	watch Watch
+}
+
+// Listen opens the TCP listener. device and driver describe the GPSDO in
+// DEVICES and TPV reports.
+func Listen(addr, device, driver string) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("gpsd listen %s: %w", addr, err)
+	}
+	slog.Info("gpsd JSON service listening", "addr", listener.Addr())
+
+	return &Server{
+		listener:  listener,
+		device:    device,
+		driver:    driver,
+		activated: time.Now(),
+		clients:   make(map[*client]struct{}),
+	}, nil
+}
+
+// Serve accepts clients until ctx is cancelled
+func (s *Server) Serve(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		s.listener.Close()
+	}()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Error("gpsd accept failed", "error", err)
+			}
+			return
+		}
+		go s.serveClient(ctx, conn)
+	}
+}
+
+// Name identifies the output in logs
+func (s *Server) Name() string {
+	return "gpsd"
+}
+
+// Send reports a sample to every watching client
+func (s *Server) Send(data *gpsdo.Sample) error {
+	tpv := s.tpv(data)
+
+	s.mutex.Lock()
+	s.lastTPV = &tpv
+	s.mutex.Unlock()
+
+	s.broadcast(tpv, false)
+
+	if !data.PPS.IsZero() {
+		s.broadcast(PPS{
+			Class:     "PPS",
+			Device:    s.device,
+			RealSec:   data.Timestamp.Unix(),
+			RealNsec:  data.Timestamp.Nanosecond(),
+			ClockSec:  data.PPS.Unix(),
+			ClockNsec: data.PPS.Nanosecond(),
+			Precision: -20,
+		}, true)
+	}
+	return nil
+}
+
+func (s *Server) tpv(data *gpsdo.Sample) TPV {
+	// A GPSDO in position hold has the equivalent of a 3D fix
+	mode := modeNoFix
+	if data.Valid {
+		mode = mode3D
+	}
+	return TPV{
+		Class:       "TPV",
+		Device:      s.device,
+		Mode:        mode,
+		Time:        data.Timestamp.Format("2006-01-02T15:04:05.000Z"),
+		LeapSeconds: data.LeapSeconds,
+	}
+}
+
+func (s *Server) broadcast(report any, pps bool) {
+	line, err := json.Marshal(report)
+	if err != nil {
+		return
+	}
+	line = append(line, '\r', '\n')
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for c := range s.clients {
+		if !c.watch.Enable || !c.watch.JSON || (pps && !c.watch.PPS) {
+			continue
+		}
+		select {
+		case c.queue <- line:
+		default:
+			// Slow client, drop the report
+		}
+	}
+}
+
+func (s *Server) serveClient(ctx context.Context, conn net.Conn) {
+	c := &client{
+		conn:  conn,
+		queue: make(chan []byte, clientQueue),
+	}
+
+	s.mutex.Lock()
+	s.clients[c] = struct{}{}
+	s.mutex.Unlock()
+
+	defer func() {
+		s.mutex.Lock()
+		delete(s.clients, c)
+		close(c.queue)
+		s.mutex.Unlock()
+		conn.Close()
+	}()
+
+	go func() {
+		for line := range c.queue {
+			if _, err := conn.Write(line); err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}()
+
+	c.queue <- s.version()
+
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() && ctx.Err() == nil {
+		for _, request := range strings.Split(scanner.Text(), ";") {
+			request = strings.TrimSpace(request)
+			if request == "" {
+				continue
+			}
+			for _, reply := range s.handle(c, request) {
+				c.queue <- reply
+			}
+		}
+	}
+}
+
+// handle answers a single ?COMMAND[=json] request
+func (s *Server) handle(c *client, request string) [][]byte {
+	name, arg, _ := strings.Cut(strings.TrimPrefix(request, "?"), "=")
+
+	switch name {
+	case "VERSION":
+		return [][]byte{s.version()}
+	case "DEVICES":
+		return [][]byte{s.devices()}
+	case "WATCH":
+		watch := Watch{Enable: true, JSON: true}
+		if arg != "" {
+			if err := json.Unmarshal([]byte(arg), &watch); err != nil {
+				return [][]byte{s.marshal(Error{Class: "ERROR", Message: "Invalid WATCH: " + err.Error()})}
+			}
+		}
+		watch.Class = "WATCH"
+
+		s.mutex.Lock()
+		c.watch = watch
+		s.mutex.Unlock()
+		return [][]byte{s.devices(), s.marshal(watch)}
+	case "POLL":
+		poll := Poll{Class: "POLL", Time: time.Now().UTC().Format("2006-01-02T15:04:05.000Z"), TPV: []TPV{}}
+		s.mutex.Lock()
+		if s.lastTPV != nil {
+			poll.Active = 1
+			poll.TPV = append(poll.TPV, *s.lastTPV)
+		}
+		s.mutex.Unlock()
+		return [][]byte{s.marshal(poll)}
+	}
+
+	return [][]byte{s.marshal(Error{Class: "ERROR", Message: fmt.Sprintf("Unrecognized request '%s'", name)})}
+}
+
+func (s *Server) version() []byte {
+	return s.marshal(Version{
+		Class:      "VERSION",
+		Release:    release,
+		Rev:        release,
+		ProtoMajor: protoMajor,
+		ProtoMinor: protoMinor,
+	})
+}
+
+func (s *Server) devices() []byte {
+	return s.marshal(Devices{
+		Class: "DEVICES",
+		Devices: []Device{{
+			Class:     "DEVICE",
+			Path:      s.device,
+			Driver:    s.driver,
+			Activated: s.activated.UTC().Format("2006-01-02T15:04:05.000Z"),
+		}},
+	})
+}
+
+func (s *Server) marshal(v any) []byte {
+	line, _ := json.Marshal(v)
+	return append(line, '\r', '\n')
+}
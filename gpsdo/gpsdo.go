@@ -0,0 +1,296 @@
+// Package gpsdo contains the types shared between the GPSDO protocol parsers
+// and the outputs that consume their time-of-day samples.
+package gpsdo
+
+import (
+	"fmt"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+// Status represents the GPSDO operational state
+type Status int
+
+const (
+	PowerUp Status = iota
+	Holdover
+	Locked
+	Unknown
+)
+
+func (s Status) String() string {
+	switch s {
+	case PowerUp:
+		return "POWER_UP"
+	case Holdover:
+		return "HOLDOVER"
+	case Locked:
+		return "LOCKED"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. Unrecognized names
+// decode as Unknown.
+func (s *Status) UnmarshalText(text []byte) error {
+	*s = Unknown
+	for _, status := range []Status{PowerUp, Holdover, Locked} {
+		if string(text) == status.String() {
+			*s = status
+		}
+	}
+	return nil
+}
+
+// Leap is a pending leap second, encoded as in chrony's sock_sample and the
+// NTP leap indicator
+type Leap int
+
+const (
+	LeapNone Leap = iota
+	LeapInsert
+	LeapDelete
+)
+
+func (l Leap) String() string {
+	switch l {
+	case LeapInsert:
+		return "INSERT"
+	case LeapDelete:
+		return "DELETE"
+	default:
+		return "NONE"
+	}
+}
+
+// TAIGPSOffset is TAI-GPS in seconds, TAI-UTC when GPS time began
+const TAIGPSOffset = 19
+
+// Timescale is the time scale of a sample Timestamp
+type Timescale int
+
+const (
+	UTC Timescale = iota
+	TAI
+	GPS
+)
+
+func (t Timescale) String() string {
+	switch t {
+	case TAI:
+		return "TAI"
+	case GPS:
+		return "GPS"
+	default:
+		return "UTC"
+	}
+}
+
+// ParseTimescale parses a time scale name, ignoring case
+func ParseTimescale(name string) (Timescale, error) {
+	for _, t := range []Timescale{UTC, TAI, GPS} {
+		if strings.EqualFold(name, t.String()) {
+			return t, nil
+		}
+	}
+	return UTC, fmt.Errorf("unknown timescale %q, expected utc, tai or gps", name)
+}
+
+// Offset is the time scale minus UTC for a TAI-UTC offset in seconds
+func (t Timescale) Offset(taiOffset int) time.Duration {
+	switch t {
+	case TAI:
+		return time.Duration(taiOffset) * time.Second
+	case GPS:
+		return time.Duration(taiOffset-TAIGPSOffset) * time.Second
+	default:
+		return 0
+	}
+}
+
+// Sample represents a single parsed time-of-day record from a GPSDO
+type Sample struct {
+	Year        int
+	DayOfYear   int
+	Hour        int
+	Minute      int
+	Second      int
+	LeapSeconds int
+	// Leap is set during the UTC day ending in a leap second
+	Leap      Leap
+	Status    Status
+	Valid     bool
+	Timestamp time.Time
+	// Timescale is the time scale of Timestamp, UTC unless the bridge
+	// converted the sample for its outputs
+	Timescale Timescale
+	ParseTime time.Time
+	// Arrival is the system time the first byte of the packet arrived, or
+	// zero if unknown, such as when the parser is used on its own
+	Arrival time.Time
+	// PPS is the system time of the PPS edge paired with this sample, or
+	// zero. When set, Timestamp is the true time of that edge.
+	PPS time.Time
+	// Correction is the receiver reported error of this second's PPS edge,
+	// such as the Oncore negative sawtooth. It is already applied to
+	// Timestamp.
+	Correction time.Duration
+	// Offset is a static calibration, such as cable and serial delay, added
+	// to the offset reported to the time daemon
+	Offset time.Duration
+}
+
+// ReceiveTime is the system time the sample refers to: the PPS edge when
+// paired, otherwise the arrival of the packet, or the time it was parsed
+func (s *Sample) ReceiveTime() time.Time {
+	switch {
+	case !s.PPS.IsZero():
+		return s.PPS
+	case !s.Arrival.IsZero():
+		return s.Arrival
+	}
+	return s.ParseTime
+}
+
+// SystemOffset is the offset of the sample time from the system clock,
+// including the calibration Offset
+func (s *Sample) SystemOffset() time.Duration {
+	return s.Timestamp.Sub(s.ReceiveTime()) + s.Offset
+}
+
+// OutputStats are the delivery counters of a connection oriented output
+type OutputStats struct {
+	Connected   bool   `json:"connected"`
+	Connects    uint64 `json:"connects"`
+	Reconnects  uint64 `json:"reconnects"`
+	WriteErrors uint64 `json:"write_errors"`
+}
+
+// Identity is the make, model and firmware a receiver reports about itself.
+// Fields the receiver doesn't report are empty.
+type Identity struct {
+	Manufacturer string
+	Model        string
+	Serial       string
+	Firmware     string
+}
+
+// Diagnostics is receiver health reported outside the time samples, such as
+// over the Z3805A SCPI port or in proprietary NMEA sentences. Fields the
+// receiver did not report are left zero.
+type Diagnostics struct {
+	// Satellites is the number of satellites being tracked
+	Satellites int
+	// EFC is the oscillator electronic frequency control in percent of range
+	EFC float64
+	// HoldoverPrediction is the predicted time uncertainty after 24 hours of
+	// holdover
+	HoldoverPrediction time.Duration
+	// Antenna is the antenna status as reported by the receiver
+	Antenna string
+	// PhaseError is the measured offset of the oscillator PPS from GPS
+	PhaseError time.Duration
+	// Discipline is the oscillator disciplining state as reported by the
+	// receiver, such as "locked" or "holdover"
+	Discipline string
+	// Signals are the tracked satellites with their signal strength, nil if
+	// the receiver doesn't report them
+	Signals []Signal
+	// Position is the antenna position and survey state, nil if the
+	// receiver doesn't report it
+	Position *Position
+	// Updated is when the diagnostics were last read, zero if never
+	Updated time.Time
+}
+
+// Position is the antenna position a timing receiver surveyed, or is
+// surveying
+type Position struct {
+	// Latitude and Longitude are in degrees, north and east positive
+	Latitude  float64
+	Longitude float64
+	// Altitude is in meters
+	Altitude float64
+	// Mode is the positioning mode as named by the receiver
+	Mode string
+	// Hold is set when the receiver holds the surveyed position fixed and
+	// solves for time only, as a timing receiver should
+	Hold bool
+	// Surveying is set while the receiver surveys its position, with
+	// Progress in percent, -1 if not reported
+	Surveying bool
+	Progress  float64
+}
+
+// Signal is a satellite tracked by the receiver
+type Signal struct {
+	PRN int
+	// Elevation and Azimuth are in degrees
+	Elevation int
+	Azimuth   int
+	// Strength is the signal strength in the units of the receiver
+	Strength int
+}
+
+// MeanStrength is the mean signal strength of signals, 0 if there are none
+func MeanStrength(signals []Signal) float64 {
+	if len(signals) == 0 {
+		return 0
+	}
+	var sum int
+	for _, s := range signals {
+		sum += s.Strength
+	}
+	return float64(sum) / float64(len(signals))
+}
+
+// RolloverPeriod is the 1024 week period of the GPS week number
+const RolloverPeriod = 1024 * 7 * 24 * time.Hour
+
+// fallbackPivot is used when the binary carries no build time
+var fallbackPivot = time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// LastRollover is the start of the current 1024 week period, the pivot for
+// recorded input that may be older than the binary
+var LastRollover = time.Date(2019, time.April, 7, 0, 0, 0, 0, time.UTC)
+
+// DefaultPivot returns the build time of the binary, from the VCS commit
+// time stamped by the go command, for use as the rollover pivot. A live
+// receiver can't be reporting a time before the software that reads it was
+// built, recorded input can, see LastRollover.
+func DefaultPivot() time.Time {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key != "vcs.time" {
+				continue
+			}
+			if t, err := time.Parse(time.RFC3339, setting.Value); err == nil {
+				return t
+			}
+		}
+	}
+	return fallbackPivot
+}
+
+// FixRollover moves a sample from a receiver affected by the GPS week number
+// rollover forward by as many 1024 week periods as it takes to land on or
+// after pivot. It reports whether the sample was changed.
+func (s *Sample) FixRollover(pivot time.Time) bool {
+	if !s.Timestamp.Before(pivot) {
+		return false
+	}
+
+	for s.Timestamp.Before(pivot) {
+		s.Timestamp = s.Timestamp.Add(RolloverPeriod)
+	}
+	s.Year = s.Timestamp.Year()
+	s.DayOfYear = s.Timestamp.YearDay()
+	return true
+}
@@ -0,0 +1,37 @@
+package gpsdo
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFixRollover(t *testing.T) {
+	pivot := time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC)
+	after := pivot.Add(36 * time.Hour)
+
+	for _, tt := range []struct {
+		name    string
+		at      time.Time
+		want    time.Time
+		changed bool
+	}{
+		{"after the pivot", after, after, false},
+		{"on the pivot", pivot, pivot, false},
+		{"one period back", after.Add(-RolloverPeriod), after, true},
+		{"two periods back", after.Add(-2 * RolloverPeriod), after, true},
+		{"just before the pivot", pivot.Add(-time.Second), pivot.Add(RolloverPeriod - time.Second), true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			s := Sample{Timestamp: tt.at, Year: tt.at.Year(), DayOfYear: tt.at.YearDay()}
+			if changed := s.FixRollover(pivot); changed != tt.changed {
+				t.Errorf("FixRollover = %t, want %t", changed, tt.changed)
+			}
+			if !s.Timestamp.Equal(tt.want) {
+				t.Errorf("timestamp = %s, want %s", s.Timestamp, tt.want)
+			}
+			if s.Year != tt.want.Year() || s.DayOfYear != tt.want.YearDay() {
+				t.Errorf("date = %d-%03d, want %d-%03d", s.Year, s.DayOfYear, tt.want.Year(), tt.want.YearDay())
+			}
+		})
+	}
+}
@@ -0,0 +1,37 @@
+// Package ks24361 decodes the time code of the Lucent KS-24361 reference
+// pair, the REF-0 (Z3809A) and REF-1 (Z3810A) units of the RFTG-u. Both speak
+// the Z3801A SCPI dialect on their console port at 9600 baud 8N1 and answer
+// the same :PTIME:TCODE? query, with two differences in status reporting:
+//
+//   - Only REF-0 has a GPS receiver. REF-1 is disciplined by REF-0 over the
+//     crosslink and flags its time invalid even while locked to it, so the
+//     validity flag is ignored and the status comes from the FFOM alone.
+//   - When REF-0 loses GPS both units report holdover, and REF-1 also while
+//     the crosslink is down, through the FFOM.
+package ks24361
+
+import (
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/z3801a"
+)
+
+// QueryTimeCode requests the time code of the next 1PPS edge
+const QueryTimeCode = z3801a.QueryTimeCode
+
+// IsTimeCode reports whether line, with any SCPI prompt, is a time code
+func IsTimeCode(line string) bool {
+	return z3801a.IsTimeCode(line)
+}
+
+// Parse decodes a time code response of either unit
+func Parse(line string) (*gpsdo.Sample, error) {
+	tc, err := z3801a.Decode(line)
+	if err != nil {
+		return nil, err
+	}
+	status, err := z3801a.FFOMStatus(tc.FFOM)
+	if err != nil {
+		return nil, err
+	}
+	return tc.Sample(status), nil
+}
@@ -0,0 +1,178 @@
+// Package leapsec reads the IERS leap-seconds.list file, the table of TAI-UTC
+// offsets shipped with tzdata, to cross-check the leap seconds reported by
+// a receiver and to know TAI-UTC when the receiver doesn't report it.
+//
+// See https://hpiers.obspm.fr/iers/bul/bulc/ntp/leap-seconds.list
+package leapsec
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// DefaultPath is where tzdata installs the file on most distributions
+const DefaultPath = "/usr/share/zoneinfo/leap-seconds.list"
+
+// ntpEpochOffset is the number of seconds from 1900 to 1970
+const ntpEpochOffset = 2208988800
+
+// Entry is a TAI-UTC offset and the time it took effect
+type Entry struct {
+	Time   time.Time
+	Offset int
+}
+
+// Table is a parsed leap-seconds.list
+type Table struct {
+	// Updated is when the file was last updated by the IERS
+	Updated time.Time
+	// Expires is when the file stops covering announced leap seconds. Past
+	// it a leap second may be missing from the table.
+	Expires time.Time
+	// Entries are in time order
+	Entries []Entry
+}
+
+// Load reads and verifies a leap-seconds.list file
+func Load(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	table, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return table, nil
+}
+
+// Parse reads a leap-seconds.list. The file's SHA-1 hash is checked when it
+// has one.
+func Parse(r io.Reader) (*Table, error) {
+	t := &Table{}
+	hash := sha1.New()
+	var want string
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := scanner.Text()
+		switch {
+		case strings.HasPrefix(text, "#$"), strings.HasPrefix(text, "#@"):
+			field := strings.TrimSpace(text[2:])
+			at, err := ntpTime(field)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			if text[1] == '$' {
+				t.Updated = at
+			} else {
+				t.Expires = at
+			}
+			hash.Write([]byte(field))
+		case strings.HasPrefix(text, "#h"):
+			// Five 32 bit words, which some copies write without leading
+			// zeros
+			for word := range strings.FieldsSeq(text[2:]) {
+				n, err := strconv.ParseUint(word, 16, 32)
+				if err != nil {
+					return nil, fmt.Errorf("line %d: invalid hash %q", line, word)
+				}
+				want += fmt.Sprintf("%08x", n)
+			}
+		case strings.HasPrefix(text, "#"):
+		default:
+			data, _, _ := strings.Cut(text, "#")
+			fields := strings.Fields(data)
+			if len(fields) == 0 {
+				continue
+			}
+			if len(fields) != 2 {
+				return nil, fmt.Errorf("line %d: expected NTP time and TAI-UTC offset", line)
+			}
+			at, err := ntpTime(fields[0])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", line, err)
+			}
+			offset, err := strconv.Atoi(fields[1])
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid offset %q", line, fields[1])
+			}
+			if n := len(t.Entries); n > 0 && !at.After(t.Entries[n-1].Time) {
+				return nil, fmt.Errorf("line %d: entries out of order", line)
+			}
+			t.Entries = append(t.Entries, Entry{Time: at, Offset: offset})
+			hash.Write([]byte(fields[0] + fields[1]))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if len(t.Entries) == 0 {
+		return nil, errors.New("no leap seconds")
+	}
+	if t.Expires.IsZero() {
+		return nil, errors.New("no expiry date")
+	}
+	if want != "" {
+		if got := hex.EncodeToString(hash.Sum(nil)); got != want {
+			return nil, fmt.Errorf("hash mismatch, file is corrupt: got %s, want %s", got, want)
+		}
+	}
+	return t, nil
+}
+
+// ntpTime parses seconds since 1900
+func ntpTime(s string) (time.Time, error) {
+	sec, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid NTP time %q", s)
+	}
+	return time.Unix(sec-ntpEpochOffset, 0).UTC(), nil
+}
+
+// Expired reports whether the table is past its expiry date at t
+func (t *Table) Expired(at time.Time) bool {
+	return !at.Before(t.Expires)
+}
+
+// Offset returns TAI-UTC at t, 0 before the first entry
+func (t *Table) Offset(at time.Time) int {
+	offset := 0
+	for _, e := range t.Entries {
+		if at.Before(e.Time) {
+			break
+		}
+		offset = e.Offset
+	}
+	return offset
+}
+
+// Next returns the first leap second after t, ok is false if none is
+// scheduled
+func (t *Table) Next(at time.Time) (next time.Time, leap gpsdo.Leap, ok bool) {
+	current := t.Offset(at)
+	for _, e := range t.Entries {
+		if !e.Time.After(at) {
+			continue
+		}
+		leap = gpsdo.LeapInsert
+		if e.Offset < current {
+			leap = gpsdo.LeapDelete
+		}
+		return e.Time, leap, true
+	}
+	return time.Time{}, gpsdo.LeapNone, false
+}
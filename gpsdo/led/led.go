@@ -0,0 +1,224 @@
+// Package led drives status LEDs on GPIO lines through the GPIO character
+// device, so a headless timing server has a physical health indicator: solid
+// while the GPSDO is locked, blinking in holdover, blinking fast in other
+// states such as warming up, and off when no samples arrive.
+package led
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/pps"
+)
+
+// GPIO v2 character device uAPI from linux/gpio.h
+const (
+	gpioLinesMax      = 64
+	gpioNameSize      = 32
+	gpioNumAttrsMax   = 10
+	gpioFlagActiveLow = 1 << 1
+	gpioFlagOutput    = 1 << 3
+)
+
+// gpioLineRequest mirrors struct gpio_v2_line_request
+type gpioLineRequest struct {
+	offsets         [gpioLinesMax]uint32
+	consumer        [gpioNameSize]byte
+	flags           uint64
+	numAttrs        uint32
+	_               [5]uint32
+	attrs           [gpioNumAttrsMax][24]byte
+	numLines        uint32
+	eventBufferSize uint32
+	_               [5]uint32
+	fd              int32
+}
+
+// gpioLineValues mirrors struct gpio_v2_line_values
+type gpioLineValues struct {
+	bits uint64
+	mask uint64
+}
+
+// GPIO_V2_GET_LINE_IOCTL and GPIO_V2_LINE_SET_VALUES_IOCTL, _IOWR(0xB4, 0x07)
+// and _IOWR(0xB4, 0x0F) with the generic ioctl encoding of x86 and arm
+const (
+	gpioGetLineIoctl   = 3<<30 | unsafe.Sizeof(gpioLineRequest{})<<16 | 0xb4<<8 | 0x07
+	gpioSetValuesIoctl = 3<<30 | unsafe.Sizeof(gpioLineValues{})<<16 | 0xb4<<8 | 0x0f
+)
+
+// StaleAfter is how long after the last sample, beyond the sample interval,
+// the LED turns off
+const StaleAfter = 10 * time.Second
+
+// Half periods of the blink patterns
+const (
+	blinkHoldover = 500 * time.Millisecond
+	blinkOther    = 125 * time.Millisecond
+)
+
+// LED is a bridge output lighting GPIO lines by the GPSDO status
+type LED struct {
+	// lines are the requested line fds, one per GPIO chip, each with the
+	// mask of its lines
+	lines []lineSet
+	// staleAfter is how long the last sample lights the LED
+	staleAfter time.Duration
+
+	mutex   sync.Mutex
+	status  gpsdo.Status
+	updated time.Time
+}
+
+type lineSet struct {
+	file *os.File
+	mask uint64
+}
+
+// Open requests the GPIO lines, given as chip:line or line like the PPS
+// GPIO, as outputs that start off. interval is the sample interval of the
+// device, the samples it skips don't turn the LED off.
+func Open(specs []string, activeLow bool, interval time.Duration) (*LED, error) {
+	chips := make(map[string][]uint32)
+	var order []string
+	for _, spec := range specs {
+		chip, line, err := pps.ParseGPIO(spec)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := chips[chip]; !ok {
+			order = append(order, chip)
+		}
+		chips[chip] = append(chips[chip], uint32(line))
+	}
+
+	l := &LED{staleAfter: StaleAfter + interval}
+	for _, chip := range order {
+		set, err := requestLines(chip, chips[chip], activeLow)
+		if err != nil {
+			l.Close()
+			return nil, err
+		}
+		l.lines = append(l.lines, set)
+	}
+	return l, nil
+}
+
+// requestLines requests lines of chip as outputs
+func requestLines(chip string, lines []uint32, activeLow bool) (lineSet, error) {
+	f, err := os.Open(chip)
+	if err != nil {
+		return lineSet{}, err
+	}
+	defer f.Close()
+
+	req := gpioLineRequest{numLines: uint32(len(lines)), flags: gpioFlagOutput}
+	if activeLow {
+		req.flags |= gpioFlagActiveLow
+	}
+	copy(req.offsets[:], lines)
+	copy(req.consumer[:], "gogpsdo-led")
+	if err := ioctl(f.Fd(), gpioGetLineIoctl, unsafe.Pointer(&req)); err != nil {
+		return lineSet{}, fmt.Errorf("GPIO_V2_GET_LINE %s lines %v: %w", chip, lines, err)
+	}
+	return lineSet{
+		file: os.NewFile(uintptr(req.fd), fmt.Sprintf("%s:%v", chip, lines)),
+		mask: 1<<len(lines) - 1,
+	}, nil
+}
+
+func ioctl(fd uintptr, request uintptr, arg unsafe.Pointer) error {
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, fd, request, uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Name identifies the output in logs
+func (l *LED) Name() string {
+	return "led"
+}
+
+// Send records the status of a sample for the LED pattern
+func (l *LED) Send(data *gpsdo.Sample) error {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.status = data.Status
+	l.updated = time.Now()
+	return nil
+}
+
+// Run blinks the LED until ctx is cancelled, then turns it off
+func (l *LED) Run(ctx context.Context) {
+	ticker := time.NewTicker(blinkOther)
+	defer ticker.Stop()
+
+	// failed keeps a failing line out of the log after the first error
+	lit, failed := false, false
+	for {
+		select {
+		case <-ctx.Done():
+			l.set(false)
+			return
+		case now := <-ticker.C:
+			on := l.lit(now)
+			if on == lit {
+				continue
+			}
+			lit = on
+			if err := l.set(on); err != nil && !failed {
+				failed = true
+				slog.Warn("LED update failed", "error", err)
+			}
+		}
+	}
+}
+
+// lit reports whether the LED is on at now
+func (l *LED) lit(now time.Time) bool {
+	l.mutex.Lock()
+	status, updated := l.status, l.updated
+	l.mutex.Unlock()
+
+	switch {
+	case updated.IsZero() || now.Sub(updated) > l.staleAfter:
+		return false
+	case status == gpsdo.Locked:
+		return true
+	case status == gpsdo.Holdover:
+		return now.UnixMilli()/blinkHoldover.Milliseconds()%2 == 0
+	default:
+		return now.UnixMilli()/blinkOther.Milliseconds()%2 == 0
+	}
+}
+
+// set turns every line on or off
+func (l *LED) set(on bool) error {
+	for _, set := range l.lines {
+		values := gpioLineValues{mask: set.mask}
+		if on {
+			values.bits = set.mask
+		}
+		if err := ioctl(set.file.Fd(), gpioSetValuesIoctl, unsafe.Pointer(&values)); err != nil {
+			return fmt.Errorf("GPIO_V2_LINE_SET_VALUES %s: %w", set.file.Name(), err)
+		}
+	}
+	return nil
+}
+
+// Close releases the lines. Run turns them off before it returns.
+func (l *LED) Close() error {
+	for _, set := range l.lines {
+		set.file.Close()
+	}
+	return nil
+}
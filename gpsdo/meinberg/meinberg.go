@@ -0,0 +1,145 @@
+// Package meinberg parses the Meinberg Standard Time String sent by Meinberg
+// GPS and DCF77 clocks:
+//
+//	<STX>D:dd.mm.yy;T:w;U:hh.mm.ss;uvxy<ETX>
+//
+// The STX is sent on the second change. The status letters are:
+//
+//	u  '#' the clock has not synchronized since reset, ' ' it has
+//	v  '*' the clock is free running, ' ' it is synchronized
+//	x  'U' UTC, 'S' CEST (UTC+2), ' ' CET (UTC+1)
+//	y  'A' a leap second is announced, '!' a DST change is announced
+package meinberg
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// Framing characters
+const (
+	STX = 0x02
+	ETX = 0x03
+)
+
+// TelegramLen is the length of a telegram including STX and ETX
+const TelegramLen = 32
+
+var (
+	// ErrFraming is returned when an ETX arrives outside a telegram
+	ErrFraming = errors.New("meinberg: ETX without STX")
+	// ErrFormat is returned for telegrams that cannot be decoded
+	ErrFormat = errors.New("meinberg: malformed telegram")
+	// ErrRange is returned for telegrams with a date or time out of range
+	ErrRange = errors.New("meinberg: field out of range")
+)
+
+// Decoder reframes telegrams from a byte stream regardless of how reads are
+// chunked
+type Decoder struct {
+	buf     []byte
+	inFrame bool
+}
+
+// Feed consumes one byte of the stream. It returns the telegram, from STX
+// to ETX, when the byte completes one, and ErrFraming when an ETX arrives
+// without an STX, such as when the bridge starts mid-telegram. The returned
+// telegram is only valid until the next call.
+func (d *Decoder) Feed(c byte) ([]byte, error) {
+	switch {
+	case c == STX:
+		d.buf = append(d.buf[:0], c)
+		d.inFrame = true
+		return nil, nil
+	case !d.inFrame:
+		if c == ETX {
+			return nil, ErrFraming
+		}
+		return nil, nil
+	}
+
+	d.buf = append(d.buf, c)
+	if c == ETX {
+		d.inFrame = false
+		return d.buf, nil
+	}
+	if len(d.buf) == TelegramLen {
+		// Too long to be a telegram, wait for the next STX
+		d.inFrame = false
+		return nil, ErrFraming
+	}
+	return nil, nil
+}
+
+// zones are the offsets from UTC of the time zone letter
+var zones = map[byte]time.Duration{
+	'U': 0,
+	'S': 2 * time.Hour,
+	' ': time.Hour,
+}
+
+// Parse decodes a telegram, converting CET and CEST times to UTC
+func Parse(data []byte) (*gpsdo.Sample, error) {
+	s := string(data)
+	if len(s) != TelegramLen || s[0] != STX || s[TelegramLen-1] != ETX ||
+		s[1:3] != "D:" || s[5] != '.' || s[8] != '.' || s[11:14] != ";T:" ||
+		s[15:18] != ";U:" || s[20] != '.' || s[23] != '.' || s[26] != ';' {
+		return nil, fmt.Errorf("%w: %q", ErrFormat, s)
+	}
+	day, err1 := strconv.Atoi(s[3:5])
+	month, err2 := strconv.Atoi(s[6:8])
+	year, err3 := strconv.Atoi(s[9:11])
+	hour, err4 := strconv.Atoi(s[18:20])
+	minute, err5 := strconv.Atoi(s[21:23])
+	second, err6 := strconv.Atoi(s[24:26])
+	if err := errors.Join(err1, err2, err3, err4, err5, err6); err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrFormat, s)
+	}
+	if month < 1 || month > 12 || day < 1 || day > 31 || year < 0 ||
+		hour < 0 || hour > 23 || minute < 0 || minute > 59 || second < 0 || second > 59 {
+		return nil, fmt.Errorf("%w: %q", ErrRange, s)
+	}
+
+	synced, running, zone, announce := s[27], s[28], s[29], s[30]
+	offset, ok := zones[zone]
+	if !ok {
+		return nil, fmt.Errorf("%w: time zone %q", ErrFormat, zone)
+	}
+	local := time.Date(2000+year, time.Month(month), day, hour, minute, second, 0, time.UTC)
+	if local.Day() != day {
+		return nil, fmt.Errorf("%w: date %q", ErrRange, s[3:11])
+	}
+	timestamp := local.Add(-offset)
+
+	var status gpsdo.Status
+	switch {
+	case synced == '#':
+		status = gpsdo.PowerUp
+	case running == '*':
+		status = gpsdo.Holdover
+	default:
+		status = gpsdo.Locked
+	}
+
+	leap := gpsdo.LeapNone
+	if announce == 'A' && timestamp.AddDate(0, 0, 1).Month() != timestamp.Month() {
+		leap = gpsdo.LeapInsert
+	}
+
+	return &gpsdo.Sample{
+		Year:      timestamp.Year(),
+		DayOfYear: timestamp.YearDay(),
+		Hour:      timestamp.Hour(),
+		Minute:    timestamp.Minute(),
+		Second:    timestamp.Second(),
+		Leap:      leap,
+		Status:    status,
+		Valid:     status == gpsdo.Locked || status == gpsdo.Holdover,
+		Timestamp: timestamp,
+		ParseTime: time.Now(),
+	}, nil
+}
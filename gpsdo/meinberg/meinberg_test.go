@@ -0,0 +1,171 @@
+package meinberg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// telegram frames the date dd.mm.yy, time hh.mm.ss and the four status
+// letters
+func telegram(date, hms, status string) []byte {
+	return fmt.Appendf(nil, "\x02D:%s;T:1;U:%s;%s\x03", date, hms, status)
+}
+
+func TestParse(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		data   []byte
+		want   time.Time
+		status gpsdo.Status
+		leap   gpsdo.Leap
+	}{
+		{
+			name:   "UTC",
+			data:   telegram("14.03.25", "12.00.00", "  U "),
+			want:   time.Date(2025, time.March, 14, 12, 0, 0, 0, time.UTC),
+			status: gpsdo.Locked,
+		},
+		{
+			name:   "CET across midnight",
+			data:   telegram("01.01.26", "00.30.00", "    "),
+			want:   time.Date(2025, time.December, 31, 23, 30, 0, 0, time.UTC),
+			status: gpsdo.Locked,
+		},
+		{
+			name:   "CEST across midnight",
+			data:   telegram("15.07.25", "01.59.59", "  S "),
+			want:   time.Date(2025, time.July, 14, 23, 59, 59, 0, time.UTC),
+			status: gpsdo.Locked,
+		},
+		{
+			name:   "CET into March",
+			data:   telegram("01.03.24", "00.00.00", "    "),
+			want:   time.Date(2024, time.February, 29, 23, 0, 0, 0, time.UTC),
+			status: gpsdo.Locked,
+		},
+		{
+			name:   "free running",
+			data:   telegram("14.03.25", "12.00.00", " *U "),
+			want:   time.Date(2025, time.March, 14, 12, 0, 0, 0, time.UTC),
+			status: gpsdo.Holdover,
+		},
+		{
+			name:   "not synchronized",
+			data:   telegram("14.03.25", "12.00.00", "#*U "),
+			want:   time.Date(2025, time.March, 14, 12, 0, 0, 0, time.UTC),
+			status: gpsdo.PowerUp,
+		},
+		{
+			name:   "leap second announced",
+			data:   telegram("30.06.25", "12.00.00", "  UA"),
+			want:   time.Date(2025, time.June, 30, 12, 0, 0, 0, time.UTC),
+			status: gpsdo.Locked,
+			leap:   gpsdo.LeapInsert,
+		},
+		{
+			// 30 June in UTC while already 1 July in CEST
+			name:   "leap second announced in CEST",
+			data:   telegram("01.07.25", "01.30.00", "  SA"),
+			want:   time.Date(2025, time.June, 30, 23, 30, 0, 0, time.UTC),
+			status: gpsdo.Locked,
+			leap:   gpsdo.LeapInsert,
+		},
+		{
+			name:   "leap second announced early",
+			data:   telegram("29.06.25", "12.00.00", "  UA"),
+			want:   time.Date(2025, time.June, 29, 12, 0, 0, 0, time.UTC),
+			status: gpsdo.Locked,
+		},
+		{
+			name:   "DST change announced",
+			data:   telegram("26.10.25", "02.00.00", "  S!"),
+			want:   time.Date(2025, time.October, 26, 0, 0, 0, 0, time.UTC),
+			status: gpsdo.Locked,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			sample, err := Parse(tt.data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !sample.Timestamp.Equal(tt.want) {
+				t.Errorf("timestamp = %s, want %s", sample.Timestamp, tt.want)
+			}
+			if sample.Status != tt.status || sample.Leap != tt.leap {
+				t.Errorf("status = %s leap %v, want %s leap %v", sample.Status, sample.Leap, tt.status, tt.leap)
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		data []byte
+		want error
+	}{
+		{"month 13", telegram("14.13.25", "12.00.00", "  U "), ErrRange},
+		{"31 April", telegram("31.04.25", "12.00.00", "  U "), ErrRange},
+		{"hour 24", telegram("14.03.25", "24.00.00", "  U "), ErrRange},
+		{"second 60", telegram("14.03.25", "12.00.60", "  U "), ErrRange},
+		{"not a number", telegram("14.03.2x", "12.00.00", "  U "), ErrFormat},
+		{"time zone", telegram("14.03.25", "12.00.00", "  X "), ErrFormat},
+		{"separator", bytes.Replace(telegram("14.03.25", "12.00.00", "  U "), []byte(";U:"), []byte(";V:"), 1), ErrFormat},
+		{"short", telegram("14.03.25", "12.00.0", "  U "), ErrFormat},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Parse(tt.data); !errors.Is(err, tt.want) {
+				t.Errorf("error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+// feed feeds the stream to a Decoder and returns copies of the telegrams and
+// the number of framing errors
+func feed(stream []byte) ([][]byte, int) {
+	var d Decoder
+	var telegrams [][]byte
+	framing := 0
+	for _, c := range stream {
+		data, err := d.Feed(c)
+		if errors.Is(err, ErrFraming) {
+			framing++
+		}
+		if data != nil {
+			telegrams = append(telegrams, bytes.Clone(data))
+		}
+	}
+	return telegrams, framing
+}
+
+func TestDecoder(t *testing.T) {
+	whole := telegram("14.03.25", "12.00.00", "  U ")
+
+	for _, tt := range []struct {
+		name    string
+		stream  []byte
+		framing int
+	}{
+		{"whole", whole, 0},
+		// The bridge started in the middle of a telegram
+		{"ETX without STX", append([]byte("12.00.00;  U \x03"), whole...), 1},
+		{"oversized", append(append([]byte{STX}, bytes.Repeat([]byte("x"), TelegramLen)...), whole...), 1},
+		{"oversized with ETX", append(append(append([]byte{STX}, bytes.Repeat([]byte("x"), TelegramLen)...), ETX), whole...), 2},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			telegrams, framing := feed(tt.stream)
+			if framing != tt.framing {
+				t.Errorf("framing errors = %d, want %d", framing, tt.framing)
+			}
+			if len(telegrams) != 1 || !bytes.Equal(telegrams[0], whole) {
+				t.Errorf("telegrams = %q, want %q", telegrams, whole)
+			}
+		})
+	}
+}
@@ -0,0 +1,362 @@
+// Package netserial reads a GPSDO through a serial port exposed on the
+// network, such as by ser2net or a terminal server, so the receiver can live
+// in another rack. tcp://host:port is a raw socket whose serial settings are
+// configured on the server; rfc2217://host:port negotiates the baud rate,
+// data bits, parity and stop bits with the server over the Telnet COM Port
+// Control Option of RFC 2217.
+package netserial
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo/protocol"
+)
+
+// Address schemes
+const (
+	SchemeTCP     = "tcp://"
+	SchemeRFC2217 = "rfc2217://"
+)
+
+// dialTimeout bounds connecting and, for RFC 2217, the option negotiation
+const dialTimeout = 10 * time.Second
+
+// errClosed is returned once the server closes the connection
+var errClosed = errors.New("connection closed by server")
+
+// IsAddress reports whether port names a network serial port rather than a
+// device node
+func IsAddress(port string) bool {
+	return strings.HasPrefix(port, SchemeTCP) || strings.HasPrefix(port, SchemeRFC2217)
+}
+
+// Split returns the scheme and host:port of a network serial port
+func Split(port string) (scheme, addr string, err error) {
+	for _, scheme := range []string{SchemeTCP, SchemeRFC2217} {
+		if addr, ok := strings.CutPrefix(port, scheme); ok {
+			if _, _, err := net.SplitHostPort(addr); err != nil {
+				return "", "", fmt.Errorf("network serial port %s: %w", port, err)
+			}
+			return scheme, addr, nil
+		}
+	}
+	return "", "", fmt.Errorf("network serial port %s: expected %shost:port or %shost:port", port, SchemeTCP, SchemeRFC2217)
+}
+
+// Conn is a connection to a network serial port. Like a serial port opened
+// with a read timeout, Read reports a timeout as io.EOF; a connection closed
+// by the server is an error.
+type Conn struct {
+	conn        net.Conn
+	readTimeout time.Duration
+	// telnet is set for RFC 2217, whose data is escaped and interleaved
+	// with Telnet commands
+	telnet *telnet
+
+	// writeMutex keeps negotiation replies from the reading goroutine out
+	// of the middle of a Write
+	writeMutex sync.Mutex
+}
+
+// Dial connects to port, a tcp:// or rfc2217:// address, and for RFC 2217
+// sets the serial settings. Reads time out after readTimeout.
+func Dial(port string, settings protocol.SerialDefaults, readTimeout time.Duration) (*Conn, error) {
+	scheme, addr, err := Split(port)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialTimeout("tcp", addr, dialTimeout)
+	if err != nil {
+		return nil, err
+	}
+	c := &Conn{conn: conn, readTimeout: readTimeout}
+	if scheme == SchemeRFC2217 {
+		c.telnet = &telnet{}
+		if err := c.negotiate(settings); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("RFC 2217 %s: %w", addr, err)
+		}
+	}
+	return c, nil
+}
+
+// Read reads serial data, waiting up to the read timeout
+func (c *Conn) Read(p []byte) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	c.conn.SetReadDeadline(time.Now().Add(c.readTimeout))
+	for {
+		if c.telnet != nil && len(c.telnet.pending) > 0 {
+			n := copy(p, c.telnet.pending)
+			c.telnet.pending = c.telnet.pending[n:]
+			return n, nil
+		}
+		n, err := c.conn.Read(p)
+		if c.telnet != nil && n > 0 {
+			n = c.filter(p[:n])
+		}
+		switch {
+		case n > 0:
+			return n, nil
+		case errors.Is(err, os.ErrDeadlineExceeded):
+			return 0, io.EOF
+		case errors.Is(err, io.EOF):
+			return 0, errClosed
+		case err != nil:
+			return 0, err
+		}
+		// Only Telnet commands were read
+	}
+}
+
+// Write sends p to the serial port
+func (c *Conn) Write(p []byte) (int, error) {
+	data := p
+	if c.telnet != nil {
+		data = escape(p)
+	}
+	if err := c.write(data); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// Close closes the connection
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *Conn) write(data []byte) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(dialTimeout))
+	_, err := c.conn.Write(data)
+	return err
+}
+
+// Telnet commands and options
+const (
+	iac  = 255
+	dont = 254
+	do   = 253
+	wont = 252
+	will = 251
+	sb   = 250
+	se   = 240
+
+	optBinary  = 0
+	optSGA     = 3
+	optComPort = 44
+)
+
+// COM Port Control Option commands sent by the client. The server answers
+// each with the command plus 100.
+const (
+	setBaudRate = 1
+	setDataSize = 2
+	setParity   = 3
+	setStopSize = 4
+	setControl  = 5
+
+	serverOffset = 100
+)
+
+// controlNone turns off flow control
+const controlNone = 1
+
+// telnet is the state of the Telnet stream parser
+type telnet struct {
+	state byte
+	// command is the DO, DONT, WILL or WONT awaiting its option
+	command byte
+	// sub is the subnegotiation being received
+	sub []byte
+	// pending is data received during the negotiation in Dial
+	pending []byte
+	// comPort is set once the server agreed to DO or DONT the COM Port
+	// option
+	comPort, refused bool
+}
+
+// Telnet parser states
+const (
+	stateData = iota
+	stateIAC
+	stateOption
+	stateSub
+	stateSubIAC
+)
+
+// maxSub bounds a subnegotiation, the COM Port ones are a few bytes
+const maxSub = 64
+
+// filter removes the Telnet commands from the n bytes of p, handling them,
+// and returns the number of data bytes left at the start of p
+func (c *Conn) filter(p []byte) int {
+	t := c.telnet
+	n := 0
+	for _, b := range p {
+		switch t.state {
+		case stateData:
+			if b == iac {
+				t.state = stateIAC
+				continue
+			}
+			p[n] = b
+			n++
+		case stateIAC:
+			switch b {
+			case iac:
+				p[n] = iac
+				n++
+				t.state = stateData
+			case do, dont, will, wont:
+				t.command = b
+				t.state = stateOption
+			case sb:
+				t.sub = t.sub[:0]
+				t.state = stateSub
+			default:
+				// NOP, GA and the other commands carry nothing
+				t.state = stateData
+			}
+		case stateOption:
+			c.option(t.command, b)
+			t.state = stateData
+		case stateSub:
+			if b == iac {
+				t.state = stateSubIAC
+			} else if len(t.sub) < maxSub {
+				t.sub = append(t.sub, b)
+			}
+		case stateSubIAC:
+			switch b {
+			case se:
+				c.subnegotiation(t.sub)
+				t.state = stateData
+			case iac:
+				if len(t.sub) < maxSub {
+					t.sub = append(t.sub, iac)
+				}
+				t.state = stateSub
+			default:
+				t.state = stateData
+			}
+		}
+	}
+	return n
+}
+
+// option answers a DO, DONT, WILL or WONT. The client offered binary mode,
+// suppress go ahead and the COM Port option and refuses everything else.
+func (c *Conn) option(command, option byte) {
+	wanted := option == optBinary || option == optSGA || option == optComPort
+	switch command {
+	case do:
+		if option == optComPort {
+			c.telnet.comPort = true
+		}
+		if !wanted {
+			c.write([]byte{iac, wont, option})
+		}
+	case dont:
+		if option == optComPort {
+			c.telnet.refused = true
+		}
+	case will:
+		if !wanted || option == optComPort {
+			c.write([]byte{iac, dont, option})
+		}
+	}
+}
+
+// subnegotiation logs the server's answers to the COM Port settings.
+// Line and modem state notifications are ignored.
+func (c *Conn) subnegotiation(sub []byte) {
+	if len(sub) < 2 || sub[0] != optComPort {
+		return
+	}
+	switch sub[1] {
+	case serverOffset + setBaudRate, serverOffset + setDataSize, serverOffset + setParity,
+		serverOffset + setStopSize, serverOffset + setControl:
+		// The baud rate is 4 bytes big endian, the others 1 byte
+		var value int
+		for _, b := range sub[2:] {
+			value = value<<8 | int(b)
+		}
+		slog.Debug("RFC 2217 setting acknowledged", "addr", c.conn.RemoteAddr(), "command", sub[1]-serverOffset, "value", value)
+	}
+}
+
+// negotiate offers the Telnet options, waits for the server to accept the
+// COM Port option and sends the serial settings
+func (c *Conn) negotiate(settings protocol.SerialDefaults) error {
+	err := c.write([]byte{
+		iac, will, optBinary, iac, do, optBinary,
+		iac, will, optSGA, iac, do, optSGA,
+		iac, will, optComPort,
+	})
+	if err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(dialTimeout)
+	buf := make([]byte, 256)
+	for !c.telnet.comPort {
+		if c.telnet.refused {
+			return errors.New("server refused the COM Port option")
+		}
+		c.conn.SetReadDeadline(deadline)
+		n, err := c.conn.Read(buf)
+		if n > 0 {
+			n = c.filter(buf[:n])
+			c.telnet.pending = append(c.telnet.pending, buf[:n]...)
+		}
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return errors.New("no answer to the COM Port option")
+		}
+		if errors.Is(err, io.EOF) {
+			return errClosed
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	parity := map[byte]byte{'N': 1, 'O': 2, 'E': 3}[settings.Parity]
+	commands := [][]byte{
+		{setBaudRate, byte(settings.Baud >> 24), byte(settings.Baud >> 16), byte(settings.Baud >> 8), byte(settings.Baud)},
+		{setDataSize, byte(settings.DataBits)},
+		{setParity, parity},
+		{setStopSize, byte(settings.StopBits)},
+		{setControl, controlNone},
+	}
+	var out []byte
+	for _, command := range commands {
+		out = append(out, iac, sb, optComPort)
+		out = append(out, escape(command)...)
+		out = append(out, iac, se)
+	}
+	return c.write(out)
+}
+
+// escape doubles the IAC bytes of p
+func escape(p []byte) []byte {
+	out := make([]byte, 0, len(p))
+	for _, b := range p {
+		out = append(out, b)
+		if b == iac {
+			out = append(out, iac)
+		}
+	}
+	return out
+}
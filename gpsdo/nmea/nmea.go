@@ -0,0 +1,271 @@
+// Package nmea parses the NMEA 0183 time sentences ($--ZDA and $--RMC)
+// emitted by ordinary GPS receivers, and the proprietary disciplining
+// sentences ($PTNTA and $PJLTS) of Trimble and Jackson Labs GPSDOs.
+package nmea
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+var (
+	// ErrChecksum is returned when a sentence checksum does not match
+	ErrChecksum = errors.New("nmea: checksum mismatch")
+	// ErrNoChecksum is returned for sentences without a *hh checksum
+	ErrNoChecksum = errors.New("nmea: missing checksum")
+	// ErrFormat is returned for sentences that cannot be decoded
+	ErrFormat = errors.New("nmea: malformed sentence")
+	// ErrRange is returned for sentences with a date or time field out of
+	// range, such as month 13 or a negative hour
+	ErrRange = errors.New("nmea: field out of range")
+)
+
+// Checksum returns the XOR of all bytes in s
+func Checksum(s string) byte {
+	var sum byte
+	for i := 0; i < len(s); i++ {
+		sum ^= s[i]
+	}
+	return sum
+}
+
+// Split validates the checksum of a sentence and returns its comma
+// separated fields, starting with the address field (e.g. "GPZDA").
+func Split(line string) ([]string, error) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "$") {
+		return nil, ErrFormat
+	}
+
+	star := strings.LastIndexByte(line, '*')
+	if star < 0 {
+		return nil, ErrNoChecksum
+	}
+
+	body := line[1:star]
+	want, err := strconv.ParseUint(line[star+1:], 16, 8)
+	if err != nil || len(line)-star-1 != 2 {
+		return nil, ErrFormat
+	}
+	if Checksum(body) != byte(want) {
+		return nil, ErrChecksum
+	}
+
+	return strings.Split(body, ","), nil
+}
+
+// Parser decodes NMEA time sentences. It is stateful: ZDA carries no fix
+// quality, so its status follows the most recent RMC sentence, and a second
+// already reported by one sentence type is not reported again by the other.
+// Once a GPSDO has reported its disciplining state, that state is the status
+// of the samples instead.
+type Parser struct {
+	sawRMC   bool
+	rmcValid bool
+	last     time.Time
+
+	// discipline is the status from the disciplining sentences
+	sawDiscipline bool
+	discipline    gpsdo.Status
+	diagnostics   gpsdo.Diagnostics
+	updated       bool
+}
+
+// Parse decodes a single sentence. Sentences other than ZDA and RMC, and
+// seconds that were already reported, return a nil sample and nil error.
+// The disciplining sentences update Diagnostics.
+func (p *Parser) Parse(line string) (*gpsdo.Sample, error) {
+	fields, err := Split(line)
+	if err != nil {
+		return nil, err
+	}
+	switch fields[0] {
+	case "PTNTA":
+		return nil, p.parsePTNTA(fields)
+	case "PJLTS":
+		return nil, p.parsePJLTS(fields)
+	}
+	if len(fields[0]) != 5 || fields[0][0] == 'P' {
+		return nil, nil
+	}
+
+	var timestamp time.Time
+	var status gpsdo.Status
+
+	switch fields[0][2:] {
+	case "ZDA":
+		timestamp, err = parseZDA(fields)
+		status = gpsdo.Locked
+		if p.sawRMC && !p.rmcValid {
+			status = gpsdo.Unknown
+		}
+	case "RMC":
+		timestamp, status, err = parseRMC(fields)
+		p.sawRMC = true
+		p.rmcValid = status == gpsdo.Locked
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if timestamp.Equal(p.last) {
+		return nil, nil
+	}
+	p.last = timestamp
+
+	valid := status == gpsdo.Locked
+	if p.sawDiscipline {
+		// The oscillator keeps time in holdover without a fix
+		status = p.discipline
+		valid = status == gpsdo.Locked || status == gpsdo.Holdover
+	}
+
+	return &gpsdo.Sample{
+		Year:      timestamp.Year(),
+		DayOfYear: timestamp.YearDay(),
+		Hour:      timestamp.Hour(),
+		Minute:    timestamp.Minute(),
+		Second:    timestamp.Second(),
+		Status:    status,
+		Valid:     valid,
+		Timestamp: timestamp,
+		ParseTime: time.Now(),
+	}, nil
+}
+
+// Diagnostics returns the diagnostics of the disciplining sentences and
+// whether they changed since the last call
+func (p *Parser) Diagnostics() (gpsdo.Diagnostics, bool) {
+	updated := p.updated
+	p.updated = false
+	return p.diagnostics, updated
+}
+
+// disciplineStates are the oscillator quality codes shared by $PTNTA and
+// $PJLTS
+var disciplineStates = []struct {
+	name   string
+	status gpsdo.Status
+}{
+	{"warming up", gpsdo.PowerUp},
+	{"holdover", gpsdo.Holdover},
+	{"disciplining", gpsdo.PowerUp},
+	{"locked", gpsdo.Locked},
+}
+
+// setDiscipline records the oscillator quality code and the phase error in
+// nanoseconds
+func (p *Parser) setDiscipline(code, phase string) error {
+	q, err := strconv.Atoi(code)
+	if err != nil || q < 0 || q >= len(disciplineStates) {
+		return fmt.Errorf("%w: oscillator quality %q", ErrFormat, code)
+	}
+	ns, err := strconv.ParseFloat(phase, 64)
+	if err != nil {
+		return fmt.Errorf("%w: phase %q", ErrFormat, phase)
+	}
+
+	p.sawDiscipline = true
+	p.discipline = disciplineStates[q].status
+	p.diagnostics.Discipline = disciplineStates[q].name
+	p.diagnostics.PhaseError = time.Duration(ns * float64(time.Nanosecond))
+	p.updated = true
+	return nil
+}
+
+// $PTNTA,YYYYMMDDhhmmss,q,T1,D,T3,T4,T5 with q the oscillator quality and D
+// the PPS phase error in ns
+func (p *Parser) parsePTNTA(fields []string) error {
+	if len(fields) < 5 {
+		return ErrFormat
+	}
+	return p.setDiscipline(fields[2], fields[4])
+}
+
+// $PJLTS,q,efc,D with q the oscillator quality as in $PTNTA, efc the EFC in
+// percent and D the PPS phase error in ns
+func (p *Parser) parsePJLTS(fields []string) error {
+	if len(fields) < 4 {
+		return ErrFormat
+	}
+	efc, err := strconv.ParseFloat(fields[2], 64)
+	if err != nil {
+		return fmt.Errorf("%w: EFC %q", ErrFormat, fields[2])
+	}
+	if err := p.setDiscipline(fields[1], fields[3]); err != nil {
+		return err
+	}
+	p.diagnostics.EFC = efc
+	return nil
+}
+
+// $--ZDA,hhmmss.ss,dd,mm,yyyy,zh,zm
+func parseZDA(fields []string) (time.Time, error) {
+	if len(fields) < 5 {
+		return time.Time{}, ErrFormat
+	}
+	day, err1 := strconv.Atoi(fields[2])
+	month, err2 := strconv.Atoi(fields[3])
+	year, err3 := strconv.Atoi(fields[4])
+	if err := errors.Join(err1, err2, err3); err != nil {
+		return time.Time{}, fmt.Errorf("%w: ZDA date: %v", ErrFormat, err)
+	}
+	return buildTime(year, month, day, fields[1])
+}
+
+// $--RMC,hhmmss.ss,A,llll.ll,a,yyyyy.yy,a,x.x,x.x,ddmmyy,x.x,a[,m]
+func parseRMC(fields []string) (time.Time, gpsdo.Status, error) {
+	if len(fields) < 10 || len(fields[9]) != 6 {
+		return time.Time{}, gpsdo.Unknown, ErrFormat
+	}
+	date, err := strconv.Atoi(fields[9])
+	if err != nil {
+		return time.Time{}, gpsdo.Unknown, fmt.Errorf("%w: RMC date: %v", ErrFormat, err)
+	}
+
+	status := gpsdo.Unknown
+	if fields[2] == "A" {
+		status = gpsdo.Locked
+	}
+
+	ts, err := buildTime(2000+date%100, date/100%100, date/10000, fields[1])
+	return ts, status, err
+}
+
+// buildTime checks the date and time fields, which time.Date would
+// otherwise normalize, such as day 31 of February into March
+func buildTime(year, month, day int, hms string) (time.Time, error) {
+	if len(hms) < 6 {
+		return time.Time{}, fmt.Errorf("%w: time %q", ErrFormat, hms)
+	}
+	hour, err1 := strconv.Atoi(hms[0:2])
+	minute, err2 := strconv.Atoi(hms[2:4])
+	second, err3 := strconv.ParseFloat(hms[4:], 64)
+	if err := errors.Join(err1, err2, err3); err != nil {
+		return time.Time{}, fmt.Errorf("%w: time %q", ErrFormat, hms)
+	}
+
+	if year < 0 || month < 1 || month > 12 {
+		return time.Time{}, fmt.Errorf("%w: date %04d-%02d-%02d", ErrRange, year, month, day)
+	}
+	// Day 0 of the next month is the last day of this one
+	if last := time.Date(year, time.Month(month)+1, 0, 0, 0, 0, 0, time.UTC).Day(); day < 1 || day > last {
+		return time.Time{}, fmt.Errorf("%w: date %04d-%02d-%02d", ErrRange, year, month, day)
+	}
+	// Up to 60.999 for a leap second, NaN fails the comparison
+	if hour < 0 || hour > 23 || minute < 0 || minute > 59 || !(second >= 0 && second < 61) {
+		return time.Time{}, fmt.Errorf("%w: time %q", ErrRange, hms)
+	}
+
+	whole := int(second)
+	nsec := int(math.Round((second-float64(whole))*1e6)) * 1000
+	return time.Date(year, time.Month(month), day, hour, minute, whole, nsec, time.UTC), nil
+}
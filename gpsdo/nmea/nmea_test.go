@@ -0,0 +1,150 @@
+package nmea
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// sentence frames body with the $ prefix and its checksum
+func sentence(body string) string {
+	return fmt.Sprintf("$%s*%02X\r\n", body, Checksum(body))
+}
+
+func TestParse(t *testing.T) {
+	for _, tt := range []struct {
+		name      string
+		sentences []string
+		want      time.Time
+		status    gpsdo.Status
+	}{
+		{
+			name:      "ZDA",
+			sentences: []string{"GPZDA,123456.25,14,03,2025,00,00"},
+			want:      time.Date(2025, time.March, 14, 12, 34, 56, 250000000, time.UTC),
+			status:    gpsdo.Locked,
+		},
+		{
+			name:      "RMC active",
+			sentences: []string{"GNRMC,235959.00,A,5130.00,N,00007.50,W,0.0,0.0,311224,,,A"},
+			want:      time.Date(2024, time.December, 31, 23, 59, 59, 0, time.UTC),
+			status:    gpsdo.Locked,
+		},
+		{
+			name:      "RMC void",
+			sentences: []string{"GPRMC,000000,V,,,,,,,010125,,"},
+			want:      time.Date(2025, time.January, 1, 0, 0, 0, 0, time.UTC),
+			status:    gpsdo.Unknown,
+		},
+		{
+			// The two-digit year is in the 2000s
+			name:      "RMC year 99",
+			sentences: []string{"GPRMC,120000,A,,,,,,,150699,,"},
+			want:      time.Date(2099, time.June, 15, 12, 0, 0, 0, time.UTC),
+			status:    gpsdo.Locked,
+		},
+		{
+			name:      "ZDA after void RMC",
+			sentences: []string{"GPRMC,120000,V,,,,,,,140325,,", "GPZDA,120001,14,03,2025,,"},
+			want:      time.Date(2025, time.March, 14, 12, 0, 1, 0, time.UTC),
+			status:    gpsdo.Unknown,
+		},
+		{
+			name:      "same second from ZDA and RMC",
+			sentences: []string{"GPZDA,120000,14,03,2025,,", "GPRMC,120000,A,,,,,,,140325,,"},
+		},
+		{
+			name:      "leap second",
+			sentences: []string{"GPZDA,235960.00,31,12,2016,,"},
+			want:      time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC),
+			status:    gpsdo.Locked,
+		},
+		{
+			name:      "other sentence",
+			sentences: []string{"GPGGA,120000,5130.00,N,00007.50,W,1,08,1.0,45.0,M,47.0,M,,"},
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Parser
+			var sample *gpsdo.Sample
+			for _, s := range tt.sentences {
+				var err error
+				if sample, err = p.Parse(sentence(s)); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			switch {
+			case tt.want.IsZero():
+				if sample != nil {
+					t.Errorf("sample at %s, want none", sample.Timestamp)
+				}
+			case sample == nil:
+				t.Errorf("no sample, want %s", tt.want)
+			default:
+				if !sample.Timestamp.Equal(tt.want) {
+					t.Errorf("timestamp = %s, want %s", sample.Timestamp, tt.want)
+				}
+				if sample.Status != tt.status || sample.Valid != (tt.status == gpsdo.Locked) {
+					t.Errorf("status = %s valid %t, want %s", sample.Status, sample.Valid, tt.status)
+				}
+			}
+		})
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	zda := sentence("GPZDA,120000,14,03,2025,,")
+
+	for _, tt := range []struct {
+		name string
+		line string
+		want error
+	}{
+		{"bad checksum", zda[:len(zda)-4] + "00\r\n", ErrChecksum},
+		{"no checksum", "$GPZDA,120000,14,03,2025,,\r\n", ErrNoChecksum},
+		{"no $", zda[1:], ErrFormat},
+		{"short checksum", "$GPZDA,120000,14,03,2025,,*7\r\n", ErrFormat},
+		{"ZDA missing fields", sentence("GPZDA,120000,14"), ErrFormat},
+		{"ZDA day not a number", sentence("GPZDA,120000,xx,03,2025,,"), ErrFormat},
+		{"ZDA short time", sentence("GPZDA,1200,14,03,2025,,"), ErrFormat},
+		{"RMC short date", sentence("GPRMC,120000,A,,,,,,,14032,,"), ErrFormat},
+		{"ZDA month 13", sentence("GPZDA,120000,14,13,2025,,"), ErrRange},
+		{"ZDA February 29", sentence("GPZDA,120000,29,02,2025,,"), ErrRange},
+		{"ZDA day 0", sentence("GPZDA,120000,00,03,2025,,"), ErrRange},
+		{"ZDA hour 24", sentence("GPZDA,240000,14,03,2025,,"), ErrRange},
+		{"ZDA negative minute", sentence("GPZDA,12-100,14,03,2025,,"), ErrRange},
+		{"ZDA second 61", sentence("GPZDA,120061,14,03,2025,,"), ErrRange},
+		{"RMC day 32", sentence("GPRMC,120000,A,,,,,,,320325,,"), ErrRange},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Parser
+			if _, err := p.Parse(tt.line); !errors.Is(err, tt.want) {
+				t.Errorf("Parse error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiscipline(t *testing.T) {
+	var p Parser
+	if _, err := p.Parse(sentence("PJLTS,1,55.25,-12")); err != nil {
+		t.Fatal(err)
+	}
+	diagnostics, updated := p.Diagnostics()
+	if !updated || diagnostics.Discipline != "holdover" || diagnostics.EFC != 55.25 || diagnostics.PhaseError != -12*time.Nanosecond {
+		t.Errorf("diagnostics = %+v, updated %t", diagnostics, updated)
+	}
+
+	// Without a fix, the oscillator in holdover still keeps time
+	sample, err := p.Parse(sentence("GPRMC,120000,V,,,,,,,140325,,"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sample.Status != gpsdo.Holdover || !sample.Valid {
+		t.Errorf("status = %s valid %t, want %s valid", sample.Status, sample.Valid, gpsdo.Holdover)
+	}
+}
@@ -0,0 +1,181 @@
+package ntp
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"slices"
+	"sync"
+	"time"
+)
+
+// queryTimeout bounds the query of one server
+const queryTimeout = 5 * time.Second
+
+// modeVersion4 is the first byte of a version 4 client request
+const modeVersion4 = 4<<3 | modeClient
+
+// Reply is the answer of a server to a client query
+type Reply struct {
+	Server string
+	// Offset is the server clock minus the system clock and Delay the round
+	// trip delay, both corrected for the server processing time
+	Offset  time.Duration
+	Delay   time.Duration
+	Stratum int
+	// Err is why the server didn't answer with a usable time
+	Err error
+}
+
+// Query asks server, a host or host:port, for the time with a single SNTP
+// request. Kiss-o'-Death and unsynchronized replies are errors.
+func Query(ctx context.Context, server string) Reply {
+	r := Reply{Server: server}
+	ctx, cancel := context.WithTimeout(ctx, queryTimeout)
+	defer cancel()
+
+	addr := server
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		addr = net.JoinHostPort(server, "123")
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", addr)
+	if err != nil {
+		r.Err = err
+		return r
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	request := make([]byte, packetLen)
+	request[0] = modeVersion4
+	sent := time.Now()
+	binary.BigEndian.PutUint64(request[40:], timestamp(sent))
+	if _, err := conn.Write(request); err != nil {
+		r.Err = err
+		return r
+	}
+
+	response := make([]byte, 1024)
+	for {
+		n, err := conn.Read(response)
+		received := time.Now()
+		if err != nil {
+			r.Err = err
+			return r
+		}
+		// Ignore stray packets that don't answer this request
+		if n < packetLen || !slices.Equal(response[24:32], request[40:48]) {
+			continue
+		}
+		r.Offset, r.Delay, r.Stratum, r.Err = parseReply(response[:n], sent, received)
+		return r
+	}
+}
+
+// parseReply checks the reply to a request sent and received at the given
+// system times and returns the offset and delay of the server
+func parseReply(response []byte, sent, received time.Time) (offset, delay time.Duration, stratum int, err error) {
+	if response[0]&0x7 != modeServer {
+		return 0, 0, 0, fmt.Errorf("reply mode %d, expected %d", response[0]&0x7, modeServer)
+	}
+	stratum = int(response[1])
+	if stratum == 0 {
+		return 0, 0, 0, fmt.Errorf("kiss-o'-death %q", response[12:16])
+	}
+	if response[0]>>6 == leapAlarm || stratum >= stratumUnsync {
+		return 0, 0, stratum, errors.New("server is unsynchronized")
+	}
+	serverReceived := fromTimestamp(binary.BigEndian.Uint64(response[32:]))
+	serverSent := fromTimestamp(binary.BigEndian.Uint64(response[40:]))
+	offset = (serverReceived.Sub(sent) + serverSent.Sub(received)) / 2
+	delay = received.Sub(sent) - serverSent.Sub(serverReceived)
+	return offset, delay, stratum, nil
+}
+
+// fromTimestamp converts a 64 bit NTP timestamp in the current era
+func fromTimestamp(ts uint64) time.Time {
+	sec := int64(ts>>32) - ntpEpochOffset
+	nsec := int64((ts & 0xffffffff) * uint64(time.Second) >> 32)
+	return time.Unix(sec, nsec)
+}
+
+// Check is a round of queries to the reference servers
+type Check struct {
+	Replies []Reply
+	// Offset is the median offset of the servers that answered from the
+	// system clock
+	Offset time.Duration
+	// Answered counts the servers that answered
+	Answered int
+	Updated  time.Time
+}
+
+// QueryAll queries the servers in parallel
+func QueryAll(ctx context.Context, servers []string) *Check {
+	c := &Check{Replies: make([]Reply, len(servers))}
+	var wg sync.WaitGroup
+	for i, server := range servers {
+		wg.Go(func() { c.Replies[i] = Query(ctx, server) })
+	}
+	wg.Wait()
+	c.Updated = time.Now()
+
+	var offsets []time.Duration
+	for _, r := range c.Replies {
+		if r.Err == nil {
+			offsets = append(offsets, r.Offset)
+		}
+	}
+	c.Answered = len(offsets)
+	if len(offsets) > 0 {
+		slices.Sort(offsets)
+		mid := len(offsets) / 2
+		c.Offset = offsets[mid]
+		if len(offsets)%2 == 0 {
+			c.Offset = (offsets[mid-1] + offsets[mid]) / 2
+		}
+	}
+	return c
+}
+
+// Poll queries the servers every interval until ctx is cancelled and passes
+// each round to update
+func Poll(ctx context.Context, servers []string, interval time.Duration, update func(*Check)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// failed keeps repeated failures out of the log
+	failed := false
+	for {
+		c := QueryAll(ctx, servers)
+		if ctx.Err() != nil {
+			return
+		}
+		for _, r := range c.Replies {
+			if r.Err != nil {
+				slog.Debug("NTP reference query failed", "server", r.Server, "error", r.Err)
+			}
+		}
+		switch {
+		case c.Answered == 0 && !failed:
+			failed = true
+			slog.Warn("No NTP reference server answered", "servers", servers, "error", c.Replies[0].Err)
+		case c.Answered > 0 && failed:
+			failed = false
+			slog.Info("NTP reference servers answer again", "answered", c.Answered)
+		}
+		update(c)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
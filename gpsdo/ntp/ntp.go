@@ -0,0 +1,201 @@
+// Package ntp answers NTP client queries with the GPSDO time, as a stratum 1
+// server, so small networks can sync from the bridge without chrony or ntpd.
+// Only client mode requests are answered, control and private mode queries
+// are ignored.
+//
+// See RFC 5905
+package ntp
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// DefaultAddr is the standard NTP port
+const DefaultAddr = ":123"
+
+// packetLen is the length of an NTP packet without extensions
+const packetLen = 48
+
+// Packet modes
+const (
+	modeClient = 3
+	modeServer = 4
+)
+
+// Leap indicators, with leapAlarm for an unsynchronized server
+const (
+	leapNone   = 0
+	leapInsert = 1
+	leapDelete = 2
+	leapAlarm  = 3
+)
+
+// stratumUnsync is reported until there is a recent sample
+const stratumUnsync = 16
+
+// staleAfter is how long after the last sample the server stays synchronized
+const staleAfter = 10 * time.Second
+
+// log2 of the accuracy of serial time-of-day and PPS paired samples, and
+// their dispersion
+const (
+	precisionTOD  = -10
+	precisionPPS  = -20
+	dispersionTOD = time.Millisecond
+	dispersionPPS = time.Microsecond
+)
+
+// maxDrift is the frequency tolerance added to the dispersion as the last
+// sample ages, 15 ppm as in RFC 5905
+const maxDrift = 15e-6
+
+// ntpEpochOffset is the number of seconds from 1900 to 1970
+const ntpEpochOffset = 2208988800
+
+// Server is an NTP server and bridge output
+type Server struct {
+	conn *net.UDPConn
+
+	mutex sync.Mutex
+	// offset is the GPSDO time minus the system clock at the last sample
+	offset    time.Duration
+	reference time.Time
+	updated   time.Time
+	leap      gpsdo.Leap
+	pps       bool
+}
+
+// Listen opens the UDP socket
+func Listen(addr string) (*Server, error) {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("ntp listen %s: %w", addr, err)
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return nil, fmt.Errorf("ntp listen %s: %w", addr, err)
+	}
+	slog.Info("NTP server listening", "addr", conn.LocalAddr())
+	return &Server{conn: conn}, nil
+}
+
+// Serve answers queries until ctx is cancelled
+func (s *Server) Serve(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		s.conn.Close()
+	}()
+
+	buf := make([]byte, 1024)
+	for {
+		n, addr, err := s.conn.ReadFromUDPAddrPort(buf)
+		received := time.Now()
+		if err != nil {
+			if ctx.Err() == nil && !errors.Is(err, net.ErrClosed) {
+				slog.Error("NTP read failed", "error", err)
+			}
+			return
+		}
+
+		response, ok := s.respond(buf[:n], received)
+		if !ok {
+			continue
+		}
+		if _, err := s.conn.WriteToUDPAddrPort(response, addr); err != nil {
+			slog.Debug("NTP response failed", "client", addr, "error", err)
+		}
+	}
+}
+
+// Name identifies the output in logs
+func (s *Server) Name() string {
+	return "ntp"
+}
+
+// Send takes the offset of the system clock from a sample for the
+// following responses
+func (s *Server) Send(data *gpsdo.Sample) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.offset = data.SystemOffset()
+	s.reference = data.Timestamp.Add(data.Offset)
+	s.updated = time.Now()
+	s.leap = data.Leap
+	s.pps = !data.PPS.IsZero()
+	return nil
+}
+
+// respond builds the response to a request received at the given system
+// time. It reports false for packets that are not client requests.
+func (s *Server) respond(request []byte, received time.Time) ([]byte, bool) {
+	if len(request) < packetLen {
+		return nil, false
+	}
+	version := request[0] >> 3 & 0x7
+	if request[0]&0x7 != modeClient || version < 1 || version > 4 {
+		return nil, false
+	}
+
+	s.mutex.Lock()
+	offset, reference, updated, leap, pps := s.offset, s.reference, s.updated, s.leap, s.pps
+	s.mutex.Unlock()
+
+	response := make([]byte, packetLen)
+	li, stratum := byte(leapAlarm), byte(stratumUnsync)
+	age := received.Sub(updated)
+	if !updated.IsZero() && age < staleAfter {
+		stratum = 1
+		switch leap {
+		case gpsdo.LeapInsert:
+			li = leapInsert
+		case gpsdo.LeapDelete:
+			li = leapDelete
+		default:
+			li = leapNone
+		}
+	}
+	precision, dispersion, refID := int8(precisionTOD), dispersionTOD, "GPS"
+	if pps {
+		precision, dispersion, refID = precisionPPS, dispersionPPS, "PPS"
+	}
+	if !updated.IsZero() {
+		dispersion += time.Duration(float64(age) * maxDrift)
+	}
+
+	response[0] = li<<6 | version<<3 | modeServer
+	response[1] = stratum
+	response[2] = request[2] // poll
+	response[3] = byte(precision)
+	// Root delay is zero for a reference clock
+	binary.BigEndian.PutUint32(response[8:], shortFormat(dispersion))
+	copy(response[12:16], refID)
+	if !reference.IsZero() {
+		binary.BigEndian.PutUint64(response[16:], timestamp(reference))
+	}
+	copy(response[24:32], request[40:48]) // origin is the client transmit time
+	binary.BigEndian.PutUint64(response[32:], timestamp(received.Add(offset)))
+	binary.BigEndian.PutUint64(response[40:], timestamp(time.Now().Add(offset)))
+	return response, true
+}
+
+// timestamp converts t to the 64 bit NTP timestamp format
+func timestamp(t time.Time) uint64 {
+	sec := uint64(t.Unix() + ntpEpochOffset)
+	frac := uint64(t.Nanosecond()) << 32 / uint64(time.Second)
+	return sec<<32 | frac
+}
+
+// shortFormat converts d to the 32 bit NTP short format
+func shortFormat(d time.Duration) uint32 {
+	return uint32(min(d.Seconds()*(1<<16), math.MaxUint32))
+}
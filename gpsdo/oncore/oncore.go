@@ -0,0 +1,217 @@
+// Package oncore parses the Motorola Oncore binary protocol used by the VP,
+// UT+ and M12+T timing receivers.
+package oncore
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// Message lengths including the @@ prefix, checksum and CR LF
+var messageLen = map[string]int{
+	"Ea": 76,  // VP/UT+ position/status/data
+	"Ha": 154, // M12 position/status/data
+	"Hn": 78,  // M12+T time RAIM status
+}
+
+// @@Hn solution status
+const solutionOK = 0
+
+// Offsets in @@Ea of the satellites tracked and the receiver status
+const (
+	eaTracked = 39
+	eaStatus  = 72
+)
+
+// @@Ea receiver status bits
+const (
+	statusPropagate    = 0x80 // position propagate mode, no fix
+	statusFix3D        = 0x20
+	statusFix2D        = 0x10
+	statusHold         = 0x08 // acquiring satellites or position hold
+	statusInsufficient = 0x02 // fewer than 3 satellites visible
+)
+
+var (
+	// ErrChecksum is returned when a message checksum does not match
+	ErrChecksum = errors.New("oncore: checksum mismatch")
+	// ErrFormat is returned for messages missing their CR LF terminator
+	ErrFormat = errors.New("oncore: malformed message")
+	// ErrRange is returned for messages with a date or time out of range
+	ErrRange = errors.New("oncore: field out of range")
+)
+
+// Message is a complete Oncore message including the @@ prefix
+type Message struct {
+	ID   string
+	Data []byte
+}
+
+// Checksum is the XOR of the ID and data bytes, i.e. everything between the
+// @@ prefix and the checksum byte
+func Checksum(data []byte) byte {
+	var sum byte
+	for _, c := range data {
+		sum ^= c
+	}
+	return sum
+}
+
+// Decoder extracts Ea, Ha and Hn messages from a byte stream. Other message
+// types are skipped.
+type Decoder struct {
+	buf    []byte
+	length int
+}
+
+// Feed consumes one byte of the stream and returns a message when the byte
+// completes one with a valid checksum.
+func (d *Decoder) Feed(c byte) (*Message, error) {
+	d.buf = append(d.buf, c)
+
+	switch len(d.buf) {
+	case 1, 2:
+		if c != '@' {
+			d.buf = d.buf[:0]
+		}
+		return nil, nil
+	case 3:
+		return nil, nil
+	case 4:
+		length, ok := messageLen[string(d.buf[2:4])]
+		if !ok {
+			d.reset()
+			return nil, nil
+		}
+		d.length = length
+		return nil, nil
+	}
+
+	if len(d.buf) < d.length {
+		return nil, nil
+	}
+
+	frame := d.buf
+	d.reset()
+
+	if frame[len(frame)-2] != '\r' || frame[len(frame)-1] != '\n' {
+		return nil, fmt.Errorf("%w: @@%s missing CR LF", ErrFormat, frame[2:4])
+	}
+	if Checksum(frame[2:len(frame)-3]) != frame[len(frame)-3] {
+		return nil, fmt.Errorf("%w: @@%s", ErrChecksum, frame[2:4])
+	}
+
+	data := make([]byte, len(frame))
+	copy(data, frame)
+	return &Message{ID: string(frame[2:4]), Data: data}, nil
+}
+
+// reset drops the buffer, keeping a trailing '@' that may start the next
+// message
+func (d *Decoder) reset() {
+	last := d.buf[len(d.buf)-1]
+	d.buf = d.buf[:0]
+	d.length = 0
+	if last == '@' {
+		d.buf = append(d.buf, last)
+	}
+}
+
+// Parser decodes Oncore messages. @@Hn supplies the time solution status and
+// the negative sawtooth of the next PPS edge, which are applied to the
+// following @@Ea or @@Ha sample. VP and UT+ receivers send no @@Hn, their
+// status is taken from the receiver status of @@Ea instead.
+type Parser struct {
+	sawHn      bool
+	solutionOK bool
+	sawtooth   time.Duration
+	last       time.Time
+}
+
+// Parse decodes one message. Messages that do not produce a sample return a
+// nil sample and nil error.
+func (p *Parser) Parse(msg *Message) (*gpsdo.Sample, error) {
+	switch msg.ID {
+	case "Ea", "Ha":
+		return p.parsePosition(msg.Data)
+	case "Hn":
+		p.parseTRAIM(msg.Data)
+	}
+	return nil, nil
+}
+
+// parseTRAIM decodes @@Hn
+func (p *Parser) parseTRAIM(data []byte) {
+	p.sawHn = true
+	p.solutionOK = data[6] == solutionOK
+	p.sawtooth = time.Duration(int8(data[14])) * time.Nanosecond
+}
+
+// eaLocked reports whether the receiver status of @@Ea is a usable fix: a 2D
+// or 3D fix, or position hold while tracking satellites
+func eaLocked(data []byte) bool {
+	status := data[eaStatus]
+	if status&(statusPropagate|statusInsufficient) != 0 {
+		return false
+	}
+	return status&(statusFix3D|statusFix2D) != 0 || status&statusHold != 0 && data[eaTracked] > 0
+}
+
+// parsePosition decodes the date and time shared by @@Ea and @@Ha
+func (p *Parser) parsePosition(data []byte) (*gpsdo.Sample, error) {
+	month := int(data[4])
+	day := int(data[5])
+	year := int(binary.BigEndian.Uint16(data[6:8]))
+	hour := int(data[8])
+	minute := int(data[9])
+	second := int(data[10])
+	nanos := binary.BigEndian.Uint32(data[11:15])
+
+	if month < 1 || month > 12 || day < 1 || day > 31 || hour > 23 || minute > 59 || second > 60 || nanos >= 1e9 {
+		return nil, fmt.Errorf("%w: @@%s date %04d-%02d-%02d %02d:%02d:%02d", ErrRange, data[2:4],
+			year, month, day, hour, minute, second)
+	}
+	// time.Date normalizes days past the end of the month, 31 Feb to 3 Mar.
+	// Check the date alone so a leap second does not roll into the next day.
+	if time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC).Day() != day {
+		return nil, fmt.Errorf("%w: @@%s date %04d-%02d-%02d", ErrRange, data[2:4], year, month, day)
+	}
+
+	label := time.Date(year, time.Month(month), day, hour, minute, second, int(nanos), time.UTC)
+	if label.Equal(p.last) {
+		return nil, nil
+	}
+	p.last = label
+
+	// The negative sawtooth is what must be added to a measurement of the
+	// edge to correct it, so the edge really occurred at label - sawtooth
+	correction := -p.sawtooth
+	timestamp := label.Add(correction)
+
+	status := gpsdo.Unknown
+	switch {
+	case p.sawHn:
+		if p.solutionOK {
+			status = gpsdo.Locked
+		}
+	case string(data[2:4]) == "Ea" && eaLocked(data):
+		status = gpsdo.Locked
+	}
+
+	return &gpsdo.Sample{
+		Year:       label.Year(),
+		DayOfYear:  label.YearDay(),
+		Hour:       hour,
+		Minute:     minute,
+		Second:     second,
+		Status:     status,
+		Valid:      status == gpsdo.Locked,
+		Timestamp:  timestamp,
+		ParseTime:  time.Now(),
+		Correction: correction,
+	}, nil
+}
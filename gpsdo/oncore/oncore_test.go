@@ -0,0 +1,237 @@
+package oncore
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// frame builds a complete message of id with its checksum and CR LF, after
+// set fills in the data
+func frame(id string, set func(data []byte)) []byte {
+	data := make([]byte, messageLen[id])
+	copy(data, "@@"+id)
+	if set != nil {
+		set(data)
+	}
+	data[len(data)-3] = Checksum(data[2 : len(data)-3])
+	data[len(data)-2], data[len(data)-1] = '\r', '\n'
+	return data
+}
+
+// position fills in the date and time of @@Ea or @@Ha
+func position(t time.Time) func(data []byte) {
+	return func(data []byte) {
+		data[4], data[5] = byte(t.Month()), byte(t.Day())
+		binary.BigEndian.PutUint16(data[6:8], uint16(t.Year()))
+		data[8], data[9], data[10] = byte(t.Hour()), byte(t.Minute()), byte(t.Second())
+		binary.BigEndian.PutUint32(data[11:15], uint32(t.Nanosecond()))
+	}
+}
+
+// ea fills in the date and time of @@Ea with the receiver status and the
+// satellites tracked
+func ea(t time.Time, status, tracked byte) func(data []byte) {
+	return func(data []byte) {
+		position(t)(data)
+		data[eaStatus], data[eaTracked] = status, tracked
+	}
+}
+
+// hn fills in the solution status and sawtooth of @@Hn
+func hn(solution byte, sawtooth int8) func(data []byte) {
+	return func(data []byte) {
+		data[6] = solution
+		data[14] = byte(sawtooth)
+	}
+}
+
+// decode feeds the stream to a Decoder and returns the messages and the
+// errors in order
+func decode(stream []byte) ([]*Message, []error) {
+	var d Decoder
+	var messages []*Message
+	var errs []error
+	for _, c := range stream {
+		msg, err := d.Feed(c)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if msg != nil {
+			messages = append(messages, msg)
+		}
+	}
+	return messages, errs
+}
+
+func TestDecoder(t *testing.T) {
+	at := time.Date(2025, time.March, 14, 12, 0, 0, 0, time.UTC)
+	good := frame("Ha", position(at))
+
+	badChecksum := frame("Ha", position(at))
+	badChecksum[20] ^= 0xFF
+	noCRLF := frame("Hn", nil)
+	noCRLF[len(noCRLF)-1] = 0
+
+	for _, tt := range []struct {
+		name   string
+		stream []byte
+		want   error
+	}{
+		{"valid", good, nil},
+		{"other message skipped", append([]byte("@@Cj junk"), good...), nil},
+		{"checksum mismatch", append(badChecksum, good...), ErrChecksum},
+		{"missing CR LF", append(noCRLF, good...), ErrFormat},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, errs := decode(tt.stream)
+			if tt.want == nil && len(errs) > 0 || tt.want != nil && (len(errs) != 1 || !errors.Is(errs[0], tt.want)) {
+				t.Errorf("errors = %v, want %v", errs, tt.want)
+			}
+			if len(messages) != 1 || messages[0].ID != "Ha" || string(messages[0].Data) != string(good) {
+				t.Errorf("messages = %v, want the @@Ha message", messages)
+			}
+		})
+	}
+}
+
+func TestParse(t *testing.T) {
+	at := time.Date(2025, time.March, 14, 12, 0, 30, 0, time.UTC)
+
+	for _, tt := range []struct {
+		name       string
+		messages   [][]byte
+		status     gpsdo.Status
+		correction time.Duration
+	}{
+		{
+			name:     "@@Ha without @@Hn",
+			messages: [][]byte{frame("Ha", position(at))},
+			status:   gpsdo.Unknown,
+		},
+		{
+			// The correction is the negative of the sawtooth
+			name:       "@@Hn negative sawtooth",
+			messages:   [][]byte{frame("Hn", hn(solutionOK, -5)), frame("Ha", position(at))},
+			status:     gpsdo.Locked,
+			correction: 5,
+		},
+		{
+			name:       "@@Hn positive sawtooth",
+			messages:   [][]byte{frame("Hn", hn(solutionOK, 12)), frame("Ha", position(at))},
+			status:     gpsdo.Locked,
+			correction: -12,
+		},
+		{
+			name:       "@@Hn bad solution",
+			messages:   [][]byte{frame("Hn", hn(1, 3)), frame("Ha", position(at))},
+			status:     gpsdo.Unknown,
+			correction: -3,
+		},
+		{
+			name:     "@@Ea 3D fix",
+			messages: [][]byte{frame("Ea", ea(at, statusFix3D, 8))},
+			status:   gpsdo.Locked,
+		},
+		{
+			name:     "@@Ea position hold",
+			messages: [][]byte{frame("Ea", ea(at, statusHold, 6))},
+			status:   gpsdo.Locked,
+		},
+		{
+			name:     "@@Ea acquiring",
+			messages: [][]byte{frame("Ea", ea(at, statusHold, 0))},
+			status:   gpsdo.Unknown,
+		},
+		{
+			name:     "@@Ea propagate",
+			messages: [][]byte{frame("Ea", ea(at, statusPropagate|statusFix3D, 8))},
+			status:   gpsdo.Unknown,
+		},
+		{
+			name:     "@@Ea insufficient satellites",
+			messages: [][]byte{frame("Ea", ea(at, statusInsufficient|statusHold, 2))},
+			status:   gpsdo.Unknown,
+		},
+		{
+			name:     "@@Hn overrides @@Ea",
+			messages: [][]byte{frame("Hn", hn(1, 0)), frame("Ea", ea(at, statusFix3D, 8))},
+			status:   gpsdo.Unknown,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Parser
+			var sample *gpsdo.Sample
+			for _, data := range tt.messages {
+				var err error
+				if sample, err = p.Parse(&Message{ID: string(data[2:4]), Data: data}); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if sample == nil {
+				t.Fatal("no sample")
+			}
+
+			if sample.Status != tt.status || sample.Valid != (tt.status == gpsdo.Locked) {
+				t.Errorf("status = %s valid %t, want %s", sample.Status, sample.Valid, tt.status)
+			}
+			if sample.Correction != tt.correction {
+				t.Errorf("correction = %s, want %s", sample.Correction, tt.correction)
+			}
+			if want := at.Add(tt.correction); !sample.Timestamp.Equal(want) {
+				t.Errorf("timestamp = %s, want %s", sample.Timestamp, want)
+			}
+			if sample.Year != 2025 || sample.DayOfYear != 73 || sample.Hour != 12 || sample.Minute != 0 || sample.Second != 30 {
+				t.Errorf("label = %d-%03d %02d:%02d:%02d, want 2025-073 12:00:30",
+					sample.Year, sample.DayOfYear, sample.Hour, sample.Minute, sample.Second)
+			}
+		})
+	}
+}
+
+func TestParseNanoseconds(t *testing.T) {
+	at := time.Date(2025, time.March, 14, 12, 0, 30, 250000000, time.UTC)
+	var p Parser
+	sample, err := p.Parse(&Message{ID: "Ha", Data: frame("Ha", position(at))})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sample.Timestamp.Equal(at) {
+		t.Errorf("timestamp = %s, want %s", sample.Timestamp, at)
+	}
+
+	// The same epoch is reported once
+	if sample, err := p.Parse(&Message{ID: "Ha", Data: frame("Ha", position(at))}); sample != nil || err != nil {
+		t.Errorf("repeated epoch = %v, %v, want none", sample, err)
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	at := time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)
+
+	for _, tt := range []struct {
+		name  string
+		field int
+		value byte
+	}{
+		{"month 13", 4, 13},
+		{"day 0", 5, 0},
+		{"February 30", 5, 30},
+		{"hour 24", 8, 24},
+		{"second 61", 10, 61},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			data := frame("Ha", func(data []byte) {
+				position(at)(data)
+				data[tt.field] = tt.value
+			})
+			var p Parser
+			if _, err := p.Parse(&Message{ID: "Ha", Data: data}); !errors.Is(err, ErrRange) {
+				t.Errorf("Parse error = %v, want ErrRange", err)
+			}
+		})
+	}
+}
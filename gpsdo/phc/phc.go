@@ -0,0 +1,266 @@
+// Package phc steers a PTP hardware clock, such as the clock of a NIC with
+// hardware timestamping, to the GPSDO samples through clock_adjtime, so that
+// ptp4l can serve it as a grandmaster without the system clock in the loop.
+//
+// See https://docs.kernel.org/driver-api/ptp.html
+package phc
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"os"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/leapsec"
+	"golang.org/x/sys/unix"
+)
+
+// PI servo gains for one sample a second, the linuxptp defaults for
+// hardware timestamping
+const (
+	kp = 0.7
+	ki = 0.3
+)
+
+// defaultMaxAdj is the frequency adjustment limit in ppb of drivers that
+// don't report one, the limit of adjtimex
+const defaultMaxAdj = 500000
+
+// TAIOffset is TAI-UTC in seconds, used for receivers that don't report the
+// GPS-UTC leap seconds when there is no leap second table
+const TAIOffset = 37
+
+// readings is the number of PHC readings taken to measure its offset from
+// the system clock, the one with the shortest system clock window is used
+const readings = 5
+
+// Options configure the steering of a clock
+type Options struct {
+	// TAI runs the clock on TAI, the time scale of PTP, instead of UTC
+	TAI bool
+	// Step is the offset above which the clock is stepped instead of
+	// slewed. The first sample always steps the clock, 0 never steps it
+	// after that.
+	Step time.Duration
+	// Leap provides TAI-UTC while it is current, nil to take it from the
+	// receiver
+	Leap *leapsec.Table
+}
+
+// Clock is an open PTP hardware clock such as /dev/ptp0
+type Clock struct {
+	device  string
+	file    *os.File
+	clockID int32
+	opts    Options
+	// maxAdj is the largest frequency adjustment of the clock in ppb
+	maxAdj  float64
+	stepped bool
+	// drift is the integral term of the servo in ppb
+	drift float64
+}
+
+// Open opens a PHC device for steering
+func Open(device string, opts Options) (*Clock, error) {
+	f, err := os.OpenFile(device, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", device, err)
+	}
+
+	caps, err := unix.IoctlPtpClockGetcaps(int(f.Fd()))
+	if err != nil {
+		f.Close()
+		return nil, fmt.Errorf("PTP_CLOCK_GETCAPS %s: %w", device, err)
+	}
+
+	c := &Clock{
+		device:  device,
+		file:    f,
+		clockID: fdClockID(int(f.Fd())),
+		opts:    opts,
+		maxAdj:  float64(caps.Max_adj),
+	}
+	if c.maxAdj <= 0 {
+		c.maxAdj = defaultMaxAdj
+	}
+
+	// Carry on from the current frequency, e.g. after a restart
+	var tx unix.Timex
+	if _, err := unix.ClockAdjtime(c.clockID, &tx); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("clock_adjtime %s: %w", device, err)
+	}
+	c.drift = -scaledPPMToPPB(int64(tx.Freq))
+
+	slog.Info("Opened PTP hardware clock", "device", device, "max_adj_ppb", caps.Max_adj, "tai", opts.TAI)
+	return c, nil
+}
+
+// fdClockID is the dynamic POSIX clock of an open PHC, FD_TO_CLOCKID from
+// the kernel's posix-timers.h
+func fdClockID(fd int) int32 {
+	return int32((^fd << 3) | 3)
+}
+
+// Name identifies the output in logs
+func (c *Clock) Name() string {
+	return "phc:" + c.device
+}
+
+// Send steers the clock with a sample, stepping it on the first sample or
+// when it is more than Step off and adjusting its frequency otherwise
+func (c *Clock) Send(data *gpsdo.Sample) error {
+	phcOffset, err := c.systemOffset()
+	if err != nil {
+		return err
+	}
+
+	// The reference is the sample time on the time scale of the clock
+	reference := data.SystemOffset()
+	scale := gpsdo.UTC
+	if c.opts.TAI {
+		scale = gpsdo.TAI
+	}
+	if data.Timescale != scale {
+		tai := c.taiOffset(data)
+		reference += scale.Offset(tai) - data.Timescale.Offset(tai)
+	}
+	offset := phcOffset - reference
+
+	if !c.stepped || (c.opts.Step > 0 && offset.Abs() > c.opts.Step) {
+		if err := c.step(-offset); err != nil {
+			return err
+		}
+		c.stepped = true
+		slog.Info("PTP hardware clock stepped", "device", c.device, "offset", offset)
+		return nil
+	}
+
+	ns := float64(offset.Nanoseconds())
+	c.drift = clamp(c.drift+ki*ns, c.maxAdj)
+	ppb := clamp(kp*ns+c.drift, c.maxAdj)
+	if err := c.adjustFrequency(-ppb); err != nil {
+		return err
+	}
+	slog.Debug("PTP hardware clock adjusted", "device", c.device, "offset", offset, "freq_ppb", math.Round(-ppb))
+	return nil
+}
+
+// Close closes the device. The clock keeps running at its last frequency.
+func (c *Clock) Close() error {
+	return c.file.Close()
+}
+
+// taiOffset is TAI-UTC for a sample, from the leap second table while it is
+// current and otherwise from the GPS-UTC leap seconds when the receiver
+// reports them
+func (c *Clock) taiOffset(data *gpsdo.Sample) int {
+	if c.opts.Leap != nil && !c.opts.Leap.Expired(data.Timestamp) {
+		return c.opts.Leap.Offset(data.Timestamp)
+	}
+	if data.LeapSeconds > 0 {
+		return data.LeapSeconds + gpsdo.TAIGPSOffset
+	}
+	return TAIOffset
+}
+
+// systemOffset measures the offset of the clock from the system clock
+func (c *Clock) systemOffset() (time.Duration, error) {
+	offset, err := c.systemOffsetExtended()
+	if err == nil {
+		return offset, nil
+	}
+
+	// Not every driver supports PTP_SYS_OFFSET_EXTENDED, read the clocks
+	// back to back instead
+	best := time.Duration(math.MaxInt64)
+	for range readings {
+		var before, phc, after unix.Timespec
+		if err := unix.ClockGettime(unix.CLOCK_REALTIME, &before); err != nil {
+			return 0, fmt.Errorf("clock_gettime: %w", err)
+		}
+		if err := unix.ClockGettime(c.clockID, &phc); err != nil {
+			return 0, fmt.Errorf("clock_gettime %s: %w", c.device, err)
+		}
+		if err := unix.ClockGettime(unix.CLOCK_REALTIME, &after); err != nil {
+			return 0, fmt.Errorf("clock_gettime: %w", err)
+		}
+		if window := time.Duration(after.Nano() - before.Nano()); window < best {
+			best = window
+			offset = time.Duration(phc.Nano() - (before.Nano()+after.Nano())/2)
+		}
+	}
+	return offset, nil
+}
+
+// systemOffsetExtended measures the offset of the clock from the system
+// clock with PTP_SYS_OFFSET_EXTENDED, which has the driver read the system
+// clock right around the PHC read
+func (c *Clock) systemOffsetExtended() (time.Duration, error) {
+	sys, err := unix.IoctlPtpSysOffsetExtended(int(c.file.Fd()), readings)
+	if err != nil {
+		return 0, err
+	}
+
+	best := time.Duration(math.MaxInt64)
+	var offset time.Duration
+	for _, ts := range sys.Ts[:sys.Samples] {
+		before, phc, after := ptpNano(ts[0]), ptpNano(ts[1]), ptpNano(ts[2])
+		if window := time.Duration(after - before); window < best {
+			best = window
+			offset = time.Duration(phc - (before+after)/2)
+		}
+	}
+	return offset, nil
+}
+
+// ptpNano converts a PTP clock time to nanoseconds
+func ptpNano(t unix.PtpClockTime) int64 {
+	return t.Sec*int64(time.Second) + int64(t.Nsec)
+}
+
+// step moves the clock by delta
+func (c *Clock) step(delta time.Duration) error {
+	sec, nsec := int64(delta/time.Second), int64(delta%time.Second)
+	if nsec < 0 {
+		// The nanoseconds must be positive
+		sec, nsec = sec-1, nsec+int64(time.Second)
+	}
+
+	tx := unix.Timex{Modes: unix.ADJ_SETOFFSET | unix.ADJ_NANO}
+	setLong(&tx.Time.Sec, sec)
+	setLong(&tx.Time.Usec, nsec)
+	if _, err := unix.ClockAdjtime(c.clockID, &tx); err != nil {
+		return fmt.Errorf("step %s: %w", c.device, err)
+	}
+	return nil
+}
+
+// setLong sets a C long field of a kernel struct, which is 32 bits on
+// 32-bit platforms
+func setLong[T ~int32 | ~int64](field *T, v int64) {
+	*field = T(v)
+}
+
+// adjustFrequency sets the frequency offset of the clock in ppb
+func (c *Clock) adjustFrequency(ppb float64) error {
+	tx := unix.Timex{Modes: unix.ADJ_FREQUENCY}
+	setLong(&tx.Freq, int64(math.Round(ppb*65.536)))
+	if _, err := unix.ClockAdjtime(c.clockID, &tx); err != nil {
+		return fmt.Errorf("adjust frequency %s: %w", c.device, err)
+	}
+	return nil
+}
+
+// scaledPPMToPPB converts a timex frequency, in ppm with a 16 bit
+// fraction, to ppb
+func scaledPPMToPPB(freq int64) float64 {
+	return float64(freq) / 65.536
+}
+
+// clamp limits v to ±limit
+func clamp(v, limit float64) float64 {
+	return math.Max(-limit, math.Min(limit, v))
+}
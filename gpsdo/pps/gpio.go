@@ -0,0 +1,154 @@
+package pps
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// GPIO v2 character device uAPI from linux/gpio.h
+const (
+	gpioLinesMax     = 64
+	gpioNameSize     = 32
+	gpioNumAttrsMax  = 10
+	gpioFlagInput    = 1 << 2
+	gpioFlagRising   = 1 << 4
+	gpioFlagRealtime = 1 << 11
+	gpioEventLen     = 48
+	gpioEventRising  = 1
+)
+
+// gpioLineRequest mirrors struct gpio_v2_line_request
+type gpioLineRequest struct {
+	offsets         [gpioLinesMax]uint32
+	consumer        [gpioNameSize]byte
+	flags           uint64
+	numAttrs        uint32
+	_               [5]uint32
+	attrs           [gpioNumAttrsMax][24]byte
+	numLines        uint32
+	eventBufferSize uint32
+	_               [5]uint32
+	fd              int32
+}
+
+// gpioGetLineIoctl is GPIO_V2_GET_LINE_IOCTL, _IOWR(0xB4, 0x07) with the
+// generic ioctl encoding of x86 and arm
+const gpioGetLineIoctl = 3<<30 | unsafe.Sizeof(gpioLineRequest{})<<16 | 0xb4<<8 | 0x07
+
+// DefaultGPIOChip is the GPIO chip of the Raspberry Pi header pins
+const DefaultGPIOChip = "/dev/gpiochip0"
+
+// ParseGPIO parses a GPIO line as chip:line, such as gpiochip0:18, or just
+// the line number on DefaultGPIOChip. The chip may be a name under /dev or
+// a path.
+func ParseGPIO(spec string) (chip string, line int, err error) {
+	chip, number, ok := strings.Cut(spec, ":")
+	if !ok {
+		chip, number = DefaultGPIOChip, spec
+	}
+	if !strings.Contains(chip, "/") {
+		chip = "/dev/" + chip
+	}
+	line, err = strconv.Atoi(number)
+	if err != nil || line < 0 {
+		return "", 0, fmt.Errorf("invalid gpio line %q, expected chip:line or line", spec)
+	}
+	return chip, line, nil
+}
+
+// GPIO timestamps the rising edges of a GPIO line through the GPIO
+// character device, for a PPS wired to a header pin without the pps-gpio
+// overlay. The kernel timestamps each edge in its interrupt handler, so
+// unlike the modem lines the wakeup latency of the bridge doesn't matter.
+type GPIO struct {
+	file *os.File
+	// monotonic is set on kernels before 5.11, which only timestamp edges
+	// with CLOCK_MONOTONIC
+	monotonic bool
+}
+
+// OpenGPIO requests line of chip as an input with rising edge events
+func OpenGPIO(chip string, line int) (*GPIO, error) {
+	f, err := os.Open(chip)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", chip, err)
+	}
+	defer f.Close()
+
+	g := &GPIO{}
+	req := gpioLineRequest{numLines: 1, flags: gpioFlagInput | gpioFlagRising | gpioFlagRealtime}
+	req.offsets[0] = uint32(line)
+	copy(req.consumer[:], "gogpsdo")
+	err = gpioIoctl(f, &req)
+	if errors.Is(err, unix.EINVAL) {
+		req.flags &^= gpioFlagRealtime
+		g.monotonic = true
+		err = gpioIoctl(f, &req)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("GPIO_V2_GET_LINE %s line %d: %w", chip, line, err)
+	}
+
+	// Non-blocking so Fetch can time out through the runtime poller
+	if err := unix.SetNonblock(int(req.fd), true); err != nil {
+		unix.Close(int(req.fd))
+		return nil, fmt.Errorf("%s line %d: %w", chip, line, err)
+	}
+	g.file = os.NewFile(uintptr(req.fd), fmt.Sprintf("%s:%d", chip, line))
+	return g, nil
+}
+
+// gpioIoctl issues GPIO_V2_GET_LINE_IOCTL on the chip
+func gpioIoctl(f *os.File, req *gpioLineRequest) error {
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, f.Fd(), uintptr(gpioGetLineIoctl), uintptr(unsafe.Pointer(req)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Fetch waits up to timeout for the next rising edge. It returns
+// os.ErrDeadlineExceeded if no edge arrived in time.
+func (g *GPIO) Fetch(timeout time.Duration) (Edge, error) {
+	if err := g.file.SetReadDeadline(time.Now().Add(timeout)); err != nil {
+		return Edge{}, err
+	}
+
+	var event [gpioEventLen]byte
+	for {
+		if _, err := g.file.Read(event[:]); err != nil {
+			return Edge{}, err
+		}
+		// struct gpio_v2_line_event: timestamp_ns, id, offset, seqno
+		if binary.NativeEndian.Uint32(event[8:]) != gpioEventRising {
+			continue
+		}
+		ns := int64(binary.NativeEndian.Uint64(event[0:]))
+		edge := Edge{Time: time.Unix(0, ns), Sequence: binary.NativeEndian.Uint32(event[16:])}
+		if g.monotonic {
+			edge.Time = fromMonotonic(ns)
+		}
+		return edge, nil
+	}
+}
+
+// fromMonotonic converts a CLOCK_MONOTONIC timestamp to system time
+func fromMonotonic(ns int64) time.Time {
+	var mono unix.Timespec
+	unix.ClockGettime(unix.CLOCK_MONOTONIC, &mono)
+	now := time.Now()
+	return now.Add(-time.Duration(mono.Nano() - ns))
+}
+
+// Close releases the line
+func (g *GPIO) Close() error {
+	return g.file.Close()
+}
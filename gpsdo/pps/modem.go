@@ -0,0 +1,198 @@
+package pps
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// pollInterval is how often the line is read on serial drivers without
+// TIOCMIWAIT
+const pollInterval = time.Millisecond
+
+// Line is a modem status line of a serial port that can carry the PPS
+type Line int
+
+const (
+	DCD Line = unix.TIOCM_CD
+	CTS Line = unix.TIOCM_CTS
+	DSR Line = unix.TIOCM_DSR
+)
+
+func (l Line) String() string {
+	switch l {
+	case DCD:
+		return "dcd"
+	case CTS:
+		return "cts"
+	case DSR:
+		return "dsr"
+	default:
+		return fmt.Sprintf("line(%#x)", int(l))
+	}
+}
+
+// ParseLine returns the line with the given name, dcd, cts or dsr
+func ParseLine(name string) (Line, error) {
+	for _, l := range []Line{DCD, CTS, DSR} {
+		if strings.EqualFold(name, l.String()) {
+			return l, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown modem line %q (dcd, cts, dsr)", name)
+}
+
+// ModemLine timestamps the assert edges of a modem status line of a serial
+// port in user space, for receivers such as the Garmin GPS 18x LVC that put
+// their PPS on DCD. The timestamps carry the wakeup latency of the bridge,
+// tens of microseconds, attaching the kernel PPS line discipline (ldattach
+// PPS) and using the resulting kernel PPS device is more accurate. Serial
+// drivers without TIOCMIWAIT, such as some USB adapters, are polled every
+// millisecond instead.
+type ModemLine struct {
+	file  *os.File
+	line  Line
+	edges chan Edge
+	// err is why the edge goroutine stopped, set before edges is closed
+	err error
+}
+
+// OpenModemLine opens port, separately from the TOD input if it is the same
+// port, and starts waiting for edges of line
+func OpenModemLine(port string, line Line) (*ModemLine, error) {
+	// Non-blocking so the open doesn't wait for carrier
+	f, err := os.OpenFile(port, os.O_RDONLY|unix.O_NOCTTY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", port, err)
+	}
+	m := &ModemLine{file: f, line: line, edges: make(chan Edge, 1)}
+	if _, err := m.lines(); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("TIOCMGET %s: %w", port, err)
+	}
+	go m.run()
+	return m, nil
+}
+
+// run waits for line changes until the port fails or is closed. A wait in
+// progress when the port is closed only returns on the next change.
+func (m *ModemLine) run() {
+	defer close(m.edges)
+
+	var sequence uint32
+	for {
+		if err := m.control(func(fd int) error {
+			return unix.IoctlSetInt(fd, unix.TIOCMIWAIT, int(m.line))
+		}); err != nil {
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
+			if errors.Is(err, unix.ENOTTY) || errors.Is(err, unix.EINVAL) {
+				m.poll(sequence)
+				return
+			}
+			m.err = fmt.Errorf("TIOCMIWAIT: %w", err)
+			return
+		}
+		now := time.Now()
+
+		lines, err := m.lines()
+		if err != nil {
+			m.err = fmt.Errorf("TIOCMGET: %w", err)
+			return
+		}
+		if lines&int(m.line) == 0 {
+			// Clear edge
+			continue
+		}
+
+		sequence++
+		m.send(Edge{Time: now, Sequence: sequence})
+	}
+}
+
+// poll reads the line every pollInterval until the port fails or is closed,
+// timestamping each assert edge halfway between the reads around it
+func (m *ModemLine) poll(sequence uint32) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	asserted := true
+	last := time.Now()
+	for range ticker.C {
+		lines, err := m.lines()
+		now := time.Now()
+		if err != nil {
+			m.err = fmt.Errorf("TIOCMGET: %w", err)
+			return
+		}
+
+		if lines&int(m.line) == 0 {
+			asserted = false
+		} else if !asserted {
+			asserted = true
+			sequence++
+			m.send(Edge{Time: last.Add(now.Sub(last) / 2), Sequence: sequence})
+		}
+		last = now
+	}
+}
+
+// send passes an edge to Fetch
+func (m *ModemLine) send(edge Edge) {
+	select {
+	case m.edges <- edge:
+	default:
+		// Nobody is fetching, drop the edge
+	}
+}
+
+// Fetch waits up to timeout for the next assert edge. It returns
+// os.ErrDeadlineExceeded if no edge arrived in time.
+func (m *ModemLine) Fetch(timeout time.Duration) (Edge, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case edge, ok := <-m.edges:
+		if !ok {
+			return Edge{}, m.err
+		}
+		return edge, nil
+	case <-timer.C:
+		return Edge{}, os.ErrDeadlineExceeded
+	}
+}
+
+// Close closes the port
+func (m *ModemLine) Close() error {
+	return m.file.Close()
+}
+
+func (m *ModemLine) lines() (int, error) {
+	var lines int
+	err := m.control(func(fd int) error {
+		var err error
+		lines, err = unix.IoctlGetInt(fd, unix.TIOCMGET)
+		return err
+	})
+	return lines, err
+}
+
+// control runs fn on the file descriptor, which stays open until fn returns
+// even if Close is called meanwhile
+func (m *ModemLine) control(fn func(fd int) error) error {
+	conn, err := m.file.SyscallConn()
+	if err != nil {
+		return err
+	}
+	var fnErr error
+	if err := conn.Control(func(fd uintptr) { fnErr = fn(int(fd)) }); err != nil {
+		return err
+	}
+	return fnErr
+}
@@ -0,0 +1,130 @@
+// Package pps reads assert timestamps from a Linux kernel PPS device using
+// the RFC 2783 ioctl interface, from a modem status line (DCD, CTS or DSR)
+// of a serial port, or from a GPIO line.
+package pps
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// RFC 2783 mode bits from linux/pps.h
+const (
+	captureAssert = 0x01
+	tsfmtTspec    = 0x1000
+	apiVersion    = 1
+)
+
+// ppsKCBind is PPS_KC_BIND, which like PPS_SETPARAMS is sized by a pointer
+const ppsKCBind = unix.PPS_SETPARAMS - 0xa2 + 0xa5
+
+// kcHardPPS is PPS_KC_HARDPPS, the kernel NTP discipline consumer
+const kcHardPPS = 0
+
+// bindArgs mirrors struct pps_bind_args
+type bindArgs struct {
+	tsformat int32
+	edge     int32
+	consumer int32
+}
+
+// Edge is a captured PPS assert event
+type Edge struct {
+	// Time is the system time of the edge
+	Time     time.Time
+	Sequence uint32
+}
+
+// Source is an open kernel PPS device such as /dev/pps0
+type Source struct {
+	file *os.File
+}
+
+// Open opens a PPS device and enables assert capture
+func Open(device string) (*Source, error) {
+	f, err := os.OpenFile(device, os.O_RDWR, 0)
+	if err != nil {
+		return nil, fmt.Errorf("open %s: %w", device, err)
+	}
+	s := &Source{file: f}
+
+	var caps int32
+	if err := s.ioctl(unix.PPS_GETCAP, unsafe.Pointer(&caps)); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("PPS_GETCAP %s: %w", device, err)
+	}
+	if caps&captureAssert == 0 {
+		f.Close()
+		return nil, fmt.Errorf("%s cannot capture assert events", device)
+	}
+
+	var params unix.PPSKParams
+	if err := s.ioctl(unix.PPS_GETPARAMS, unsafe.Pointer(&params)); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("PPS_GETPARAMS %s: %w", device, err)
+	}
+	params.Api_version = apiVersion
+	params.Mode |= captureAssert | tsfmtTspec
+	if err := s.ioctl(unix.PPS_SETPARAMS, unsafe.Pointer(&params)); err != nil {
+		// pps-gpio and friends are often fixed to assert capture, which
+		// is all we need, so only root can change params
+		if !errors.Is(err, unix.EPERM) {
+			f.Close()
+			return nil, fmt.Errorf("PPS_SETPARAMS %s: %w", device, err)
+		}
+	}
+
+	return s, nil
+}
+
+// Fetch waits up to timeout for the next assert edge. It returns
+// os.ErrDeadlineExceeded if no edge arrived in time.
+func (s *Source) Fetch(timeout time.Duration) (Edge, error) {
+	var data unix.PPSFData
+	data.Timeout.Sec = int64(timeout / time.Second)
+	data.Timeout.Nsec = int32(timeout % time.Second)
+
+	if err := s.ioctl(unix.PPS_FETCH, unsafe.Pointer(&data)); err != nil {
+		if errors.Is(err, unix.ETIMEDOUT) {
+			return Edge{}, os.ErrDeadlineExceeded
+		}
+		return Edge{}, fmt.Errorf("PPS_FETCH: %w", err)
+	}
+
+	return Edge{
+		Time:     time.Unix(data.Info.Assert_tu.Sec, int64(data.Info.Assert_tu.Nsec)),
+		Sequence: data.Info.Assert_sequence,
+	}, nil
+}
+
+// BindKernel passes the assert edges of the device to the kernel NTP
+// discipline (hardpps), or stops passing them when bind is false. Only one
+// device can be bound, and the kernel needs CONFIG_NTP_PPS.
+func (s *Source) BindKernel(bind bool) error {
+	args := bindArgs{tsformat: tsfmtTspec, consumer: kcHardPPS}
+	if bind {
+		args.edge = captureAssert
+	}
+	if err := s.ioctl(ppsKCBind, unsafe.Pointer(&args)); err != nil {
+		return fmt.Errorf("PPS_KC_BIND: %w", err)
+	}
+	return nil
+}
+
+// Close closes the device
+func (s *Source) Close() error {
+	return s.file.Close()
+}
+
+func (s *Source) ioctl(req uint, arg unsafe.Pointer) error {
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, s.file.Fd(), uintptr(req), uintptr(arg))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package protocol
+
+import (
+	"bufio"
+	"errors"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/generic"
+)
+
+func init() {
+	Register(Protocol{
+		Name:    "generic",
+		Summary: "Fixed-width packets described by the generic config section",
+		New: func(opts Options) Parser {
+			p := &genericParser{base: base{"generic", serial8N1}, layout: opts.Generic}
+			p.decoder = generic.NewDecoder(&p.layout)
+			return p
+		},
+		Validate: func(opts Options) error {
+			return opts.Generic.Validate()
+		},
+	})
+}
+
+type genericParser struct {
+	base
+	layout  generic.Layout
+	decoder *generic.Decoder
+}
+
+func (p *genericParser) Parse(stream *bufio.Reader) (*gpsdo.Sample, error) {
+	for {
+		c, err := stream.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		packet, err := p.decoder.Feed(c)
+		if errors.Is(err, generic.ErrFraming) {
+			return nil, framing(err)
+		}
+		if packet != nil {
+			sample, err := p.layout.Parse(packet)
+			if errors.Is(err, generic.ErrRange) {
+				return nil, outOfRange(err)
+			}
+			return sample, err
+		}
+	}
+}
@@ -0,0 +1,44 @@
+package protocol
+
+import (
+	"bufio"
+	"errors"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/meinberg"
+)
+
+func init() {
+	Register(Protocol{
+		Name:    "meinberg",
+		Summary: "Meinberg Standard Time String",
+		New: func(Options) Parser {
+			return &meinbergParser{base: base{"meinberg", serial8N1}}
+		},
+	})
+}
+
+type meinbergParser struct {
+	base
+	decoder meinberg.Decoder
+}
+
+func (p *meinbergParser) Parse(stream *bufio.Reader) (*gpsdo.Sample, error) {
+	for {
+		c, err := stream.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		telegram, err := p.decoder.Feed(c)
+		if errors.Is(err, meinberg.ErrFraming) {
+			return nil, framing(err)
+		}
+		if telegram != nil {
+			sample, err := meinberg.Parse(telegram)
+			if errors.Is(err, meinberg.ErrRange) {
+				return nil, outOfRange(err)
+			}
+			return sample, err
+		}
+	}
+}
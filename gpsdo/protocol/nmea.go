@@ -0,0 +1,44 @@
+package protocol
+
+import (
+	"bufio"
+	"errors"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/nmea"
+)
+
+func init() {
+	Register(Protocol{
+		Name:    "nmea",
+		Summary: "NMEA 0183 ZDA and RMC, with Trimble and Jackson Labs disciplining sentences",
+		New: func(Options) Parser {
+			return &nmeaParser{base: base{"nmea", serial8N1}}
+		},
+	})
+}
+
+type nmeaParser struct {
+	base
+	parser nmea.Parser
+}
+
+// Parse decodes one sentence, sentences without time return a nil sample
+func (p *nmeaParser) Parse(stream *bufio.Reader) (*gpsdo.Sample, error) {
+	line, err := readLine(stream)
+	if err != nil {
+		return nil, err
+	}
+	sample, err := p.parser.Parse(line)
+	switch {
+	case errors.Is(err, nmea.ErrChecksum):
+		return nil, checksum(err)
+	case errors.Is(err, nmea.ErrRange):
+		return nil, outOfRange(err)
+	}
+	return sample, err
+}
+
+func (p *nmeaParser) Diagnostics() (gpsdo.Diagnostics, bool) {
+	return p.parser.Diagnostics()
+}
@@ -0,0 +1,49 @@
+package protocol
+
+import (
+	"bufio"
+	"errors"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/oncore"
+)
+
+func init() {
+	Register(Protocol{
+		Name:    "oncore",
+		Summary: "Motorola Oncore binary messages",
+		New: func(Options) Parser {
+			return &oncoreParser{base: base{"oncore", serial8N1}}
+		},
+	})
+}
+
+type oncoreParser struct {
+	base
+	decoder oncore.Decoder
+	parser  oncore.Parser
+}
+
+func (p *oncoreParser) Parse(stream *bufio.Reader) (*gpsdo.Sample, error) {
+	for {
+		c, err := stream.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		// Checksum errors are corrupted messages rather than lost framing
+		msg, err := p.decoder.Feed(c)
+		if errors.Is(err, oncore.ErrChecksum) {
+			return nil, checksum(err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if msg != nil {
+			sample, err := p.parser.Parse(msg)
+			if errors.Is(err, oncore.ErrRange) {
+				return nil, outOfRange(err)
+			}
+			return sample, err
+		}
+	}
+}
@@ -0,0 +1,273 @@
+// Package protocol is the registry of the receiver protocols the bridge can
+// read. Each protocol is a Parser registered under its name, the built-in
+// ones by the files of this package. A new protocol can be added as another
+// file here, or as its own package that calls Register from init and is
+// imported by the command.
+package protocol
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/generic"
+	"github.com/karlcswanson/gogpsdo/gpsdo/z3805a"
+)
+
+// maxLineLen bounds the lines of the text protocols, longer lines are
+// discarded
+const maxLineLen = 256
+
+// Default is the protocol of the Z3805A, the receiver the bridge was
+// written for
+const Default = "z3805a"
+
+var (
+	// ErrFraming wraps the errors of a parser that lost the packet
+	// boundaries, such as when the bridge starts mid-packet
+	ErrFraming = errors.New("framing error")
+	// ErrChecksum wraps the errors of packets whose checksum doesn't match,
+	// corrupted on the way
+	ErrChecksum = errors.New("checksum error")
+	// ErrRange wraps the errors of packets with an implausible field, such
+	// as an hour of 25
+	ErrRange = errors.New("range error")
+)
+
+// Parser decodes the raw input of a receiver
+type Parser interface {
+	// Name is the protocol name, as selected with -protocol
+	Name() string
+	// SerialDefaults are the port settings of the receiver
+	SerialDefaults() SerialDefaults
+	// Parse reads the next packet from stream. It returns the sample of the
+	// packet, or a nil sample for packets that carry none. Errors wrapping
+	// ErrFraming mean the packet boundaries were lost, ErrChecksum and
+	// ErrRange a corrupted or implausible packet, other errors a malformed
+	// packet, and errors of the stream are returned as they are.
+	Parse(stream *bufio.Reader) (*gpsdo.Sample, error)
+}
+
+// DiagnosticsParser is implemented by parsers that decode receiver
+// diagnostics along with the time
+type DiagnosticsParser interface {
+	Parser
+	// Diagnostics returns the diagnostics and whether they changed since
+	// the last call
+	Diagnostics() (gpsdo.Diagnostics, bool)
+}
+
+// Surveyor is implemented by parsers of timing receivers whose position
+// survey is controlled over the port they send the time on
+type Surveyor interface {
+	Parser
+	// SurveyCommand starts a new survey of the antenna position
+	SurveyCommand() []byte
+	// PositionCommand sets the antenna position the receiver holds
+	PositionCommand(p gpsdo.Position) []byte
+}
+
+// Identifier is implemented by parsers of receivers that report their
+// identity when asked, the query is written to the port whenever it opens
+type Identifier interface {
+	Parser
+	IdentityQuery() []byte
+	// Identity returns the identity and whether more of it was decoded
+	// since the last call
+	Identity() (gpsdo.Identity, bool)
+}
+
+// Poller is implemented by parsers of receivers that only send the time
+// when asked, the query is written to the port once a second
+type Poller interface {
+	Parser
+	Query() []byte
+}
+
+// SerialDefaults are the serial port settings of a protocol
+type SerialDefaults struct {
+	Baud     int
+	DataBits int
+	// Parity is 'N', 'O' or 'E'
+	Parity   byte
+	StopBits int
+}
+
+func (s SerialDefaults) String() string {
+	return fmt.Sprintf("%d %d%c%d", s.Baud, s.DataBits, s.Parity, s.StopBits)
+}
+
+// Override returns s with the non-zero settings of o
+func (s SerialDefaults) Override(o SerialDefaults) SerialDefaults {
+	if o.Baud != 0 {
+		s.Baud = o.Baud
+	}
+	if o.DataBits != 0 {
+		s.DataBits = o.DataBits
+	}
+	if o.Parity != 0 {
+		s.Parity = o.Parity
+	}
+	if o.StopBits != 0 {
+		s.StopBits = o.StopBits
+	}
+	return s
+}
+
+// ParseParity parses none, odd or even, or their first letter, ignoring
+// case
+func ParseParity(name string) (byte, error) {
+	for _, parity := range []string{"none", "odd", "even"} {
+		if strings.EqualFold(name, parity) || strings.EqualFold(name, parity[:1]) {
+			return strings.ToUpper(parity)[0], nil
+		}
+	}
+	return 0, fmt.Errorf("unknown parity %q, expected none, odd or even", name)
+}
+
+// serial8N1 are the settings of most receivers
+var serial8N1 = SerialDefaults{Baud: 9600, DataBits: 8, Parity: 'N', StopBits: 1}
+
+// Options configure the parsers that need more than a name
+type Options struct {
+	// Generic is the packet layout of the generic protocol
+	Generic generic.Layout
+	// Z3805A is the packet framing of the z3805a protocol, zero for the
+	// standard packet
+	Z3805A z3805a.Format
+}
+
+// Protocol is a registered protocol
+type Protocol struct {
+	Name string
+	// Summary describes the receivers of the protocol in a few words
+	Summary string
+	// New returns a parser for one input
+	New func(opts Options) Parser
+	// Validate checks the options of the protocol before New, nil if it
+	// takes none
+	Validate func(opts Options) error
+	// Models are the receiver models the protocol is meant for, any of which
+	// the reported model contains. Empty accepts any receiver.
+	Models []string
+}
+
+// Matches reports whether id is a receiver the protocol is meant for, true
+// if the model is unknown
+func (p Protocol) Matches(id gpsdo.Identity) bool {
+	if len(p.Models) == 0 || id.Model == "" {
+		return true
+	}
+	model := strings.ToUpper(id.Model)
+	return slices.ContainsFunc(p.Models, func(m string) bool {
+		return strings.Contains(model, strings.ToUpper(m))
+	})
+}
+
+var (
+	mutex     sync.RWMutex
+	protocols = make(map[string]Protocol)
+)
+
+// Register makes a protocol available. It panics if the name is taken.
+func Register(p Protocol) {
+	mutex.Lock()
+	defer mutex.Unlock()
+	if _, ok := protocols[p.Name]; ok {
+		panic("protocol: duplicate registration of " + p.Name)
+	}
+	protocols[p.Name] = p
+}
+
+// Lookup returns the protocol registered under name
+func Lookup(name string) (Protocol, bool) {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	p, ok := protocols[name]
+	return p, ok
+}
+
+// Protocols returns the registered protocols sorted by name
+func Protocols() []Protocol {
+	mutex.RLock()
+	defer mutex.RUnlock()
+	list := make([]Protocol, 0, len(protocols))
+	for _, p := range protocols {
+		list = append(list, p)
+	}
+	slices.SortFunc(list, func(a, b Protocol) int { return strings.Compare(a.Name, b.Name) })
+	return list
+}
+
+// New returns a parser of the protocol registered under name, or an error
+// if the options can't be decoded with
+func New(name string, opts Options) (Parser, error) {
+	p, ok := Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown protocol %q", name)
+	}
+	if p.Validate != nil {
+		if err := p.Validate(opts); err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+	}
+	return p.New(opts), nil
+}
+
+// base implements the Name and SerialDefaults of a parser
+type base struct {
+	name   string
+	serial SerialDefaults
+}
+
+func (b base) Name() string {
+	return b.name
+}
+
+func (b base) SerialDefaults() SerialDefaults {
+	return b.serial
+}
+
+// framing wraps a decoder error in ErrFraming
+func framing(err error) error {
+	return fmt.Errorf("%w: %w", ErrFraming, err)
+}
+
+// checksum wraps a decoder error in ErrChecksum
+func checksum(err error) error {
+	return fmt.Errorf("%w: %w", ErrChecksum, err)
+}
+
+// outOfRange wraps a parser error in ErrRange
+func outOfRange(err error) error {
+	return fmt.Errorf("%w: %w", ErrRange, err)
+}
+
+// readLine returns the next line of stream including its terminating
+// newline. Lines longer than maxLineLen are discarded.
+func readLine(stream *bufio.Reader) (string, error) {
+	var line []byte
+	overlong := false
+	for {
+		chunk, err := stream.ReadSlice('\n')
+		if len(line)+len(chunk) > maxLineLen {
+			overlong = true
+		} else {
+			line = append(line, chunk...)
+		}
+		switch {
+		case errors.Is(err, bufio.ErrBufferFull):
+			continue
+		case err != nil:
+			return "", err
+		case overlong:
+			line, overlong = line[:0], false
+			continue
+		}
+		return string(line), nil
+	}
+}
@@ -0,0 +1,41 @@
+package protocol
+
+import (
+	"bufio"
+	"io"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// Reader decodes the packets of a protocol from any byte stream, such as a
+// serial port, a capture or a file of recorded input, regardless of how the
+// reads of the stream are chunked
+type Reader struct {
+	parser Parser
+	stream *bufio.Reader
+}
+
+// NewReader returns a Reader decoding r with parser
+func NewReader(r io.Reader, parser Parser) *Reader {
+	return &Reader{parser: parser, stream: bufio.NewReader(r)}
+}
+
+// Next decodes the next packet. It returns the sample of the packet, or a
+// nil sample for packets that carry none. Errors wrapping ErrFraming mean
+// the packet boundaries were lost, ErrChecksum and ErrRange a corrupted or
+// implausible packet and other errors of the parser a malformed packet, all
+// of which the next call recovers from. Errors of the stream, io.EOF at its
+// end, are returned as they are.
+func (r *Reader) Next() (*gpsdo.Sample, error) {
+	return r.parser.Parse(r.stream)
+}
+
+// Buffered is the number of bytes read from the stream but not yet decoded
+func (r *Reader) Buffered() int {
+	return r.stream.Buffered()
+}
+
+// Parser returns the parser of the Reader
+func (r *Reader) Parser() Parser {
+	return r.parser
+}
@@ -0,0 +1,139 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/generic"
+	"github.com/karlcswanson/gogpsdo/gpsdo/z3805a"
+)
+
+// fuzzLayout is the Z3805A packet described for the generic protocol, as in
+// the README
+var fuzzLayout = generic.Layout{
+	Length:     16,
+	Terminator: 0x0D,
+	Encoding:   generic.EncodingBCD,
+	Fields: generic.Fields{
+		Year:        generic.Field{Offset: 0, Width: 2},
+		DayOfYear:   generic.Field{Offset: 2, Width: 3},
+		Hour:        generic.Field{Offset: 5, Width: 2},
+		Minute:      generic.Field{Offset: 7, Width: 2},
+		Second:      generic.Field{Offset: 9, Width: 2},
+		LeapSeconds: generic.Field{Offset: 11, Width: 2},
+		Status:      generic.Field{Offset: 13, Width: 2},
+	},
+	Status: generic.StatusValues{
+		Locked:   []string{"0000"},
+		Holdover: []string{"1000"},
+		PowerUp:  []string{"0100"},
+	},
+}
+
+// decodeAll decodes input with a new parser of p, returning one line per
+// packet. It fails the test if a packet consumes no input.
+func decodeAll(t *testing.T, p Protocol, r io.Reader, size int) []string {
+	reader := NewReader(r, p.New(Options{Generic: fuzzLayout}))
+	var decoded []string
+	for read := 0; ; read++ {
+		data, err := reader.Next()
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return decoded
+		}
+		// Every packet is at least one byte of the input
+		if read >= size {
+			t.Fatalf("%s: more than %d packets decoded from %d bytes", p.Name, read, size)
+		}
+
+		switch {
+		case err != nil:
+			decoded = append(decoded, "error: "+err.Error())
+		case data == nil:
+			decoded = append(decoded, "no sample")
+		default:
+			if data.Timestamp.IsZero() {
+				t.Fatalf("%s: sample without a timestamp: %+v", p.Name, data)
+			}
+			decoded = append(decoded, describe(data))
+		}
+	}
+}
+
+// describe formats the fields of a sample decoded from the input
+func describe(data *gpsdo.Sample) string {
+	return fmt.Sprintf("%s %s valid=%t leap=%d/%s", data.Timestamp.Format(time.RFC3339Nano),
+		data.Status, data.Valid, data.LeapSeconds, data.Leap)
+}
+
+// sentence frames an NMEA sentence with its checksum
+func sentence(body string) string {
+	var sum byte
+	for i := range len(body) {
+		sum ^= body[i]
+	}
+	return fmt.Sprintf("$%s*%02X\r\n", body, sum)
+}
+
+// FuzzReader feeds arbitrary input, such as line noise on the serial port,
+// to every protocol. Decoding must not panic, must make progress and must
+// not depend on how the reads of the stream are chunked.
+func FuzzReader(f *testing.F) {
+	at := time.Date(2025, 9, 7, 0, 43, 18, 0, time.UTC)
+	packet := z3805a.Encode(at, 18, gpsdo.Locked)
+	f.Add(packet)
+	f.Add(append(packet[5:], z3805a.Encode(at.Add(time.Second), 18, gpsdo.Holdover)...))
+	f.Add([]byte(sentence("GPZDA,004318.00,07,09,2025,00,00") + sentence("GPRMC,004319.00,A,4807.038,N,01131.000,E,0.0,0.0,070925,,,A")))
+	f.Add([]byte("\x02D:07.09.25;T:7;U:02.43.18;    \x03"))
+	f.Add([]byte("\r\n   250 00:43:18  TZ=00\r\n\r\n  25 250 00:43:19.000  S\r\n"))
+	f.Add([]byte("\x01250:00:43:18 \r\n"))
+	f.Add([]byte(":PTIME:TCODE?\r\nT2202509070043183000054\r\nscpi > "))
+	f.Add([]byte("\x10\x8f\xab\x00\x01\x10\x10\x03\x10\x03"))
+	f.Add([]byte("\xb5\x62\x01\x21\x14\x00"))
+	f.Add([]byte("@@Ha\x00\x00\r\n@@Hn"))
+
+	f.Fuzz(func(t *testing.T, input []byte) {
+		for _, p := range Protocols() {
+			whole := decodeAll(t, p, bytes.NewReader(input), len(input))
+			chunked := decodeAll(t, p, iotest.OneByteReader(bytes.NewReader(input)), len(input))
+			if len(whole) != len(chunked) {
+				t.Fatalf("%s: %d packets read at once, %d byte by byte", p.Name, len(whole), len(chunked))
+			}
+			for i := range whole {
+				if whole[i] != chunked[i] {
+					t.Fatalf("%s: packet %d is %q read at once, %q byte by byte", p.Name, i, whole[i], chunked[i])
+				}
+			}
+		}
+	})
+}
+
+// TestNewInvalidOptions checks that options the decoders can't work with are
+// rejected by New rather than panicking on the first read
+func TestNewInvalidOptions(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		opts Options
+	}{
+		{"generic", Options{}},
+		{"generic", Options{Generic: generic.Layout{Length: -1, Encoding: generic.EncodingBCD, Fields: fuzzLayout.Fields}}},
+		{Default, Options{Z3805A: z3805a.Format{Length: -1}}},
+		{Default, Options{Z3805A: z3805a.Format{Length: 8}}},
+		{Default, Options{Z3805A: z3805a.Format{Terminator: 0x05}}},
+	} {
+		if _, err := New(tt.name, tt.opts); err == nil {
+			t.Errorf("New(%q, %+v) accepted invalid options", tt.name, tt.opts)
+		}
+	}
+
+	for _, p := range Protocols() {
+		if _, err := New(p.Name, Options{Generic: fuzzLayout}); err != nil {
+			t.Errorf("New(%q) = %v with valid options", p.Name, err)
+		}
+	}
+}
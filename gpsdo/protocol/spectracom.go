@@ -0,0 +1,41 @@
+package protocol
+
+import (
+	"bufio"
+	"errors"
+	"strings"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/spectracom"
+)
+
+func init() {
+	Register(Protocol{
+		Name:    "spectracom",
+		Summary: "Spectracom ASCII time code format 0 or 2",
+		New: func(Options) Parser {
+			return &spectracomParser{base{"spectracom", serial8N1}}
+		},
+	})
+}
+
+type spectracomParser struct {
+	base
+}
+
+func (p *spectracomParser) Parse(stream *bufio.Reader) (*gpsdo.Sample, error) {
+	for {
+		line, err := readLine(stream)
+		if err != nil {
+			return nil, err
+		}
+		// Each time code is framed by an empty line
+		if strings.Trim(line, "\r\n") != "" {
+			sample, err := spectracom.Parse(line)
+			if errors.Is(err, spectracom.ErrRange) {
+				return nil, outOfRange(err)
+			}
+			return sample, err
+		}
+	}
+}
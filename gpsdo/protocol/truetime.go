@@ -0,0 +1,35 @@
+package protocol
+
+import (
+	"bufio"
+	"strings"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/truetime"
+)
+
+func init() {
+	Register(Protocol{
+		Name:    "truetime",
+		Summary: "TrueTime and Datum TymServe ASCII time code",
+		New: func(Options) Parser {
+			return &trueTimeParser{base{"truetime", serial8N1}}
+		},
+	})
+}
+
+type trueTimeParser struct {
+	base
+}
+
+func (p *trueTimeParser) Parse(stream *bufio.Reader) (*gpsdo.Sample, error) {
+	for {
+		line, err := readLine(stream)
+		if err != nil {
+			return nil, err
+		}
+		if strings.Trim(line, "\r\n") != "" {
+			return truetime.Parse(line)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package protocol
+
+import (
+	"bufio"
+	"errors"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/tsip"
+)
+
+func init() {
+	Register(Protocol{
+		Name:    "tsip",
+		Summary: "Trimble Thunderbolt TSIP timing packets",
+		New: func(Options) Parser {
+			return &tsipParser{base: base{"tsip", serial8N1}}
+		},
+		Models: []string{"Thunderbolt", "Resolution"},
+	})
+}
+
+type tsipParser struct {
+	base
+	decoder tsip.Decoder
+	parser  tsip.Parser
+}
+
+func (p *tsipParser) Parse(stream *bufio.Reader) (*gpsdo.Sample, error) {
+	for {
+		c, err := stream.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		packet, err := p.decoder.Feed(c)
+		if errors.Is(err, tsip.ErrFraming) {
+			return nil, framing(err)
+		}
+		if packet != nil {
+			sample, err := p.parser.Parse(packet)
+			if errors.Is(err, tsip.ErrRange) {
+				return nil, outOfRange(err)
+			}
+			return sample, err
+		}
+	}
+}
+
+func (p *tsipParser) Diagnostics() (gpsdo.Diagnostics, bool) {
+	return p.parser.Diagnostics()
+}
+
+func (p *tsipParser) SurveyCommand() []byte {
+	return tsip.SurveyCommand()
+}
+
+func (p *tsipParser) PositionCommand(position gpsdo.Position) []byte {
+	return tsip.PositionCommand(position)
+}
+
+func (p *tsipParser) IdentityQuery() []byte {
+	return tsip.IdentityQuery()
+}
+
+func (p *tsipParser) Identity() (gpsdo.Identity, bool) {
+	return p.parser.Identity()
+}
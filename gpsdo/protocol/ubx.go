@@ -0,0 +1,71 @@
+package protocol
+
+import (
+	"bufio"
+	"errors"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/ubx"
+)
+
+func init() {
+	Register(Protocol{
+		Name:    "ubx",
+		Summary: "u-blox UBX timing messages",
+		New: func(Options) Parser {
+			return &ubxParser{base: base{"ubx", serial8N1}}
+		},
+		// The timing modules
+		Models: []string{"6T", "M8T", "F9T", "F10T"},
+	})
+}
+
+type ubxParser struct {
+	base
+	decoder ubx.Decoder
+	parser  ubx.Parser
+}
+
+func (p *ubxParser) Parse(stream *bufio.Reader) (*gpsdo.Sample, error) {
+	for {
+		c, err := stream.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		// Checksum errors are corrupted messages rather than lost framing
+		msg, err := p.decoder.Feed(c)
+		if errors.Is(err, ubx.ErrChecksum) {
+			return nil, checksum(err)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if msg != nil {
+			sample, err := p.parser.Parse(msg)
+			if errors.Is(err, ubx.ErrRange) {
+				return nil, outOfRange(err)
+			}
+			return sample, err
+		}
+	}
+}
+
+func (p *ubxParser) Diagnostics() (gpsdo.Diagnostics, bool) {
+	return p.parser.Diagnostics()
+}
+
+func (p *ubxParser) SurveyCommand() []byte {
+	return ubx.SurveyCommand()
+}
+
+func (p *ubxParser) PositionCommand(position gpsdo.Position) []byte {
+	return ubx.PositionCommand(position)
+}
+
+func (p *ubxParser) IdentityQuery() []byte {
+	return ubx.IdentityQuery()
+}
+
+func (p *ubxParser) Identity() (gpsdo.Identity, bool) {
+	return p.parser.Identity()
+}
@@ -0,0 +1,72 @@
+package protocol
+
+import (
+	"bufio"
+	"strings"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/ks24361"
+	"github.com/karlcswanson/gogpsdo/gpsdo/scpi"
+	"github.com/karlcswanson/gogpsdo/gpsdo/z3801a"
+)
+
+func init() {
+	Register(Protocol{
+		Name:    "z3801a",
+		Summary: "HP Z3801A and 58503A time code, polled over the SCPI port",
+		New: func(Options) Parser {
+			serial := SerialDefaults{Baud: 19200, DataBits: 7, Parity: 'O', StopBits: 1}
+			return &timeCodeParser{base: base{"z3801a", serial}, isTimeCode: z3801a.IsTimeCode, parse: z3801a.Parse}
+		},
+		Models: []string{"Z3801A", "58503"},
+	})
+	Register(Protocol{
+		Name:    "ks24361",
+		Summary: "Lucent KS-24361 REF-0 and REF-1 time code, polled over the console port",
+		New: func(Options) Parser {
+			return &timeCodeParser{base: base{"ks24361", serial8N1}, isTimeCode: ks24361.IsTimeCode, parse: ks24361.Parse}
+		},
+	})
+}
+
+// timeCodeParser decodes the SCPI time code responses of the Z3801A family
+type timeCodeParser struct {
+	base
+	isTimeCode func(line string) bool
+	parse      func(line string) (*gpsdo.Sample, error)
+	identity   gpsdo.Identity
+	identified bool
+}
+
+func (p *timeCodeParser) Parse(stream *bufio.Reader) (*gpsdo.Sample, error) {
+	for {
+		line, err := readLine(stream)
+		if err != nil {
+			return nil, err
+		}
+		// The SCPI shell echoes the query and prompts before each response
+		if p.isTimeCode(line) {
+			return p.parse(line)
+		}
+		// The response to the identity query has four fields
+		if strings.Count(line, ",") == 3 {
+			if id, err := scpi.ParseIdentity(strings.TrimSpace(line)); err == nil {
+				p.identity, p.identified = id, true
+			}
+		}
+	}
+}
+
+func (p *timeCodeParser) Query() []byte {
+	return []byte(z3801a.QueryTimeCode + "\r\n")
+}
+
+func (p *timeCodeParser) IdentityQuery() []byte {
+	return []byte(scpi.QueryIdentity + "\r\n")
+}
+
+func (p *timeCodeParser) Identity() (gpsdo.Identity, bool) {
+	identified := p.identified
+	p.identified = false
+	return p.identity, identified
+}
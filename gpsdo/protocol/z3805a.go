@@ -0,0 +1,53 @@
+package protocol
+
+import (
+	"bufio"
+	"errors"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/z3805a"
+)
+
+func init() {
+	Register(Protocol{
+		Name:    Default,
+		Summary: "HP Z3805A time-of-day packets",
+		New: func(opts Options) Parser {
+			return &z3805aParser{
+				base:    base{Default, serial8N1},
+				format:  opts.Z3805A,
+				decoder: z3805a.NewDecoder(opts.Z3805A),
+			}
+		},
+		Validate: func(opts Options) error {
+			return opts.Z3805A.Validate()
+		},
+		Models: []string{"Z3805A"},
+	})
+}
+
+type z3805aParser struct {
+	base
+	format  z3805a.Format
+	decoder *z3805a.Decoder
+}
+
+func (p *z3805aParser) Parse(stream *bufio.Reader) (*gpsdo.Sample, error) {
+	for {
+		c, err := stream.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		packet, err := p.decoder.Feed(c)
+		if errors.Is(err, z3805a.ErrFraming) {
+			return nil, framing(err)
+		}
+		if packet != nil {
+			sample, err := p.format.Parse(packet)
+			if errors.Is(err, z3805a.ErrRange) {
+				return nil, outOfRange(err)
+			}
+			return sample, err
+		}
+	}
+}
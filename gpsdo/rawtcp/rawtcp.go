@@ -0,0 +1,127 @@
+// Package rawtcp rebroadcasts the raw serial input of a GPSDO to TCP
+// clients, like a read-only ser2net port, so other machines and analysis
+// tools can watch the receiver output while the bridge keeps using it.
+package rawtcp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"slices"
+	"sync"
+)
+
+// clientQueue is the number of input chunks buffered for a slow client
+const clientQueue = 64
+
+// Server is a raw TCP listener and the writer the bridge copies its input
+// to
+type Server struct {
+	listener net.Listener
+
+	mutex   sync.Mutex
+	clients map[*client]struct{}
+}
+
+type client struct {
+	conn  net.Conn
+	queue chan []byte
+	// dropped is set once the client fell behind and is being disconnected
+	dropped bool
+}
+
+// Listen opens the TCP listener
+func Listen(addr string) (*Server, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("raw TCP listen %s: %w", addr, err)
+	}
+	slog.Info("Raw TCP rebroadcast listening", "addr", listener.Addr())
+
+	return &Server{
+		listener: listener,
+		clients:  make(map[*client]struct{}),
+	}, nil
+}
+
+// Serve accepts clients until ctx is cancelled, then disconnects them
+func (s *Server) Serve(ctx context.Context) {
+	go func() {
+		<-ctx.Done()
+		s.listener.Close()
+		s.mutex.Lock()
+		for c := range s.clients {
+			c.conn.Close()
+		}
+		s.mutex.Unlock()
+	}()
+
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if ctx.Err() == nil {
+				slog.Error("Raw TCP accept failed", "error", err)
+			}
+			return
+		}
+		go s.serveClient(conn)
+	}
+}
+
+// Write copies p to every client. It never blocks: a client that falls
+// behind is disconnected rather than sent a stream with holes in it.
+func (s *Server) Write(p []byte) (int, error) {
+	chunk := slices.Clone(p)
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for c := range s.clients {
+		if c.dropped {
+			continue
+		}
+		select {
+		case c.queue <- chunk:
+		default:
+			slog.Warn("Raw TCP client too slow, disconnecting", "client", c.conn.RemoteAddr())
+			c.dropped = true
+			c.conn.Close()
+		}
+	}
+	return len(p), nil
+}
+
+func (s *Server) serveClient(conn net.Conn) {
+	c := &client{
+		conn:  conn,
+		queue: make(chan []byte, clientQueue),
+	}
+	slog.Debug("Raw TCP client connected", "client", conn.RemoteAddr())
+
+	s.mutex.Lock()
+	s.clients[c] = struct{}{}
+	s.mutex.Unlock()
+
+	defer func() {
+		s.mutex.Lock()
+		delete(s.clients, c)
+		close(c.queue)
+		s.mutex.Unlock()
+		conn.Close()
+		slog.Debug("Raw TCP client disconnected", "client", conn.RemoteAddr())
+	}()
+
+	go func() {
+		for chunk := range c.queue {
+			if _, err := conn.Write(chunk); err != nil {
+				conn.Close()
+				return
+			}
+		}
+	}()
+
+	// The port is read only, anything the client sends is discarded until
+	// it disconnects
+	io.Copy(io.Discard, conn)
+}
@@ -0,0 +1,521 @@
+// Package scpi queries receiver diagnostics over the interactive SCPI port of
+// the HP Z3805A and related SmartClock GPSDOs.
+package scpi
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/tarm/serial"
+)
+
+// Queries used to fill gpsdo.Diagnostics
+const (
+	QuerySatellites = ":GPS:SAT:TRAC:COUN?"
+	QueryEFC        = ":DIAG:ROSC:EFC:REL?"
+	QueryHoldover   = ":SYNC:HOLD:TUNC:PRED?"
+	QueryAntenna    = ":DIAG:ANT:STAT?"
+	// QueryStatus returns the status screen, whose satellite table has the
+	// signal strengths and whose position block the surveyed position
+	QueryStatus = ":SYST:STAT?"
+)
+
+// QueryIdentity returns the manufacturer, model, serial number and firmware
+// revision, such as HEWLETT-PACKARD,Z3805A,3542A01234,3805A-02-01
+const QueryIdentity = "*IDN?"
+
+// ParseIdentity parses the response to QueryIdentity
+func ParseIdentity(response string) (gpsdo.Identity, error) {
+	fields := strings.Split(response, ",")
+	if len(fields) != 4 {
+		return gpsdo.Identity{}, fmt.Errorf("identity %q is not four fields", response)
+	}
+	for i, field := range fields {
+		fields[i] = strings.Trim(strings.TrimSpace(field), `"`)
+	}
+	return gpsdo.Identity{Manufacturer: fields[0], Model: fields[1], Serial: fields[2], Firmware: fields[3]}, nil
+}
+
+// QueryError returns the oldest error of the error queue, +0 if none
+const QueryError = ":SYST:ERR?"
+
+// SurveyCommand starts a new survey of the antenna position, after which the
+// receiver holds the surveyed position
+const SurveyCommand = ":GPS:POS:SURV:STAT ONCE"
+
+// PositionCommand sets the antenna position, which the receiver holds
+// instead of surveying one
+func PositionCommand(p gpsdo.Position) string {
+	return fmt.Sprintf(":GPS:POS %s,%s,%.2f", dms(p.Latitude, "N", "S"), dms(p.Longitude, "E", "W"), p.Altitude)
+}
+
+// dms formats an angle as hemisphere,degrees,minutes,seconds
+func dms(angle float64, positive, negative string) string {
+	hemisphere := positive
+	if angle < 0 {
+		hemisphere, angle = negative, -angle
+	}
+	// Round to the 0.001 s the receiver shows before splitting
+	ms := int64(math.Round(angle * 3600 * 1000))
+	return fmt.Sprintf("%s,%d,%d,%.3f", hemisphere, ms/3600000, ms/60000%60, float64(ms%60000)/1000)
+}
+
+// queryTimeout bounds the wait for a single response
+const queryTimeout = 5 * time.Second
+
+// ErrTimeout is returned when the receiver does not answer a query
+var ErrTimeout = errors.New("scpi: no response")
+
+// prompt matches the shell prompt, "scpi > " or "E-113> " after an error,
+// which precedes the echo of the next command
+var prompt = regexp.MustCompile(`^(scpi|E-\d+)\s*>\s*`)
+
+// Client sends queries over an SCPI serial connection
+type Client struct {
+	rw io.ReadWriter
+	r  *bufio.Reader
+}
+
+// NewClient uses rw as the SCPI connection. Reads should time out rather
+// than block forever so a silent receiver is detected.
+func NewClient(rw io.ReadWriter) *Client {
+	return &Client{rw: rw, r: bufio.NewReader(rw)}
+}
+
+// Query sends cmd and returns the response line. The command echo and shell
+// prompt are skipped.
+func (c *Client) Query(cmd string) (string, error) {
+	if _, err := io.WriteString(c.rw, cmd+"\r\n"); err != nil {
+		return "", err
+	}
+	return c.response(cmd, cmd)
+}
+
+// Command sends cmd, which has no response, and reads the error queue to
+// check that the receiver accepted it
+func (c *Client) Command(cmd string) error {
+	if _, err := io.WriteString(c.rw, cmd+"\r\n"+QueryError+"\r\n"); err != nil {
+		return err
+	}
+	response, err := c.response(QueryError, cmd, QueryError)
+	if err != nil {
+		return err
+	}
+	code, _, _ := strings.Cut(response, ",")
+	if n, err := strconv.Atoi(strings.TrimPrefix(code, "+")); err != nil || n != 0 {
+		return fmt.Errorf("%s: %s", cmd, response)
+	}
+	return nil
+}
+
+// response reads the response line to cmd, skipping the echoes of the
+// commands sent
+func (c *Client) response(cmd string, echoes ...string) (string, error) {
+	deadline := time.Now().Add(queryTimeout)
+	var line string
+	for time.Now().Before(deadline) {
+		chunk, err := c.r.ReadString('\n')
+		line += chunk
+		if err != nil && !errors.Is(err, io.EOF) {
+			return "", err
+		}
+		if err != nil {
+			// Read timeout, keep any partial line
+			continue
+		}
+
+		response := strings.TrimSpace(prompt.ReplaceAllString(strings.TrimSpace(line), ""))
+		line = ""
+		if response == "" || slices.ContainsFunc(echoes, func(echo string) bool {
+			return strings.EqualFold(response, echo)
+		}) {
+			continue
+		}
+		return response, nil
+	}
+	return "", fmt.Errorf("%w to %s", ErrTimeout, cmd)
+}
+
+// QueryLines sends cmd and returns the lines of a response of several
+// lines, such as the status screen, up to the next shell prompt
+func (c *Client) QueryLines(cmd string) ([]string, error) {
+	if _, err := io.WriteString(c.rw, cmd+"\r\n"); err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(queryTimeout)
+	var lines []string
+	var line string
+	for time.Now().Before(deadline) {
+		chunk, err := c.r.ReadString('\n')
+		line += chunk
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+		if err != nil {
+			// The prompt has no newline, a read times out after it
+			if len(lines) > 0 && prompt.MatchString(strings.TrimSpace(line)) {
+				return lines, nil
+			}
+			continue
+		}
+
+		response := strings.TrimRight(prompt.ReplaceAllString(line, ""), "\r\n")
+		line = ""
+		if len(lines) == 0 && (strings.TrimSpace(response) == "" || strings.EqualFold(strings.TrimSpace(response), cmd)) {
+			continue
+		}
+		lines = append(lines, response)
+	}
+	if len(lines) > 0 {
+		return lines, nil
+	}
+	return nil, fmt.Errorf("%w to %s", ErrTimeout, cmd)
+}
+
+// parseSignals reads the tracked satellites from the satellite table of the
+// status screen. Tracked satellites are listed in the columns up to SS, the
+// satellites not tracked to their right.
+func parseSignals(lines []string) ([]gpsdo.Signal, error) {
+	width := -1
+	signals := []gpsdo.Signal{}
+	for _, line := range lines {
+		if width < 0 {
+			if strings.HasPrefix(line, "PRN") {
+				if i := strings.Index(line, "SS"); i > 0 {
+					width = i + len("SS")
+				}
+			}
+			continue
+		}
+		if strings.HasPrefix(line, "ELEV") || strings.Contains(line, "....") {
+			break
+		}
+
+		fields := strings.Fields(line[:min(width, len(line))])
+		if len(fields) != 4 {
+			continue
+		}
+		var values [4]int
+		for i, field := range fields {
+			v, err := strconv.Atoi(field)
+			if err != nil {
+				values[0] = -1
+				break
+			}
+			values[i] = v
+		}
+		if values[0] <= 0 {
+			continue
+		}
+		signals = append(signals, gpsdo.Signal{PRN: values[0], Elevation: values[1], Azimuth: values[2], Strength: values[3]})
+	}
+	if width < 0 {
+		return nil, errors.New("no satellite table")
+	}
+	return signals, nil
+}
+
+// Fields of the position block of the status screen, such as "MODE Hold" or
+// "MODE Survey: 24.3% complete", "LAT N 37:23:44.915" and "HGT +158.38 m"
+var (
+	positionMode     = regexp.MustCompile(`\bMODE\s+(\S.*?)\s*$`)
+	positionProgress = regexp.MustCompile(`([\d.]+)\s*%`)
+	positionAngle    = regexp.MustCompile(`\b(LAT|LON)\s+([NSEW])\s+(\d+)(?::(\d+)(?::([\d.]+))?)?`)
+	positionHeight   = regexp.MustCompile(`\bHGT\s+([+-]?[\d.]+)\s*m\b`)
+)
+
+// parsePosition reads the position block of the status screen, nil if the
+// screen has none
+func parsePosition(lines []string) *gpsdo.Position {
+	var p *gpsdo.Position
+	for _, line := range lines {
+		if m := positionMode.FindStringSubmatch(line); m != nil {
+			p = &gpsdo.Position{Mode: m[1], Progress: -1}
+			p.Hold = strings.HasPrefix(m[1], "Hold")
+			p.Surveying = strings.HasPrefix(m[1], "Survey")
+			if m := positionProgress.FindStringSubmatch(m[1]); m != nil && p.Surveying {
+				p.Progress, _ = strconv.ParseFloat(m[1], 64)
+			}
+		}
+		if p == nil {
+			continue
+		}
+		if m := positionAngle.FindStringSubmatch(line); m != nil {
+			var angle float64
+			for i, scale := range []float64{1, 60, 3600} {
+				v, _ := strconv.ParseFloat(m[3+i], 64)
+				angle += v / scale
+			}
+			if m[2] == "S" || m[2] == "W" {
+				angle = -angle
+			}
+			if m[1] == "LAT" {
+				p.Latitude = angle
+			} else {
+				p.Longitude = angle
+			}
+		}
+		if m := positionHeight.FindStringSubmatch(line); m != nil {
+			p.Altitude, _ = strconv.ParseFloat(m[1], 64)
+		}
+	}
+	return p
+}
+
+// Diagnostics queries every diagnostic the client knows about. A query that
+// fails leaves its field zero; an error is only returned if all of them fail.
+func (c *Client) Diagnostics() (gpsdo.Diagnostics, error) {
+	var d gpsdo.Diagnostics
+	var errs []error
+
+	query := func(cmd string, parse func(string) error) {
+		response, err := c.Query(cmd)
+		if err == nil {
+			err = parse(response)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", cmd, err))
+		}
+	}
+
+	query(QuerySatellites, func(s string) (err error) {
+		d.Satellites, err = strconv.Atoi(s)
+		return err
+	})
+	query(QueryEFC, func(s string) (err error) {
+		d.EFC, err = strconv.ParseFloat(s, 64)
+		return err
+	})
+	query(QueryHoldover, func(s string) error {
+		// The prediction may be followed by the holdover period
+		seconds, err := strconv.ParseFloat(strings.Split(s, ",")[0], 64)
+		d.HoldoverPrediction = time.Duration(seconds * float64(time.Second))
+		return err
+	})
+	query(QueryAntenna, func(s string) error {
+		d.Antenna = strings.Trim(s, `"`)
+		return nil
+	})
+	lines, err := c.QueryLines(QueryStatus)
+	if err == nil {
+		d.Position = parsePosition(lines)
+		d.Signals, err = parseSignals(lines)
+	}
+	if err != nil {
+		errs = append(errs, fmt.Errorf("%s: %w", QueryStatus, err))
+	}
+
+	if len(errs) == 5 {
+		return d, errors.Join(errs...)
+	}
+	for _, err := range errs {
+		slog.Debug("SCPI query failed", "error", err)
+	}
+	d.Updated = time.Now()
+	return d, nil
+}
+
+// Poller polls the diagnostics of an SCPI port and runs the commands of
+// other users, such as the gRPC API, between the polls
+type Poller struct {
+	device   string
+	interval time.Duration
+	update   func(gpsdo.Diagnostics)
+	identify func(gpsdo.Identity)
+	requests chan request
+}
+
+// request is a command waiting for the port
+type request struct {
+	cmd   string
+	kind  requestKind
+	reply chan result
+}
+
+// requestKind is how the response to a request is read
+type requestKind int
+
+const (
+	// queryLine reads one response line
+	queryLine requestKind = iota
+	// queryLines reads the lines up to the next prompt
+	queryLines
+	// command reads the error queue after a command without a response
+	command
+)
+
+type result struct {
+	lines []string
+	err   error
+}
+
+// run sends the request with c
+func (r request) run(c *Client) result {
+	switch r.kind {
+	case queryLines:
+		lines, err := c.QueryLines(r.cmd)
+		return result{lines, err}
+	case command:
+		return result{err: c.Command(r.cmd)}
+	}
+	response, err := c.Query(r.cmd)
+	return result{[]string{response}, err}
+}
+
+// NewPoller creates a poller for the SCPI port on device, passing the
+// diagnostics read every interval to update
+func NewPoller(device string, interval time.Duration, update func(gpsdo.Diagnostics)) *Poller {
+	return &Poller{
+		device:   device,
+		interval: interval,
+		update:   update,
+		requests: make(chan request),
+	}
+}
+
+// SetIdentify passes the identity of the receiver, queried whenever the
+// port opens, to identify. It must be called before Run.
+func (p *Poller) SetIdentify(identify func(gpsdo.Identity)) {
+	p.identify = identify
+}
+
+// Poll opens the SCPI port on device and reads the diagnostics every
+// interval until ctx is cancelled, passing each result to update. The port
+// is reopened after a failure.
+func Poll(ctx context.Context, device string, interval time.Duration, update func(gpsdo.Diagnostics)) {
+	NewPoller(device, interval, update).Run(ctx)
+}
+
+// Query sends cmd to the port once it is free and returns the response
+// line. It fails if the poller is not running or the port is closed.
+func (p *Poller) Query(ctx context.Context, cmd string) (string, error) {
+	lines, err := p.send(ctx, cmd, queryLine)
+	if err != nil {
+		return "", err
+	}
+	return lines[0], nil
+}
+
+// QueryLines sends cmd to the port once it is free and returns the lines of
+// the response up to the next prompt, such as the status screen
+func (p *Poller) QueryLines(ctx context.Context, cmd string) ([]string, error) {
+	return p.send(ctx, cmd, queryLines)
+}
+
+// Command sends cmd, which has no response, to the port once it is free,
+// failing if the receiver reports an error for it
+func (p *Poller) Command(ctx context.Context, cmd string) error {
+	_, err := p.send(ctx, cmd, command)
+	return err
+}
+
+// Survey starts a new survey of the antenna position
+func (p *Poller) Survey(ctx context.Context) error {
+	return p.Command(ctx, SurveyCommand)
+}
+
+// HoldPosition sets the antenna position the receiver holds
+func (p *Poller) HoldPosition(ctx context.Context, position gpsdo.Position) error {
+	return p.Command(ctx, PositionCommand(position))
+}
+
+func (p *Poller) send(ctx context.Context, cmd string, kind requestKind) ([]string, error) {
+	req := request{cmd: cmd, kind: kind, reply: make(chan result, 1)}
+	select {
+	case p.requests <- req:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	select {
+	case r := <-req.reply:
+		return r.lines, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// queryIdentity passes the identity of the receiver to identify. A receiver
+// that doesn't answer is left to the diagnostics poll to detect.
+func (p *Poller) queryIdentity(c *Client) {
+	var id gpsdo.Identity
+	response, err := c.Query(QueryIdentity)
+	if err == nil {
+		id, err = ParseIdentity(response)
+	}
+	if err != nil {
+		slog.Debug("SCPI identity query failed", "port", p.device, "error", err)
+		return
+	}
+	p.identify(id)
+}
+
+// Run polls until ctx is cancelled
+func (p *Poller) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	var port *serial.Port
+	defer func() {
+		if port != nil {
+			port.Close()
+		}
+	}()
+
+	poll := true
+	for {
+		if port == nil {
+			var err error
+			port, err = serial.OpenPort(&serial.Config{
+				Name:        p.device,
+				Baud:        9600,
+				Size:        8,
+				Parity:      serial.ParityNone,
+				StopBits:    serial.Stop1,
+				ReadTimeout: time.Second,
+			})
+			if err != nil {
+				slog.Warn("SCPI port unavailable", "port", p.device, "error", err)
+				port = nil
+			} else if p.identify != nil {
+				p.queryIdentity(NewClient(port))
+			}
+		}
+
+		if port != nil && poll {
+			d, err := NewClient(port).Diagnostics()
+			if err != nil {
+				slog.Warn("SCPI diagnostics failed", "port", p.device, "error", err)
+				port.Close()
+				port = nil
+			} else {
+				p.update(d)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			poll = true
+		case req := <-p.requests:
+			poll = false
+			if port == nil {
+				req.reply <- result{err: fmt.Errorf("SCPI port %s unavailable", p.device)}
+				continue
+			}
+			req.reply <- req.run(NewClient(port))
+		}
+	}
+}
@@ -0,0 +1,121 @@
+// Package shm publishes GPSDO samples through the NTP shared memory
+// refclock driver used by ntpd, ntpsec and chronyd.
+//
+// See https://www.ntpsec.org/docs/driver_shm.html
+package shm
+
+import (
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"unsafe"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"golang.org/x/sys/unix"
+)
+
+// baseKey is the SysV IPC key of unit 0 ("NTP0")
+const baseKey = 0x4e545030
+
+// MaxUnit is the highest SHM unit number
+const MaxUnit = 3
+
+// log2 of the accuracy of serial time-of-day and PPS paired samples
+const (
+	precisionTOD = -1
+	precisionPPS = -20
+)
+
+// shmTime mirrors struct shmTime from ntpd's refclock_shm.c
+type shmTime struct {
+	Mode                 int32
+	Count                int32
+	ClockTimeStampSec    unix.Time_t
+	ClockTimeStampUSec   int32
+	ReceiveTimeStampSec  unix.Time_t
+	ReceiveTimeStampUSec int32
+	Leap                 int32
+	Precision            int32
+	Nsamples             int32
+	Valid                int32
+	ClockTimeStampNSec   uint32
+	ReceiveTimeStampNSec uint32
+	Dummy                [8]int32
+}
+
+// Segment is an attached NTP SHM unit
+type Segment struct {
+	unit int
+	mem  []byte
+	shm  *shmTime
+}
+
+// Open attaches to (creating if needed) the SHM segment for unit. Units 0
+// and 1 are only accessible by root, 2 and 3 by everyone, matching ntpd.
+func Open(unit int) (*Segment, error) {
+	if unit < 0 || unit > MaxUnit {
+		return nil, fmt.Errorf("shm unit %d out of range 0-%d", unit, MaxUnit)
+	}
+
+	perm := 0600
+	if unit >= 2 {
+		perm = 0666
+	}
+
+	size := int(unsafe.Sizeof(shmTime{}))
+	id, err := unix.SysvShmGet(baseKey+unit, size, unix.IPC_CREAT|perm)
+	if err != nil {
+		return nil, fmt.Errorf("shmget unit %d: %w", unit, err)
+	}
+
+	mem, err := unix.SysvShmAttach(id, 0, 0)
+	if err != nil {
+		return nil, fmt.Errorf("shmat unit %d: %w", unit, err)
+	}
+
+	s := &Segment{
+		unit: unit,
+		mem:  mem,
+		shm:  (*shmTime)(unsafe.Pointer(&mem[0])),
+	}
+	s.shm.Mode = 1
+	slog.Info("Attached to NTP SHM", "unit", unit)
+	return s, nil
+}
+
+// Name identifies the output in logs
+func (s *Segment) Name() string {
+	return fmt.Sprintf("shm%d", s.unit)
+}
+
+// Send publishes a sample using the mode 1 count/valid handshake
+func (s *Segment) Send(data *gpsdo.Sample) error {
+	clock := data.Timestamp.Add(data.Offset)
+	receive := data.ReceiveTime()
+	precision := int32(precisionTOD)
+	if !data.PPS.IsZero() {
+		precision = precisionPPS
+	}
+
+	atomic.StoreInt32(&s.shm.Valid, 0)
+	atomic.AddInt32(&s.shm.Count, 1)
+
+	s.shm.ClockTimeStampSec = unix.Time_t(clock.Unix())
+	s.shm.ClockTimeStampUSec = int32(clock.Nanosecond() / 1000)
+	s.shm.ClockTimeStampNSec = uint32(clock.Nanosecond())
+	s.shm.ReceiveTimeStampSec = unix.Time_t(receive.Unix())
+	s.shm.ReceiveTimeStampUSec = int32(receive.Nanosecond() / 1000)
+	s.shm.ReceiveTimeStampNSec = uint32(receive.Nanosecond())
+	s.shm.Leap = int32(data.Leap)
+	s.shm.Precision = precision
+
+	atomic.AddInt32(&s.shm.Count, 1)
+	atomic.StoreInt32(&s.shm.Valid, 1)
+	return nil
+}
+
+// Close detaches from the segment. The segment itself is left in place for
+// the NTP daemon.
+func (s *Segment) Close() error {
+	return unix.SysvShmDetach(s.mem)
+}
@@ -0,0 +1,180 @@
+// Package spectracom parses the Spectracom (Netclock) ASCII time code
+// formats 0 and 2, output by many legacy time servers and GPS clocks.
+//
+// Both formats are framed by <CR><LF>, with the first <CR> on time:
+//
+//	Format 0: <CR><LF>i  ddd hh:mm:ss  TZ=zz<CR><LF>
+//	Format 2: <CR><LF>iqyy ddd hh:mm:ss.fff ld<CR><LF>
+//
+// i is the synchronization flag, ' ' when in sync and '?' when not. q is the
+// format 2 time quality, ' ' when locked with an error under 1 ms and 'A' to
+// 'D' for errors under 10, 100 and 500 ms and over 500 ms. l is 'L' in a
+// month ending in a leap second.
+package spectracom
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+var (
+	// ErrFormat is returned for lines that are neither format 0 nor format 2
+	ErrFormat = errors.New("spectracom: malformed time code")
+	// ErrRange is returned for time codes with a day or time out of range
+	ErrRange = errors.New("spectracom: field out of range")
+)
+
+// Lengths of the printing characters of each format
+const (
+	format0Len = 22
+	format2Len = 24
+)
+
+// Parse decodes one line. Empty lines, such as the <CR><LF> that frames each
+// time code, return a nil sample and nil error. Format 0 carries no year, it
+// is taken from the system clock.
+func Parse(line string) (*gpsdo.Sample, error) {
+	// The sync and quality flags may be spaces, only strip the framing
+	line = strings.Trim(line, "\r\n")
+	if line == "" {
+		return nil, nil
+	}
+
+	received := time.Now()
+	var data *gpsdo.Sample
+	var err error
+	switch len(line) {
+	case format0Len:
+		data, err = parseFormat0(line, received)
+	case format2Len:
+		data, err = parseFormat2(line)
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrFormat, line)
+	}
+	if err != nil {
+		return nil, err
+	}
+	data.ParseTime = received
+	return data, nil
+}
+
+// i  ddd hh:mm:ss  TZ=zz
+func parseFormat0(line string, received time.Time) (*gpsdo.Sample, error) {
+	if line[1:3] != "  " || line[6] != ' ' || line[15:20] != "  TZ=" {
+		return nil, fmt.Errorf("%w: %q", ErrFormat, line)
+	}
+	day, err := strconv.Atoi(line[3:6])
+	if err != nil {
+		return nil, fmt.Errorf("%w: day %q", ErrFormat, line[3:6])
+	}
+
+	// The year is the one that puts the day closest to the receive time
+	year := received.UTC().Year()
+	best := time.Duration(-1)
+	var timestamp time.Time
+	for _, y := range []int{year - 1, year, year + 1} {
+		var ts time.Time
+		ts, err = buildTime(y, day, line[7:15])
+		if err != nil {
+			continue
+		}
+		if d := ts.Sub(received).Abs(); best < 0 || d < best {
+			best, timestamp = d, ts
+		}
+	}
+	if best < 0 {
+		return nil, err
+	}
+
+	status := gpsdo.Locked
+	if line[0] != ' ' {
+		status = gpsdo.Unknown
+	}
+	return newSample(timestamp, status, status == gpsdo.Locked, gpsdo.LeapNone), nil
+}
+
+// iqyy ddd hh:mm:ss.fff ld
+func parseFormat2(line string) (*gpsdo.Sample, error) {
+	if line[4] != ' ' || line[8] != ' ' || line[21] != ' ' {
+		return nil, fmt.Errorf("%w: %q", ErrFormat, line)
+	}
+	yy, err1 := strconv.Atoi(line[2:4])
+	day, err2 := strconv.Atoi(line[5:8])
+	if err := errors.Join(err1, err2); err != nil {
+		return nil, fmt.Errorf("%w: date %q", ErrFormat, line[2:8])
+	}
+	timestamp, err := buildTime(2000+yy, day, line[9:21])
+	if err != nil {
+		return nil, err
+	}
+
+	var status gpsdo.Status
+	valid := false
+	switch quality := line[1]; {
+	case line[0] != ' ':
+		status = gpsdo.Unknown
+	case quality == ' ':
+		status = gpsdo.Locked
+		valid = true
+	case quality >= 'A' && quality <= 'D':
+		// Flywheeling on the oscillator, still usable while the error is
+		// under 10 ms
+		status = gpsdo.Holdover
+		valid = quality == 'A'
+	default:
+		return nil, fmt.Errorf("%w: quality %q", ErrFormat, quality)
+	}
+
+	leap := gpsdo.LeapNone
+	if line[22] == 'L' && lastDayOfMonth(timestamp) {
+		leap = gpsdo.LeapInsert
+	}
+	return newSample(timestamp, status, valid, leap), nil
+}
+
+// buildTime returns the time of day of hms, hh:mm:ss with optional
+// fraction, on day of year
+func buildTime(year, day int, hms string) (time.Time, error) {
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	if day < 1 || day > start.AddDate(1, 0, -1).YearDay() {
+		return time.Time{}, fmt.Errorf("%w: day %d", ErrRange, day)
+	}
+	if len(hms) < 8 || hms[2] != ':' || hms[5] != ':' {
+		return time.Time{}, fmt.Errorf("%w: time %q", ErrFormat, hms)
+	}
+	hour, err1 := strconv.Atoi(hms[0:2])
+	minute, err2 := strconv.Atoi(hms[3:5])
+	second, err3 := strconv.ParseFloat(hms[6:], 64)
+	if err := errors.Join(err1, err2, err3); err != nil {
+		return time.Time{}, fmt.Errorf("%w: time %q", ErrFormat, hms)
+	}
+	if hour < 0 || hour > 23 || minute < 0 || minute > 59 || second < 0 || second >= 61 {
+		return time.Time{}, fmt.Errorf("%w: time %q", ErrRange, hms)
+	}
+
+	seconds := time.Duration(second * float64(time.Second)).Round(time.Millisecond)
+	return start.AddDate(0, 0, day-1).Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute + seconds), nil
+}
+
+func lastDayOfMonth(t time.Time) bool {
+	return t.AddDate(0, 0, 1).Month() != t.Month()
+}
+
+func newSample(timestamp time.Time, status gpsdo.Status, valid bool, leap gpsdo.Leap) *gpsdo.Sample {
+	return &gpsdo.Sample{
+		Year:      timestamp.Year(),
+		DayOfYear: timestamp.YearDay(),
+		Hour:      timestamp.Hour(),
+		Minute:    timestamp.Minute(),
+		Second:    timestamp.Second(),
+		Leap:      leap,
+		Status:    status,
+		Valid:     valid,
+		Timestamp: timestamp,
+	}
+}
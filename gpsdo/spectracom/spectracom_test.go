@@ -0,0 +1,144 @@
+package spectracom
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+func TestFormat0Year(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		line     string
+		received time.Time
+		want     time.Time
+	}{
+		{
+			name:     "same year",
+			line:     "   073 12:00:00  TZ=00",
+			received: time.Date(2025, time.March, 14, 12, 0, 0, 300000000, time.UTC),
+			want:     time.Date(2025, time.March, 14, 12, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "last day received after New Year",
+			line:     "   365 23:59:59  TZ=00",
+			received: time.Date(2026, time.January, 1, 0, 0, 0, 300000000, time.UTC),
+			want:     time.Date(2025, time.December, 31, 23, 59, 59, 0, time.UTC),
+		},
+		{
+			name:     "first day received before New Year",
+			line:     "   001 00:00:00  TZ=00",
+			received: time.Date(2025, time.December, 31, 23, 59, 59, 900000000, time.UTC),
+			want:     time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:     "day 366 of the previous leap year",
+			line:     "   366 23:59:59  TZ=00",
+			received: time.Date(2025, time.January, 1, 0, 0, 0, 300000000, time.UTC),
+			want:     time.Date(2024, time.December, 31, 23, 59, 59, 0, time.UTC),
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			sample, err := parseFormat0(tt.line, tt.received)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !sample.Timestamp.Equal(tt.want) {
+				t.Errorf("timestamp = %s, want %s", sample.Timestamp, tt.want)
+			}
+			if sample.Status != gpsdo.Locked || !sample.Valid {
+				t.Errorf("status = %s valid %t, want %s valid", sample.Status, sample.Valid, gpsdo.Locked)
+			}
+		})
+	}
+}
+
+func TestFormat0Range(t *testing.T) {
+	// No year around 2026 has a day 366
+	received := time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)
+	for _, line := range []string{
+		"   366 12:00:00  TZ=00",
+		"   000 12:00:00  TZ=00",
+		"   073 24:00:00  TZ=00",
+	} {
+		if _, err := parseFormat0(line, received); !errors.Is(err, ErrRange) {
+			t.Errorf("%q: error = %v, want ErrRange", line, err)
+		}
+	}
+}
+
+func TestFormat2Quality(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		line   string
+		status gpsdo.Status
+		valid  bool
+	}{
+		{"locked", "  25 073 12:00:00.000  S", gpsdo.Locked, true},
+		{"under 10 ms", " A25 073 12:00:00.000  S", gpsdo.Holdover, true},
+		{"under 100 ms", " B25 073 12:00:00.000  S", gpsdo.Holdover, false},
+		{"under 500 ms", " C25 073 12:00:00.000  S", gpsdo.Holdover, false},
+		{"500 ms or worse", " D25 073 12:00:00.000  S", gpsdo.Holdover, false},
+		{"not synchronized", "? 25 073 12:00:00.000  S", gpsdo.Unknown, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			sample, err := Parse(tt.line + "\r\n")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sample.Status != tt.status || sample.Valid != tt.valid {
+				t.Errorf("status = %s valid %t, want %s valid %t", sample.Status, sample.Valid, tt.status, tt.valid)
+			}
+			if want := time.Date(2025, time.March, 14, 12, 0, 0, 0, time.UTC); !sample.Timestamp.Equal(want) {
+				t.Errorf("timestamp = %s, want %s", sample.Timestamp, want)
+			}
+		})
+	}
+
+	if _, err := Parse(" E25 073 12:00:00.000  S"); !errors.Is(err, ErrFormat) {
+		t.Errorf("quality E: error = %v, want ErrFormat", err)
+	}
+}
+
+func TestFormat2Leap(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		line string
+		want gpsdo.Leap
+	}{
+		{"30 June", "  25 181 12:00:00.000 LS", gpsdo.LeapInsert},
+		{"31 December", "  25 365 12:00:00.000 LS", gpsdo.LeapInsert},
+		{"not the last day of the month", "  25 166 12:00:00.000 LS", gpsdo.LeapNone},
+		{"no flag", "  25 181 12:00:00.000  S", gpsdo.LeapNone},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			sample, err := Parse(tt.line)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sample.Leap != tt.want {
+				t.Errorf("leap = %v, want %v", sample.Leap, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormat2Errors(t *testing.T) {
+	for _, tt := range []struct {
+		line string
+		want error
+	}{
+		{"  25 366 12:00:00.000  S", ErrRange},
+		{"  25 073 12:60:00.000  S", ErrRange},
+		{"  25 073 12:00:61.000  S", ErrRange},
+		{"  25 073 12-00:00.000  S", ErrFormat},
+		{"  2x 073 12:00:00.000  S", ErrFormat},
+		{"  25 073 12:00:00.000", ErrFormat},
+	} {
+		if _, err := Parse(tt.line); !errors.Is(err, tt.want) {
+			t.Errorf("%q: error = %v, want %v", tt.line, err, tt.want)
+		}
+	}
+}
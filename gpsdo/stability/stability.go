@@ -0,0 +1,172 @@
+// Package stability characterizes a GPSDO and its serial path from the offset
+// between each sample and the system clock. It keeps a sliding window of
+// offsets, one per GPS second, and computes their spread and the overlapping
+// Allan deviation of the phase data at several averaging times.
+package stability
+
+import (
+	"math"
+	"slices"
+	"time"
+)
+
+// DefaultTaus are the averaging times reported when none are given
+var DefaultTaus = []time.Duration{time.Second, 10 * time.Second, 100 * time.Second, 1000 * time.Second}
+
+// Summary are the statistics of the offsets in the window. Offsets are in
+// seconds, reference time minus system time.
+type Summary struct {
+	Samples int
+	Mean    float64
+	StdDev  float64
+	// MAD is the median absolute deviation from the median offset
+	MAD float64
+	// ADEV holds the averaging times with enough samples
+	ADEV []Deviation
+}
+
+// Deviation is the Allan deviation at one averaging time
+type Deviation struct {
+	Tau       time.Duration
+	Deviation float64
+	// Terms is the number of second differences averaged
+	Terms int
+}
+
+// Tracker records offsets over a sliding window. It is not safe for
+// concurrent use.
+type Tracker struct {
+	window  int64
+	latest  int64
+	seconds []int64
+	offsets []float64
+}
+
+// NewTracker creates a tracker keeping window worth of seconds
+func NewTracker(window time.Duration) *Tracker {
+	n := max(int64(window/time.Second), 1)
+	return &Tracker{
+		window:  n,
+		seconds: make([]int64, n),
+		offsets: make([]float64, n),
+	}
+}
+
+// Add records the offset in seconds measured for the sample at GPS time at.
+// Times are rounded to the second; a later offset for the same second
+// replaces the earlier one.
+func (t *Tracker) Add(at time.Time, offset float64) {
+	second := at.Round(time.Second).Unix()
+	if second <= t.latest-t.window {
+		return
+	}
+	t.latest = max(t.latest, second)
+
+	slot := second % t.window
+	t.seconds[slot] = second
+	t.offsets[slot] = offset
+}
+
+// lookup returns the offset recorded for second, if still in the window
+func (t *Tracker) lookup(second int64) (float64, bool) {
+	if second <= t.latest-t.window || second > t.latest {
+		return 0, false
+	}
+	slot := second % t.window
+	return t.offsets[slot], t.seconds[slot] == second
+}
+
+// Summary computes the statistics of the window, with the Allan deviation at
+// each of taus or DefaultTaus
+func (t *Tracker) Summary(taus ...time.Duration) Summary {
+	if len(taus) == 0 {
+		taus = DefaultTaus
+	}
+	// Nothing recorded yet, the window would reach before the epoch
+	if t.latest == 0 {
+		return Summary{}
+	}
+
+	var offsets []float64
+	for second := t.latest - t.window + 1; second <= t.latest; second++ {
+		if x, ok := t.lookup(second); ok {
+			offsets = append(offsets, x)
+		}
+	}
+
+	var summary Summary
+	summary.Samples = len(offsets)
+	if summary.Samples == 0 {
+		return summary
+	}
+
+	for _, x := range offsets {
+		summary.Mean += x
+	}
+	summary.Mean /= float64(len(offsets))
+
+	if len(offsets) > 1 {
+		var sum float64
+		for _, x := range offsets {
+			sum += (x - summary.Mean) * (x - summary.Mean)
+		}
+		summary.StdDev = math.Sqrt(sum / float64(len(offsets)-1))
+	}
+
+	mid := median(offsets)
+	deviations := make([]float64, len(offsets))
+	for i, x := range offsets {
+		deviations[i] = math.Abs(x - mid)
+	}
+	summary.MAD = median(deviations)
+
+	for _, tau := range taus {
+		if dev, ok := t.adev(tau); ok {
+			summary.ADEV = append(summary.ADEV, dev)
+		}
+	}
+	return summary
+}
+
+// adev computes the overlapping Allan deviation at tau from the phase data,
+// skipping any second difference that spans a missing sample
+func (t *Tracker) adev(tau time.Duration) (Deviation, bool) {
+	m := int64(tau / time.Second)
+	if m < 1 || 2*m >= t.window {
+		return Deviation{}, false
+	}
+
+	var sum float64
+	var terms int
+	for second := t.latest - t.window + 1; second+2*m <= t.latest; second++ {
+		x0, ok0 := t.lookup(second)
+		x1, ok1 := t.lookup(second + m)
+		x2, ok2 := t.lookup(second + 2*m)
+		if !ok0 || !ok1 || !ok2 {
+			continue
+		}
+		d := x2 - 2*x1 + x0
+		sum += d * d
+		terms++
+	}
+	if terms == 0 {
+		return Deviation{}, false
+	}
+
+	seconds := float64(m)
+	return Deviation{
+		Tau:       time.Duration(m) * time.Second,
+		Deviation: math.Sqrt(sum / (2 * seconds * seconds * float64(terms))),
+		Terms:     terms,
+	}, true
+}
+
+func median(values []float64) float64 {
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
@@ -0,0 +1,109 @@
+// Package truetime parses the TrueTime (Kinemetrics) ASCII time code, also
+// output by the Symmetricom/Datum TymServe and other legacy timing hardware:
+//
+//	<SOH>ddd:hh:mm:ssQ<CR><LF>
+//
+// The SOH is on time. ddd is the day of year and Q the time quality, the
+// possible error of the time:
+//
+//	' '  under 1 ms, locked
+//	'.'  within 1 ms
+//	'*'  within 5 ms
+//	'#'  within 50 ms
+//	'?'  within 500 ms, not synchronized
+package truetime
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// SOH starts each time code
+const SOH = 0x01
+
+// timeCodeLen is the length of a time code from SOH to the quality
+const timeCodeLen = 14
+
+// ErrFormat is returned for lines that are not a valid time code
+var ErrFormat = errors.New("truetime: malformed time code")
+
+// Parse decodes one line. The time code carries no year, it is taken from
+// the system clock.
+func Parse(line string) (*gpsdo.Sample, error) {
+	// The quality may be a space, only strip the framing
+	line = strings.Trim(line, "\r\n")
+	if i := strings.LastIndexByte(line, SOH); i > 0 {
+		// Noise before the time code
+		line = line[i:]
+	}
+	if len(line) != timeCodeLen || line[0] != SOH || line[4] != ':' || line[7] != ':' || line[10] != ':' {
+		return nil, fmt.Errorf("%w: %q", ErrFormat, line)
+	}
+	day, err1 := strconv.Atoi(line[1:4])
+	hour, err2 := strconv.Atoi(line[5:7])
+	minute, err3 := strconv.Atoi(line[8:10])
+	second, err4 := strconv.Atoi(line[11:13])
+	if err := errors.Join(err1, err2, err3, err4); err != nil || hour > 23 || minute > 59 || second > 60 {
+		return nil, fmt.Errorf("%w: %q", ErrFormat, line)
+	}
+
+	received := time.Now()
+	timestamp, ok := closestYear(day, hour, minute, second, received)
+	if !ok {
+		return nil, fmt.Errorf("%w: day %d", ErrFormat, day)
+	}
+
+	var status gpsdo.Status
+	valid := false
+	switch quality := line[13]; quality {
+	case ' ':
+		status = gpsdo.Locked
+		valid = true
+	case '.', '*', '#':
+		// Flywheeling on the oscillator, still usable while the error is
+		// under 10 ms
+		status = gpsdo.Holdover
+		valid = quality != '#'
+	case '?':
+		status = gpsdo.Unknown
+	default:
+		return nil, fmt.Errorf("%w: quality %q", ErrFormat, quality)
+	}
+
+	return &gpsdo.Sample{
+		Year:      timestamp.Year(),
+		DayOfYear: timestamp.YearDay(),
+		Hour:      timestamp.Hour(),
+		Minute:    timestamp.Minute(),
+		Second:    timestamp.Second(),
+		Status:    status,
+		Valid:     valid,
+		Timestamp: timestamp,
+		ParseTime: received,
+	}, nil
+}
+
+// closestYear returns the time of day on day of year in the year that puts
+// it closest to received
+func closestYear(day, hour, minute, second int, received time.Time) (time.Time, bool) {
+	year := received.UTC().Year()
+	best := time.Duration(-1)
+	var timestamp time.Time
+	for _, y := range []int{year - 1, year, year + 1} {
+		start := time.Date(y, time.January, 1, 0, 0, 0, 0, time.UTC)
+		if day < 1 || day > start.AddDate(1, 0, -1).YearDay() {
+			continue
+		}
+		ts := start.AddDate(0, 0, day-1).Add(time.Duration(hour)*time.Hour +
+			time.Duration(minute)*time.Minute + time.Duration(second)*time.Second)
+		if d := ts.Sub(received).Abs(); best < 0 || d < best {
+			best, timestamp = d, ts
+		}
+	}
+	return timestamp, best >= 0
+}
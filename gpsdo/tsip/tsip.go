@@ -0,0 +1,392 @@
+// Package tsip parses the Trimble Standard Interface Protocol timing packets
+// sent by the Thunderbolt family of GPSDOs.
+package tsip
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// Framing bytes
+const (
+	DLE = 0x10
+	ETX = 0x03
+)
+
+// maxPacketLen bounds a packet when the closing DLE ETX is lost
+const maxPacketLen = 512
+
+// Command packets
+const (
+	idSetPosition = 0x32
+	idCommand     = 0x8E
+	subSurvey     = 0xA6
+)
+
+// Identity packets: 0x1F asks for the 0x45 software version and 8E-41 for
+// the 8F-41 manufacturing parameters of the Thunderbolt, 1C-01 and 1C-03 for
+// the 1C-81 firmware and 1C-83 hardware versions of later receivers
+const (
+	idVersionQuery     = 0x1F
+	idVersion          = 0x45
+	idHardware         = 0x1C
+	subFirmwareQuery   = 0x01
+	subHardwareQuery   = 0x03
+	subFirmware        = 0x81
+	subHardwareVersion = 0x83
+	subManufacturing   = 0x41
+)
+
+// Superpacket 0x8F subcodes
+const (
+	idSuperpacket    = 0x8F
+	subPrimaryTiming = 0xAB
+	subSuppTiming    = 0xAC
+)
+
+// Timing flags from packet 8F-AB
+const (
+	flagUTCTime    = 1 << 0
+	flagTimeNotSet = 1 << 2
+	flagNoUTCInfo  = 1 << 3
+)
+
+// Disciplining modes from packet 8F-AC
+const (
+	modeNormal         = 0
+	modePowerUp        = 1
+	modeAutoHoldover   = 2
+	modeManualHoldover = 3
+	modeRecovery       = 4
+	modeDisabled       = 6
+)
+
+// Receiver modes from packet 8F-AC, a timing receiver in position hold
+// reports the overdetermined clock mode
+var receiverModes = map[byte]string{
+	0: "automatic",
+	1: "single satellite",
+	3: "2D",
+	4: "3D",
+	5: "DGPS",
+	6: "2D clock hold",
+	7: "overdetermined clock",
+}
+
+const receiverModeHold = 7
+
+// minorSurvey is the minor alarm bit of packet 8F-AC set during the
+// self-survey
+const minorSurvey = 1 << 5
+
+var (
+	// ErrFormat is returned for packets that are too short to decode
+	ErrFormat = errors.New("tsip: malformed packet")
+	// ErrFraming is returned when a packet is cut short by the next one or
+	// grows past maxPacketLen without its closing DLE ETX
+	ErrFraming = errors.New("tsip: packet boundaries lost")
+	// ErrRange is returned for timing packets with a date or time out of
+	// range
+	ErrRange = errors.New("tsip: field out of range")
+)
+
+// Decoder removes DLE framing and stuffing from a TSIP byte stream
+type Decoder struct {
+	packet   []byte
+	inPacket bool
+	dle      bool
+}
+
+// Feed consumes one byte of the stream. It returns the packet (ID followed
+// by data) when the byte completes one, and ErrFraming when a packet is
+// dropped for losing its closing DLE ETX. The returned packet is only valid
+// until the next call.
+func (d *Decoder) Feed(c byte) ([]byte, error) {
+	if d.dle {
+		d.dle = false
+		switch {
+		case c == DLE && d.inPacket:
+			d.packet = append(d.packet, DLE)
+		case c == ETX && d.inPacket:
+			d.inPacket = false
+			return d.packet, nil
+		case c != DLE && c != ETX:
+			// DLE followed by a packet ID starts a new packet, even if the
+			// previous one was never terminated
+			cut := d.inPacket
+			d.inPacket = true
+			d.packet = append(d.packet[:0], c)
+			if cut {
+				return nil, fmt.Errorf("%w: packet cut short by packet 0x%02X", ErrFraming, c)
+			}
+		}
+		return nil, nil
+	}
+
+	switch {
+	case c == DLE:
+		d.dle = true
+	case d.inPacket:
+		d.packet = append(d.packet, c)
+		if len(d.packet) > maxPacketLen {
+			d.inPacket = false
+			return nil, fmt.Errorf("%w: packet 0x%02X longer than %d bytes", ErrFraming, d.packet[0], maxPacketLen)
+		}
+	}
+	return nil, nil
+}
+
+// Encode frames a packet, an ID followed by data, with DLE stuffing
+func Encode(id byte, data []byte) []byte {
+	packet := []byte{DLE, id}
+	for _, c := range data {
+		if c == DLE {
+			packet = append(packet, DLE)
+		}
+		packet = append(packet, c)
+	}
+	return append(packet, DLE, ETX)
+}
+
+// SurveyCommand is packet 8E-A6, which restarts the self-survey
+func SurveyCommand() []byte {
+	return Encode(idCommand, []byte{subSurvey, 0})
+}
+
+// PositionCommand is packet 0x32 with the accurate position in double
+// precision, which the receiver holds instead of surveying one
+func PositionCommand(p gpsdo.Position) []byte {
+	var data [24]byte
+	binary.BigEndian.PutUint64(data[0:8], math.Float64bits(p.Latitude*math.Pi/180))
+	binary.BigEndian.PutUint64(data[8:16], math.Float64bits(p.Longitude*math.Pi/180))
+	binary.BigEndian.PutUint64(data[16:24], math.Float64bits(p.Altitude))
+	return Encode(idSetPosition, data[:])
+}
+
+// IdentityQuery asks for the packets that identify the receiver
+func IdentityQuery() []byte {
+	query := Encode(idVersionQuery, nil)
+	query = append(query, Encode(idCommand, []byte{subManufacturing})...)
+	query = append(query, Encode(idHardware, []byte{subFirmwareQuery})...)
+	return append(query, Encode(idHardware, []byte{subHardwareQuery})...)
+}
+
+// Parser decodes Thunderbolt timing packets. Packet 8F-AC carries the
+// disciplining state, which is applied to the following 8F-AB timing packets,
+// and the position and self-survey state returned by Diagnostics. The
+// identity packets are returned by Identity.
+type Parser struct {
+	status      gpsdo.Status
+	seen        bool
+	diagnostics gpsdo.Diagnostics
+	updated     bool
+	identity    gpsdo.Identity
+	identified  bool
+}
+
+// Identity returns the identity of the receiver and whether an identity
+// packet was decoded since the last call
+func (p *Parser) Identity() (gpsdo.Identity, bool) {
+	identified := p.identified
+	p.identified = false
+	return p.identity, identified
+}
+
+// Diagnostics returns the position of the last 8F-AC packet and whether one
+// was decoded since the last call
+func (p *Parser) Diagnostics() (gpsdo.Diagnostics, bool) {
+	updated := p.updated
+	p.updated = false
+	return p.diagnostics, updated
+}
+
+// Parse decodes one unstuffed packet. Packets other than 8F-AB return a nil
+// sample and nil error.
+func (p *Parser) Parse(packet []byte) (*gpsdo.Sample, error) {
+	if len(packet) < 2 {
+		return nil, nil
+	}
+
+	switch {
+	case packet[0] == idVersion:
+		return nil, p.parseVersion(packet[1:])
+	case packet[0] == idHardware:
+		return nil, p.parseHardware(packet[1:])
+	case packet[0] != idSuperpacket:
+		return nil, nil
+	}
+
+	switch packet[1] {
+	case subSuppTiming:
+		return nil, p.parseSupplemental(packet[1:])
+	case subPrimaryTiming:
+		return p.parsePrimary(packet[1:])
+	case subManufacturing:
+		return nil, p.parseManufacturing(packet[1:])
+	}
+	return nil, nil
+}
+
+// parseVersion decodes 0x45, the application and GPS core versions
+func (p *Parser) parseVersion(data []byte) error {
+	if len(data) < 10 {
+		return fmt.Errorf("%w: 0x45 length %d", ErrFormat, len(data))
+	}
+	p.identify(func(id *gpsdo.Identity) {
+		id.Firmware = fmt.Sprintf("%d.%02d, GPS core %d.%02d", data[0], data[1], data[5], data[6])
+	})
+	return nil
+}
+
+// parseManufacturing decodes 8F-41, whose board serial number is printed
+// with its prefix on the label
+func (p *Parser) parseManufacturing(data []byte) error {
+	if len(data) < 7 {
+		return fmt.Errorf("%w: 8F-41 length %d", ErrFormat, len(data))
+	}
+	prefix := int16(binary.BigEndian.Uint16(data[1:3]))
+	serial := binary.BigEndian.Uint32(data[3:7])
+	p.identify(func(id *gpsdo.Identity) {
+		id.Serial = fmt.Sprintf("%d-%d", prefix, serial)
+	})
+	return nil
+}
+
+// parseHardware decodes the 1C-81 firmware and 1C-83 hardware versions,
+// each ending with a string prefixed by its length
+func (p *Parser) parseHardware(data []byte) error {
+	name := func(at int) (string, bool) {
+		if len(data) <= at || len(data) < at+1+int(data[at]) {
+			return "", false
+		}
+		return string(data[at+1 : at+1+int(data[at])]), true
+	}
+
+	switch data[0] {
+	case subFirmware:
+		if _, ok := name(9); !ok {
+			return fmt.Errorf("%w: 1C-81 length %d", ErrFormat, len(data))
+		}
+		p.identify(func(id *gpsdo.Identity) {
+			id.Firmware = fmt.Sprintf("%d.%d.%d", data[2], data[3], data[4])
+		})
+	case subHardwareVersion:
+		model, ok := name(12)
+		if !ok {
+			return fmt.Errorf("%w: 1C-83 length %d", ErrFormat, len(data))
+		}
+		p.identify(func(id *gpsdo.Identity) {
+			id.Model = model
+			id.Serial = strconv.FormatUint(uint64(binary.BigEndian.Uint32(data[1:5])), 10)
+		})
+	}
+	return nil
+}
+
+// identify updates the identity with set
+func (p *Parser) identify(set func(id *gpsdo.Identity)) {
+	p.identity.Manufacturer = "Trimble"
+	set(&p.identity)
+	p.identified = true
+}
+
+// parseSupplemental decodes 8F-AC supplemental timing
+func (p *Parser) parseSupplemental(data []byte) error {
+	if len(data) < 68 {
+		return fmt.Errorf("%w: 8F-AC length %d", ErrFormat, len(data))
+	}
+
+	switch data[2] {
+	case modeNormal:
+		p.status = gpsdo.Locked
+	case modePowerUp:
+		p.status = gpsdo.PowerUp
+	case modeAutoHoldover, modeManualHoldover, modeRecovery:
+		p.status = gpsdo.Holdover
+	default:
+		p.status = gpsdo.Unknown
+	}
+	p.seen = true
+
+	mode, ok := receiverModes[data[1]]
+	if !ok {
+		mode = fmt.Sprintf("mode %d", data[1])
+	}
+	minor := binary.BigEndian.Uint16(data[10:12])
+	p.diagnostics.Position = &gpsdo.Position{
+		// Latitude and longitude are in radians
+		Latitude:  double(data[36:44]) * 180 / math.Pi,
+		Longitude: double(data[44:52]) * 180 / math.Pi,
+		Altitude:  double(data[52:60]),
+		Mode:      mode,
+		Hold:      data[1] == receiverModeHold,
+		Surveying: minor&minorSurvey != 0,
+		Progress:  float64(data[3]),
+	}
+	p.updated = true
+	return nil
+}
+
+// double decodes a big-endian IEEE 754 double
+func double(b []byte) float64 {
+	return math.Float64frombits(binary.BigEndian.Uint64(b))
+}
+
+// parsePrimary decodes 8F-AB primary timing
+func (p *Parser) parsePrimary(data []byte) (*gpsdo.Sample, error) {
+	if len(data) < 17 {
+		return nil, fmt.Errorf("%w: 8F-AB length %d", ErrFormat, len(data))
+	}
+
+	utcOffset := int(int16(binary.BigEndian.Uint16(data[7:9])))
+	flags := data[9]
+	second := int(data[10])
+	minute := int(data[11])
+	hour := int(data[12])
+	day := int(data[13])
+	month := int(data[14])
+	year := int(binary.BigEndian.Uint16(data[15:17]))
+
+	if month < 1 || month > 12 || day < 1 || day > 31 || hour > 23 || minute > 59 || second > 60 {
+		return nil, fmt.Errorf("%w: 8F-AB %04d-%02d-%02d %02d:%02d:%02d", ErrRange, year, month, day, hour, minute, second)
+	}
+
+	// time.Date normalizes days past the end of the month, 31 Feb to 3 Mar.
+	// Check the date alone so a leap second does not roll into the next day.
+	if time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC).Day() != day {
+		return nil, fmt.Errorf("%w: 8F-AB %04d-%02d-%02d", ErrRange, year, month, day)
+	}
+
+	timestamp := time.Date(year, time.Month(month), day, hour, minute, second, 0, time.UTC)
+	if flags&flagUTCTime == 0 {
+		// Reported in GPS time
+		timestamp = timestamp.Add(-time.Duration(utcOffset) * time.Second)
+	}
+
+	status := gpsdo.Unknown
+	if p.seen {
+		status = p.status
+	}
+	if flags&(flagTimeNotSet|flagNoUTCInfo) != 0 {
+		status = gpsdo.Unknown
+	}
+
+	return &gpsdo.Sample{
+		Year:        timestamp.Year(),
+		DayOfYear:   timestamp.YearDay(),
+		Hour:        timestamp.Hour(),
+		Minute:      timestamp.Minute(),
+		Second:      timestamp.Second(),
+		LeapSeconds: utcOffset,
+		Status:      status,
+		Valid:       status == gpsdo.Locked || status == gpsdo.Holdover,
+		Timestamp:   timestamp,
+		ParseTime:   time.Now(),
+	}, nil
+}
@@ -0,0 +1,290 @@
+package tsip
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// decode feeds the framed stream to a Decoder and returns copies of the
+// packets and the errors in order
+func decode(stream []byte) ([][]byte, []error) {
+	var d Decoder
+	var packets [][]byte
+	var errs []error
+	for _, c := range stream {
+		packet, err := d.Feed(c)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if packet != nil {
+			packets = append(packets, bytes.Clone(packet))
+		}
+	}
+	return packets, errs
+}
+
+// primary is an 8F-AB packet, without its 0x8F ID, for the given time
+func primary(utcOffset int16, flags byte, year, month, day, hour, minute, second int) []byte {
+	data := make([]byte, 17)
+	data[0] = subPrimaryTiming
+	binary.BigEndian.PutUint16(data[7:9], uint16(utcOffset))
+	data[9] = flags
+	data[10], data[11], data[12] = byte(second), byte(minute), byte(hour)
+	data[13], data[14] = byte(day), byte(month)
+	binary.BigEndian.PutUint16(data[15:17], uint16(year))
+	return data
+}
+
+// supplemental is an 8F-AC packet, without its 0x8F ID
+func supplemental(receiverMode, disciplining, progress byte, minor uint16, lat, lon, alt float64) []byte {
+	data := make([]byte, 68)
+	data[0] = subSuppTiming
+	data[1], data[2], data[3] = receiverMode, disciplining, progress
+	binary.BigEndian.PutUint16(data[10:12], minor)
+	binary.BigEndian.PutUint64(data[36:44], math.Float64bits(lat*math.Pi/180))
+	binary.BigEndian.PutUint64(data[44:52], math.Float64bits(lon*math.Pi/180))
+	binary.BigEndian.PutUint64(data[52:60], math.Float64bits(alt))
+	return data
+}
+
+// parse frames each packet with Encode, decodes the stream and parses every
+// packet, returning the last sample
+func parse(t *testing.T, p *Parser, packets ...[]byte) (*gpsdo.Sample, error) {
+	t.Helper()
+	var stream []byte
+	for _, packet := range packets {
+		stream = append(stream, Encode(packet[0], packet[1:])...)
+	}
+	decoded, errs := decode(stream)
+	if len(errs) > 0 {
+		t.Fatalf("decode: %v", errs)
+	}
+	if len(decoded) != len(packets) {
+		t.Fatalf("decoded %d packets, want %d", len(decoded), len(packets))
+	}
+
+	var sample *gpsdo.Sample
+	var err error
+	for _, packet := range decoded {
+		sample, err = p.Parse(packet)
+	}
+	return sample, err
+}
+
+func TestDecoderStuffing(t *testing.T) {
+	want := []byte{idSuperpacket, DLE, ETX, DLE, DLE, 0x42}
+	stream := Encode(want[0], want[1:])
+	if !bytes.Equal(stream, []byte{DLE, idSuperpacket, DLE, DLE, ETX, DLE, DLE, DLE, DLE, 0x42, DLE, ETX}) {
+		t.Fatalf("Encode = % X", stream)
+	}
+
+	packets, errs := decode(stream)
+	if len(errs) > 0 || len(packets) != 1 || !bytes.Equal(packets[0], want) {
+		t.Errorf("decoded %X, %v, want [% X]", packets, errs, want)
+	}
+}
+
+func TestDecoderFraming(t *testing.T) {
+	whole := Encode(idVersion, make([]byte, 10))
+
+	for _, tt := range []struct {
+		name   string
+		stream []byte
+	}{
+		{"cut short", append([]byte{DLE, idSuperpacket, subPrimaryTiming, 1, 2}, whole...)},
+		{"oversized", append(append([]byte{DLE, idSuperpacket}, make([]byte, maxPacketLen)...), whole...)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			packets, errs := decode(tt.stream)
+			if len(errs) != 1 || !errors.Is(errs[0], ErrFraming) {
+				t.Errorf("errors = %v, want one ErrFraming", errs)
+			}
+			if len(packets) != 1 || packets[0][0] != idVersion {
+				t.Errorf("packets = %X, want the 0x45 packet after the lost one", packets)
+			}
+		})
+	}
+}
+
+func TestParsePrimary(t *testing.T) {
+	locked := append([]byte{idSuperpacket}, supplemental(receiverModeHold, modeNormal, 100, 0, 0, 0, 0)...)
+	holdover := append([]byte{idSuperpacket}, supplemental(receiverModeHold, modeAutoHoldover, 100, 0, 0, 0, 0)...)
+
+	for _, tt := range []struct {
+		name   string
+		before [][]byte
+		data   []byte
+		want   time.Time
+		status gpsdo.Status
+		valid  bool
+	}{
+		{
+			// A UTC offset of 16 and second 16 are stuffed DLE bytes
+			name:   "GPS time",
+			data:   primary(16, 0, 2025, 3, 14, 12, 0, 16),
+			want:   time.Date(2025, time.March, 14, 12, 0, 0, 0, time.UTC),
+			status: gpsdo.Unknown,
+		},
+		{
+			name:   "UTC time",
+			data:   primary(18, flagUTCTime, 2025, 3, 14, 12, 0, 16),
+			want:   time.Date(2025, time.March, 14, 12, 0, 16, 0, time.UTC),
+			status: gpsdo.Unknown,
+		},
+		{
+			name:   "locked",
+			before: [][]byte{locked},
+			data:   primary(18, flagUTCTime, 2025, 3, 14, 12, 0, 0),
+			want:   time.Date(2025, time.March, 14, 12, 0, 0, 0, time.UTC),
+			status: gpsdo.Locked,
+			valid:  true,
+		},
+		{
+			name:   "holdover",
+			before: [][]byte{holdover},
+			data:   primary(18, flagUTCTime, 2025, 3, 14, 12, 0, 0),
+			want:   time.Date(2025, time.March, 14, 12, 0, 0, 0, time.UTC),
+			status: gpsdo.Holdover,
+			valid:  true,
+		},
+		{
+			name:   "time not set",
+			before: [][]byte{locked},
+			data:   primary(18, flagUTCTime|flagTimeNotSet, 2025, 3, 14, 12, 0, 0),
+			want:   time.Date(2025, time.March, 14, 12, 0, 0, 0, time.UTC),
+			status: gpsdo.Unknown,
+		},
+		{
+			name:   "leap second",
+			data:   primary(18, flagUTCTime, 2016, 12, 31, 23, 59, 60),
+			want:   time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC),
+			status: gpsdo.Unknown,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Parser
+			packets := append(tt.before, append([]byte{idSuperpacket}, tt.data...))
+			sample, err := parse(t, &p, packets...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !sample.Timestamp.Equal(tt.want) {
+				t.Errorf("timestamp = %s, want %s", sample.Timestamp, tt.want)
+			}
+			if sample.Status != tt.status || sample.Valid != tt.valid {
+				t.Errorf("status = %s valid %t, want %s valid %t", sample.Status, sample.Valid, tt.status, tt.valid)
+			}
+		})
+	}
+}
+
+func TestParsePrimaryRange(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		data []byte
+	}{
+		{"month 13", primary(18, flagUTCTime, 2025, 13, 1, 0, 0, 0)},
+		{"day 0", primary(18, flagUTCTime, 2025, 1, 0, 0, 0, 0)},
+		{"hour 24", primary(18, flagUTCTime, 2025, 1, 1, 24, 0, 0)},
+		{"February 30", primary(18, flagUTCTime, 2024, 2, 30, 0, 0, 0)},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Parser
+			if _, err := p.Parse(append([]byte{idSuperpacket}, tt.data...)); !errors.Is(err, ErrRange) {
+				t.Errorf("Parse error = %v, want ErrRange", err)
+			}
+		})
+	}
+}
+
+func TestParseSupplemental(t *testing.T) {
+	var p Parser
+	packet := supplemental(receiverModeHold, modePowerUp, 42, minorSurvey, 51.5, -0.125, 45.5)
+	if _, err := parse(t, &p, append([]byte{idSuperpacket}, packet...)); err != nil {
+		t.Fatal(err)
+	}
+
+	diagnostics, updated := p.Diagnostics()
+	if !updated || diagnostics.Position == nil {
+		t.Fatal("Diagnostics not updated")
+	}
+	want := gpsdo.Position{
+		Latitude:  51.5,
+		Longitude: -0.125,
+		Altitude:  45.5,
+		Mode:      "overdetermined clock",
+		Hold:      true,
+		Surveying: true,
+		Progress:  42,
+	}
+	got := *diagnostics.Position
+	if math.Abs(got.Latitude-want.Latitude) > 1e-9 || math.Abs(got.Longitude-want.Longitude) > 1e-9 {
+		t.Errorf("position = %v, %v, want %v, %v", got.Latitude, got.Longitude, want.Latitude, want.Longitude)
+	}
+	got.Latitude, got.Longitude = want.Latitude, want.Longitude
+	if got != want {
+		t.Errorf("position = %+v, want %+v", got, want)
+	}
+	if _, updated := p.Diagnostics(); updated {
+		t.Error("Diagnostics updated twice by one packet")
+	}
+	if p.status != gpsdo.PowerUp {
+		t.Errorf("status = %s, want %s", p.status, gpsdo.PowerUp)
+	}
+}
+
+func TestIdentity(t *testing.T) {
+	version := []byte{idVersion, 3, 0, 5, 12, 9, 1, 16, 3, 1, 10}
+	manufacturing := []byte{idSuperpacket, subManufacturing, 0, 45, 0, 0, 0x10, 0x10}
+	firmware := append([]byte{idHardware, subFirmware, 0, 1, 2, 3, 0, 1, 1, 20, 3}, "abc"...)
+	hardware := append([]byte{idHardware, subHardwareVersion, 0, 0, 0x30, 0x39, 0, 0, 0, 0, 0, 0, 0, 11}, "Thunderbolt"...)
+
+	for _, tt := range []struct {
+		name   string
+		packet []byte
+		want   gpsdo.Identity
+	}{
+		{"0x45", version, gpsdo.Identity{Manufacturer: "Trimble", Firmware: "3.00, GPS core 1.16"}},
+		{"8F-41", manufacturing, gpsdo.Identity{Manufacturer: "Trimble", Serial: "45-4112"}},
+		{"1C-81", firmware, gpsdo.Identity{Manufacturer: "Trimble", Firmware: "1.2.3"}},
+		{"1C-83", hardware, gpsdo.Identity{Manufacturer: "Trimble", Model: "Thunderbolt", Serial: "12345"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Parser
+			if _, err := parse(t, &p, tt.packet); err != nil {
+				t.Fatal(err)
+			}
+			identity, identified := p.Identity()
+			if !identified || identity != tt.want {
+				t.Errorf("Identity = %+v, %t, want %+v", identity, identified, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseTruncated(t *testing.T) {
+	for _, tt := range []struct {
+		name   string
+		packet []byte
+	}{
+		{"8F-AB", append([]byte{idSuperpacket}, primary(18, 0, 2025, 1, 1, 0, 0, 0)[:16]...)},
+		{"8F-AC", append([]byte{idSuperpacket}, supplemental(0, 0, 0, 0, 0, 0, 0)[:67]...)},
+		{"8F-41", []byte{idSuperpacket, subManufacturing, 0, 45, 0, 0, 0}},
+		{"0x45", []byte{idVersion, 3, 0, 5, 12, 9, 1, 16, 3, 1}},
+		{"1C-81", []byte{idHardware, subFirmware, 0, 1, 2, 3, 0, 1, 1, 20, 3, 'a'}},
+		{"1C-83", []byte{idHardware, subHardwareVersion, 0, 0, 0x30, 0x39}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Parser
+			if _, err := p.Parse(tt.packet); !errors.Is(err, ErrFormat) {
+				t.Errorf("Parse error = %v, want ErrFormat", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,478 @@
+// Package ubx parses the u-blox UBX binary timing messages used by timing
+// modules such as the LEA-M8T and ZED-F9T.
+package ubx
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// Frame sync characters
+const (
+	Sync1 = 0xB5
+	Sync2 = 0x62
+)
+
+// maxPayloadLen bounds a frame when the length field is corrupted
+const maxPayloadLen = 1024
+
+// Message classes and IDs
+const (
+	classNAV = 0x01
+	classCFG = 0x06
+	classMON = 0x0A
+	classTIM = 0x0D
+	classSEC = 0x27
+
+	idCfgTMODE2 = 0x3D
+	idCfgVALSET = 0x8A
+	idMonVER    = 0x04
+	idSecUNIQID = 0x03
+
+	idNavPVT     = 0x07
+	idNavTimeUTC = 0x21
+	idTimTP      = 0x01
+	idTimSVIN    = 0x04
+)
+
+// NAV-PVT valid and flags bits
+const (
+	pvtValidDate     = 1 << 0
+	pvtValidTime     = 1 << 1
+	pvtFullyResolved = 1 << 2
+	pvtGNSSFixOK     = 1 << 0
+)
+
+// NAV-PVT fix types that provide usable time
+const (
+	fix2D   = 2
+	fix3D   = 3
+	fixDR   = 4
+	fixTime = 5
+)
+
+// fixTypes names the NAV-PVT fix types, a timing receiver in position hold
+// reports the time only fix
+var fixTypes = []string{"no fix", "dead reckoning", "2D", "3D", "GNSS and dead reckoning", "time only"}
+
+// Time modes of CFG-TMODE2 and the CFG-TMODE-MODE configuration item
+const (
+	tmodeSurveyIn = 1
+	tmodeFixed    = 2
+)
+
+// Survey-in limits: the survey ends once both the duration and accuracy
+// are reached
+const (
+	SurveyMinDuration = time.Hour
+	// SurveyAccuracy is in meters
+	SurveyAccuracy = 2
+)
+
+// Configuration items of the ZED-F9T and later, which replaced CFG-TMODE2
+const (
+	keyTmodeMode         = 0x20030001
+	keyTmodePosType      = 0x20030002
+	keyTmodeLat          = 0x40030009
+	keyTmodeLon          = 0x4003000A
+	keyTmodeHeight       = 0x4003000B
+	keyTmodeSvinMinDur   = 0x40030010
+	keyTmodeSvinAccLimit = 0x40030011
+)
+
+// NAV-TIMEUTC valid bits
+const timeUTCValidUTC = 1 << 2
+
+// TIM-TP flags bits
+const tpUTCAvailable = 1 << 1
+
+var (
+	// ErrChecksum is returned when a frame checksum does not match
+	ErrChecksum = errors.New("ubx: checksum mismatch")
+	// ErrFormat is returned for messages that are too short to decode
+	ErrFormat = errors.New("ubx: malformed message")
+	// ErrRange is returned for messages with a date or time out of range
+	ErrRange = errors.New("ubx: field out of range")
+)
+
+// Message is a single UBX frame
+type Message struct {
+	Class   byte
+	ID      byte
+	Payload []byte
+}
+
+// Checksum computes the 8-bit Fletcher checksum over class, ID, length and
+// payload
+func Checksum(data []byte) (byte, byte) {
+	var a, b byte
+	for _, c := range data {
+		a += c
+		b += a
+	}
+	return a, b
+}
+
+// Encode frames a message with its checksum
+func Encode(class, id byte, payload []byte) []byte {
+	frame := []byte{Sync1, Sync2, class, id, 0, 0}
+	binary.LittleEndian.PutUint16(frame[4:6], uint16(len(payload)))
+	frame = append(frame, payload...)
+	a, b := Checksum(frame[2:])
+	return append(frame, a, b)
+}
+
+// SurveyCommand starts a new survey-in, as CFG-TMODE2 for the LEA-M8T and
+// as configuration items for the ZED-F9T, each receiver rejecting the other
+func SurveyCommand() []byte {
+	var tmode2 [28]byte
+	tmode2[0] = tmodeSurveyIn
+	binary.LittleEndian.PutUint32(tmode2[20:24], uint32(SurveyMinDuration.Seconds()))
+	binary.LittleEndian.PutUint32(tmode2[24:28], SurveyAccuracy*1000)
+
+	return append(Encode(classCFG, idCfgTMODE2, tmode2[:]), valset(
+		item{keyTmodeMode, 1, tmodeSurveyIn},
+		item{keyTmodeSvinMinDur, 4, uint32(SurveyMinDuration.Seconds())},
+		item{keyTmodeSvinAccLimit, 4, SurveyAccuracy * 10000},
+	)...)
+}
+
+// PositionCommand sets the fixed position mode with the position p, as
+// CFG-TMODE2 and as configuration items like SurveyCommand
+func PositionCommand(p gpsdo.Position) []byte {
+	lat := uint32(int32(math.Round(p.Latitude * 1e7)))
+	lon := uint32(int32(math.Round(p.Longitude * 1e7)))
+	// Centimeters
+	height := uint32(int32(math.Round(p.Altitude * 100)))
+
+	var tmode2 [28]byte
+	tmode2[0] = tmodeFixed
+	// Latitude, longitude and altitude rather than ECEF coordinates
+	tmode2[2] = 1
+	binary.LittleEndian.PutUint32(tmode2[4:8], lat)
+	binary.LittleEndian.PutUint32(tmode2[8:12], lon)
+	binary.LittleEndian.PutUint32(tmode2[12:16], height)
+
+	return append(Encode(classCFG, idCfgTMODE2, tmode2[:]), valset(
+		item{keyTmodeMode, 1, tmodeFixed},
+		item{keyTmodePosType, 1, 1},
+		item{keyTmodeLat, 4, lat},
+		item{keyTmodeLon, 4, lon},
+		item{keyTmodeHeight, 4, height},
+	)...)
+}
+
+// item is a configuration item of CFG-VALSET with its size in bytes
+type item struct {
+	key   uint32
+	size  int
+	value uint32
+}
+
+// valset encodes CFG-VALSET applying items to the RAM and battery-backed
+// layers
+func valset(items ...item) []byte {
+	payload := []byte{0, 0x01 | 0x02, 0, 0}
+	for _, it := range items {
+		payload = binary.LittleEndian.AppendUint32(payload, it.key)
+		if it.size == 1 {
+			payload = append(payload, byte(it.value))
+		} else {
+			payload = binary.LittleEndian.AppendUint32(payload, it.value)
+		}
+	}
+	return Encode(classCFG, idCfgVALSET, payload)
+}
+
+// IdentityQuery polls MON-VER for the versions and module, and SEC-UNIQID
+// for the chip ID, which serves as the serial number
+func IdentityQuery() []byte {
+	return append(Encode(classMON, idMonVER, nil), Encode(classSEC, idSecUNIQID, nil)...)
+}
+
+// Decoder extracts UBX frames from a byte stream
+type Decoder struct {
+	buf []byte
+}
+
+// Feed consumes one byte of the stream. It returns a message when the byte
+// completes a frame with a valid checksum, and ErrChecksum for a corrupted
+// frame.
+func (d *Decoder) Feed(c byte) (*Message, error) {
+	switch len(d.buf) {
+	case 0:
+		if c == Sync1 {
+			d.buf = append(d.buf, c)
+		}
+		return nil, nil
+	case 1:
+		if c != Sync2 {
+			d.buf = d.buf[:0]
+			return d.Feed(c)
+		}
+	}
+	d.buf = append(d.buf, c)
+
+	if len(d.buf) < 6 {
+		return nil, nil
+	}
+	length := int(binary.LittleEndian.Uint16(d.buf[4:6]))
+	if length > maxPayloadLen {
+		d.buf = d.buf[:0]
+		return nil, nil
+	}
+	if len(d.buf) < 6+length+2 {
+		return nil, nil
+	}
+
+	frame := d.buf
+	d.buf = d.buf[:0]
+
+	a, b := Checksum(frame[2 : 6+length])
+	if a != frame[6+length] || b != frame[7+length] {
+		return nil, ErrChecksum
+	}
+
+	payload := make([]byte, length)
+	copy(payload, frame[6:6+length])
+	return &Message{Class: frame[2], ID: frame[3], Payload: payload}, nil
+}
+
+// Parser decodes UBX timing messages. NAV-PVT fix status and TIM-TP UTC
+// availability are remembered and applied to later samples, and an epoch
+// already reported by one message is not reported again by another. The
+// position of NAV-PVT and the survey-in state of TIM-SVIN are returned by
+// Diagnostics.
+type Parser struct {
+	sawPVT bool
+	fixOK  bool
+	sawTP  bool
+	tpUTC  bool
+	last   time.Time
+
+	position gpsdo.Position
+	sawSVIN  bool
+	updated  bool
+
+	identity   gpsdo.Identity
+	identified bool
+}
+
+// Identity returns the identity from MON-VER and SEC-UNIQID and whether
+// either was decoded since the last call
+func (p *Parser) Identity() (gpsdo.Identity, bool) {
+	identified := p.identified
+	p.identified = false
+	return p.identity, identified
+}
+
+// Diagnostics returns the position and survey-in state and whether they
+// were updated since the last call
+func (p *Parser) Diagnostics() (gpsdo.Diagnostics, bool) {
+	updated := p.updated
+	p.updated = false
+	if !p.sawPVT {
+		return gpsdo.Diagnostics{}, updated
+	}
+	position := p.position
+	return gpsdo.Diagnostics{Position: &position}, updated
+}
+
+// Parse decodes one message. Messages that do not produce a sample return
+// a nil sample and nil error.
+func (p *Parser) Parse(msg *Message) (*gpsdo.Sample, error) {
+	var timestamp time.Time
+	var status gpsdo.Status
+	var err error
+
+	switch {
+	case msg.Class == classNAV && msg.ID == idNavPVT:
+		timestamp, status, err = p.parsePVT(msg.Payload)
+	case msg.Class == classNAV && msg.ID == idNavTimeUTC:
+		timestamp, status, err = p.parseTimeUTC(msg.Payload)
+	case msg.Class == classTIM && msg.ID == idTimTP:
+		return nil, p.parseTimTP(msg.Payload)
+	case msg.Class == classTIM && msg.ID == idTimSVIN:
+		return nil, p.parseSVIN(msg.Payload)
+	case msg.Class == classMON && msg.ID == idMonVER:
+		return nil, p.parseVersion(msg.Payload)
+	case msg.Class == classSEC && msg.ID == idSecUNIQID:
+		return nil, p.parseUniqueID(msg.Payload)
+	default:
+		return nil, nil
+	}
+	if err != nil || timestamp.IsZero() {
+		return nil, err
+	}
+
+	if p.sawTP && !p.tpUTC {
+		status = gpsdo.Unknown
+	}
+
+	if timestamp.Equal(p.last) {
+		return nil, nil
+	}
+	p.last = timestamp
+
+	return &gpsdo.Sample{
+		Year:      timestamp.Year(),
+		DayOfYear: timestamp.YearDay(),
+		Hour:      timestamp.Hour(),
+		Minute:    timestamp.Minute(),
+		Second:    timestamp.Second(),
+		Status:    status,
+		Valid:     status == gpsdo.Locked,
+		Timestamp: timestamp,
+		ParseTime: time.Now(),
+	}, nil
+}
+
+// parsePVT decodes UBX-NAV-PVT
+func (p *Parser) parsePVT(data []byte) (time.Time, gpsdo.Status, error) {
+	if len(data) < 92 {
+		return time.Time{}, gpsdo.Unknown, fmt.Errorf("%w: NAV-PVT length %d", ErrFormat, len(data))
+	}
+
+	valid := data[11]
+	fixType := data[20]
+	flags := data[21]
+
+	p.sawPVT = true
+	p.fixOK = flags&pvtGNSSFixOK != 0 &&
+		(fixType == fix2D || fixType == fix3D || fixType == fixDR || fixType == fixTime)
+
+	p.position.Mode = fmt.Sprintf("fix type %d", fixType)
+	if int(fixType) < len(fixTypes) {
+		p.position.Mode = fixTypes[fixType]
+	}
+	p.position.Hold = fixType == fixTime
+	p.position.Longitude = float64(int32(binary.LittleEndian.Uint32(data[24:28]))) * 1e-7
+	p.position.Latitude = float64(int32(binary.LittleEndian.Uint32(data[28:32]))) * 1e-7
+	p.position.Altitude = float64(int32(binary.LittleEndian.Uint32(data[36:40]))) / 1000
+	if !p.sawSVIN {
+		p.position.Progress = -1
+	}
+	p.updated = true
+
+	if valid&(pvtValidDate|pvtValidTime|pvtFullyResolved) != pvtValidDate|pvtValidTime|pvtFullyResolved {
+		return time.Time{}, gpsdo.Unknown, nil
+	}
+
+	status := gpsdo.Unknown
+	if p.fixOK {
+		status = gpsdo.Locked
+	}
+	nano := int32(binary.LittleEndian.Uint32(data[16:20]))
+	timestamp, err := buildTime("NAV-PVT", data[4:11], nano)
+	return timestamp, status, err
+}
+
+// parseTimeUTC decodes UBX-NAV-TIMEUTC
+func (p *Parser) parseTimeUTC(data []byte) (time.Time, gpsdo.Status, error) {
+	if len(data) < 20 {
+		return time.Time{}, gpsdo.Unknown, fmt.Errorf("%w: NAV-TIMEUTC length %d", ErrFormat, len(data))
+	}
+	if data[19]&timeUTCValidUTC == 0 {
+		return time.Time{}, gpsdo.Unknown, nil
+	}
+
+	status := gpsdo.Locked
+	if p.sawPVT && !p.fixOK {
+		status = gpsdo.Unknown
+	}
+	nano := int32(binary.LittleEndian.Uint32(data[8:12]))
+	timestamp, err := buildTime("NAV-TIMEUTC", data[12:19], nano)
+	return timestamp, status, err
+}
+
+// parseTimTP decodes UBX-TIM-TP, which describes the next time pulse
+func (p *Parser) parseTimTP(data []byte) error {
+	if len(data) < 16 {
+		return fmt.Errorf("%w: TIM-TP length %d", ErrFormat, len(data))
+	}
+	p.sawTP = true
+	p.tpUTC = data[14]&tpUTCAvailable != 0
+	return nil
+}
+
+// parseSVIN decodes UBX-TIM-SVIN, the state of the survey-in. The progress
+// is unknown as the survey ends on both a minimum duration and accuracy.
+func (p *Parser) parseSVIN(data []byte) error {
+	if len(data) < 28 {
+		return fmt.Errorf("%w: TIM-SVIN length %d", ErrFormat, len(data))
+	}
+	p.sawSVIN = true
+	p.position.Surveying = data[25] != 0
+	p.position.Progress = -1
+	if data[24] != 0 {
+		p.position.Progress = 100
+	}
+	p.updated = true
+	return nil
+}
+
+// parseVersion decodes UBX-MON-VER: the software and hardware versions
+// followed by extensions such as FWVER=TIM 2.20 and MOD=ZED-F9T, each a
+// NUL-padded string
+func (p *Parser) parseVersion(data []byte) error {
+	if len(data) < 40 || (len(data)-40)%30 != 0 {
+		return fmt.Errorf("%w: MON-VER length %d", ErrFormat, len(data))
+	}
+	text := func(b []byte) string {
+		return strings.TrimRight(string(b), "\x00 ")
+	}
+
+	p.identity.Manufacturer = "u-blox"
+	p.identity.Firmware = text(data[0:30])
+	for ext := data[40:]; len(ext) > 0; ext = ext[30:] {
+		key, value, _ := strings.Cut(text(ext[:30]), "=")
+		switch key {
+		case "FWVER":
+			p.identity.Firmware = value
+		case "MOD":
+			p.identity.Model = value
+		}
+	}
+	p.identified = true
+	return nil
+}
+
+// parseUniqueID decodes UBX-SEC-UNIQID, the chip ID of 5 bytes, 6 from
+// message version 2
+func (p *Parser) parseUniqueID(data []byte) error {
+	if len(data) < 9 {
+		return fmt.Errorf("%w: SEC-UNIQID length %d", ErrFormat, len(data))
+	}
+	p.identity.Manufacturer = "u-blox"
+	p.identity.Serial = fmt.Sprintf("%X", data[4:min(len(data), 10)])
+	p.identified = true
+	return nil
+}
+
+// buildTime converts the year(u16) month day hour min sec layout shared by
+// NAV-PVT and NAV-TIMEUTC
+func buildTime(name string, data []byte, nano int32) (time.Time, error) {
+	year := int(binary.LittleEndian.Uint16(data[0:2]))
+	month, day := int(data[2]), int(data[3])
+	hour, minute, second := int(data[4]), int(data[5]), int(data[6])
+
+	if month < 1 || month > 12 || day < 1 || day > 31 || hour > 23 || minute > 59 || second > 60 ||
+		nano <= -1e9 || nano >= 1e9 {
+		return time.Time{}, fmt.Errorf("%w: %s %04d-%02d-%02d %02d:%02d:%02d", ErrRange, name,
+			year, month, day, hour, minute, second)
+	}
+	// time.Date normalizes days past the end of the month, 31 Feb to 3 Mar.
+	// Check the date alone so a leap second does not roll into the next day.
+	if time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC).Day() != day {
+		return time.Time{}, fmt.Errorf("%w: %s %04d-%02d-%02d", ErrRange, name, year, month, day)
+	}
+
+	return time.Date(year, time.Month(month), day, hour, minute, second, 0, time.UTC).
+		Add(time.Duration(nano)), nil
+}
@@ -0,0 +1,369 @@
+package ubx
+
+import (
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// decode feeds the stream to a Decoder and returns the messages and the
+// errors in order
+func decode(stream []byte) ([]*Message, []error) {
+	var d Decoder
+	var messages []*Message
+	var errs []error
+	for _, c := range stream {
+		msg, err := d.Feed(c)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if msg != nil {
+			messages = append(messages, msg)
+		}
+	}
+	return messages, errs
+}
+
+// putTime writes the year(u16) month day hour min sec layout of t
+func putTime(data []byte, t time.Time) {
+	binary.LittleEndian.PutUint16(data[0:2], uint16(t.Year()))
+	data[2], data[3] = byte(t.Month()), byte(t.Day())
+	data[4], data[5], data[6] = byte(t.Hour()), byte(t.Minute()), byte(t.Second())
+}
+
+// navPVT is a NAV-PVT message at t with the valid bits, fix type and flags
+func navPVT(t time.Time, nano int32, valid, fixType, flags byte) *Message {
+	data := make([]byte, 92)
+	putTime(data[4:11], t)
+	data[11] = valid
+	binary.LittleEndian.PutUint32(data[16:20], uint32(nano))
+	data[20], data[21] = fixType, flags
+	lon, lat := int32(-1250000), int32(515000000)
+	binary.LittleEndian.PutUint32(data[24:28], uint32(lon))
+	binary.LittleEndian.PutUint32(data[28:32], uint32(lat))
+	binary.LittleEndian.PutUint32(data[36:40], 45500)
+	return &Message{Class: classNAV, ID: idNavPVT, Payload: data}
+}
+
+// timeUTC is a NAV-TIMEUTC message at t with the valid bits
+func timeUTC(t time.Time, nano int32, valid byte) *Message {
+	data := make([]byte, 20)
+	binary.LittleEndian.PutUint32(data[8:12], uint32(nano))
+	putTime(data[12:19], t)
+	data[19] = valid
+	return &Message{Class: classNAV, ID: idNavTimeUTC, Payload: data}
+}
+
+// timTP is a TIM-TP message with the UTC available flag
+func timTP(utc bool) *Message {
+	data := make([]byte, 16)
+	if utc {
+		data[14] = tpUTCAvailable
+	}
+	return &Message{Class: classTIM, ID: idTimTP, Payload: data}
+}
+
+const pvtValid = pvtValidDate | pvtValidTime | pvtFullyResolved
+
+func TestParse(t *testing.T) {
+	at := time.Date(2025, time.March, 14, 12, 0, 10, 0, time.UTC)
+	later := at.Add(time.Second)
+
+	for _, tt := range []struct {
+		name     string
+		messages []*Message
+		want     time.Time
+		status   gpsdo.Status
+	}{
+		{
+			name:     "NAV-PVT time only fix",
+			messages: []*Message{navPVT(at, 0, pvtValid, fixTime, pvtGNSSFixOK)},
+			want:     at,
+			status:   gpsdo.Locked,
+		},
+		{
+			name:     "NAV-PVT negative nanoseconds",
+			messages: []*Message{navPVT(at, -250000000, pvtValid, fixTime, pvtGNSSFixOK)},
+			want:     at.Add(-250 * time.Millisecond),
+			status:   gpsdo.Locked,
+		},
+		{
+			name:     "NAV-PVT without a fix",
+			messages: []*Message{navPVT(at, 0, pvtValid, 0, 0)},
+			want:     at,
+			status:   gpsdo.Unknown,
+		},
+		{
+			name:     "NAV-PVT not fully resolved",
+			messages: []*Message{navPVT(at, 0, pvtValidDate|pvtValidTime, fixTime, pvtGNSSFixOK)},
+		},
+		{
+			name:     "NAV-TIMEUTC",
+			messages: []*Message{timeUTC(at, 500, timeUTCValidUTC)},
+			want:     at.Add(500),
+			status:   gpsdo.Locked,
+		},
+		{
+			name:     "NAV-TIMEUTC invalid",
+			messages: []*Message{timeUTC(at, 0, 0)},
+		},
+		{
+			name:     "NAV-TIMEUTC after NAV-PVT without a fix",
+			messages: []*Message{navPVT(at, 0, pvtValid, 0, 0), timeUTC(later, 0, timeUTCValidUTC)},
+			want:     later,
+			status:   gpsdo.Unknown,
+		},
+		{
+			name:     "same epoch reported twice",
+			messages: []*Message{navPVT(at, 0, pvtValid, fixTime, pvtGNSSFixOK), timeUTC(at, 0, timeUTCValidUTC)},
+		},
+		{
+			name:     "TIM-TP without UTC",
+			messages: []*Message{timTP(false), navPVT(at, 0, pvtValid, fixTime, pvtGNSSFixOK)},
+			want:     at,
+			status:   gpsdo.Unknown,
+		},
+		{
+			name:     "TIM-TP with UTC",
+			messages: []*Message{timTP(true), navPVT(at, 0, pvtValid, fixTime, pvtGNSSFixOK)},
+			want:     at,
+			status:   gpsdo.Locked,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Parser
+			var sample *gpsdo.Sample
+			for _, msg := range tt.messages {
+				var err error
+				if sample, err = p.Parse(msg); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			switch {
+			case tt.want.IsZero():
+				if sample != nil {
+					t.Errorf("sample at %s, want none", sample.Timestamp)
+				}
+			case sample == nil:
+				t.Errorf("no sample, want %s", tt.want)
+			default:
+				if !sample.Timestamp.Equal(tt.want) {
+					t.Errorf("timestamp = %s, want %s", sample.Timestamp, tt.want)
+				}
+				if sample.Status != tt.status || sample.Valid != (tt.status == gpsdo.Locked) {
+					t.Errorf("status = %s valid %t, want %s", sample.Status, sample.Valid, tt.status)
+				}
+			}
+		})
+	}
+}
+
+func TestParseRange(t *testing.T) {
+	at := time.Date(2024, time.February, 29, 0, 0, 0, 0, time.UTC)
+	pvt := func() *Message { return navPVT(at, 0, pvtValid, fixTime, pvtGNSSFixOK) }
+	utc := func() *Message { return timeUTC(at, 0, timeUTCValidUTC) }
+
+	for _, tt := range []struct {
+		name  string
+		msg   *Message
+		field int
+		value byte
+	}{
+		{"NAV-PVT month 0", pvt(), 6, 0},
+		{"NAV-PVT second 61", pvt(), 10, 61},
+		{"NAV-TIMEUTC hour 25", utc(), 16, 25},
+		{"NAV-TIMEUTC February 30", utc(), 15, 30},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.msg.Payload[tt.field] = tt.value
+			var p Parser
+			if _, err := p.Parse(tt.msg); !errors.Is(err, ErrRange) {
+				t.Errorf("Parse error = %v, want ErrRange", err)
+			}
+		})
+	}
+}
+
+func TestParseLeapSecond(t *testing.T) {
+	msg := timeUTC(time.Date(2016, time.December, 31, 23, 59, 0, 0, time.UTC), 0, timeUTCValidUTC)
+	msg.Payload[18] = 60
+
+	var p Parser
+	sample, err := p.Parse(msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2017, time.January, 1, 0, 0, 0, 0, time.UTC); !sample.Timestamp.Equal(want) {
+		t.Errorf("timestamp = %s, want %s", sample.Timestamp, want)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	var p Parser
+	if _, err := p.Parse(navPVT(time.Date(2025, time.March, 14, 0, 0, 0, 0, time.UTC), 0, pvtValid, fixTime, pvtGNSSFixOK)); err != nil {
+		t.Fatal(err)
+	}
+	diagnostics, updated := p.Diagnostics()
+	if !updated || diagnostics.Position == nil {
+		t.Fatal("Diagnostics not updated by NAV-PVT")
+	}
+	want := gpsdo.Position{Latitude: 51.5, Longitude: -0.125, Altitude: 45.5, Mode: "time only", Hold: true, Progress: -1}
+	if got := *diagnostics.Position; got != want {
+		t.Errorf("position = %+v, want %+v", got, want)
+	}
+
+	for _, tt := range []struct {
+		name      string
+		valid     byte
+		active    byte
+		surveying bool
+		progress  float64
+	}{
+		{"surveying", 0, 1, true, -1},
+		{"surveyed", 1, 0, false, 100},
+	} {
+		data := make([]byte, 28)
+		data[24], data[25] = tt.valid, tt.active
+		if _, err := p.Parse(&Message{Class: classTIM, ID: idTimSVIN, Payload: data}); err != nil {
+			t.Fatal(err)
+		}
+		diagnostics, updated := p.Diagnostics()
+		if !updated || diagnostics.Position.Surveying != tt.surveying || diagnostics.Position.Progress != tt.progress {
+			t.Errorf("%s: position = %+v, updated %t", tt.name, diagnostics.Position, updated)
+		}
+	}
+}
+
+func TestParseTruncated(t *testing.T) {
+	for _, msg := range []*Message{
+		{Class: classNAV, ID: idNavPVT, Payload: make([]byte, 91)},
+		{Class: classNAV, ID: idNavTimeUTC, Payload: make([]byte, 19)},
+		{Class: classTIM, ID: idTimTP, Payload: make([]byte, 15)},
+		{Class: classTIM, ID: idTimSVIN, Payload: make([]byte, 27)},
+		{Class: classMON, ID: idMonVER, Payload: make([]byte, 39)},
+		{Class: classSEC, ID: idSecUNIQID, Payload: make([]byte, 8)},
+	} {
+		var p Parser
+		if _, err := p.Parse(msg); !errors.Is(err, ErrFormat) {
+			t.Errorf("0x%02X 0x%02X: Parse error = %v, want ErrFormat", msg.Class, msg.ID, err)
+		}
+	}
+}
+
+func TestDecoderChecksum(t *testing.T) {
+	msg := timTP(true)
+	corrupted := Encode(msg.Class, msg.ID, msg.Payload)
+	corrupted[len(corrupted)-1] ^= 0xFF
+	// Noise before the frame is skipped
+	stream := append([]byte{0x00, Sync1, 0x00}, corrupted...)
+	stream = append(stream, Encode(msg.Class, msg.ID, msg.Payload)...)
+
+	messages, errs := decode(stream)
+	if len(errs) != 1 || !errors.Is(errs[0], ErrChecksum) {
+		t.Errorf("errors = %v, want one ErrChecksum", errs)
+	}
+	if len(messages) != 1 || messages[0].Class != classTIM || messages[0].ID != idTimTP ||
+		messages[0].Payload[14] != tpUTCAvailable {
+		t.Errorf("messages = %+v, want the TIM-TP after the corrupted frame", messages)
+	}
+}
+
+// valsetItems decodes the configuration items of a CFG-VALSET payload
+func valsetItems(t *testing.T, payload []byte) map[uint32]uint32 {
+	t.Helper()
+	if len(payload) < 4 || payload[1] != 0x03 {
+		t.Fatalf("CFG-VALSET header % X", payload[:min(len(payload), 4)])
+	}
+	items := make(map[uint32]uint32)
+	for rest := payload[4:]; len(rest) > 0; {
+		key := binary.LittleEndian.Uint32(rest[0:4])
+		// Bits 28-30 of the key are the size, 2 for one byte
+		if key>>28&0x7 == 2 {
+			items[key] = uint32(rest[4])
+			rest = rest[5:]
+			continue
+		}
+		items[key] = binary.LittleEndian.Uint32(rest[4:8])
+		rest = rest[8:]
+	}
+	return items
+}
+
+// commands decodes a command into its CFG-TMODE2 payload and CFG-VALSET
+// items
+func commands(t *testing.T, command []byte) ([]byte, map[uint32]uint32) {
+	t.Helper()
+	messages, errs := decode(command)
+	if len(errs) > 0 || len(messages) != 2 {
+		t.Fatalf("decoded %d messages, errors %v, want 2", len(messages), errs)
+	}
+	tmode2, valset := messages[0], messages[1]
+	if tmode2.Class != classCFG || tmode2.ID != idCfgTMODE2 || len(tmode2.Payload) != 28 {
+		t.Fatalf("first message 0x%02X 0x%02X length %d, want CFG-TMODE2", tmode2.Class, tmode2.ID, len(tmode2.Payload))
+	}
+	if valset.Class != classCFG || valset.ID != idCfgVALSET {
+		t.Fatalf("second message 0x%02X 0x%02X, want CFG-VALSET", valset.Class, valset.ID)
+	}
+	return tmode2.Payload, valsetItems(t, valset.Payload)
+}
+
+func TestSurveyCommand(t *testing.T) {
+	tmode2, items := commands(t, SurveyCommand())
+
+	if tmode2[0] != tmodeSurveyIn {
+		t.Errorf("CFG-TMODE2 mode = %d, want %d", tmode2[0], tmodeSurveyIn)
+	}
+	if got := binary.LittleEndian.Uint32(tmode2[20:24]); got != 3600 {
+		t.Errorf("CFG-TMODE2 duration = %d s, want 3600", got)
+	}
+	// 0.1 mm in the configuration items, mm in CFG-TMODE2
+	if got := binary.LittleEndian.Uint32(tmode2[24:28]); got != 2000 {
+		t.Errorf("CFG-TMODE2 accuracy = %d mm, want 2000", got)
+	}
+
+	want := map[uint32]uint32{
+		keyTmodeMode:         tmodeSurveyIn,
+		keyTmodeSvinMinDur:   3600,
+		keyTmodeSvinAccLimit: 20000,
+	}
+	if len(items) != len(want) {
+		t.Errorf("CFG-VALSET items = %v, want %v", items, want)
+	}
+	for key, value := range want {
+		if items[key] != value {
+			t.Errorf("CFG-VALSET 0x%08X = %d, want %d", key, items[key], value)
+		}
+	}
+}
+
+func TestPositionCommand(t *testing.T) {
+	position := gpsdo.Position{Latitude: 51.5, Longitude: -0.125, Altitude: -12.34}
+	tmode2, items := commands(t, PositionCommand(position))
+
+	lat := int32(binary.LittleEndian.Uint32(tmode2[4:8]))
+	lon := int32(binary.LittleEndian.Uint32(tmode2[8:12]))
+	height := int32(binary.LittleEndian.Uint32(tmode2[12:16]))
+	if tmode2[0] != tmodeFixed || tmode2[2] != 1 || lat != 515000000 || lon != -1250000 || height != -1234 {
+		t.Errorf("CFG-TMODE2 mode %d lla %d position %d %d %d", tmode2[0], tmode2[2], lat, lon, height)
+	}
+
+	want := map[uint32]int32{
+		keyTmodeMode:    tmodeFixed,
+		keyTmodePosType: 1,
+		keyTmodeLat:     515000000,
+		keyTmodeLon:     -1250000,
+		keyTmodeHeight:  -1234,
+	}
+	if len(items) != len(want) {
+		t.Errorf("CFG-VALSET items = %v, want %v", items, want)
+	}
+	for key, value := range want {
+		if int32(items[key]) != value {
+			t.Errorf("CFG-VALSET 0x%08X = %d, want %d", key, int32(items[key]), value)
+		}
+	}
+}
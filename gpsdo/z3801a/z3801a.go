@@ -0,0 +1,138 @@
+// Package z3801a decodes the time code of the HP Z3801A and 58503A GPSDOs.
+// Unlike the Z3805A they have no time-of-day port: the time is read from the
+// SCPI port, at 19200 baud 7O1, with the :PTIME:TCODE? query, which returns
+//
+//	T2YYYYMMDDHHMMSSMFLRVcc
+//
+// M is the time figure of merit (TFOM) and F the frequency figure of merit
+// (FFOM), L the leap second indicator ('+', '-' or '0'), R the request for
+// service flag, V '0' when the time is valid and cc a checksum. The time is
+// that of the next 1PPS edge, the time code itself carries no sub-second
+// timing, so it is paired with the 1PPS output through a kernel PPS device.
+package z3801a
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+// QueryTimeCode requests the time code of the next 1PPS edge
+const QueryTimeCode = ":PTIME:TCODE?"
+
+// timeCodeLen is the length of a time code without line ending
+const timeCodeLen = 23
+
+// ErrFormat is returned for lines that are not a valid time code
+var ErrFormat = errors.New("z3801a: malformed time code")
+
+// IsTimeCode reports whether line, with any SCPI prompt, is a time code
+// rather than a command echo or another response
+func IsTimeCode(line string) bool {
+	_, ok := findTimeCode(line)
+	return ok
+}
+
+func findTimeCode(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	i := strings.LastIndex(line, "T2")
+	if i < 0 || len(line)-i != timeCodeLen {
+		return "", false
+	}
+	return line[i:], true
+}
+
+// TimeCode is a decoded time code
+type TimeCode struct {
+	// Next is the time of the next 1PPS edge
+	Next time.Time
+	// TFOM and FFOM are the time and frequency figures of merit
+	TFOM, FFOM byte
+	// Leap is the leap second announced for the end of the current day
+	Leap gpsdo.Leap
+	// Valid is the validity flag, false while the time is not trusted
+	Valid bool
+}
+
+// Decode decodes a time code response without interpreting its status
+func Decode(line string) (TimeCode, error) {
+	code, ok := findTimeCode(line)
+	if !ok {
+		return TimeCode{}, fmt.Errorf("%w: %q", ErrFormat, strings.TrimSpace(line))
+	}
+	next, err := time.Parse("20060102150405", code[2:16])
+	if err != nil {
+		return TimeCode{}, fmt.Errorf("%w: %q", ErrFormat, code)
+	}
+	tc := TimeCode{
+		Next:  next,
+		TFOM:  code[16],
+		FFOM:  code[17],
+		Valid: code[20] == '0',
+	}
+
+	// The indicator is set for the whole month, the leap is at its end
+	if next.AddDate(0, 0, 1).Month() != next.Month() {
+		switch code[18] {
+		case '+':
+			tc.Leap = gpsdo.LeapInsert
+		case '-':
+			tc.Leap = gpsdo.LeapDelete
+		}
+	}
+	return tc, nil
+}
+
+// FFOMStatus maps the frequency figure of merit to a status
+func FFOMStatus(ffom byte) (gpsdo.Status, error) {
+	switch ffom {
+	case '0', '1':
+		// PLL stabilized or stabilizing
+		return gpsdo.Locked, nil
+	case '2':
+		// PLL unlocked, in holdover
+		return gpsdo.Holdover, nil
+	case '3':
+		return gpsdo.PowerUp, nil
+	}
+	return gpsdo.Unknown, fmt.Errorf("%w: FFOM %q", ErrFormat, ffom)
+}
+
+// Sample returns the sample of the time code with the given status. It is
+// labelled with the 1PPS edge before the response, one second before the
+// edge the time code names, so it pairs with the most recent kernel PPS edge.
+func (tc TimeCode) Sample(status gpsdo.Status) *gpsdo.Sample {
+	timestamp := tc.Next.Add(-time.Second)
+	return &gpsdo.Sample{
+		Year:      timestamp.Year(),
+		DayOfYear: timestamp.YearDay(),
+		Hour:      timestamp.Hour(),
+		Minute:    timestamp.Minute(),
+		Second:    timestamp.Second(),
+		Leap:      tc.Leap,
+		Status:    status,
+		Valid:     status == gpsdo.Locked || status == gpsdo.Holdover,
+		Timestamp: timestamp,
+		ParseTime: time.Now(),
+	}
+}
+
+// Parse decodes a time code response, with the status taken from the FFOM
+// unless the time is flagged invalid
+func Parse(line string) (*gpsdo.Sample, error) {
+	tc, err := Decode(line)
+	if err != nil {
+		return nil, err
+	}
+	if !tc.Valid {
+		return tc.Sample(gpsdo.Unknown), nil
+	}
+	status, err := FFOMStatus(tc.FFOM)
+	if err != nil {
+		return nil, err
+	}
+	return tc.Sample(status), nil
+}
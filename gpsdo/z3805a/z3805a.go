@@ -0,0 +1,233 @@
+// Package z3805a parses the time-of-day packets sent by the HP Z3805A GPSDO
+// on its second serial port.
+package z3805a
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+)
+
+const (
+	// PacketLen is the length of a standard TOD packet including the
+	// terminator
+	PacketLen = 16
+	// Terminator is the final byte of a standard TOD packet
+	Terminator = 0x0D
+	// fieldsLen is the length of the time and status fields at the start of
+	// every packet
+	fieldsLen = 15
+)
+
+var (
+	// ErrFraming is returned when a terminator arrives before a full packet
+	ErrFraming = errors.New("z3805a: short packet")
+	// ErrRange is returned for packets with a field that isn't a plausible
+	// value
+	ErrRange = errors.New("z3805a: field out of range")
+)
+
+// Format is the framing of the TOD packets, which differs slightly between
+// firmware revisions. The time and status fields always start the packet,
+// anything between them and the terminator is ignored.
+type Format struct {
+	// Length is the packet length including the terminator, 0 for PacketLen
+	Length int `yaml:"length"`
+	// Terminator is the final byte of every packet, 0 for Terminator
+	Terminator byte `yaml:"terminator"`
+}
+
+// DefaultFormat is the packet of most Z3805A firmware
+var DefaultFormat = Format{Length: PacketLen, Terminator: Terminator}
+
+// withDefaults returns f with the zero settings replaced by the standard ones
+func (f Format) withDefaults() Format {
+	if f.Length == 0 {
+		f.Length = PacketLen
+	}
+	if f.Terminator == 0 {
+		f.Terminator = Terminator
+	}
+	return f
+}
+
+// Validate checks that packets of the format can be framed and decoded
+func (f Format) Validate() error {
+	f = f.withDefaults()
+	if f.Length < fieldsLen+1 {
+		return fmt.Errorf("length must be at least %d", fieldsLen+1)
+	}
+	// The terminator must not occur inside a packet
+	if f.Terminator <= 0x09 || f.Terminator == 0x10 {
+		return fmt.Errorf("terminator 0x%02X is a BCD digit or status flag", f.Terminator)
+	}
+	return nil
+}
+
+// field is a BCD field of the packet with the range of valid values
+type field struct {
+	name          string
+	offset, width int
+	min, max      int
+}
+
+// value decodes the field, returning an ErrRange error if a byte isn't a
+// BCD digit or the value is out of range
+func (f field) value(data []byte) (int, error) {
+	n := 0
+	for _, digit := range data[f.offset : f.offset+f.width] {
+		if digit > 9 {
+			return 0, fmt.Errorf("%w: %s digit 0x%02X", ErrRange, f.name, digit)
+		}
+		n = n*10 + int(digit)
+	}
+	if n < f.min || n > f.max {
+		return 0, fmt.Errorf("%w: %s %d", ErrRange, f.name, n)
+	}
+	return n, nil
+}
+
+// The time fields as documented, the year counts from 2000
+var (
+	fieldYear        = field{"year", 0, 2, 0, 99}
+	fieldDayOfYear   = field{"day of year", 2, 3, 1, 366}
+	fieldHour        = field{"hour", 5, 2, 0, 23}
+	fieldMinute      = field{"minute", 7, 2, 0, 59}
+	fieldSecond      = field{"second", 9, 2, 0, 59}
+	fieldLeapSeconds = field{"leap seconds", 11, 2, 0, 99}
+)
+
+// statusOffset is the offset of the two status bytes
+const statusOffset = 13
+
+// Decoder reframes TOD packets from a byte stream regardless of how reads are
+// chunked. The terminator never occurs inside a packet since every other byte
+// is a BCD digit or status flag, so a packet is simply the Length bytes
+// ending in a terminator. The zero Decoder decodes the DefaultFormat.
+type Decoder struct {
+	format Format
+	buf    []byte
+}
+
+// NewDecoder returns a decoder of packets of format f
+func NewDecoder(f Format) *Decoder {
+	return &Decoder{format: f}
+}
+
+// Feed consumes one byte of the stream. It returns the packet when the byte
+// is a terminator completing one, and ErrFraming when a terminator follows a
+// partial packet, such as when the bridge starts mid-packet. The returned
+// packet is only valid until the next call.
+func (d *Decoder) Feed(c byte) ([]byte, error) {
+	f := d.format.withDefaults()
+	if len(d.buf) == f.Length {
+		// Slide the window, the oldest byte cannot start a packet
+		copy(d.buf, d.buf[1:])
+		d.buf = d.buf[:f.Length-1]
+	}
+	d.buf = append(d.buf, c)
+
+	if c != f.Terminator {
+		return nil, nil
+	}
+
+	packet := d.buf
+	d.buf = d.buf[:0]
+	if len(packet) != f.Length {
+		return nil, ErrFraming
+	}
+	return packet, nil
+}
+
+// Parse decodes a single TOD packet of the DefaultFormat. It returns nil if
+// the packet is malformed or any field is out of range.
+func Parse(data []byte) *gpsdo.Sample {
+	sample, _ := DefaultFormat.Parse(data)
+	return sample
+}
+
+// Parse decodes a single TOD packet. It returns ErrFraming if the packet
+// isn't framed as f, and ErrRange if any field is out of range. Units
+// affected by the GPS week rollover report dates 1024 weeks in the past, see
+// gpsdo.Sample.FixRollover.
+func (f Format) Parse(data []byte) (*gpsdo.Sample, error) {
+	f = f.withDefaults()
+	if f.Length < fieldsLen+1 || len(data) != f.Length || data[f.Length-1] != f.Terminator {
+		return nil, ErrFraming
+	}
+
+	year, err1 := fieldYear.value(data)
+	dayOfYear, err2 := fieldDayOfYear.value(data)
+	hour, err3 := fieldHour.value(data)
+	minute, err4 := fieldMinute.value(data)
+	second, err5 := fieldSecond.value(data)
+	leapSeconds, err6 := fieldLeapSeconds.value(data)
+	// The first field out of range
+	if err := cmp.Or(err1, err2, err3, err4, err5, err6); err != nil {
+		return nil, err
+	}
+	year += 2000
+	statusVal := [2]byte{data[statusOffset], data[statusOffset+1]}
+
+	// Convert status to enum per Z3805A documentation
+	var status gpsdo.Status
+	switch statusVal {
+	case [2]byte{0x00, 0x00}:
+		status = gpsdo.Locked // GPS Lock Mode
+	case [2]byte{0x01, 0x00}:
+		status = gpsdo.PowerUp // Power-Up Mode
+	case [2]byte{0x10, 0x00}:
+		status = gpsdo.Holdover // Holdover Mode
+	default:
+		status = gpsdo.Unknown
+	}
+
+	// Day of year as a day of January, time.Date normalizes it to the date
+	timestamp := time.Date(year, time.January, dayOfYear, hour, minute, second, 0, time.UTC)
+
+	return &gpsdo.Sample{
+		Year:        year,
+		DayOfYear:   dayOfYear,
+		Hour:        hour,
+		Minute:      minute,
+		Second:      second,
+		LeapSeconds: leapSeconds,
+		Status:      status,
+		Valid:       status == gpsdo.Locked || status == gpsdo.Holdover,
+		Timestamp:   timestamp,
+		ParseTime:   time.Now(),
+	}, nil
+}
+
+// Encode builds the TOD packet for t, the inverse of Parse. Status values
+// other than Locked, PowerUp and Holdover are encoded as an unknown status.
+func Encode(t time.Time, leapSeconds int, status gpsdo.Status) []byte {
+	t = t.UTC()
+	year := t.Year() - 2000
+	day := t.YearDay()
+
+	packet := []byte{
+		byte(year / 10 % 10), byte(year % 10),
+		byte(day / 100), byte(day / 10 % 10), byte(day % 10),
+		byte(t.Hour() / 10), byte(t.Hour() % 10),
+		byte(t.Minute() / 10), byte(t.Minute() % 10),
+		byte(t.Second() / 10), byte(t.Second() % 10),
+		byte(leapSeconds / 10 % 10), byte(leapSeconds % 10),
+		0x00, 0x00,
+		Terminator,
+	}
+
+	switch status {
+	case gpsdo.PowerUp:
+		packet[13] = 0x01
+	case gpsdo.Holdover:
+		packet[13] = 0x10
+	case gpsdo.Locked:
+	default:
+		packet[13], packet[14] = 0xFF, 0xFF
+	}
+	return packet
+}
@@ -0,0 +1,861 @@
+// Package api serves the bridge status over HTTP as JSON, along with a small
+// embedded web dashboard.
+package api
+
+import (
+	"context"
+	"embed"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/bridge"
+	"github.com/karlcswanson/gogpsdo/gpsdo/chrony"
+	"github.com/karlcswanson/gogpsdo/internal/history"
+	"github.com/karlcswanson/gogpsdo/internal/prometheus"
+)
+
+//go:embed static
+var static embed.FS
+
+// Status is the document returned by GET /api/v1/status
+type Status struct {
+	// Device is the bridge name, empty for a single unnamed device
+	Device      string     `json:"device,omitempty"`
+	Status      string     `json:"status"`
+	Valid       bool       `json:"valid"`
+	Timestamp   *time.Time `json:"timestamp"`
+	LeapSeconds int        `json:"leap_seconds"`
+	Leap        string     `json:"leap"`
+	// TAIOffset is TAI-UTC in seconds, null if unknown
+	TAIOffset *int `json:"tai_offset"`
+	// SampleAge is the seconds since the last sample, -1 if none yet
+	SampleAge  float64    `json:"sample_age"`
+	LastUpdate *time.Time `json:"last_update"`
+	// PacketAge is the seconds since the last packet, valid or not, -1 if
+	// none yet
+	PacketAge float64 `json:"packet_age"`
+
+	Input     Input                        `json:"input"`
+	Packets   Packets                      `json:"packets"`
+	Samples   Samples                      `json:"samples"`
+	PPS       PPS                          `json:"pps"`
+	Holdover  Holdover                     `json:"holdover"`
+	Lock      Lock                         `json:"lock"`
+	Clock     Clock                        `json:"clock"`
+	Identity  *Identity                    `json:"identity"`
+	Receiver  *Receiver                    `json:"receiver"`
+	Chrony    *Chrony                      `json:"chrony"`
+	NTPCheck  *NTPCheck                    `json:"ntp_check"`
+	Stability *Stability                   `json:"stability"`
+	LeapTable *LeapTable                   `json:"leap_table"`
+	Outputs   map[string]gpsdo.OutputStats `json:"outputs"`
+}
+
+// LeapTable describes the leap-seconds.list file, null if none is loaded
+type LeapTable struct {
+	Updated time.Time `json:"updated"`
+	Expires time.Time `json:"expires"`
+	Expired bool      `json:"expired"`
+	// NextLeap is the next scheduled leap second, null if none
+	NextLeap *time.Time `json:"next_leap"`
+	// Mismatch describes how the receiver disagrees with the table, empty
+	// if it agrees
+	Mismatch string `json:"mismatch,omitempty"`
+}
+
+// Input describes the serial connection
+type Input struct {
+	Connected  bool   `json:"connected"`
+	Reconnects uint64 `json:"reconnects"`
+}
+
+// Packets are the input counters
+type Packets struct {
+	Total         uint64 `json:"total"`
+	Valid         uint64 `json:"valid"`
+	FramingErrors uint64 `json:"framing_errors"`
+	// ChecksumErrors, RangeErrors and Malformed are the packets rejected
+	// for a bad checksum, an implausible field or otherwise
+	ChecksumErrors uint64 `json:"checksum_errors"`
+	RangeErrors    uint64 `json:"range_errors"`
+	Malformed      uint64 `json:"malformed"`
+	Outliers       uint64 `json:"outliers"`
+	// Missed are the packets expected but not received, in Gaps gaps
+	Missed uint64 `json:"missed"`
+	Gaps   uint64 `json:"gaps"`
+}
+
+// Samples are the output counters
+type Samples struct {
+	Sent    uint64 `json:"sent"`
+	Dropped uint64 `json:"dropped"`
+}
+
+// PPS are the kernel PPS counters
+type PPS struct {
+	Edges  uint64 `json:"edges"`
+	Paired uint64 `json:"paired"`
+}
+
+// Holdover describes the holdover state
+type Holdover struct {
+	Since     *time.Time `json:"since"`
+	LastEntry *time.Time `json:"last_entry"`
+	LastExit  *time.Time `json:"last_exit"`
+	Rejected  uint64     `json:"rejected"`
+	// Episodes counts the holdovers entered
+	Episodes uint64 `json:"episodes"`
+	// Duration is the seconds in the current holdover, null outside holdover
+	Duration *float64 `json:"duration"`
+	// LastDuration is the length of the last holdover to end in seconds,
+	// null if none has ended
+	LastDuration *float64 `json:"last_duration"`
+	// Longest is the length of the longest holdover in seconds, including
+	// the current one
+	Longest float64 `json:"longest"`
+}
+
+// Lock describes the time the GPSDO takes to lock after power-up
+type Lock struct {
+	// PowerUpSince is when the GPSDO was first seen powering up, null once
+	// it locked
+	PowerUpSince *time.Time `json:"power_up_since"`
+	// LastTimeToLock is the seconds the last lock after power-up took, null
+	// if none was seen
+	LastTimeToLock *float64 `json:"last_time_to_lock"`
+	// Acquisitions are the last locks after power-up, oldest first
+	Acquisitions []LockAcquisition `json:"acquisitions"`
+}
+
+// LockAcquisition is a lock after power-up
+type LockAcquisition struct {
+	PowerUp time.Time `json:"power_up"`
+	Locked  time.Time `json:"locked"`
+	// TimeToLock is the seconds from power-up to lock
+	TimeToLock float64 `json:"time_to_lock"`
+}
+
+// Clock compares the samples with the system clock
+type Clock struct {
+	// Offset is the sample time minus the system clock in seconds, null
+	// without a valid sample
+	Offset *float64 `json:"offset"`
+	// AlarmSince is when the offset exceeded the clock alarm threshold,
+	// null if it is within it or the alarm is disabled
+	AlarmSince *time.Time `json:"alarm_since"`
+	Alarms     uint64     `json:"alarms"`
+}
+
+// NTPCheck compares the samples with the NTP reference servers, null unless
+// ntp_check.servers is set and the servers were queried
+type NTPCheck struct {
+	// Divergence is the sample time minus the median of the servers in
+	// seconds, null without a recent valid sample or an answer
+	Divergence *float64 `json:"divergence"`
+	// AlarmSince is when the divergence exceeded the threshold, null if it
+	// is within it
+	AlarmSince *time.Time  `json:"alarm_since"`
+	Alarms     uint64      `json:"alarms"`
+	Servers    []NTPServer `json:"servers"`
+	Updated    time.Time   `json:"updated"`
+}
+
+// NTPServer is the reply of an NTP reference server
+type NTPServer struct {
+	Server string `json:"server"`
+	// Offset is the server clock minus the system clock and Delay the
+	// round trip delay, in seconds
+	Offset  float64 `json:"offset"`
+	Delay   float64 `json:"delay"`
+	Stratum int     `json:"stratum"`
+	// Error is why the server didn't answer, empty if it did
+	Error string `json:"error,omitempty"`
+}
+
+// Devices is the document returned by GET /api/v1/devices
+type Devices struct {
+	Devices []Status `json:"devices"`
+}
+
+// Identity is the make, model and firmware the receiver reported, null if
+// it wasn't queried or didn't answer
+type Identity struct {
+	Manufacturer string `json:"manufacturer"`
+	Model        string `json:"model"`
+	Serial       string `json:"serial"`
+	Firmware     string `json:"firmware"`
+}
+
+// Receiver holds diagnostics reported by the receiver outside the samples
+type Receiver struct {
+	Satellites int     `json:"satellites"`
+	EFC        float64 `json:"efc"`
+	// HoldoverPrediction is the predicted holdover uncertainty in seconds
+	HoldoverPrediction float64 `json:"holdover_prediction"`
+	Antenna            string  `json:"antenna"`
+	// AntennaFaultSince is when the receiver began to report an antenna
+	// fault, null if the antenna is OK
+	AntennaFaultSince *time.Time `json:"antenna_fault_since"`
+	AntennaFaults     uint64     `json:"antenna_faults"`
+	// PhaseError is the oscillator PPS phase error in seconds
+	PhaseError float64 `json:"phase_error"`
+	Discipline string  `json:"discipline"`
+	// Signals are the tracked satellites, null if the receiver doesn't
+	// report them
+	Signals []Signal `json:"signals"`
+	// Position is the surveyed position, null if the receiver doesn't
+	// report it
+	Position *Position `json:"position"`
+	Updated  time.Time `json:"updated"`
+}
+
+// Position is the antenna position and survey state of the receiver
+type Position struct {
+	// Latitude and Longitude are in degrees, Altitude in meters
+	Latitude  float64 `json:"latitude"`
+	Longitude float64 `json:"longitude"`
+	Altitude  float64 `json:"altitude"`
+	Mode      string  `json:"mode"`
+	// Hold is whether the receiver holds the surveyed position
+	Hold      bool `json:"hold"`
+	Surveying bool `json:"surveying"`
+	// Progress is the survey progress in percent, null if unknown
+	Progress *float64 `json:"progress"`
+}
+
+// Signal is a tracked satellite with its signal strength
+type Signal struct {
+	PRN       int `json:"prn"`
+	Elevation int `json:"elevation"`
+	Azimuth   int `json:"azimuth"`
+	Strength  int `json:"strength"`
+}
+
+// Chrony is chronyd's view of the refclock and the system clock, null unless
+// chronyc.refid is set and chronyd answers. Times are in seconds and
+// frequencies in ppm.
+type Chrony struct {
+	RefID string `json:"refid"`
+	// Selected is whether chronyd synchronizes the system clock to the
+	// refclock
+	Selected bool `json:"selected"`
+	// Source and Stats are null if chronyd has no refclock of the refid
+	Source   *ChronySource  `json:"source"`
+	Stats    *ChronyStats   `json:"stats"`
+	Tracking ChronyTracking `json:"tracking"`
+	Updated  time.Time      `json:"updated"`
+}
+
+// ChronySource is the refclock in chronyc sources
+type ChronySource struct {
+	State string `json:"state"`
+	// Reach is the reachability register, 255 when the last 8 polls
+	// received samples
+	Reach int `json:"reach"`
+	// LastRx is the seconds since the last sample, -1 if none
+	LastRx float64 `json:"last_rx"`
+	Offset float64 `json:"offset"`
+	Error  float64 `json:"error"`
+}
+
+// ChronyStats is the refclock in chronyc sourcestats
+type ChronyStats struct {
+	Samples   int     `json:"samples"`
+	Span      float64 `json:"span"`
+	Frequency float64 `json:"frequency"`
+	Skew      float64 `json:"skew"`
+	Offset    float64 `json:"offset"`
+	// StdDev is the jitter of the samples
+	StdDev float64 `json:"std_dev"`
+}
+
+// ChronyTracking is chronyc tracking
+type ChronyTracking struct {
+	// Reference is the refclock or server the system clock is synchronized
+	// to
+	Reference      string  `json:"reference"`
+	Stratum        int     `json:"stratum"`
+	SystemTime     float64 `json:"system_time"`
+	LastOffset     float64 `json:"last_offset"`
+	RMSOffset      float64 `json:"rms_offset"`
+	Frequency      float64 `json:"frequency"`
+	Skew           float64 `json:"skew"`
+	RootDelay      float64 `json:"root_delay"`
+	RootDispersion float64 `json:"root_dispersion"`
+	LeapStatus     string  `json:"leap_status"`
+}
+
+// newChrony converts a chronyc report
+func newChrony(t *chrony.Tracking) *Chrony {
+	c := &Chrony{
+		RefID:    t.RefID,
+		Selected: t.Selected(),
+		Tracking: ChronyTracking{
+			Reference:      t.System.Name,
+			Stratum:        t.System.Stratum,
+			SystemTime:     t.System.SystemTime,
+			LastOffset:     t.System.LastOffset,
+			RMSOffset:      t.System.RMSOffset,
+			Frequency:      t.System.Frequency,
+			Skew:           t.System.Skew,
+			RootDelay:      t.System.RootDelay,
+			RootDispersion: t.System.RootDispersion,
+			LeapStatus:     t.System.LeapStatus,
+		},
+		Updated: t.Updated,
+	}
+	if s := t.Source; s != nil {
+		c.Source = &ChronySource{
+			State:  s.State,
+			Reach:  int(s.Reach),
+			LastRx: s.LastRx.Seconds(),
+			Offset: s.Offset,
+			Error:  s.Error,
+		}
+		if s.LastRx < 0 {
+			c.Source.LastRx = -1
+		}
+	}
+	if s := t.Stats; s != nil {
+		c.Stats = &ChronyStats{
+			Samples:   s.Samples,
+			Span:      s.Span.Seconds(),
+			Frequency: s.Frequency,
+			Skew:      s.Skew,
+			Offset:    s.Offset,
+			StdDev:    s.StdDev,
+		}
+	}
+	return c
+}
+
+// Stability are the offset statistics over the last hour, in seconds
+type Stability struct {
+	Samples int     `json:"samples"`
+	Mean    float64 `json:"mean"`
+	StdDev  float64 `json:"std_dev"`
+	MAD     float64 `json:"mad"`
+	ADEV    []ADEV  `json:"adev"`
+}
+
+// ADEV is the Allan deviation at an averaging time tau in seconds
+type ADEV struct {
+	Tau       float64 `json:"tau"`
+	Deviation float64 `json:"deviation"`
+}
+
+// History is the document returned by GET /api/v1/history
+type History struct {
+	Transitions []bridge.Transition `json:"transitions"`
+}
+
+// maxHistory is the number of transitions returned by GET /api/v1/history
+const maxHistory = 100
+
+// Recent is the document returned by GET /api/v1/recent and the recent
+// control command: the samples and transitions kept in memory
+type Recent struct {
+	Device      string              `json:"device"`
+	Samples     []StreamSample      `json:"samples"`
+	Transitions []bridge.Transition `json:"transitions"`
+}
+
+// NewRecent builds the recent document for b, from since
+func NewRecent(b *bridge.Bridge, since time.Time) Recent {
+	samples, transitions := b.Recent(since)
+	recent := Recent{
+		Device:      b.Name(),
+		Samples:     make([]StreamSample, 0, len(samples)),
+		Transitions: transitions,
+	}
+	for i := range samples {
+		recent.Samples = append(recent.Samples, NewStreamSample(&samples[i]))
+	}
+	if recent.Transitions == nil {
+		recent.Transitions = []bridge.Transition{}
+	}
+	return recent
+}
+
+// SampleHistory is the document returned by GET /api/v1/samples
+type SampleHistory struct {
+	// Step is the bucket width in seconds
+	Step   float64         `json:"step"`
+	Points []history.Point `json:"points"`
+}
+
+// ReceiverHistory is the document returned by GET /api/v1/receiver
+type ReceiverHistory struct {
+	// Step is the bucket width in seconds
+	Step   float64                 `json:"step"`
+	Points []history.ReceiverPoint `json:"points"`
+}
+
+// StatisticsHistory is the document returned by GET /api/v1/statistics
+type StatisticsHistory struct {
+	Statistics []history.Statistics `json:"statistics"`
+}
+
+// NewStatus builds the status document for b
+func NewStatus(b *bridge.Bridge) Status {
+	stats := b.Stats()
+	data := b.Current()
+
+	status := Status{
+		Device:     b.Name(),
+		Status:     gpsdo.Unknown.String(),
+		Leap:       gpsdo.LeapNone.String(),
+		SampleAge:  -1,
+		LastUpdate: timePtr(stats.LastUpdate),
+		PacketAge:  -1,
+		Input: Input{
+			Connected:  stats.InputConnected,
+			Reconnects: stats.InputReconnects,
+		},
+		Packets: Packets{
+			Total:          stats.TotalPackets,
+			Valid:          stats.ValidPackets,
+			FramingErrors:  stats.FramingErrors,
+			ChecksumErrors: stats.ChecksumErrors,
+			RangeErrors:    stats.RangeErrors,
+			Malformed:      stats.MalformedPackets,
+			Outliers:       stats.OutliersRejected,
+			Missed:         stats.MissedPackets,
+			Gaps:           stats.PacketGaps,
+		},
+		Samples: Samples{
+			Sent:    stats.SentSamples,
+			Dropped: stats.DroppedSamples,
+		},
+		PPS: PPS{
+			Edges:  stats.PPSEdges,
+			Paired: stats.PPSPaired,
+		},
+		Holdover: Holdover{
+			Since:     timePtr(stats.HoldoverSince),
+			LastEntry: timePtr(stats.LastHoldoverEntry),
+			LastExit:  timePtr(stats.LastHoldoverExit),
+			Rejected:  stats.HoldoverRejected,
+			Episodes:  stats.HoldoverEpisodes,
+			Longest:   stats.LongestHoldover.Seconds(),
+		},
+		Clock: Clock{
+			AlarmSince: timePtr(stats.ClockAlarmSince),
+			Alarms:     stats.ClockAlarms,
+		},
+		Outputs: stats.Outputs,
+	}
+	if !stats.HoldoverSince.IsZero() {
+		duration := time.Since(stats.HoldoverSince).Seconds()
+		status.Holdover.Duration = &duration
+		status.Holdover.Longest = max(status.Holdover.Longest, duration)
+	}
+	if stats.LastHoldoverDuration > 0 {
+		last := stats.LastHoldoverDuration.Seconds()
+		status.Holdover.LastDuration = &last
+	}
+
+	status.Lock = Lock{
+		PowerUpSince: timePtr(stats.PowerUpSince),
+		Acquisitions: []LockAcquisition{},
+	}
+	for _, a := range b.LockAcquisitions() {
+		status.Lock.Acquisitions = append(status.Lock.Acquisitions, LockAcquisition{
+			PowerUp:    a.PowerUp,
+			Locked:     a.Locked,
+			TimeToLock: a.Duration().Seconds(),
+		})
+	}
+	if n := len(status.Lock.Acquisitions); n > 0 {
+		status.Lock.LastTimeToLock = &status.Lock.Acquisitions[n-1].TimeToLock
+	}
+
+	if id := b.Identity(); id != (gpsdo.Identity{}) {
+		status.Identity = (*Identity)(&id)
+	}
+
+	if diag := b.Diagnostics(); !diag.Updated.IsZero() {
+		status.Receiver = &Receiver{
+			Satellites:         diag.Satellites,
+			EFC:                diag.EFC,
+			HoldoverPrediction: diag.HoldoverPrediction.Seconds(),
+			Antenna:            diag.Antenna,
+			AntennaFaultSince:  timePtr(stats.AntennaFaultSince),
+			AntennaFaults:      stats.AntennaFaults,
+			PhaseError:         diag.PhaseError.Seconds(),
+			Discipline:         diag.Discipline,
+			Updated:            diag.Updated,
+		}
+		if diag.Signals != nil {
+			status.Receiver.Signals = []Signal{}
+		}
+		for _, s := range diag.Signals {
+			status.Receiver.Signals = append(status.Receiver.Signals, Signal(s))
+		}
+		if p := diag.Position; p != nil {
+			status.Receiver.Position = &Position{
+				Latitude:  p.Latitude,
+				Longitude: p.Longitude,
+				Altitude:  p.Altitude,
+				Mode:      p.Mode,
+				Hold:      p.Hold,
+				Surveying: p.Surveying,
+			}
+			if p.Progress >= 0 {
+				status.Receiver.Position.Progress = &p.Progress
+			}
+		}
+	}
+
+	if t := b.Chrony(); t != nil {
+		status.Chrony = newChrony(t)
+	}
+
+	if c := b.NTPCheck(); c != nil {
+		status.NTPCheck = &NTPCheck{
+			AlarmSince: timePtr(stats.NTPAlarmSince),
+			Alarms:     stats.NTPAlarms,
+			Servers:    []NTPServer{},
+			Updated:    c.Updated,
+		}
+		if c.Compared {
+			divergence := c.Divergence.Seconds()
+			status.NTPCheck.Divergence = &divergence
+		}
+		for _, r := range c.Replies {
+			server := NTPServer{Server: r.Server}
+			if r.Err != nil {
+				server.Error = r.Err.Error()
+			} else {
+				server.Offset = r.Offset.Seconds()
+				server.Delay = r.Delay.Seconds()
+				server.Stratum = r.Stratum
+			}
+			status.NTPCheck.Servers = append(status.NTPCheck.Servers, server)
+		}
+	}
+
+	if summary := b.Stability(); summary.Samples > 0 {
+		status.Stability = &Stability{
+			Samples: summary.Samples,
+			Mean:    summary.Mean,
+			StdDev:  summary.StdDev,
+			MAD:     summary.MAD,
+			ADEV:    []ADEV{},
+		}
+		for _, dev := range summary.ADEV {
+			status.Stability.ADEV = append(status.Stability.ADEV, ADEV{
+				Tau:       dev.Tau.Seconds(),
+				Deviation: dev.Deviation,
+			})
+		}
+	}
+
+	leap := b.LeapStatus()
+	if leap.TAIOffset != 0 {
+		status.TAIOffset = &leap.TAIOffset
+	}
+	if table := leap.Table; table != nil {
+		now := time.Now()
+		status.LeapTable = &LeapTable{
+			Updated:  table.Updated,
+			Expires:  table.Expires,
+			Expired:  table.Expired(now),
+			Mismatch: leap.Mismatch,
+		}
+		if next, _, ok := table.Next(now); ok {
+			status.LeapTable.NextLeap = &next
+		}
+	}
+
+	if data != nil {
+		status.Status = data.Status.String()
+		status.Valid = data.Valid
+		status.Timestamp = timePtr(data.Timestamp)
+		status.LeapSeconds = data.LeapSeconds
+		status.Leap = data.Leap.String()
+		status.SampleAge = time.Since(stats.LastUpdate).Seconds()
+		if data.Valid {
+			offset := data.SystemOffset().Seconds()
+			status.Clock.Offset = &offset
+		}
+	}
+	if !stats.LastPacket.IsZero() {
+		status.PacketAge = time.Since(stats.LastPacket).Seconds()
+	}
+	return status
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
+
+// Server is the HTTP API
+type Server struct {
+	bridges []*bridge.Bridge
+	history *history.Store
+	mux     *http.ServeMux
+	// closing is closed on shutdown, which leaves the WebSocket streams to
+	// their handlers
+	closing   chan struct{}
+	closeOnce sync.Once
+}
+
+// New creates the API for one or more bridges. The status and history
+// endpoints select a bridge by name with the device query parameter and
+// default to the first.
+func New(bridges ...*bridge.Bridge) *Server {
+	s := &Server{
+		bridges: bridges,
+		mux:     http.NewServeMux(),
+		closing: make(chan struct{}),
+	}
+	s.mux.HandleFunc("GET /api/v1/status", s.handleStatus)
+	s.mux.HandleFunc("GET /api/v1/history", s.handleHistory)
+	s.mux.HandleFunc("GET /api/v1/devices", s.handleDevices)
+	s.mux.HandleFunc("GET /api/v1/recent", s.handleRecent)
+	s.mux.HandleFunc("GET /api/v1/samples", s.handleSamples)
+	s.mux.HandleFunc("GET /api/v1/statistics", s.handleStatistics)
+	s.mux.HandleFunc("GET /api/v1/receiver", s.handleReceiver)
+	s.mux.HandleFunc("GET /api/v1/stream", s.handleStream)
+	s.mux.HandleFunc("GET /api/v1/events", s.handleEvents)
+	s.mux.HandleFunc("GET /metrics", s.handleMetrics)
+
+	assets, _ := fs.Sub(static, "static")
+	s.mux.Handle("GET /", http.FileServerFS(assets))
+	return s
+}
+
+// SetHistory serves the stored history. It must be called before serving.
+func (s *Server) SetHistory(store *history.Store) {
+	s.history = store
+}
+
+// ServeHTTP implements http.Handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// ListenAndServe serves the API on addr until ctx is cancelled
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return s.Serve(ctx, listener)
+}
+
+// Serve serves the API on listener until ctx is cancelled
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	slog.Info("HTTP API listening", "addr", listener.Addr())
+
+	server := &http.Server{
+		Handler:           s,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	server.RegisterOnShutdown(func() {
+		s.closeOnce.Do(func() { close(s.closing) })
+	})
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdownCtx)
+	}()
+
+	if err := server.Serve(listener); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// lookup returns the bridge selected by the device query parameter, or
+// writes a 404 and returns nil
+func (s *Server) lookup(w http.ResponseWriter, r *http.Request) *bridge.Bridge {
+	name := r.URL.Query().Get("device")
+	for _, b := range s.bridges {
+		if name == "" || b.Name() == name {
+			return b
+		}
+	}
+	http.Error(w, "unknown device", http.StatusNotFound)
+	return nil
+}
+
+func (s *Server) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if b := s.lookup(w, r); b != nil {
+		writeJSON(w, NewStatus(b))
+	}
+}
+
+func (s *Server) handleHistory(w http.ResponseWriter, r *http.Request) {
+	b := s.lookup(w, r)
+	if b == nil {
+		return
+	}
+	transitions := b.Transitions()
+	if s.history != nil && len(transitions) < maxHistory {
+		previous, err := s.history.Previous(b.Name(), maxHistory-len(transitions))
+		if err != nil {
+			slog.Warn("History query failed", "error", err)
+		}
+		transitions = append(previous, transitions...)
+	}
+	writeJSON(w, History{Transitions: transitions})
+}
+
+func (s *Server) handleRecent(w http.ResponseWriter, r *http.Request) {
+	b := s.lookup(w, r)
+	if b == nil {
+		return
+	}
+	// Everything kept by default
+	var since time.Time
+	if r.URL.Query().Has("since") {
+		var ok bool
+		if since, ok = parseSince(w, r); !ok {
+			return
+		}
+	}
+	writeJSON(w, NewRecent(b, since))
+}
+
+func (s *Server) handleSamples(w http.ResponseWriter, r *http.Request) {
+	b := s.lookupHistory(w, r)
+	if b == nil {
+		return
+	}
+	since, ok := parseSince(w, r)
+	if !ok {
+		return
+	}
+	step, ok := parseStep(w, r, since)
+	if !ok {
+		return
+	}
+
+	points, err := s.history.Samples(b.Name(), since, step)
+	if err != nil {
+		slog.Warn("History query failed", "error", err)
+		http.Error(w, "history query failed", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, SampleHistory{Step: step.Seconds(), Points: points})
+}
+
+func (s *Server) handleReceiver(w http.ResponseWriter, r *http.Request) {
+	b := s.lookupHistory(w, r)
+	if b == nil {
+		return
+	}
+	since, ok := parseSince(w, r)
+	if !ok {
+		return
+	}
+	step, ok := parseStep(w, r, since)
+	if !ok {
+		return
+	}
+
+	points, err := s.history.Receiver(b.Name(), since, step)
+	if err != nil {
+		slog.Warn("History query failed", "error", err)
+		http.Error(w, "history query failed", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, ReceiverHistory{Step: step.Seconds(), Points: points})
+}
+
+// parseStep returns the bucket width given by the step query parameter,
+// about 500 points from since by default, enough for a chart, or writes a
+// 400
+func parseStep(w http.ResponseWriter, r *http.Request, since time.Time) (time.Duration, bool) {
+	step := max(time.Since(since)/500, time.Second).Truncate(time.Second)
+	if value := r.URL.Query().Get("step"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			http.Error(w, "step must be a positive duration", http.StatusBadRequest)
+			return 0, false
+		}
+		step = d
+	}
+	return step, true
+}
+
+func (s *Server) handleStatistics(w http.ResponseWriter, r *http.Request) {
+	b := s.lookupHistory(w, r)
+	if b == nil {
+		return
+	}
+	since, ok := parseSince(w, r)
+	if !ok {
+		return
+	}
+
+	stats, err := s.history.Statistics(b.Name(), since)
+	if err != nil {
+		slog.Warn("History query failed", "error", err)
+		http.Error(w, "history query failed", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, StatisticsHistory{Statistics: stats})
+}
+
+// lookupHistory is lookup for the history endpoints, which are not found
+// without a history store
+func (s *Server) lookupHistory(w http.ResponseWriter, r *http.Request) *bridge.Bridge {
+	if s.history == nil {
+		http.Error(w, "history is not enabled", http.StatusNotFound)
+		return nil
+	}
+	return s.lookup(w, r)
+}
+
+// parseSince returns the start of the time range given by the since query
+// parameter, a duration before now defaulting to 24h, or writes a 400
+func parseSince(w http.ResponseWriter, r *http.Request) (time.Time, bool) {
+	since := 24 * time.Hour
+	if value := r.URL.Query().Get("since"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			http.Error(w, "since must be a positive duration", http.StatusBadRequest)
+			return time.Time{}, false
+		}
+		since = d
+	}
+	return time.Now().Add(-since), true
+}
+
+func (s *Server) handleDevices(w http.ResponseWriter, r *http.Request) {
+	devices := Devices{Devices: make([]Status, 0, len(s.bridges))}
+	for _, b := range s.bridges {
+		devices.Devices = append(devices.Devices, NewStatus(b))
+	}
+	writeJSON(w, devices)
+}
+
+func (s *Server) handleMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", prometheus.ContentType)
+	if err := prometheus.Write(w, s.bridges...); err != nil {
+		slog.Warn("HTTP API write failed", "error", err)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		slog.Warn("HTTP API write failed", "error", err)
+	}
+}
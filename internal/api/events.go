@@ -0,0 +1,91 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo/bridge"
+)
+
+// eventsKeepalive is how often an idle events feed sends a comment, so
+// proxies keep it open and dead clients are noticed
+const eventsKeepalive = 30 * time.Second
+
+// handleEvents is the Server-Sent Events version of the stream: the status
+// of each bridge on connect and after each transition, and the sample and
+// transition events, each as a JSON StreamEvent named after its type
+func (s *Server) handleEvents(w http.ResponseWriter, r *http.Request) {
+	bridges := s.streamBridges(w, r)
+	if bridges == nil {
+		return
+	}
+	merged, stop := subscribe(bridges)
+	defer stop()
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Keep nginx from buffering the feed
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	send := func(event StreamEvent) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+	status := func(b *bridge.Bridge) StreamEvent {
+		st := NewStatus(b)
+		return StreamEvent{Type: "status", Device: b.Name(), Status: &st}
+	}
+
+	byName := make(map[string]*bridge.Bridge, len(bridges))
+	for _, b := range bridges {
+		byName[b.Name()] = b
+		if err := send(status(b)); err != nil {
+			return
+		}
+	}
+	// The status follows the sample that changed it
+	var changed *bridge.Bridge
+	keepalive := time.NewTicker(eventsKeepalive)
+	defer keepalive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-s.closing:
+			return
+		case <-keepalive.C:
+			rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if _, err := fmt.Fprint(w, ": keepalive\n\n"); err != nil {
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		case event := <-merged:
+			err := send(event)
+			if err == nil && event.Type == "sample" && changed != nil {
+				err = send(status(changed))
+				changed = nil
+			}
+			if err != nil {
+				slog.Debug("HTTP API events closed", "error", err)
+				return
+			}
+			if event.Type == "transition" {
+				changed = byName[event.Device]
+			}
+		}
+	}
+}
@@ -0,0 +1,302 @@
+// Renders the dashboard, refreshing on the events of the live stream or by
+// polling the status API while the stream is down
+const refreshMs = 2000;
+const streamRefreshMs = 10000;
+const streamThrottleMs = 500;
+const streamRetryMs = 5000;
+const staleSeconds = 10;
+const chartRefreshMs = 60000;
+let chartUpdated = 0;
+let chartQuery = null;
+
+function set(id, value) {
+  document.getElementById(id).textContent = value;
+}
+
+function formatTime(value) {
+  return value ? new Date(value).toISOString().replace("T", " ").replace(/\.\d+Z$/, "Z") : "-";
+}
+
+function formatDuration(seconds) {
+  const s = Math.floor(seconds);
+  if (s < 60) return `${s}s`;
+  if (s < 3600) return `${Math.floor(s / 60)}m ${s % 60}s`;
+  return `${Math.floor(s / 3600)}h ${Math.floor(s % 3600 / 60)}m`;
+}
+
+function formatPosition(p) {
+  if (!p) return "-";
+  const where = `${Math.abs(p.latitude).toFixed(5)} ${p.latitude < 0 ? "S" : "N"} ` +
+    `${Math.abs(p.longitude).toFixed(5)} ${p.longitude < 0 ? "W" : "E"} ${p.altitude.toFixed(1)} m`;
+  if (p.hold) return `hold, ${where}`;
+  if (p.surveying) return p.progress === null ? "surveying" : `surveying, ${p.progress.toFixed(1)} %`;
+  return `not in hold (${p.mode})`;
+}
+
+function row(cells) {
+  const tr = document.createElement("tr");
+  for (const cell of cells) {
+    const td = document.createElement("td");
+    td.textContent = cell;
+    tr.appendChild(td);
+  }
+  return tr;
+}
+
+function renderStatus(s) {
+  const stale = s.sample_age < 0 || s.sample_age > staleSeconds;
+  const label = stale ? "STALE" : s.status;
+  const el = document.getElementById("status");
+  el.textContent = stale && s.sample_age >= 0 ? `${s.status} (stale)` : label;
+  el.className = "status " + label;
+
+  const id = s.identity;
+  set("identity", !id ? "-" : [`${id.manufacturer} ${id.model}`.trim(),
+    id.serial && `serial ${id.serial}`, id.firmware && `firmware ${id.firmware}`].filter(Boolean).join(", "));
+  set("timestamp", formatTime(s.timestamp));
+  set("age", s.sample_age < 0 ? "-" : `${s.sample_age.toFixed(1)} s`);
+  set("leap_seconds", s.leap_seconds);
+  set("leap", s.leap);
+  set("holdover_since", formatTime(s.holdover.since));
+  set("holdovers", s.holdover.episodes === 0 ? "none" :
+    `${s.holdover.episodes}, longest ${formatDuration(s.holdover.longest)}`);
+  const lock = s.lock;
+  set("time_to_lock", lock.power_up_since ? `powering up since ${formatTime(lock.power_up_since)}` :
+    lock.last_time_to_lock === null ? "-" : formatDuration(lock.last_time_to_lock));
+  set("clock_offset", s.clock.offset === null ? "-" : `${(s.clock.offset * 1e3).toFixed(1)} ms`);
+  set("clock_alarm_since", formatTime(s.clock.alarm_since));
+  const ntp = s.ntp_check;
+  set("ntp_divergence", !ntp || ntp.divergence === null ? "-" : `${(ntp.divergence * 1e3).toFixed(1)} ms`);
+  set("ntp_alarm_since", formatTime(ntp ? ntp.alarm_since : null));
+
+  set("packets_total", s.packets.total);
+  set("packets_valid", s.packets.valid);
+  set("packets_framing", s.packets.framing_errors);
+  set("packets_rejected", `${s.packets.checksum_errors} checksum, ${s.packets.range_errors} range, ${s.packets.malformed} malformed`);
+  set("packets_missed", `${s.packets.missed} in ${s.packets.gaps} gaps`);
+  set("packet_age", s.packet_age < 0 ? "-" : `${s.packet_age.toFixed(1)} s`);
+  set("samples_sent", s.samples.sent);
+  set("samples_dropped", s.samples.dropped);
+  set("pps", `${s.pps.edges} / ${s.pps.paired}`);
+
+  const receiver = s.receiver;
+  document.getElementById("receiver").hidden = !receiver;
+  if (receiver) {
+    set("satellites", receiver.satellites);
+    set("signals", !receiver.signals ? "-" :
+      receiver.signals.map(s => `${s.prn}: ${s.strength}`).join(", ") || "none");
+    set("efc", `${receiver.efc.toFixed(2)} %`);
+    set("holdover_prediction", `${(receiver.holdover_prediction * 1e6).toFixed(1)} us / 24 h`);
+    set("antenna", !receiver.antenna_fault_since ? receiver.antenna || "-" :
+      `${receiver.antenna}, fault since ${formatTime(receiver.antenna_fault_since)}`);
+    set("position", formatPosition(receiver.position));
+    set("discipline", receiver.discipline || "-");
+    set("phase_error", receiver.discipline ? `${(receiver.phase_error * 1e9).toFixed(1)} ns` : "-");
+  }
+
+  const chrony = s.chrony;
+  document.getElementById("chrony").hidden = !chrony;
+  if (chrony) {
+    const source = chrony.source;
+    set("chrony_refid", source ? `${chrony.refid} ${source.state}` : `${chrony.refid} (not in chrony.conf)`);
+    set("chrony_reach", source ? source.reach.toString(8) : "-");
+    set("chrony_offset", source && source.last_rx >= 0 ?
+      `${(source.offset * 1e6).toFixed(1)} us +/- ${(source.error * 1e6).toFixed(1)} us` : "-");
+    set("chrony_std_dev", chrony.stats ? `${(chrony.stats.std_dev * 1e6).toFixed(1)} us` : "-");
+    set("chrony_reference", chrony.tracking.reference || "-");
+    set("chrony_system_time", `${(chrony.tracking.system_time * 1e6).toFixed(1)} us`);
+    set("chrony_frequency", `${chrony.tracking.frequency.toFixed(3)} ppm`);
+  }
+
+  const stability = s.stability;
+  document.getElementById("stability").hidden = !stability;
+  if (stability) {
+    set("offset_mean", `${(stability.mean * 1e3).toFixed(3)} ms`);
+    set("offset_std_dev", `${(stability.std_dev * 1e6).toFixed(1)} us`);
+    set("offset_mad", `${(stability.mad * 1e6).toFixed(1)} us`);
+    document.getElementById("adev").replaceChildren(...stability.adev.map(a =>
+      row([`${a.tau} s`, a.deviation.toExponential(2)])));
+  }
+
+  const outputs = document.getElementById("outputs");
+  outputs.replaceChildren(...Object.entries(s.outputs || {}).map(([name, o]) =>
+    row([name, o.connected ? "yes" : "no", o.reconnects, o.write_errors])));
+}
+
+function renderHistory(transitions) {
+  const history = document.getElementById("history");
+  history.replaceChildren(...transitions.slice().reverse().map(t =>
+    row([formatTime(t.time), t.from, t.to])));
+}
+
+// parseDuration converts the Go durations of the range picker, e.g. 168h
+function parseDuration(value) {
+  const units = {s: 1e3, m: 60e3, h: 3600e3};
+  return parseFloat(value) * units[value.slice(-1)];
+}
+
+// Draws the stored history of the selected range: the offset as a mean line
+// over the min to max band, the packet rate from the statistics snapshots,
+// the oscillator EFC when the receiver reports it and the lock state per
+// sample bucket, shading buckets that were not fully locked on the offset
+// chart as well. The card stays hidden when the history store is disabled.
+function renderCharts(samples, statistics, receiver, range) {
+  const card = document.getElementById("charts");
+  card.hidden = !samples;
+  if (card.hidden) {
+    return;
+  }
+  const t1 = Date.now();
+  const t0 = t1 - range;
+  const x = t => ((Date.parse(t) - t0) / range * 1000).toFixed(1);
+  const width = Math.max(samples.step * 1000 / range * 1000, 0.5).toFixed(1);
+  const scale = (low, high, height) => {
+    const span = Math.max(high - low, 1e-9);
+    return v => (height - (v - low) / span * height).toFixed(1);
+  };
+
+  const points = samples.points;
+  const unlocked = points.filter(p => p.locked < 1);
+  const shading = unlocked.map(p =>
+    `<rect class="holdover" x="${x(p.time)}" width="${width}" y="0" height="200"/>`).join("");
+  if (points.length > 0) {
+    const low = Math.min(...points.map(p => p.min));
+    const high = Math.max(...points.map(p => p.max));
+    const y = scale(low, high, 200);
+    const band = points.map(p => `${x(p.time)},${y(p.max)}`)
+      .concat(points.slice().reverse().map(p => `${x(p.time)},${y(p.min)}`));
+    const mean = points.map(p => `${x(p.time)},${y(p.offset)}`);
+    document.getElementById("offset_chart").innerHTML = shading +
+      `<polygon class="band" points="${band.join(" ")}"/>` +
+      `<polyline class="mean" points="${mean.join(" ")}"/>`;
+    set("offset_max", `${(high * 1e3).toFixed(3)} ms`);
+    set("offset_min", `${(low * 1e3).toFixed(3)} ms`);
+  } else {
+    document.getElementById("offset_chart").replaceChildren();
+    set("offset_max", "-");
+    set("offset_min", "-");
+  }
+
+  // Rates between consecutive snapshots, a restart or counter reset breaks
+  // the line
+  const stats = statistics ? statistics.statistics : [];
+  const rates = [];
+  for (let i = 1; i < stats.length; i++) {
+    const seconds = (Date.parse(stats[i].time) - Date.parse(stats[i - 1].time)) / 1000;
+    const packets = stats[i].packets_total - stats[i - 1].packets_total;
+    rates.push(packets >= 0 && seconds > 0 ? {time: stats[i].time, rate: packets / seconds} : null);
+  }
+  const valid = rates.filter(r => r);
+  if (valid.length > 0) {
+    const high = Math.max(...valid.map(r => r.rate));
+    const y = scale(0, Math.max(high, 1e-3), 100);
+    let path = "";
+    let move = true;
+    for (const r of rates) {
+      if (!r) {
+        move = true;
+        continue;
+      }
+      path += `${move ? "M" : "L"}${x(r.time)},${y(r.rate)} `;
+      move = false;
+    }
+    document.getElementById("rate_chart").innerHTML = `<path class="mean" d="${path}"/>`;
+    set("rate_max", `${high.toFixed(2)} /s`);
+  } else {
+    document.getElementById("rate_chart").replaceChildren();
+    set("rate_max", "-");
+  }
+
+  // EFC drifting steadily toward one end of its range is oscillator aging
+  const efc = receiver ? receiver.points : [];
+  document.getElementById("efc_history").hidden = efc.length === 0;
+  if (efc.length > 0) {
+    const low = Math.min(...efc.map(p => p.efc_min));
+    const high = Math.max(...efc.map(p => p.efc_max));
+    const y = scale(low, high, 100);
+    const band = efc.map(p => `${x(p.time)},${y(p.efc_max)}`)
+      .concat(efc.slice().reverse().map(p => `${x(p.time)},${y(p.efc_min)}`));
+    const mean = efc.map(p => `${x(p.time)},${y(p.efc)}`);
+    document.getElementById("efc_chart").innerHTML =
+      `<polygon class="band" points="${band.join(" ")}"/>` +
+      `<polyline class="mean" points="${mean.join(" ")}"/>`;
+    set("efc_max", `${high.toFixed(2)} %`);
+    set("efc_min", `${low.toFixed(2)} %`);
+  }
+
+  document.getElementById("lock_chart").innerHTML = points.map(p =>
+    `<rect class="${p.locked >= 1 ? "LOCKED" : "HOLDOVER"}" x="${x(p.time)}" width="${width}" y="0" height="20"/>`).join("");
+  const total = points.reduce((sum, p) => sum + p.samples, 0);
+  const locked = points.reduce((sum, p) => sum + p.locked * p.samples, 0);
+  set("locked_fraction", total ? `${(locked / total * 100).toFixed(2)} % of samples locked` : "no samples");
+  set("chart_start", formatTime(new Date(t0).toISOString()));
+  set("chart_end", formatTime(new Date(t1).toISOString()));
+}
+
+async function refresh() {
+  try {
+    const devices = await fetch("api/v1/devices").then(r => r.json());
+    const status = renderDevices(devices.devices);
+    const query = status.device ? "?device=" + encodeURIComponent(status.device) : "";
+    const history = await fetch("api/v1/history" + query).then(r => r.json());
+    renderStatus(status);
+    renderHistory(history.transitions);
+    const since = document.getElementById("range").value;
+    const range = parseDuration(since);
+    const params = (query ? query + "&" : "?") + "since=" + since;
+    // Longer ranges change little between refreshes
+    if (Date.now() - chartUpdated > Math.max(chartRefreshMs, range / 500) || chartQuery !== params) {
+      const [samples, statistics, receiver] = await Promise.all([
+        fetch("api/v1/samples" + params).then(r => r.ok ? r.json() : null),
+        fetch("api/v1/statistics" + params).then(r => r.ok ? r.json() : null),
+        fetch("api/v1/receiver" + params).then(r => r.ok ? r.json() : null),
+      ]);
+      renderCharts(samples, statistics, receiver, range);
+      chartUpdated = Date.now();
+      chartQuery = params;
+    }
+    set("updated", "updated " + new Date().toLocaleTimeString());
+  } catch (err) {
+    set("updated", "bridge unreachable");
+  }
+}
+
+let streaming = false;
+let streamRefresh = null;
+
+// Refreshes at most every streamThrottleMs on the events of the selected device
+function onStreamEvent(message) {
+  const event = JSON.parse(message.data);
+  if ((event.device || "") !== document.getElementById("device").value || streamRefresh) {
+    return;
+  }
+  streamRefresh = setTimeout(() => {
+    streamRefresh = null;
+    refresh();
+  }, streamThrottleMs);
+}
+
+function connectStream() {
+  const url = new URL("api/v1/stream", location.href);
+  url.protocol = url.protocol === "https:" ? "wss:" : "ws:";
+  const ws = new WebSocket(url);
+  ws.onopen = () => { streaming = true; };
+  ws.onmessage = onStreamEvent;
+  ws.onclose = () => {
+    streaming = false;
+    setTimeout(connectStream, streamRetryMs);
+  };
+}
+
+let polled = 0;
+setInterval(() => {
+  if (Date.now() - polled >= (streaming ? streamRefreshMs : refreshMs)) {
+    polled = Date.now();
+    refresh();
+  }
+}, refreshMs);
+
+document.getElementById("device").addEventListener("change", refresh);
+document.getElementById("range").addEventListener("change", refresh);
+refresh();
+connectStream();
@@ -0,0 +1,177 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/bridge"
+)
+
+// streamWriteTimeout bounds the write of one message to a stream client
+const streamWriteTimeout = 10 * time.Second
+
+// streamPing is how often an idle stream is pinged, so proxies keep it open
+// and dead clients are noticed
+const streamPing = 30 * time.Second
+
+// upgrader accepts WebSocket connections from the dashboard and from clients
+// that send no Origin, such as scripts, but not from other web pages
+var upgrader = websocket.Upgrader{}
+
+// StreamEvent is a message of the /api/v1/stream WebSocket and the
+// /api/v1/events feed. A sample that changes the status is preceded by a
+// transition event.
+type StreamEvent struct {
+	// Type is sample or transition, or status on the events feed
+	Type       string             `json:"type"`
+	Device     string             `json:"device"`
+	Sample     *StreamSample      `json:"sample,omitempty"`
+	Transition *bridge.Transition `json:"transition,omitempty"`
+	Status     *Status            `json:"status,omitempty"`
+}
+
+// StreamSample is a sample accepted by the bridge
+type StreamSample struct {
+	Timestamp   time.Time `json:"timestamp"`
+	Status      string    `json:"status"`
+	Valid       bool      `json:"valid"`
+	LeapSeconds int       `json:"leap_seconds"`
+	Leap        string    `json:"leap"`
+	// Offset is the sample time minus the system clock in seconds, null for
+	// an invalid sample
+	Offset *float64 `json:"offset"`
+	// PPS is set when the sample was paired with a PPS edge
+	PPS bool `json:"pps"`
+	// Received is the system time the sample refers to
+	Received time.Time `json:"received"`
+}
+
+// events converts a bridge event to stream messages
+func events(b *bridge.Bridge, e bridge.Event) []StreamEvent {
+	var out []StreamEvent
+	if e.Transition != nil {
+		out = append(out, StreamEvent{Type: "transition", Device: b.Name(), Transition: e.Transition})
+	}
+	sample := NewStreamSample(e.Sample)
+	return append(out, StreamEvent{Type: "sample", Device: b.Name(), Sample: &sample})
+}
+
+// NewStreamSample converts a sample accepted by the bridge
+func NewStreamSample(data *gpsdo.Sample) StreamSample {
+	sample := StreamSample{
+		Timestamp:   data.Timestamp,
+		Status:      data.Status.String(),
+		Valid:       data.Valid,
+		LeapSeconds: data.LeapSeconds,
+		Leap:        data.Leap.String(),
+		PPS:         !data.PPS.IsZero(),
+		Received:    data.ReceiveTime(),
+	}
+	if data.Valid {
+		offset := data.SystemOffset().Seconds()
+		sample.Offset = &offset
+	}
+	return sample
+}
+
+// streamBridges returns every bridge, or the one selected with the device
+// query parameter, or writes a 404
+func (s *Server) streamBridges(w http.ResponseWriter, r *http.Request) []*bridge.Bridge {
+	if !r.URL.Query().Has("device") {
+		return s.bridges
+	}
+	if b := s.lookup(w, r); b != nil {
+		return []*bridge.Bridge{b}
+	}
+	return nil
+}
+
+// subscribe merges the events of bridges into one channel. stop ends the
+// subscriptions.
+func subscribe(bridges []*bridge.Bridge) (merged <-chan StreamEvent, stop func()) {
+	out := make(chan StreamEvent)
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for _, b := range bridges {
+		ch, unsubscribe := b.Subscribe()
+		wg.Go(func() {
+			defer unsubscribe()
+			for {
+				select {
+				case <-done:
+					return
+				case e := <-ch:
+					for _, event := range events(b, e) {
+						select {
+						case out <- event:
+						case <-done:
+							return
+						}
+					}
+				}
+			}
+		})
+	}
+	return out, func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// handleStream pushes the events of the bridges, or of the one selected
+// with the device query parameter, until the client goes away or the server
+// shuts down
+func (s *Server) handleStream(w http.ResponseWriter, r *http.Request) {
+	bridges := s.streamBridges(w, r)
+	if bridges == nil {
+		return
+	}
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// The upgrader answered with an error
+		return
+	}
+	defer conn.Close()
+
+	// The client sends nothing, reading only notices it closing
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	merged, stop := subscribe(bridges)
+	defer stop()
+
+	ping := time.NewTicker(streamPing)
+	defer ping.Stop()
+	for {
+		select {
+		case <-gone:
+			return
+		case <-s.closing:
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(time.Second))
+			return
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout)); err != nil {
+				return
+			}
+		case event := <-merged:
+			conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if err := conn.WriteJSON(event); err != nil {
+				slog.Debug("HTTP API stream closed", "error", err)
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,1198 @@
+// Package config loads the gogpsdo YAML configuration file and merges it with
+// command line flags.
+package config
+
+import (
+	"bytes"
+	"cmp"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/generic"
+	"github.com/karlcswanson/gogpsdo/gpsdo/leapsec"
+	"github.com/karlcswanson/gogpsdo/gpsdo/netserial"
+	"github.com/karlcswanson/gogpsdo/gpsdo/pps"
+	"github.com/karlcswanson/gogpsdo/gpsdo/protocol"
+	"github.com/karlcswanson/gogpsdo/gpsdo/z3805a"
+	"github.com/karlcswanson/gogpsdo/internal/syslog"
+)
+
+// DefaultPath is the conventional location of the config file
+const DefaultPath = "/etc/gogpsdo.yaml"
+
+// Config is the complete gogpsdo configuration
+type Config struct {
+	// Device is the single GPSDO configured by the top level keys and flags.
+	// It is ignored when Devices is set.
+	Device `yaml:",inline"`
+	// Devices configures several GPSDOs, each with its own outputs
+	Devices  deviceList `yaml:"devices"`
+	HTTP     HTTP       `yaml:"http"`
+	Control  Control    `yaml:"control"`
+	GRPC     GRPC       `yaml:"grpc"`
+	MQTT     MQTT       `yaml:"mqtt"`
+	DBus     DBus       `yaml:"dbus"`
+	InfluxDB InfluxDB   `yaml:"influxdb"`
+	// Prometheus metrics are served at /metrics on the HTTP API, and
+	// optionally written to a textfile
+	Prometheus Prometheus `yaml:"prometheus"`
+	History    History    `yaml:"history"`
+	State      State      `yaml:"state"`
+	Webhook    Webhook    `yaml:"webhook"`
+	SMTP       SMTP       `yaml:"smtp"`
+	Logging    Logging    `yaml:"logging"`
+	Replay     Replay     `yaml:"replay"`
+	Capture    Capture    `yaml:"capture"`
+	// LeapSeconds cross-checks the leap seconds of every device
+	LeapSeconds LeapSeconds `yaml:"leap_seconds"`
+	// NTPCheck cross-checks the time of every device against NTP servers
+	NTPCheck NTPCheck `yaml:"ntp_check"`
+	// Privileges are dropped to once the devices are open
+	Privileges Privileges `yaml:"privileges"`
+	Daemon     Daemon     `yaml:"daemon"`
+	// DryRun prints the chrony samples instead of sending them and disables
+	// SHM
+	DryRun bool `yaml:"dry_run"`
+}
+
+// Device configures one GPSDO input and its outputs
+type Device struct {
+	// Name identifies the device in logs and the HTTP API
+	Name string `yaml:"name"`
+	// Profile is a ready-made configuration for a common receiver, it sets
+	// the protocol, baud rate and PPS source
+	Profile  string `yaml:"profile"`
+	Serial   Serial `yaml:"serial"`
+	Protocol string `yaml:"protocol"`
+	// Generic is the packet layout of the generic protocol
+	Generic generic.Layout `yaml:"generic"`
+	// Z3805A is the packet framing of the z3805a protocol, for firmware
+	// revisions that differ from the standard packet
+	Z3805A z3805a.Format `yaml:"z3805a"`
+	PPS    PPS           `yaml:"pps"`
+	// RolloverPivot is the earliest plausible date, YYYY-MM-DD, empty for the
+	// build date
+	RolloverPivot string `yaml:"rollover_pivot"`
+	// MaxJump rejects samples this far from the expected time, 0 to disable
+	MaxJump time.Duration `yaml:"max_jump"`
+	// ClockAlarm raises an alarm when the sample time and the system clock
+	// differ by more than this, 0 to disable
+	ClockAlarm time.Duration `yaml:"clock_alarm"`
+	// Offset is a static calibration in seconds added to the sample offset
+	Offset float64 `yaml:"offset"`
+	// SampleInterval sends one sample per interval to the outputs, 0 to send
+	// every sample
+	SampleInterval time.Duration `yaml:"sample_interval"`
+	// SampleAverage sends the mean offset of each interval instead of its
+	// first sample
+	SampleAverage bool `yaml:"sample_average"`
+	// Timescale of the samples sent to the outputs: utc, tai or gps
+	Timescale string   `yaml:"timescale"`
+	Holdover  Holdover `yaml:"holdover"`
+	Recent    Recent   `yaml:"recent"`
+	Outputs   Outputs  `yaml:"outputs"`
+	SCPI      SCPI     `yaml:"scpi"`
+	Chronyc   Chronyc  `yaml:"chronyc"`
+	Display   Display  `yaml:"display"`
+	RawTCP    RawTCP   `yaml:"raw_tcp"`
+}
+
+// Serial configures the TOD input port
+type Serial struct {
+	Port string `yaml:"port"`
+	// Baud overrides the baud rate of the protocol, 0 for its default
+	Baud int `yaml:"baud"`
+	// Wait is how long to wait at startup for the port and PPS device to
+	// appear, 0 to fail at once
+	Wait time.Duration `yaml:"wait"`
+	// DataBits, Parity and StopBits override the character format of the
+	// protocol, such as 7, odd and 1 for 7O1, when set
+	DataBits int    `yaml:"data_bits"`
+	Parity   string `yaml:"parity"`
+	StopBits int    `yaml:"stop_bits"`
+}
+
+// Settings returns the overrides of the protocol port settings, zero where
+// the protocol default applies
+func (s Serial) Settings() (protocol.SerialDefaults, error) {
+	settings := protocol.SerialDefaults{Baud: s.Baud, DataBits: s.DataBits, StopBits: s.StopBits}
+	if s.Parity != "" {
+		parity, err := protocol.ParseParity(s.Parity)
+		if err != nil {
+			return settings, err
+		}
+		settings.Parity = parity
+	}
+	return settings, nil
+}
+
+// PPS configures the optional kernel PPS device or modem line
+type PPS struct {
+	Device string `yaml:"device"`
+	// Line takes the PPS from a modem status line instead, dcd, cts or dsr
+	Line string `yaml:"line"`
+	// DCD is short for line dcd
+	DCD bool `yaml:"dcd"`
+	// Port is the serial port of Line, empty for the TOD port
+	Port string `yaml:"port"`
+	// GPIO takes the PPS from a GPIO line instead, as chip:line or line
+	GPIO         string `yaml:"gpio"`
+	SecondOffset int    `yaml:"second_offset"`
+}
+
+// ModemLine returns the modem line carrying the PPS, empty for none
+func (p PPS) ModemLine() string {
+	if p.Line == "" && p.DCD {
+		return "dcd"
+	}
+	return p.Line
+}
+
+// Holdover configures the holdover policy
+type Holdover struct {
+	// Max stops forwarding samples after this long in holdover, 0 for no
+	// limit
+	Max time.Duration `yaml:"max"`
+}
+
+// Recent configures the samples and status transitions kept in memory for
+// the API and control socket
+type Recent struct {
+	// Samples is the number of samples kept, 0 to keep none
+	Samples int `yaml:"samples"`
+	// Transitions is the number of status transitions kept
+	Transitions int `yaml:"transitions"`
+}
+
+// SCPI configures the optional receiver control port
+type SCPI struct {
+	// Port is the Z3805A SCPI serial port, empty to disable
+	Port string `yaml:"port"`
+	// Interval is how often the diagnostics are read
+	Interval time.Duration `yaml:"interval"`
+}
+
+// Chronyc configures querying chronyd about the refclock of the device
+type Chronyc struct {
+	// RefID is the refid of the SOCK or SHM refclock in chrony.conf, empty
+	// to disable
+	RefID string `yaml:"refid"`
+	// Interval is how often chronyd is queried
+	Interval time.Duration `yaml:"interval"`
+}
+
+// Display configures an I2C OLED status display
+type Display struct {
+	// I2C is the bus device, such as /dev/i2c-1, empty to disable
+	I2C string `yaml:"i2c"`
+	// Address is the 7 bit I2C address of the module, usually 0x3c
+	Address int `yaml:"address"`
+	// Controller is ssd1306 or sh1106
+	Controller string `yaml:"controller"`
+}
+
+// Outputs configures where samples are sent
+type Outputs struct {
+	Chrony Chrony `yaml:"chrony"`
+	SHM    SHM    `yaml:"shm"`
+	GPSD   GPSD   `yaml:"gpsd"`
+	CSV    CSV    `yaml:"csv"`
+	FIFO   FIFO   `yaml:"fifo"`
+	PHC    PHC    `yaml:"phc"`
+	Kernel Kernel `yaml:"kernel"`
+	NTP    NTP    `yaml:"ntp"`
+	LED    LED    `yaml:"led"`
+}
+
+// Chrony configures the chrony SOCK refclock output
+type Chrony struct {
+	// Socket is the refclock socket path, empty to disable
+	Socket string `yaml:"socket"`
+}
+
+// CSV configures appending every accepted sample to a CSV file
+type CSV struct {
+	// File is the CSV file, empty to disable
+	File string `yaml:"file"`
+	// MaxSizeMB rotates the file once it reaches this size, 0 to never
+	// rotate
+	MaxSizeMB int `yaml:"max_size_mb"`
+	// MaxFiles is the number of rotated files kept
+	MaxFiles int `yaml:"max_files"`
+	// Permissions apply to each file created
+	Permissions Permissions `yaml:"permissions"`
+}
+
+// FIFO configures writing every sample as a line of JSON to a named pipe
+type FIFO struct {
+	// Path is the named pipe, created if missing, empty to disable
+	Path string `yaml:"path"`
+	// Permissions apply to the pipe if it is created
+	Permissions Permissions `yaml:"permissions"`
+}
+
+// SHM configures the NTP shared memory output
+type SHM struct {
+	// Unit is the SHM unit 0-3, -1 to disable
+	Unit int `yaml:"unit"`
+}
+
+// PHC configures steering a PTP hardware clock
+type PHC struct {
+	// Device is the PHC, such as /dev/ptp0, empty to disable
+	Device string `yaml:"device"`
+	// TAI runs the PHC on TAI, the time scale of PTP, instead of UTC
+	TAI bool `yaml:"tai"`
+	// Step is the offset above which the PHC is stepped instead of slewed
+	// after the first sample, 0 to never step it again
+	Step time.Duration `yaml:"step"`
+}
+
+// Kernel configures disciplining the system clock through adjtimex
+type Kernel struct {
+	// Enabled steers the system clock without chrony or ntpd
+	Enabled bool `yaml:"enabled"`
+	// Step is the offset above which the clock is stepped instead of
+	// slewed, 0 to never step
+	Step time.Duration `yaml:"step"`
+}
+
+// NTP configures the built in NTP server
+type NTP struct {
+	// Listen is the UDP address to serve on, empty to disable
+	Listen string `yaml:"listen"`
+}
+
+// LED configures status LEDs on GPIO lines
+type LED struct {
+	// GPIO are the lines, as chip:line or a line of gpiochip0, empty to
+	// disable
+	GPIO StringList `yaml:"gpio"`
+	// ActiveLow lights LEDs wired between the line and the supply, which
+	// are on while the line is low
+	ActiveLow bool `yaml:"active_low"`
+}
+
+// GPSD configures the gpsd compatible JSON service
+type GPSD struct {
+	// Listen is the TCP address to serve on, empty to disable
+	Listen string `yaml:"listen"`
+}
+
+// RawTCP configures rebroadcasting the raw serial input to TCP clients
+type RawTCP struct {
+	// Listen is the TCP address to serve on, empty to disable
+	Listen string `yaml:"listen"`
+}
+
+// HTTP configures the HTTP status API
+type HTTP struct {
+	// Listen is the TCP address to serve on, or unix:/path for a unix
+	// socket, empty to disable
+	Listen string `yaml:"listen"`
+	// Permissions apply to a unix socket
+	Permissions Permissions `yaml:"permissions"`
+}
+
+// Control configures the local control socket
+type Control struct {
+	// Socket is the unix socket path, empty to disable
+	Socket string `yaml:"socket"`
+	// Permissions apply to the socket. Anyone who can connect can reset the
+	// counters and change the offset.
+	Permissions Permissions `yaml:"permissions"`
+}
+
+// GRPC configures the gRPC monitoring and control API
+type GRPC struct {
+	// Listen is the TCP address to serve on, or unix:/path for a unix
+	// socket, empty to disable
+	Listen string `yaml:"listen"`
+	// Control serves the RPCs that change the bridge, setting the offset,
+	// resetting the counters and sending SCPI commands, to anyone who can
+	// connect
+	Control bool `yaml:"control"`
+	// Permissions apply to a unix socket
+	Permissions Permissions `yaml:"permissions"`
+}
+
+// StringList is a list given as a comma separated flag
+type StringList []string
+
+// String joins the list with commas
+func (l *StringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+// Set replaces the list with the comma separated items of s
+func (l *StringList) Set(s string) error {
+	*l = nil
+	for item := range strings.SplitSeq(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			*l = append(*l, item)
+		}
+	}
+	return nil
+}
+
+// Permissions set the owner, group and mode of a file or socket the bridge
+// creates, so other users such as chrony or monitoring can access it
+type Permissions struct {
+	// Owner and Group are names or numeric ids, empty to leave unchanged
+	Owner string `yaml:"owner"`
+	Group string `yaml:"group"`
+	// Mode is an octal file mode such as "0640", empty to leave unchanged
+	Mode string `yaml:"mode"`
+}
+
+// FileMode parses Mode, ok is false if it is unset
+func (p Permissions) FileMode() (mode os.FileMode, ok bool, err error) {
+	if p.Mode == "" {
+		return 0, false, nil
+	}
+	n, err := strconv.ParseUint(p.Mode, 8, 32)
+	if err != nil || n > 0o7777 {
+		return 0, false, fmt.Errorf("mode %q must be octal, e.g. 0640", p.Mode)
+	}
+	return os.FileMode(n), true, nil
+}
+
+// MQTT configures publishing to an MQTT broker
+type MQTT struct {
+	// Broker is the broker URL, e.g. tcp://localhost:1883, empty to disable
+	Broker   string `yaml:"broker"`
+	ClientID string `yaml:"client_id"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// TopicPrefix is prepended to every topic
+	TopicPrefix string `yaml:"topic_prefix"`
+	QoS         int    `yaml:"qos"`
+	// Interval is how often the status is published
+	Interval time.Duration `yaml:"interval"`
+	// Discovery publishes Home Assistant MQTT discovery messages below
+	// DiscoveryPrefix
+	Discovery       bool   `yaml:"discovery"`
+	DiscoveryPrefix string `yaml:"discovery_prefix"`
+}
+
+// D-Bus buses
+const (
+	SystemBus  = "system"
+	SessionBus = "session"
+)
+
+// DBus configures the D-Bus interface
+type DBus struct {
+	// Bus is the bus to expose the status on, system or session, empty to
+	// disable
+	Bus string `yaml:"bus"`
+}
+
+// Prometheus configures the node_exporter textfile output
+type Prometheus struct {
+	// Textfile is the .prom file to write for the node_exporter textfile
+	// collector, empty to disable
+	Textfile string        `yaml:"textfile"`
+	Interval time.Duration `yaml:"interval"`
+	// Permissions apply to the textfile, readable by everyone by default
+	Permissions Permissions `yaml:"permissions"`
+}
+
+// History configures the SQLite database keeping samples, status
+// transitions and statistics across restarts
+type History struct {
+	// Database is the SQLite file, empty to disable
+	Database string `yaml:"database"`
+	// Retention is how long rows are kept
+	Retention time.Duration `yaml:"retention"`
+	// StatsInterval is how often the statistics are stored
+	StatsInterval time.Duration `yaml:"stats_interval"`
+	// Permissions apply to the database file
+	Permissions Permissions `yaml:"permissions"`
+}
+
+// State configures the file that keeps the counters, last known status and
+// calibration across restarts
+type State struct {
+	// File is where the state is saved, empty to disable
+	File string `yaml:"file"`
+	// Interval is how often the state is saved, besides on shutdown
+	Interval time.Duration `yaml:"interval"`
+}
+
+// InfluxDB configures writing statistics to InfluxDB. The v2 API is used
+// when a bucket is set and the v1 API otherwise.
+type InfluxDB struct {
+	// URL is the server address, e.g. http://localhost:8086, empty to
+	// disable
+	URL string `yaml:"url"`
+	// Database, Username and Password are used with InfluxDB 1.x
+	Database string `yaml:"database"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// Org, Bucket and Token are used with InfluxDB 2.x
+	Org    string `yaml:"org"`
+	Bucket string `yaml:"bucket"`
+	Token  string `yaml:"token"`
+
+	Measurement string        `yaml:"measurement"`
+	Interval    time.Duration `yaml:"interval"`
+}
+
+// Webhook configures JSON notifications of state changes
+type Webhook struct {
+	// URL receives a POST for each event, empty to disable
+	URL string `yaml:"url"`
+	// StaleAfter raises a stale event after this long without a sample, 0
+	// to disable
+	StaleAfter time.Duration `yaml:"stale_after"`
+	// MinInterval is the minimum time between two events of the same kind
+	MinInterval time.Duration `yaml:"min_interval"`
+	// Retries is how often a failed delivery is retried
+	Retries int `yaml:"retries"`
+}
+
+// SMTP configures email alerts for lock loss, long holdover and stale data
+type SMTP struct {
+	// Server is the mail server host:port, empty to disable
+	Server   string `yaml:"server"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	// TLS connects with implicit TLS, usually on port 465. Otherwise
+	// STARTTLS is used when the server offers it.
+	TLS  bool     `yaml:"tls"`
+	From string   `yaml:"from"`
+	To   []string `yaml:"to"`
+	// StaleAfter raises a stale alert after this long without a sample, 0
+	// to disable
+	StaleAfter time.Duration `yaml:"stale_after"`
+	// HoldoverAfter raises a holdover alert once a holdover lasts this long,
+	// 0 to disable
+	HoldoverAfter time.Duration `yaml:"holdover_after"`
+	// MinInterval is the minimum time between two alerts of the same kind
+	MinInterval time.Duration `yaml:"min_interval"`
+	// Retries is how often a failed delivery is retried
+	Retries int `yaml:"retries"`
+	// Templates override the subject and body of the lock_lost, holdover,
+	// stale and clock_alarm alerts
+	Templates map[string]EmailTemplate `yaml:"templates"`
+}
+
+// EmailTemplate is a text/template for an alert email. Empty fields use the
+// built in template.
+type EmailTemplate struct {
+	Subject string `yaml:"subject"`
+	Body    string `yaml:"body"`
+}
+
+// Logging configures log output
+type Logging struct {
+	// Level is the minimum level logged: debug, info, warn or error
+	Level string `yaml:"level"`
+	// Format is text or json
+	Format string `yaml:"format"`
+	// File receives log output instead of stderr when set
+	File string `yaml:"file"`
+	// MaxSizeMB rotates the file once it reaches this size, 0 to never
+	// rotate by size
+	MaxSizeMB int `yaml:"max_size_mb"`
+	// RotateInterval rotates the file at multiples of this interval in UTC,
+	// such as 24h for daily files, 0 to never rotate by time
+	RotateInterval time.Duration `yaml:"rotate_interval"`
+	// MaxFiles is the number of rotated files kept
+	MaxFiles int `yaml:"max_files"`
+	// Syslog receives log output instead of stderr when enabled
+	Syslog Syslog `yaml:"syslog"`
+	// Journal logs to the systemd journal natively: auto when stderr is the
+	// journal, always or never. File and Syslog take precedence.
+	Journal        string        `yaml:"journal"`
+	StatusInterval time.Duration `yaml:"status_interval"`
+	// Permissions apply to the log file
+	Permissions Permissions `yaml:"permissions"`
+}
+
+// Syslog configures logging to a local or remote syslog server
+type Syslog struct {
+	Enabled bool `yaml:"enabled"`
+	// Server is host:port or udp://host:port for UDP, tcp://host:port for
+	// TCP, empty for the local syslog daemon
+	Server string `yaml:"server"`
+	// Format is rfc3164 or rfc5424
+	Format   string `yaml:"format"`
+	Facility string `yaml:"facility"`
+	// Tag is the program name in each message
+	Tag string `yaml:"tag"`
+}
+
+// Validate checks the server, format and facility
+func (s Syslog) Validate() error {
+	if _, _, err := syslog.ParseAddress(s.Server); err != nil {
+		return err
+	}
+	if _, err := syslog.ParseFormat(s.Format); err != nil {
+		return err
+	}
+	if _, err := syslog.ParseFacility(s.Facility); err != nil {
+		return err
+	}
+	if s.Tag == "" || strings.ContainsAny(s.Tag, " :[]") {
+		return fmt.Errorf("invalid tag %q", s.Tag)
+	}
+	return nil
+}
+
+// Replay configures playback of a capture file instead of the serial port
+type Replay struct {
+	// File is the capture to replay, empty to read the serial port
+	File string `yaml:"file"`
+	// Speed divides the gaps between records, 0 to replay without waiting
+	Speed float64 `yaml:"speed"`
+}
+
+// Capture configures recording of the raw input
+type Capture struct {
+	// File receives the raw input for later replay, empty to disable
+	File string `yaml:"file"`
+	// Permissions apply to the capture file
+	Permissions Permissions `yaml:"permissions"`
+}
+
+// LeapSeconds configures the leap second table
+type LeapSeconds struct {
+	// File is an IERS leap-seconds.list, empty to trust the receiver
+	File string `yaml:"file"`
+}
+
+// NTPCheck configures the comparison of the samples with independent NTP
+// servers
+type NTPCheck struct {
+	// Servers are queried as host or host:port, empty to disable
+	Servers StringList `yaml:"servers"`
+	// Interval is how often the servers are queried
+	Interval time.Duration `yaml:"interval"`
+	// Threshold raises an alarm when the samples and the median of the
+	// servers differ by more than this
+	Threshold time.Duration `yaml:"threshold"`
+}
+
+// minNTPInterval keeps the queries to public servers polite
+const minNTPInterval = time.Minute
+
+// Privileges configures the unprivileged user the bridge switches to after
+// startup
+type Privileges struct {
+	// User is a name or uid, empty to keep running as the starting user
+	User string `yaml:"user"`
+	// Group is a name or gid, empty for the primary group of User
+	Group string `yaml:"group"`
+}
+
+// Daemon configures running in the background without systemd
+type Daemon struct {
+	// Enabled detaches from the terminal once started
+	Enabled bool `yaml:"enabled"`
+	// PIDFile is written with the process ID, empty for none
+	PIDFile string `yaml:"pidfile"`
+}
+
+// Default returns the built in configuration
+func Default() *Config {
+	dev := defaultDevice()
+	dev.Serial.Port = "/dev/ttyAMA0"
+	dev.Outputs.Chrony.Socket = "/var/run/chrony/gpsdo.sock"
+
+	return &Config{
+		Device:     dev,
+		MQTT:       MQTT{TopicPrefix: "gogpsdo", Interval: 30 * time.Second, DiscoveryPrefix: "homeassistant"},
+		InfluxDB:   InfluxDB{Measurement: "gogpsdo", Interval: 10 * time.Second},
+		Prometheus: Prometheus{Interval: 15 * time.Second},
+		History:    History{Retention: 7 * 24 * time.Hour, StatsInterval: time.Minute},
+		State:      State{Interval: 5 * time.Minute},
+		Webhook:    Webhook{StaleAfter: 10 * time.Second, MinInterval: 5 * time.Minute, Retries: 3},
+		SMTP:       SMTP{StaleAfter: time.Minute, HoldoverAfter: time.Hour, MinInterval: 30 * time.Minute, Retries: 3},
+		Logging:    Logging{Level: "info", Format: "text", Journal: "auto", MaxFiles: 5, Syslog: Syslog{Format: "rfc3164", Facility: "daemon", Tag: "gogpsdo"}, StatusInterval: 30 * time.Second},
+		Replay:     Replay{Speed: 1},
+		NTPCheck:   NTPCheck{Interval: 5 * time.Minute, Threshold: 100 * time.Millisecond},
+	}
+}
+
+// defaultDevice returns the defaults of an entry in the devices list
+func defaultDevice() Device {
+	return Device{
+		Protocol:  protocol.Default,
+		MaxJump:   500 * time.Millisecond,
+		Timescale: "utc",
+		Recent:    Recent{Samples: 600, Transitions: 100},
+		Outputs:   Outputs{SHM: SHM{Unit: -1}, CSV: CSV{MaxSizeMB: 10, MaxFiles: 5}, PHC: PHC{TAI: true}, Kernel: Kernel{Step: 128 * time.Millisecond}},
+		SCPI:      SCPI{Interval: time.Minute},
+		Chronyc:   Chronyc{Interval: 16 * time.Second},
+		Display:   Display{Address: 0x3c, Controller: "ssd1306"},
+	}
+}
+
+// deviceList is the devices list of the config file
+type deviceList []Device
+
+// UnmarshalYAML decodes the devices list. Unlike the top level device,
+// entries start without a serial port or chrony socket so that two devices
+// never share one by default.
+func (l *deviceList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind != yaml.SequenceNode {
+		return fmt.Errorf("line %d: devices must be a list", value.Line)
+	}
+
+	for _, node := range value.Content {
+		dev := defaultDevice()
+
+		// Node.Decode does not reject unknown fields, decode a copy instead
+		data, err := yaml.Marshal(node)
+		if err != nil {
+			return err
+		}
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(&dev); err != nil {
+			return fmt.Errorf("device at line %d: %w", node.Line, err)
+		}
+		*l = append(*l, dev)
+	}
+	return nil
+}
+
+// DeviceList returns the configured devices, either the Devices list or the
+// single top level device
+func (c *Config) DeviceList() []Device {
+	if len(c.Devices) > 0 {
+		return c.Devices
+	}
+	return []Device{c.Device}
+}
+
+// Load reads a YAML config file on top of the defaults
+func Load(path string) (*Config, error) {
+	cfg := Default()
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dec := yaml.NewDecoder(f)
+	dec.KnownFields(true)
+	if err := dec.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Validate checks the configuration for values the bridge cannot use
+func (c *Config) Validate() error {
+	devices := c.DeviceList()
+	if len(devices) > 1 {
+		if err := validateUnique(devices); err != nil {
+			return err
+		}
+		if c.Replay.File != "" || c.Capture.File != "" {
+			return errors.New("capture and replay require a single device")
+		}
+	}
+	for _, d := range devices {
+		if err := d.Validate(); err != nil {
+			if d.Name != "" {
+				return fmt.Errorf("device %s: %w", d.Name, err)
+			}
+			return err
+		}
+		if d.Serial.Port == "-" && c.Daemon.Enabled {
+			return errors.New("a daemon has no standard input, port - can't be used with daemon")
+		}
+		if d.Outputs.Kernel.Enabled && c.Privileges.User != "" {
+			return errors.New("kernel discipline needs root to steer the system clock, it can't drop privileges")
+		}
+	}
+
+	if c.MQTT.Broker != "" {
+		if c.MQTT.QoS < 0 || c.MQTT.QoS > 2 {
+			return fmt.Errorf("mqtt qos %d out of range 0..2", c.MQTT.QoS)
+		}
+		if c.MQTT.Interval <= 0 {
+			return errors.New("mqtt interval must be positive")
+		}
+		if c.MQTT.TopicPrefix == "" {
+			return errors.New("mqtt topic_prefix is required")
+		}
+		if c.MQTT.Discovery && c.MQTT.DiscoveryPrefix == "" {
+			return errors.New("mqtt discovery_prefix is required with discovery")
+		}
+	}
+	switch c.DBus.Bus {
+	case "", SystemBus, SessionBus:
+	default:
+		return fmt.Errorf("dbus bus %q must be system or session", c.DBus.Bus)
+	}
+	if c.InfluxDB.URL != "" {
+		if c.InfluxDB.Bucket == "" && c.InfluxDB.Database == "" {
+			return errors.New("influxdb needs a database (1.x) or bucket (2.x)")
+		}
+		if c.InfluxDB.Measurement == "" {
+			return errors.New("influxdb measurement is required")
+		}
+		if c.InfluxDB.Interval <= 0 {
+			return errors.New("influxdb interval must be positive")
+		}
+	}
+	if c.Prometheus.Textfile != "" {
+		if !strings.HasSuffix(c.Prometheus.Textfile, ".prom") {
+			return fmt.Errorf("prometheus textfile %q must end in .prom for node_exporter to read it", c.Prometheus.Textfile)
+		}
+		if c.Prometheus.Interval <= 0 {
+			return errors.New("prometheus interval must be positive")
+		}
+	}
+	if c.History.Database != "" && (c.History.Retention <= 0 || c.History.StatsInterval <= 0) {
+		return errors.New("history retention and stats_interval must be positive")
+	}
+	if c.State.File != "" && c.State.Interval <= 0 {
+		return errors.New("state interval must be positive")
+	}
+	if c.Webhook.URL != "" {
+		u, err := url.Parse(c.Webhook.URL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			return fmt.Errorf("webhook url %q must be http or https", c.Webhook.URL)
+		}
+		if c.Webhook.StaleAfter < 0 || c.Webhook.MinInterval < 0 || c.Webhook.Retries < 0 {
+			return errors.New("webhook stale_after, min_interval and retries must not be negative")
+		}
+	}
+	if c.SMTP.Server != "" {
+		if _, _, err := net.SplitHostPort(c.SMTP.Server); err != nil {
+			return fmt.Errorf("smtp server %q must be host:port", c.SMTP.Server)
+		}
+		if c.SMTP.From == "" || len(c.SMTP.To) == 0 {
+			return errors.New("smtp needs a from address and at least one recipient")
+		}
+		if c.SMTP.StaleAfter < 0 || c.SMTP.HoldoverAfter < 0 || c.SMTP.MinInterval < 0 || c.SMTP.Retries < 0 {
+			return errors.New("smtp stale_after, holdover_after, min_interval and retries must not be negative")
+		}
+		for name := range c.SMTP.Templates {
+			switch name {
+			case "lock_lost", "holdover", "stale", "clock_alarm", "ntp_alarm", "antenna_fault":
+			default:
+				return fmt.Errorf("unknown smtp template %q, expected lock_lost, holdover, stale, clock_alarm, ntp_alarm or antenna_fault", name)
+			}
+		}
+	}
+	if len(c.NTPCheck.Servers) > 0 {
+		if c.NTPCheck.Interval < minNTPInterval {
+			return fmt.Errorf("ntp_check interval must be at least %s", minNTPInterval)
+		}
+		if c.NTPCheck.Threshold <= 0 {
+			return errors.New("ntp_check threshold must be positive")
+		}
+	}
+	if c.Privileges.Group != "" && c.Privileges.User == "" {
+		return errors.New("group requires a user to drop privileges to")
+	}
+	if _, _, err := c.HTTP.Permissions.FileMode(); err != nil {
+		return fmt.Errorf("http permissions: %w", err)
+	}
+	if _, _, err := c.Control.Permissions.FileMode(); err != nil {
+		return fmt.Errorf("control permissions: %w", err)
+	}
+	if _, _, err := c.GRPC.Permissions.FileMode(); err != nil {
+		return fmt.Errorf("grpc permissions: %w", err)
+	}
+	if _, _, err := c.Prometheus.Permissions.FileMode(); err != nil {
+		return fmt.Errorf("prometheus permissions: %w", err)
+	}
+	if _, _, err := c.History.Permissions.FileMode(); err != nil {
+		return fmt.Errorf("history permissions: %w", err)
+	}
+	if _, _, err := c.Logging.Permissions.FileMode(); err != nil {
+		return fmt.Errorf("logging permissions: %w", err)
+	}
+	if _, _, err := c.Capture.Permissions.FileMode(); err != nil {
+		return fmt.Errorf("capture permissions: %w", err)
+	}
+	if c.Logging.Format != "text" && c.Logging.Format != "json" {
+		return fmt.Errorf("log format %q must be text or json", c.Logging.Format)
+	}
+	if c.Logging.MaxSizeMB < 0 || c.Logging.RotateInterval < 0 || c.Logging.MaxFiles < 0 {
+		return errors.New("logging max_size_mb, rotate_interval and max_files must not be negative")
+	}
+	switch c.Logging.Journal {
+	case "auto", "always", "never":
+	default:
+		return fmt.Errorf("logging journal %q must be auto, always or never", c.Logging.Journal)
+	}
+	if c.Logging.File != "" && c.Logging.Syslog.Enabled {
+		return errors.New("logging file and syslog are exclusive")
+	}
+	if c.Logging.Syslog.Enabled {
+		if err := c.Logging.Syslog.Validate(); err != nil {
+			return fmt.Errorf("logging: %w", err)
+		}
+	}
+	if c.Logging.StatusInterval <= 0 {
+		return errors.New("logging status_interval must be positive")
+	}
+	if c.Replay.Speed < 0 {
+		return errors.New("replay speed must not be negative")
+	}
+	if c.Replay.File != "" && c.Replay.File == c.Capture.File {
+		return errors.New("capture file must differ from the replay file")
+	}
+	return nil
+}
+
+// Validate checks a single device
+func (d *Device) Validate() error {
+	if d.Serial.Port == "" {
+		return errors.New("serial port is required")
+	}
+	if _, ok := protocol.Lookup(d.Protocol); !ok {
+		return fmt.Errorf("unknown protocol %q, see gogpsdo protocols", d.Protocol)
+	}
+	if d.Protocol == "generic" {
+		if err := d.Generic.Validate(); err != nil {
+			return fmt.Errorf("generic: %w", err)
+		}
+	}
+	if d.Protocol == protocol.Default {
+		if err := d.Z3805A.Validate(); err != nil {
+			return fmt.Errorf("z3805a: %w", err)
+		}
+	}
+	if d.Serial.Baud < 0 {
+		return errors.New("serial baud must not be negative")
+	}
+	if d.Serial.Wait < 0 {
+		return errors.New("serial wait must not be negative")
+	}
+	if d.Serial.DataBits != 0 && (d.Serial.DataBits < 5 || d.Serial.DataBits > 8) {
+		return fmt.Errorf("serial data bits %d out of range 5..8", d.Serial.DataBits)
+	}
+	if d.Serial.StopBits != 0 && d.Serial.StopBits != 1 && d.Serial.StopBits != 2 {
+		return fmt.Errorf("serial stop bits %d must be 1 or 2", d.Serial.StopBits)
+	}
+	if _, err := d.Serial.Settings(); err != nil {
+		return fmt.Errorf("serial: %w", err)
+	}
+	if netserial.IsAddress(d.Serial.Port) {
+		if _, _, err := netserial.Split(d.Serial.Port); err != nil {
+			return err
+		}
+	}
+	sources := 0
+	for _, set := range []bool{d.PPS.Device != "", d.PPS.ModemLine() != "", d.PPS.GPIO != ""} {
+		if set {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return errors.New("pps device, pps line and pps gpio are exclusive")
+	}
+	if line := d.PPS.ModemLine(); line != "" {
+		l, err := pps.ParseLine(line)
+		if err != nil {
+			return fmt.Errorf("pps line: %w", err)
+		}
+		if d.PPS.DCD && l != pps.DCD {
+			return fmt.Errorf("pps dcd and pps line %s are exclusive", line)
+		}
+		if port := cmp.Or(d.PPS.Port, d.Serial.Port); netserial.IsAddress(port) || port == "-" {
+			return fmt.Errorf("pps line %s needs a local serial port, set pps port", line)
+		}
+	} else if d.PPS.Port != "" {
+		return errors.New("pps port needs a pps line")
+	}
+	if d.PPS.GPIO != "" {
+		if _, _, err := pps.ParseGPIO(d.PPS.GPIO); err != nil {
+			return fmt.Errorf("pps gpio: %w", err)
+		}
+	}
+	if d.Outputs.SHM.Unit < -1 || d.Outputs.SHM.Unit > 3 {
+		return fmt.Errorf("shm unit %d out of range -1..3", d.Outputs.SHM.Unit)
+	}
+	if d.Outputs.Chrony.Socket == "" && d.Outputs.SHM.Unit < 0 && d.Outputs.GPSD.Listen == "" && d.Outputs.CSV.File == "" && d.Outputs.FIFO.Path == "" && d.Outputs.PHC.Device == "" && !d.Outputs.Kernel.Enabled && d.Outputs.NTP.Listen == "" {
+		return errors.New("no outputs configured, set a chrony socket, shm unit, gpsd listener, ntp listener, csv file, fifo, phc device or kernel discipline")
+	}
+	if d.Outputs.CSV.MaxSizeMB < 0 || d.Outputs.CSV.MaxFiles < 0 {
+		return errors.New("csv max_size_mb and max_files must not be negative")
+	}
+	if _, _, err := d.Outputs.CSV.Permissions.FileMode(); err != nil {
+		return fmt.Errorf("csv permissions: %w", err)
+	}
+	if _, _, err := d.Outputs.FIFO.Permissions.FileMode(); err != nil {
+		return fmt.Errorf("fifo permissions: %w", err)
+	}
+	ppsGPIO := ""
+	if d.PPS.GPIO != "" {
+		chip, line, _ := pps.ParseGPIO(d.PPS.GPIO)
+		ppsGPIO = fmt.Sprintf("%s:%d", chip, line)
+	}
+	leds := make(map[string]bool)
+	for _, spec := range d.Outputs.LED.GPIO {
+		chip, line, err := pps.ParseGPIO(spec)
+		if err != nil {
+			return fmt.Errorf("led gpio: %w", err)
+		}
+		key := fmt.Sprintf("%s:%d", chip, line)
+		if leds[key] {
+			return fmt.Errorf("led gpio %s is used twice", key)
+		}
+		if key == ppsGPIO {
+			return fmt.Errorf("led gpio %s is the pps gpio", key)
+		}
+		leds[key] = true
+	}
+	if d.Outputs.PHC.Step < 0 {
+		return errors.New("phc step must not be negative")
+	}
+	if d.Outputs.Kernel.Enabled && (d.Outputs.Chrony.Socket != "" || d.Outputs.SHM.Unit >= 0) {
+		return errors.New("kernel discipline steers the system clock itself, disable the chrony socket and shm unit")
+	}
+	if d.Outputs.Kernel.Step < 0 {
+		return errors.New("kernel step must not be negative")
+	}
+	if d.SampleInterval < 0 {
+		return errors.New("sample interval must not be negative")
+	}
+	scale, err := gpsdo.ParseTimescale(d.Timescale)
+	if err != nil {
+		return err
+	}
+	if scale != gpsdo.UTC && (d.Outputs.Kernel.Enabled || d.Outputs.NTP.Listen != "" || d.Outputs.GPSD.Listen != "") {
+		return fmt.Errorf("kernel discipline, ntp and gpsd outputs are UTC only, they can't be used with timescale %s", d.Timescale)
+	}
+	if d.RolloverPivot != "" {
+		if _, err := time.Parse(time.DateOnly, d.RolloverPivot); err != nil {
+			return fmt.Errorf("rollover pivot: %w", err)
+		}
+	}
+	if d.MaxJump < 0 {
+		return errors.New("max jump must not be negative")
+	}
+	if d.ClockAlarm < 0 {
+		return errors.New("clock alarm must not be negative")
+	}
+	if d.Holdover.Max < 0 {
+		return errors.New("holdover max must not be negative")
+	}
+	if d.Recent.Samples < 0 {
+		return errors.New("recent samples must not be negative")
+	}
+	if d.Recent.Transitions < 1 {
+		return errors.New("recent transitions must be at least 1")
+	}
+	if d.SCPI.Port != "" && d.SCPI.Interval <= 0 {
+		return errors.New("scpi interval must be positive")
+	}
+	if len(d.Chronyc.RefID) > 4 {
+		return fmt.Errorf("chronyc refid %q is longer than 4 characters", d.Chronyc.RefID)
+	}
+	if d.Chronyc.RefID != "" && d.Chronyc.Interval <= 0 {
+		return errors.New("chronyc interval must be positive")
+	}
+	if d.Display.I2C != "" {
+		if d.Display.Address < 0x03 || d.Display.Address > 0x77 {
+			return fmt.Errorf("display address 0x%02x out of range 0x03..0x77", d.Display.Address)
+		}
+		if d.Display.Controller != "ssd1306" && d.Display.Controller != "sh1106" {
+			return fmt.Errorf("display controller %q must be ssd1306 or sh1106", d.Display.Controller)
+		}
+	}
+	return nil
+}
+
+// validateUnique checks that devices have distinct names and never share a
+// port or output
+func validateUnique(devices []Device) error {
+	seen := make(map[string]string)
+	claim := func(kind, value, name string) error {
+		if value == "" {
+			return nil
+		}
+		key := kind + " " + value
+		if other, ok := seen[key]; ok {
+			return fmt.Errorf("devices %s and %s share %s", other, name, key)
+		}
+		seen[key] = name
+		return nil
+	}
+
+	for _, d := range devices {
+		if d.Name == "" {
+			return errors.New("every device needs a name")
+		}
+		shm := ""
+		if d.Outputs.SHM.Unit >= 0 {
+			shm = fmt.Sprint(d.Outputs.SHM.Unit)
+		}
+		ppsPort := ""
+		if d.PPS.Port != d.Serial.Port {
+			ppsPort = d.PPS.Port
+		}
+		var gpio []string
+		for _, spec := range append([]string{d.PPS.GPIO}, d.Outputs.LED.GPIO...) {
+			if spec != "" {
+				chip, line, _ := pps.ParseGPIO(spec)
+				gpio = append(gpio, fmt.Sprintf("%s:%d", chip, line))
+			}
+		}
+		display := ""
+		if d.Display.I2C != "" {
+			display = fmt.Sprintf("%s 0x%02x", d.Display.I2C, d.Display.Address)
+		}
+		kernel := ""
+		if d.Outputs.Kernel.Enabled {
+			kernel = "of the system clock"
+		}
+		for _, line := range gpio {
+			if err := claim("gpio line", line, d.Name); err != nil {
+				return err
+			}
+		}
+		for _, err := range []error{
+			claim("name", d.Name, d.Name),
+			claim("serial port", d.Serial.Port, d.Name),
+			claim("serial port", d.SCPI.Port, d.Name),
+			claim("display", display, d.Name),
+			claim("pps device", d.PPS.Device, d.Name),
+			claim("serial port", ppsPort, d.Name),
+			claim("chrony socket", d.Outputs.Chrony.Socket, d.Name),
+			claim("shm unit", shm, d.Name),
+			claim("gpsd listener", d.Outputs.GPSD.Listen, d.Name),
+			claim("ntp listener", d.Outputs.NTP.Listen, d.Name),
+			claim("raw tcp listener", d.RawTCP.Listen, d.Name),
+			claim("csv file", d.Outputs.CSV.File, d.Name),
+			claim("fifo", d.Outputs.FIFO.Path, d.Name),
+			claim("phc device", d.Outputs.PHC.Device, d.Name),
+			claim("kernel discipline", kernel, d.Name),
+		} {
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// bindFlags registers the command line flags that override cfg
+func bindFlags(fs *flag.FlagSet, cfg *Config) {
+	fs.StringVar(&cfg.Serial.Port, "port", cfg.Serial.Port, "TOD TTY Input, auto to probe the USB and onboard serial ports, a tcp:// or rfc2217:// network serial port, or - for stdin")
+	fs.DurationVar(&cfg.Serial.Wait, "port-wait", cfg.Serial.Wait, "Wait this long at startup for the serial port and PPS device to appear (0 to fail at once)")
+	fs.IntVar(&cfg.Serial.Baud, "baud", cfg.Serial.Baud, "Serial baud rate (0 for the protocol default)")
+	fs.IntVar(&cfg.Serial.DataBits, "data-bits", cfg.Serial.DataBits, "Serial data bits, 5 to 8 (0 for the protocol default)")
+	fs.StringVar(&cfg.Serial.Parity, "parity", cfg.Serial.Parity, "Serial parity, none, odd or even (default the protocol's)")
+	fs.IntVar(&cfg.Serial.StopBits, "stop-bits", cfg.Serial.StopBits, "Serial stop bits, 1 or 2 (0 for the protocol default)")
+	fs.StringVar(&cfg.Profile, "profile", cfg.Profile, "Receiver profile setting protocol, baud rate and PPS source (garmin18x)")
+	fs.StringVar(&cfg.Protocol, "protocol", cfg.Protocol, "Input protocol, see gogpsdo protocols for the list")
+	fs.StringVar(&cfg.PPS.Device, "pps", cfg.PPS.Device, "Kernel PPS device paired with the TOD input, e.g. /dev/pps0")
+	fs.BoolVar(&cfg.PPS.DCD, "pps-dcd", cfg.PPS.DCD, "Take the PPS from the DCD line of the serial port, short for -pps-line dcd")
+	fs.StringVar(&cfg.PPS.Line, "pps-line", cfg.PPS.Line, "Take the PPS from this modem line of the serial port (dcd, cts, dsr)")
+	fs.StringVar(&cfg.PPS.Port, "pps-port", cfg.PPS.Port, "Serial port of the PPS line, if not the TOD port")
+	fs.StringVar(&cfg.PPS.GPIO, "pps-gpio", cfg.PPS.GPIO, "Take the PPS from this GPIO line, as chip:line or a line of gpiochip0, e.g. 18")
+	fs.IntVar(&cfg.PPS.SecondOffset, "pps-second-offset", cfg.PPS.SecondOffset, "Seconds added to the TOD time to label the preceding PPS edge")
+	fs.StringVar(&cfg.RolloverPivot, "rollover-pivot", cfg.RolloverPivot, "Earliest plausible GPS date, YYYY-MM-DD, older dates get 1024 week rollover corrections (default build date)")
+	fs.DurationVar(&cfg.MaxJump, "max-jump", cfg.MaxJump, "Reject samples this far from the time predicted by the previous sample (0 to disable)")
+	fs.DurationVar(&cfg.ClockAlarm, "clock-alarm", cfg.ClockAlarm, "Alarm when the sample time and the system clock differ by more than this, e.g. 1s (0 to disable)")
+	fs.Float64Var(&cfg.Offset, "offset", cfg.Offset, "Static calibration in seconds added to the sample offset, e.g. -0.245")
+	fs.DurationVar(&cfg.SampleInterval, "sample-interval", cfg.SampleInterval, "Send one sample per interval to the outputs, e.g. 16s (0 for every sample)")
+	fs.BoolVar(&cfg.SampleAverage, "sample-average", cfg.SampleAverage, "Send the mean offset of each sample interval instead of its first sample")
+	fs.StringVar(&cfg.Timescale, "timescale", cfg.Timescale, "Time scale of the samples sent to the outputs (utc, tai, gps)")
+	fs.DurationVar(&cfg.Holdover.Max, "holdover-max", cfg.Holdover.Max, "Stop forwarding samples after this long in holdover (0 for no limit)")
+	fs.IntVar(&cfg.Recent.Samples, "recent", cfg.Recent.Samples, "Keep this many samples in memory for the recent API and control command (0 to disable)")
+	fs.StringVar(&cfg.SCPI.Port, "scpi", cfg.SCPI.Port, "Z3805A SCPI port for receiver diagnostics, e.g. /dev/ttyUSB0")
+	fs.DurationVar(&cfg.SCPI.Interval, "scpi-interval", cfg.SCPI.Interval, "How often to read the SCPI diagnostics")
+	fs.StringVar(&cfg.Chronyc.RefID, "chrony-refid", cfg.Chronyc.RefID, "Report chronyd's view of the refclock with this refid in the status API, e.g. GPSD")
+	fs.StringVar(&cfg.Display.I2C, "display", cfg.Display.I2C, "Show the time and status on an SSD1306 or SH1106 OLED on this I2C bus, e.g. /dev/i2c-1")
+	fs.StringVar(&cfg.Outputs.Chrony.Socket, "sock", cfg.Outputs.Chrony.Socket, "Chrony SOCK refclock path (empty to disable)")
+	fs.IntVar(&cfg.Outputs.SHM.Unit, "shm", cfg.Outputs.SHM.Unit, "NTP SHM refclock unit 0-3 (-1 to disable)")
+	fs.StringVar(&cfg.Outputs.GPSD.Listen, "gpsd", cfg.Outputs.GPSD.Listen, "Serve gpsd JSON on this address, e.g. 127.0.0.1:2947")
+	fs.StringVar(&cfg.Outputs.NTP.Listen, "ntp", cfg.Outputs.NTP.Listen, "Serve NTP on this address, e.g. :123")
+	fs.StringVar(&cfg.RawTCP.Listen, "raw-tcp", cfg.RawTCP.Listen, "Rebroadcast the raw serial input to TCP clients on this address, e.g. :2000")
+	fs.StringVar(&cfg.Outputs.CSV.File, "csv", cfg.Outputs.CSV.File, "Append every accepted sample to this CSV file")
+	fs.StringVar(&cfg.Outputs.FIFO.Path, "fifo", cfg.Outputs.FIFO.Path, "Write every sample as a line of JSON to this named pipe, created if missing")
+	fs.StringVar(&cfg.Outputs.PHC.Device, "phc", cfg.Outputs.PHC.Device, "Steer this PTP hardware clock to the samples, e.g. /dev/ptp0")
+	fs.Var(&cfg.Outputs.LED.GPIO, "led", "Light status LEDs on these GPIO `lines`, comma separated, as chip:line or a line of gpiochip0, e.g. 17")
+	fs.BoolVar(&cfg.Outputs.Kernel.Enabled, "kernel", cfg.Outputs.Kernel.Enabled, "Discipline the system clock through adjtimex, without chrony or ntpd")
+	fs.StringVar(&cfg.HTTP.Listen, "http", cfg.HTTP.Listen, "Serve the HTTP status API on this address, e.g. :8080")
+	fs.StringVar(&cfg.Control.Socket, "control", cfg.Control.Socket, "Serve the control socket at this path, e.g. /run/gogpsdo.sock")
+	fs.StringVar(&cfg.GRPC.Listen, "grpc", cfg.GRPC.Listen, "Serve the gRPC API on this address, e.g. localhost:50051")
+	fs.BoolVar(&cfg.GRPC.Control, "grpc-control", cfg.GRPC.Control, "Serve the gRPC control RPCs, which change the offset and send SCPI commands")
+	fs.StringVar(&cfg.InfluxDB.URL, "influx", cfg.InfluxDB.URL, "Write statistics to this InfluxDB server, e.g. http://localhost:8086")
+	fs.StringVar(&cfg.InfluxDB.Database, "influx-db", cfg.InfluxDB.Database, "InfluxDB 1.x database")
+	fs.StringVar(&cfg.Prometheus.Textfile, "prom-textfile", cfg.Prometheus.Textfile, "Write Prometheus metrics to this node_exporter textfile, e.g. /var/lib/node_exporter/gogpsdo.prom")
+	fs.StringVar(&cfg.History.Database, "history", cfg.History.Database, "Keep samples, transitions and statistics in this SQLite database")
+	fs.StringVar(&cfg.State.File, "state-file", cfg.State.File, "Keep the counters, last status and calibration in this file across restarts, e.g. /var/lib/gogpsdo/state.json")
+	fs.StringVar(&cfg.MQTT.Broker, "mqtt", cfg.MQTT.Broker, "Publish status to this MQTT broker, e.g. tcp://localhost:1883")
+	fs.StringVar(&cfg.MQTT.TopicPrefix, "mqtt-prefix", cfg.MQTT.TopicPrefix, "MQTT topic prefix")
+	fs.IntVar(&cfg.MQTT.QoS, "mqtt-qos", cfg.MQTT.QoS, "MQTT QoS 0-2")
+	fs.BoolVar(&cfg.MQTT.Discovery, "mqtt-discovery", cfg.MQTT.Discovery, "Publish Home Assistant MQTT discovery messages")
+	fs.StringVar(&cfg.DBus.Bus, "dbus", cfg.DBus.Bus, "Expose the status on D-Bus, on the system or session `bus`")
+	fs.StringVar(&cfg.Webhook.URL, "webhook", cfg.Webhook.URL, "POST state change notifications to this URL")
+	fs.StringVar(&cfg.Logging.Level, "log-level", cfg.Logging.Level, "Log level (debug, info, warn, error)")
+	fs.StringVar(&cfg.Logging.Format, "log-format", cfg.Logging.Format, "Log format (text, json)")
+	fs.StringVar(&cfg.Logging.File, "log-file", cfg.Logging.File, "Append log output to this file instead of stderr")
+	fs.StringVar(&cfg.Logging.Journal, "log-journal", cfg.Logging.Journal, "Log to the systemd journal with priorities and fields (auto when stderr is the journal, always, never)")
+	fs.BoolVar(&cfg.Logging.Syslog.Enabled, "log-syslog", cfg.Logging.Syslog.Enabled, "Send log output to syslog instead of stderr")
+	fs.StringVar(&cfg.Logging.Syslog.Server, "log-syslog-server", cfg.Logging.Syslog.Server, "Remote syslog server, host:port for UDP or tcp://host:port (default the local syslog daemon)")
+	fs.StringVar(&cfg.Logging.Syslog.Format, "log-syslog-format", cfg.Logging.Syslog.Format, "Syslog message format (rfc3164, rfc5424)")
+	fs.StringVar(&cfg.Logging.Syslog.Facility, "log-syslog-facility", cfg.Logging.Syslog.Facility, "Syslog facility, e.g. daemon or local0")
+	fs.BoolVar(&cfg.Daemon.Enabled, "daemon", cfg.Daemon.Enabled, "Run in the background, logging to syslog unless -log-file is set")
+	fs.StringVar(&cfg.Daemon.PIDFile, "pidfile", cfg.Daemon.PIDFile, "Write the process ID to this file, e.g. /var/run/gogpsdo.pid")
+	fs.StringVar(&cfg.Privileges.User, "user", cfg.Privileges.User, "Switch to this user once the devices are open")
+	fs.StringVar(&cfg.Privileges.Group, "group", cfg.Privileges.Group, "Switch to this group once the devices are open (default the user's primary group)")
+	fs.BoolVar(&cfg.DryRun, "dry-run", cfg.DryRun, "Print the samples that would be sent to chrony instead of sending them, with SHM, PHC and kernel discipline disabled")
+	fs.StringVar(&cfg.Capture.File, "capture", cfg.Capture.File, "Append the raw serial input to this capture file")
+	fs.StringVar(&cfg.LeapSeconds.File, "leap-seconds", cfg.LeapSeconds.File, "Check the receiver leap seconds against this leap-seconds.list, e.g. "+leapsec.DefaultPath)
+	fs.Var(&cfg.NTPCheck.Servers, "ntp-check", "Alarm when the samples disagree with these NTP `servers`, comma separated, e.g. time.cloudflare.com,pool.ntp.org")
+	fs.StringVar(&cfg.Replay.File, "replay", cfg.Replay.File, "Replay a capture file instead of reading the serial port")
+	fs.Float64Var(&cfg.Replay.Speed, "replay-speed", cfg.Replay.Speed, "Replay speed multiplier (0 for as fast as possible)")
+}
+
+// Parse parses args into a Config. When -config is given the file is loaded
+// first and any flags set on the command line override its values.
+func Parse(fs *flag.FlagSet, args []string) (*Config, error) {
+	cfg := Default()
+	path := fs.String("config", "", "YAML config file, e.g. "+DefaultPath)
+	bindFlags(fs, cfg)
+
+	if err := fs.Parse(args); err != nil {
+		return nil, err
+	}
+
+	if *path != "" {
+		fileCfg, err := Load(*path)
+		if err != nil {
+			return nil, err
+		}
+
+		overlay := flag.NewFlagSet(fs.Name(), flag.ContinueOnError)
+		bindFlags(overlay, fileCfg)
+		fs.Visit(func(f *flag.Flag) {
+			if f.Name != "config" {
+				overlay.Set(f.Name, f.Value.String())
+			}
+		})
+		cfg = fileCfg
+	}
+
+	if err := cfg.applyProfiles(); err != nil {
+		return nil, err
+	}
+	return cfg, cfg.Validate()
+}
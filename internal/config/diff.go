@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Change is a value that differs between two configs
+type Change struct {
+	// Key is the YAML path of the value, e.g. devices[1].offset
+	Key string
+	Old any
+	New any
+}
+
+func (c Change) String() string {
+	return fmt.Sprintf("%s: %v -> %v", c.Key, c.Old, c.New)
+}
+
+// secretKeys are masked in changes
+var secretKeys = []string{"password", "token"}
+
+// Diff returns the values that differ from a to b, in file order. Lists of
+// equal length are compared element by element, other lists and maps as a
+// whole. Passwords and tokens are masked.
+func Diff(a, b *Config) []Change {
+	var changes []Change
+	diffValue(&changes, "", reflect.ValueOf(a).Elem(), reflect.ValueOf(b).Elem())
+	return changes
+}
+
+func diffValue(changes *[]Change, key string, a, b reflect.Value) {
+	switch {
+	case a.Kind() == reflect.Struct:
+		for i := range a.NumField() {
+			field := a.Type().Field(i)
+			name, opts, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+			switch {
+			case !field.IsExported() || name == "-":
+				continue
+			case opts == "inline":
+				diffValue(changes, key, a.Field(i), b.Field(i))
+			default:
+				diffValue(changes, joinKey(key, name), a.Field(i), b.Field(i))
+			}
+		}
+	case a.Kind() == reflect.Slice && a.Len() == b.Len() && a.Type().Elem().Kind() == reflect.Struct:
+		for i := range a.Len() {
+			diffValue(changes, fmt.Sprintf("%s[%d]", key, i), a.Index(i), b.Index(i))
+		}
+	case !reflect.DeepEqual(a.Interface(), b.Interface()):
+		change := Change{Key: key, Old: a.Interface(), New: b.Interface()}
+		last := key[strings.LastIndex(key, ".")+1:]
+		for _, secret := range secretKeys {
+			if last == secret {
+				change.Old, change.New = "***", "***"
+			}
+		}
+		*changes = append(*changes, change)
+	}
+}
+
+func joinKey(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
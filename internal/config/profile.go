@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// profile is a ready-made device configuration for a common receiver
+type profile struct {
+	protocol string
+	baud     int
+	// ppsDCD takes the PPS from the DCD line unless a kernel PPS device or
+	// another line is configured
+	ppsDCD bool
+}
+
+// profiles are the receivers selectable with the profile key
+var profiles = map[string]profile{
+	// Garmin GPS 18x LVC: NMEA at 4800 baud, PPS commonly wired to DCD
+	"garmin18x": {protocol: "nmea", baud: 4800, ppsDCD: true},
+}
+
+// profileNames returns the known profiles for error messages
+func profileNames() string {
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return strings.Join(names, ", ")
+}
+
+// applyProfiles fills in the profile settings of every device
+func (c *Config) applyProfiles() error {
+	if err := c.Device.applyProfile(); err != nil {
+		return err
+	}
+	for i := range c.Devices {
+		if err := c.Devices[i].applyProfile(); err != nil {
+			return fmt.Errorf("device %s: %w", c.Devices[i].Name, err)
+		}
+	}
+	return nil
+}
+
+// applyProfile fills in the settings of the device profile. The profile sets
+// the protocol, an explicit baud rate or PPS source is kept.
+func (d *Device) applyProfile() error {
+	if d.Profile == "" {
+		return nil
+	}
+	p, ok := profiles[d.Profile]
+	if !ok {
+		return fmt.Errorf("unknown profile %q (%s)", d.Profile, profileNames())
+	}
+	d.Protocol = p.protocol
+	if d.Serial.Baud == 0 {
+		d.Serial.Baud = p.baud
+	}
+	if p.ppsDCD && d.PPS.Device == "" && d.PPS.Line == "" && d.PPS.GPIO == "" {
+		d.PPS.DCD = true
+	}
+	return nil
+}
@@ -0,0 +1,45 @@
+package control
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// Request sends one request line to the control socket at path and returns
+// the response body. An ERROR response is returned as an error.
+func Request(path, request string) (string, error) {
+	conn, err := net.DialTimeout("unix", path, timeout)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if _, err := fmt.Fprintf(conn, "%s\n", strings.TrimSpace(request)); err != nil {
+		return "", err
+	}
+
+	reader := bufio.NewReader(conn)
+	status, err := reader.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("reading response: %w", err)
+	}
+	status = strings.TrimSpace(status)
+	if msg, ok := strings.CutPrefix(status, "ERROR "); ok {
+		return "", errors.New(msg)
+	}
+	if status != "OK" {
+		return "", fmt.Errorf("unexpected response %q", status)
+	}
+
+	body, err := io.ReadAll(reader)
+	if err != nil {
+		return "", fmt.Errorf("reading response: %w", err)
+	}
+	return string(body), nil
+}
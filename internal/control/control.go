@@ -0,0 +1,351 @@
+// Package control serves a local unix socket for inspecting and adjusting a
+// running bridge, in the spirit of chronyc. Each connection carries one
+// request line, a command with its arguments, and receives a response
+// starting with OK or ERROR.
+package control
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/bridge"
+	"github.com/karlcswanson/gogpsdo/gpsdo/scpi"
+	"github.com/karlcswanson/gogpsdo/internal/api"
+)
+
+// DefaultSocket is the conventional control socket path
+const DefaultSocket = "/run/gogpsdo.sock"
+
+// timeout bounds a request and its response, including a command that
+// waits for the SCPI port between diagnostics polls
+const timeout = 15 * time.Second
+
+// receiverTimeout bounds a command sent to the receiver, leaving time to
+// respond within timeout
+const receiverTimeout = timeout - time.Second
+
+// maxRequest is the longest accepted request line
+const maxRequest = 1024
+
+// Stats is the response body of the stats command
+type Stats struct {
+	Device string `json:"device,omitempty"`
+	// Offset is the calibration offset in seconds
+	Offset           float64                      `json:"offset"`
+	Input            api.Input                    `json:"input"`
+	Packets          api.Packets                  `json:"packets"`
+	Samples          api.Samples                  `json:"samples"`
+	PPS              api.PPS                      `json:"pps"`
+	HoldoverRejected uint64                       `json:"holdover_rejected"`
+	Outputs          map[string]gpsdo.OutputStats `json:"outputs"`
+}
+
+// handler runs a command of s on one bridge, writing any response body to
+// out
+type handler func(s *Server, b *bridge.Bridge, args []string, out *bytes.Buffer) error
+
+// command is a control request type
+type command struct {
+	name    string
+	args    string
+	summary string
+	run     handler
+	// single commands need a device=NAME argument with several bridges
+	single bool
+}
+
+var commands = []command{
+	{"status", "", "Print the full status as JSON", (*Server).cmdStatus, false},
+	{"stats", "", "Print the counters and offset as JSON", (*Server).cmdStats, false},
+	{"recent", "[DURATION]", "Print the samples and transitions kept in memory as JSON, e.g. 10m", (*Server).cmdRecent, false},
+	{"reset-counters", "", "Zero the packet, sample and rejection counters", (*Server).cmdResetCounters, false},
+	{"set-offset", "SECONDS", "Change the calibration offset, e.g. -0.245", (*Server).cmdSetOffset, true},
+	{"survey", "", "Start a new survey of the antenna position", (*Server).cmdSurvey, true},
+	{"hold-position", "LAT LON ALT", "Hold a known antenna position, degrees and meters, e.g. 40.1234 -76.5432 158.4", (*Server).cmdHoldPosition, true},
+	{"scpi", "COMMAND", "Send a command to the SCPI port and print the response, e.g. :SYST:STAT?", (*Server).cmdSCPI, true},
+}
+
+// Server answers control requests for one or more bridges
+type Server struct {
+	bridges []*bridge.Bridge
+	scpi    map[*bridge.Bridge]*scpi.Poller
+}
+
+// New creates a control server for the bridges. A request selects a bridge
+// with a device=NAME argument, otherwise it applies to every bridge. The
+// SCPI ports are added with SetSCPI before serving.
+func New(bridges ...*bridge.Bridge) *Server {
+	return &Server{bridges: bridges, scpi: make(map[*bridge.Bridge]*scpi.Poller)}
+}
+
+// SetSCPI sends the SCPI commands for b to its poller
+func (s *Server) SetSCPI(b *bridge.Bridge, p *scpi.Poller) {
+	s.scpi[b] = p
+}
+
+// Serve accepts connections on listener until ctx is cancelled
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	slog.Info("Control socket listening", "addr", listener.Addr())
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	reader := bufio.NewReaderSize(conn, maxRequest)
+	line, err := reader.ReadSlice('\n')
+	if errors.Is(err, bufio.ErrBufferFull) {
+		fmt.Fprintf(conn, "ERROR request longer than %d bytes\n", maxRequest)
+		return
+	}
+	// A request without a newline is accepted once the client closes
+	if err != nil && len(line) == 0 {
+		return
+	}
+
+	body, err := s.execute(string(line))
+	if err != nil {
+		fmt.Fprintf(conn, "ERROR %v\n", err)
+		return
+	}
+	fmt.Fprintf(conn, "OK\n%s", body)
+}
+
+// execute runs one request line and returns the response body
+func (s *Server) execute(line string) ([]byte, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, errors.New("empty request")
+	}
+
+	var out bytes.Buffer
+	name, args := fields[0], fields[1:]
+	if name == "help" {
+		writeHelp(&out)
+		return out.Bytes(), nil
+	}
+	i := slices.IndexFunc(commands, func(c command) bool { return c.name == name })
+	if i < 0 {
+		return nil, fmt.Errorf("unknown command %q, try help", name)
+	}
+	cmd := commands[i]
+
+	bridges, args, err := s.selectBridges(args)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.single && len(bridges) > 1 {
+		return nil, fmt.Errorf("%s needs device=NAME with several devices", name)
+	}
+	for _, b := range bridges {
+		if err := cmd.run(s, b, args, &out); err != nil {
+			return nil, err
+		}
+	}
+	return out.Bytes(), nil
+}
+
+// selectBridges removes a device=NAME argument from args and returns the
+// bridges it selects, all of them if there is none
+func (s *Server) selectBridges(args []string) ([]*bridge.Bridge, []string, error) {
+	var rest []string
+	name, selected := "", false
+	for _, arg := range args {
+		if v, ok := strings.CutPrefix(arg, "device="); ok {
+			name, selected = v, true
+		} else {
+			rest = append(rest, arg)
+		}
+	}
+	if !selected {
+		return s.bridges, rest, nil
+	}
+
+	for _, b := range s.bridges {
+		if b.Name() == name {
+			return []*bridge.Bridge{b}, rest, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("unknown device %q", name)
+}
+
+func writeHelp(out *bytes.Buffer) {
+	for _, cmd := range commands {
+		fmt.Fprintf(out, "%-26s %s\n", strings.TrimSpace(cmd.name+" "+cmd.args), cmd.summary)
+	}
+	fmt.Fprintf(out, "%-26s %s\n", "help", "List the commands")
+	fmt.Fprintf(out, "\nAdd device=NAME to select one of several devices.\n")
+}
+
+func writeJSON(out *bytes.Buffer, v any) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func noArgs(name string, args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("%s takes no arguments", name)
+	}
+	return nil
+}
+
+func (s *Server) cmdStatus(b *bridge.Bridge, args []string, out *bytes.Buffer) error {
+	if err := noArgs("status", args); err != nil {
+		return err
+	}
+	return writeJSON(out, api.NewStatus(b))
+}
+
+func (s *Server) cmdStats(b *bridge.Bridge, args []string, out *bytes.Buffer) error {
+	if err := noArgs("stats", args); err != nil {
+		return err
+	}
+	status := api.NewStatus(b)
+	return writeJSON(out, Stats{
+		Device:           status.Device,
+		Offset:           b.Offset().Seconds(),
+		Input:            status.Input,
+		Packets:          status.Packets,
+		Samples:          status.Samples,
+		PPS:              status.PPS,
+		HoldoverRejected: status.Holdover.Rejected,
+		Outputs:          status.Outputs,
+	})
+}
+
+func (s *Server) cmdRecent(b *bridge.Bridge, args []string, out *bytes.Buffer) error {
+	var since time.Time
+	switch len(args) {
+	case 0:
+	case 1:
+		d, err := time.ParseDuration(args[0])
+		if err != nil || d <= 0 {
+			return fmt.Errorf("duration %q must be positive, e.g. 10m", args[0])
+		}
+		since = time.Now().Add(-d)
+	default:
+		return errors.New("usage: recent [DURATION]")
+	}
+	return writeJSON(out, api.NewRecent(b, since))
+}
+
+func (s *Server) cmdResetCounters(b *bridge.Bridge, args []string, out *bytes.Buffer) error {
+	if err := noArgs("reset-counters", args); err != nil {
+		return err
+	}
+	b.ResetCounters()
+	slog.Info("Counters reset by control request", "device", b.Name())
+	return nil
+}
+
+func (s *Server) cmdSetOffset(b *bridge.Bridge, args []string, out *bytes.Buffer) error {
+	if len(args) != 1 {
+		return errors.New("usage: set-offset SECONDS")
+	}
+	seconds, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return fmt.Errorf("offset %q is not a number of seconds", args[0])
+	}
+
+	previous := b.Offset()
+	offset := time.Duration(seconds * float64(time.Second))
+	b.SetOffset(offset)
+	slog.Info("Offset changed by control request", "device", b.Name(), "from", previous, "to", offset)
+	fmt.Fprintf(out, "offset %s -> %s\n", previous, offset)
+	return nil
+}
+
+func (s *Server) cmdSurvey(b *bridge.Bridge, args []string, out *bytes.Buffer) error {
+	if err := noArgs("survey", args); err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), receiverTimeout)
+	defer cancel()
+	if err := b.Survey(ctx); err != nil {
+		return err
+	}
+	slog.Info("Position survey started by control request", "device", b.Name())
+	fmt.Fprintln(out, "survey started")
+	return nil
+}
+
+func (s *Server) cmdHoldPosition(b *bridge.Bridge, args []string, out *bytes.Buffer) error {
+	if len(args) != 3 {
+		return errors.New("usage: hold-position LAT LON ALT")
+	}
+	var values [3]float64
+	for i, arg := range args {
+		v, err := strconv.ParseFloat(arg, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf("%q is not a number", arg)
+		}
+		values[i] = v
+	}
+
+	p := gpsdo.Position{Latitude: values[0], Longitude: values[1], Altitude: values[2]}
+	ctx, cancel := context.WithTimeout(context.Background(), receiverTimeout)
+	defer cancel()
+	if err := b.HoldPosition(ctx, p); err != nil {
+		return err
+	}
+	slog.Info("Position hold set by control request", "device", b.Name(),
+		"latitude", p.Latitude, "longitude", p.Longitude, "altitude", p.Altitude)
+	fmt.Fprintf(out, "holding %.7f %.7f %.2f m\n", p.Latitude, p.Longitude, p.Altitude)
+	return nil
+}
+
+func (s *Server) cmdSCPI(b *bridge.Bridge, args []string, out *bytes.Buffer) error {
+	if len(args) == 0 {
+		return errors.New("usage: scpi COMMAND")
+	}
+	poller, ok := s.scpi[b]
+	if !ok {
+		return errors.New("no SCPI port is configured")
+	}
+	cmd := strings.Join(args, " ")
+
+	ctx, cancel := context.WithTimeout(context.Background(), receiverTimeout)
+	defer cancel()
+	slog.Info("SCPI command sent by control request", "device", b.Name(), "command", cmd)
+	// Queries answer up to the next prompt, the errors of other commands are
+	// read from the error queue
+	if !strings.HasSuffix(cmd, "?") {
+		return poller.Command(ctx, cmd)
+	}
+	lines, err := poller.QueryLines(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		fmt.Fprintln(out, line)
+	}
+	return nil
+}
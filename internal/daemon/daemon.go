@@ -0,0 +1,134 @@
+// Package daemon detaches the bridge into the background and manages its
+// pidfile, for init systems other than systemd such as OpenWrt procd, runit
+// and SysV scripts.
+//
+// Go can't fork a running process, so Start runs the binary again in a new
+// session with the same arguments. The parent waits until the child reports
+// Ready, so an init script sees startup errors in the exit status.
+package daemon
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// childEnv marks the background process, so it doesn't detach again
+const childEnv = "GOGPSDO_DAEMON"
+
+// readyFd is the descriptor of the pipe the child reports readiness on, the
+// first of cmd.ExtraFiles
+const readyFd = 3
+
+// Child reports whether this process is the detached child of Start
+func Child() bool {
+	return os.Getenv(childEnv) != ""
+}
+
+// Start runs this program again in the background and waits until it calls
+// Ready. It returns an error if the child exits first, the caller exits in
+// either case.
+func Start() error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	null, err := os.OpenFile(os.DevNull, os.O_RDWR, 0)
+	if err != nil {
+		return err
+	}
+	defer null.Close()
+	r, w, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), childEnv+"=1")
+	// Startup errors still reach the terminal, Ready detaches stdout and
+	// stderr
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = null, os.Stdout, os.Stderr
+	cmd.ExtraFiles = []*os.File{w}
+	// A new session without a controlling terminal, so closing the terminal
+	// doesn't send SIGHUP
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setsid: true}
+	err = cmd.Start()
+	w.Close()
+	if err != nil {
+		return err
+	}
+
+	// The pipe closes without a byte when the child exits
+	if n, _ := r.Read(make([]byte, 1)); n == 0 {
+		cmd.Wait()
+		return fmt.Errorf("exited during startup (%s), see the log", cmd.ProcessState)
+	}
+	cmd.Process.Release()
+	return nil
+}
+
+// Ready tells the parent waiting in Start that startup succeeded. It is a
+// no-op outside the child.
+func Ready() {
+	if !Child() {
+		return
+	}
+	if null, err := os.OpenFile(os.DevNull, os.O_RDWR, 0); err == nil {
+		unix.Dup2(int(null.Fd()), 1)
+		unix.Dup2(int(null.Fd()), 2)
+		null.Close()
+	}
+	f := os.NewFile(readyFd, "ready")
+	f.Write([]byte{1})
+	f.Close()
+	os.Unsetenv(childEnv)
+}
+
+// WritePIDFile writes the process ID to path, failing if it names a process
+// that is still running. A pidfile left by a crash is replaced.
+func WritePIDFile(path string) error {
+	if pid, err := ReadPIDFile(path); err == nil && running(pid) {
+		return fmt.Errorf("%s: already running as pid %d", path, pid)
+	}
+	return os.WriteFile(path, []byte(strconv.Itoa(os.Getpid())+"\n"), 0644)
+}
+
+// RemovePIDFile removes path if it still holds the process ID
+func RemovePIDFile(path string) error {
+	if pid, err := ReadPIDFile(path); err != nil || pid != os.Getpid() {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// ReadPIDFile returns the process ID in path
+func ReadPIDFile(path string) (int, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	data, err := io.ReadAll(io.LimitReader(f, 32))
+	if err != nil {
+		return 0, err
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 {
+		return 0, fmt.Errorf("%s: invalid pid %q", path, strings.TrimSpace(string(data)))
+	}
+	return pid, nil
+}
+
+// running reports whether a process with the ID exists
+func running(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,288 @@
+// Package dbus exposes the bridge status on D-Bus, like systemd-timedated
+// does for the system clock, so desktop tools and other daemons can read the
+// lock state and react to its changes through PropertiesChanged signals.
+//
+// Each device is an object below /io/github/karlcswanson/gogpsdo1, the object
+// itself for a single unnamed device, with the properties of the
+// io.github.karlcswanson.gogpsdo1.Device interface:
+//
+//	busctl introspect io.github.karlcswanson.gogpsdo1 /io/github/karlcswanson/gogpsdo1
+package dbus
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	godbus "github.com/godbus/dbus/v5"
+	"github.com/godbus/dbus/v5/introspect"
+	"github.com/godbus/dbus/v5/prop"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/bridge"
+	"github.com/karlcswanson/gogpsdo/internal/api"
+	"github.com/karlcswanson/gogpsdo/internal/config"
+)
+
+// Bus name, object path and interface
+const (
+	BusName   = "io.github.karlcswanson.gogpsdo1"
+	Path      = "/io/github/karlcswanson/gogpsdo1"
+	Interface = "io.github.karlcswanson.gogpsdo1.Device"
+)
+
+const propertiesInterface = "org.freedesktop.DBus.Properties"
+
+// pollInterval is how often the bridges are checked for changed properties
+const pollInterval = time.Second
+
+// retryInterval is how long to wait before connecting again after the bus
+// connection failed or was lost
+const retryInterval = 30 * time.Second
+
+// deviceIntrospection describes the interface. LastUpdateUSec changes with
+// every sample and is not signalled, like TimeUSec of timedated.
+const deviceIntrospection = `
+	<interface name="` + Interface + `">
+		<property name="Name" type="s" access="read"/>
+		<property name="Status" type="s" access="read"/>
+		<property name="Valid" type="b" access="read"/>
+		<property name="Locked" type="b" access="read"/>
+		<property name="LeapSeconds" type="i" access="read"/>
+		<property name="Leap" type="s" access="read"/>
+		<property name="HoldoverSinceUSec" type="t" access="read"/>
+		<property name="LastUpdateUSec" type="t" access="read">
+			<annotation name="org.freedesktop.DBus.Property.EmitsChangedSignal" value="false"/>
+		</property>
+	</interface>`
+
+// unsignalled properties change without a PropertiesChanged signal
+var unsignalled = map[string]bool{"LastUpdateUSec": true}
+
+// Service serves the bridges on D-Bus
+type Service struct {
+	cfg     config.DBus
+	objects []*object
+	// failed keeps a missing bus out of the log after the first error
+	failed bool
+}
+
+// object is the D-Bus object of one bridge
+type object struct {
+	bridge *bridge.Bridge
+	path   godbus.ObjectPath
+
+	mutex      sync.Mutex
+	properties map[string]any
+}
+
+// New creates the D-Bus service for the bridges. The connection is made by
+// Run.
+func New(cfg config.DBus, bridges ...*bridge.Bridge) *Service {
+	s := &Service{cfg: cfg}
+	for _, b := range bridges {
+		s.objects = append(s.objects, &object{
+			bridge:     b,
+			path:       objectPath(b.Name()),
+			properties: properties(b),
+		})
+	}
+	return s
+}
+
+// objectPath is the path of the device named name, limited to the
+// characters allowed in object paths
+func objectPath(name string) godbus.ObjectPath {
+	if name == "" {
+		return Path
+	}
+	return godbus.ObjectPath(Path + "/" + strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, name))
+}
+
+// properties returns the current properties of b
+func properties(b *bridge.Bridge) map[string]any {
+	status := api.NewStatus(b)
+	return map[string]any{
+		"Name":              b.Name(),
+		"Status":            status.Status,
+		"Valid":             status.Valid,
+		"Locked":            status.Status == gpsdo.Locked.String(),
+		"LeapSeconds":       int32(status.LeapSeconds),
+		"Leap":              status.Leap,
+		"HoldoverSinceUSec": usec(status.Holdover.Since),
+		"LastUpdateUSec":    usec(status.LastUpdate),
+	}
+}
+
+// usec is t in microseconds since the epoch, 0 for none
+func usec(t *time.Time) uint64 {
+	if t == nil {
+		return 0
+	}
+	return uint64(t.UnixMicro())
+}
+
+// Run serves the bridges until ctx is cancelled, connecting again when the
+// bus connection is lost
+func (s *Service) Run(ctx context.Context) {
+	slog.Info("D-Bus interface starting", "bus", s.cfg.Bus, "name", BusName)
+	for {
+		err := s.serve(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		if !s.failed {
+			slog.Warn("D-Bus interface unavailable, retrying", "bus", s.cfg.Bus, "error", err)
+		}
+		s.failed = true
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// serve connects to the bus, exports the objects and signals their changes
+// until ctx is cancelled or the connection is lost
+func (s *Service) serve(ctx context.Context) error {
+	connect := godbus.ConnectSystemBus
+	if s.cfg.Bus == config.SessionBus {
+		connect = godbus.ConnectSessionBus
+	}
+	conn, err := connect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if err := s.export(conn); err != nil {
+		return err
+	}
+	reply, err := conn.RequestName(BusName, godbus.NameFlagDoNotQueue)
+	if err != nil {
+		return fmt.Errorf("request name %s: %w", BusName, err)
+	}
+	if reply != godbus.RequestNameReplyPrimaryOwner {
+		return fmt.Errorf("name %s is already taken", BusName)
+	}
+	slog.Info("D-Bus interface ready", "bus", s.cfg.Bus, "name", BusName)
+	s.failed = false
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-conn.Context().Done():
+			return errors.New("connection lost")
+		case <-ticker.C:
+			for _, o := range s.objects {
+				if err := o.update(conn); err != nil {
+					slog.Debug("D-Bus signal failed", "path", o.path, "error", err)
+				}
+			}
+		}
+	}
+}
+
+// export registers the objects and the introspection data of every path,
+// including the parent of named devices
+func (s *Service) export(conn *godbus.Conn) error {
+	var children []introspect.Node
+	for _, o := range s.objects {
+		if err := conn.Export(o, o.path, propertiesInterface); err != nil {
+			return err
+		}
+		node := `<node>` + introspect.IntrospectDataString + prop.IntrospectDataString + deviceIntrospection + `</node>`
+		if err := conn.Export(introspect.Introspectable(node), o.path, "org.freedesktop.DBus.Introspectable"); err != nil {
+			return err
+		}
+		if o.path != Path {
+			children = append(children, introspect.Node{Name: strings.TrimPrefix(string(o.path), Path+"/")})
+		}
+	}
+	if len(children) == 0 {
+		return nil
+	}
+	root := introspect.NewIntrospectable(&introspect.Node{Children: children})
+	return conn.Export(root, Path, "org.freedesktop.DBus.Introspectable")
+}
+
+// update refreshes the properties and signals the changed ones in a single
+// PropertiesChanged signal
+func (o *object) update(conn *godbus.Conn) error {
+	current := properties(o.bridge)
+	changed := make(map[string]godbus.Variant)
+
+	o.mutex.Lock()
+	for name, value := range current {
+		if value != o.properties[name] && !unsignalled[name] {
+			changed[name] = godbus.MakeVariant(value)
+		}
+	}
+	o.properties = current
+	o.mutex.Unlock()
+
+	if len(changed) == 0 {
+		return nil
+	}
+	return conn.Emit(o.path, propertiesInterface+".PropertiesChanged", Interface, changed, []string{})
+}
+
+// Get implements org.freedesktop.DBus.Properties.Get
+func (o *object) Get(iface, property string) (godbus.Variant, *godbus.Error) {
+	if iface != Interface {
+		return godbus.Variant{}, unknownInterface(iface)
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	value, ok := o.properties[property]
+	if !ok {
+		return godbus.Variant{}, godbus.NewError("org.freedesktop.DBus.Error.UnknownProperty",
+			[]any{fmt.Sprintf("Unknown property %s", property)})
+	}
+	return godbus.MakeVariant(value), nil
+}
+
+// GetAll implements org.freedesktop.DBus.Properties.GetAll
+func (o *object) GetAll(iface string) (map[string]godbus.Variant, *godbus.Error) {
+	if iface != Interface {
+		return nil, unknownInterface(iface)
+	}
+	o.mutex.Lock()
+	defer o.mutex.Unlock()
+	all := make(map[string]godbus.Variant, len(o.properties))
+	for name, value := range o.properties {
+		all[name] = godbus.MakeVariant(value)
+	}
+	return all, nil
+}
+
+// Set implements org.freedesktop.DBus.Properties.Set, every property is read
+// only
+func (o *object) Set(iface, property string, _ godbus.Variant) *godbus.Error {
+	if iface != Interface {
+		return unknownInterface(iface)
+	}
+	return godbus.NewError("org.freedesktop.DBus.Error.PropertyReadOnly",
+		[]any{fmt.Sprintf("Property %s is read only", property)})
+}
+
+func unknownInterface(iface string) *godbus.Error {
+	return godbus.NewError("org.freedesktop.DBus.Error.UnknownInterface",
+		[]any{fmt.Sprintf("Unknown interface %s", iface)})
+}
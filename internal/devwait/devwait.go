@@ -0,0 +1,122 @@
+// Package devwait waits for a device node to appear, such as a USB serial
+// adapter that is still being enumerated when the bridge starts at boot.
+package devwait
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// recheck is how often the path is checked when no inotify event arrives,
+// in case inotify is unavailable or an event was missed
+const recheck = time.Second
+
+// ErrTimeout is returned when the path didn't appear in time
+var ErrTimeout = errors.New("timed out")
+
+// Wait returns once path exists, or an error wrapping ErrTimeout after
+// timeout. It watches the nearest existing parent directory with inotify,
+// so a path under a directory created later, such as /dev/serial/by-id,
+// works too.
+func Wait(ctx context.Context, path string, timeout time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	// Without inotify the path is only checked every second
+	watcher, err := newWatcher()
+	if err == nil {
+		defer watcher.Close()
+	}
+
+	watched := ""
+	for {
+		if _, err := os.Stat(path); err == nil {
+			return nil
+		}
+
+		if watcher != nil {
+			if dir := existingParent(path); dir != watched {
+				// Missing the event of a directory that went away is fine,
+				// the path is checked every second anyway
+				watcher.add(dir)
+				watched = dir
+			}
+		}
+
+		if err := wait(ctx, watcher); err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return fmt.Errorf("%s: %w after %s", path, ErrTimeout, timeout)
+			}
+			return err
+		}
+	}
+}
+
+// wait returns after an inotify event, recheck or ctx ending
+func wait(ctx context.Context, w *watcher) error {
+	deadline := time.Now().Add(recheck)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	if w != nil {
+		w.wait(deadline)
+	} else {
+		time.Sleep(time.Until(deadline))
+	}
+	return ctx.Err()
+}
+
+// existingParent returns the nearest parent directory of path that exists
+func existingParent(path string) string {
+	dir := filepath.Dir(filepath.Clean(path))
+	for {
+		if _, err := os.Stat(dir); err == nil {
+			return dir
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dir
+		}
+		dir = parent
+	}
+}
+
+// watcher is an inotify instance for directory entry events
+type watcher struct {
+	// fd is the descriptor of file, kept as calling file.Fd would make it
+	// blocking
+	fd   int
+	file *os.File
+}
+
+func newWatcher() (*watcher, error) {
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("inotify_init1: %w", err)
+	}
+	// Non-blocking so reads time out through the runtime poller
+	return &watcher{fd: fd, file: os.NewFile(uintptr(fd), "inotify")}, nil
+}
+
+// add watches dir for new entries, including symlinks created by udev
+func (w *watcher) add(dir string) {
+	unix.InotifyAddWatch(w.fd, dir, unix.IN_CREATE|unix.IN_MOVED_TO|unix.IN_ATTRIB)
+}
+
+// wait blocks until an event arrives or until deadline, discarding the
+// events
+func (w *watcher) wait(deadline time.Time) {
+	w.file.SetReadDeadline(deadline)
+	buf := make([]byte, 4096)
+	w.file.Read(buf)
+}
+
+func (w *watcher) Close() error {
+	return w.file.Close()
+}
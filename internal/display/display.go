@@ -0,0 +1,157 @@
+// Package display shows the GPSDO time and status on a small SSD1306 or
+// SH1106 OLED module on an I2C bus, for bench and appliance builds without a
+// network monitor: the UTC time, lock status, holdover duration and sample
+// age.
+package display
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/bridge"
+	"github.com/karlcswanson/gogpsdo/internal/config"
+)
+
+// refreshInterval is how often the display is redrawn. Only changed pages
+// are sent over the bus.
+const refreshInterval = 200 * time.Millisecond
+
+// staleAfter is how long after the last sample the display shows NO DATA and
+// falls back to the system clock
+const staleAfter = 10 * time.Second
+
+// image is a frame buffer in the controller's page layout
+type image [pages][width]byte
+
+// set lights the pixel at x, y
+func (img *image) set(x, y int) {
+	if x >= 0 && x < width && y >= 0 && y < height {
+		img[y/8][x] |= 1 << (y % 8)
+	}
+}
+
+// text draws s with its top left corner at x, y, every font pixel scaled to
+// scale by scale pixels
+func (img *image) text(x, y, scale int, s string) {
+	for _, r := range s {
+		if r < ' ' || r > '~' {
+			r = '?'
+		}
+		for col, bits := range font[r-' '] {
+			for row := range fontHeight {
+				if bits&(1<<row) == 0 {
+					continue
+				}
+				for dx := range scale {
+					for dy := range scale {
+						img.set(x+col*scale+dx, y+row*scale+dy)
+					}
+				}
+			}
+		}
+		x += (fontWidth + 1) * scale
+	}
+}
+
+// centered draws s centered horizontally at y
+func (img *image) centered(y, scale int, s string) {
+	w := len(s)*(fontWidth+1)*scale - scale
+	img.text((width-w)/2, y, scale, s)
+}
+
+// Display shows the state of a bridge on an OLED module
+type Display struct {
+	cfg    config.Display
+	bridge *bridge.Bridge
+	oled   *oled
+}
+
+// Open initializes the display. It must be called before privileges are
+// dropped, the I2C bus is usually root or i2c group only.
+func Open(cfg config.Display, b *bridge.Bridge) (*Display, error) {
+	d, err := openOLED(cfg.I2C, cfg.Address, cfg.Controller)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Display opened", "device", b.Name(), "i2c", cfg.I2C,
+		"address", fmt.Sprintf("0x%02x", cfg.Address), "controller", cfg.Controller)
+	return &Display{cfg: cfg, bridge: b, oled: d}, nil
+}
+
+// Run redraws the display until ctx is cancelled, then turns it off
+func (d *Display) Run(ctx context.Context) {
+	defer d.oled.Close()
+
+	ticker := time.NewTicker(refreshInterval)
+	defer ticker.Stop()
+
+	// failed keeps a disconnected display out of the log, and reinitializes
+	// it once it answers again, as it may have lost power
+	failed := false
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			img := d.render(now)
+			err := error(nil)
+			if failed {
+				err = d.oled.command(initCommands[d.cfg.Controller]...)
+			}
+			if err == nil {
+				err = d.oled.show(&img, failed)
+			}
+			switch {
+			case err != nil && !failed:
+				failed = true
+				slog.Warn("Display update failed", "device", d.bridge.Name(), "error", err)
+			case err == nil && failed:
+				failed = false
+				slog.Info("Display updated again", "device", d.bridge.Name())
+			}
+		}
+	}
+}
+
+// render draws the state of the bridge at now
+func (d *Display) render(now time.Time) image {
+	var img image
+	data := d.bridge.Current()
+	stats := d.bridge.Stats()
+	age := now.Sub(stats.LastUpdate)
+	stale := data == nil || age > staleAfter
+
+	// The GPSDO time while samples arrive, the system clock otherwise
+	clock, label := now.UTC(), "system"
+	if !stale && data.Valid {
+		clock, label = now.Add(data.SystemOffset()).UTC(), "UTC"
+	}
+	img.centered(0, 2, clock.Format(time.TimeOnly))
+	img.centered(17, 1, clock.Format(time.DateOnly)+" "+label)
+
+	status := "NO DATA"
+	if !stale {
+		status = data.Status.String()
+	}
+	img.centered(29, 2, status)
+
+	switch {
+	case !stats.HoldoverSince.IsZero():
+		img.text(0, 47, 1, "Holdover "+now.Sub(stats.HoldoverSince).Truncate(time.Second).String())
+	case d.bridge.Name() != "":
+		img.text(0, 47, 1, d.bridge.Name())
+	}
+	if !stats.LastUpdate.IsZero() {
+		img.text(0, 56, 1, fmt.Sprintf("Age %.1fs", age.Seconds()))
+	}
+	if !stale && !data.PPS.IsZero() {
+		img.text(width-3*(fontWidth+1)+1, 56, 1, "PPS")
+	}
+	if !stale && data.Leap != gpsdo.LeapNone {
+		img.text(width-4*(fontWidth+1)+1, 47, 1, "LEAP")
+	}
+	return img
+}
@@ -0,0 +1,139 @@
+package display
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Display size in pixels. The controller RAM is organized in pages of 8
+// rows, with one byte per column and the top row in the least significant
+// bit.
+const (
+	width  = 128
+	height = 64
+	pages  = height / 8
+)
+
+// i2cSlave is the I2C_SLAVE ioctl from linux/i2c-dev.h
+const i2cSlave = 0x0703
+
+// Control bytes preceding a command or display data transfer
+const (
+	controlCommand = 0x00
+	controlData    = 0x40
+)
+
+// Controllers
+const (
+	SSD1306 = "ssd1306"
+	SH1106  = "sh1106"
+)
+
+// initCommands configure each controller for a 128x64 panel, rows and
+// columns mirrored for the usual module orientation, and turn it on
+var initCommands = map[string][]byte{
+	SSD1306: {
+		0xae,       // display off
+		0xd5, 0x80, // clock divide ratio
+		0xa8, 0x3f, // multiplex ratio 64
+		0xd3, 0x00, // display offset
+		0x40,       // start line 0
+		0x8d, 0x14, // charge pump on
+		0x20, 0x02, // page addressing mode
+		0xa1,       // segment remap
+		0xc8,       // COM scan direction
+		0xda, 0x12, // COM pins
+		0x81, 0xcf, // contrast
+		0xd9, 0xf1, // precharge period
+		0xdb, 0x40, // VCOMH level
+		0xa4, // display from RAM
+		0xa6, // normal, not inverted
+		0xaf, // display on
+	},
+	SH1106: {
+		0xae,       // display off
+		0xd5, 0x80, // clock divide ratio
+		0xa8, 0x3f, // multiplex ratio 64
+		0xd3, 0x00, // display offset
+		0x40,       // start line 0
+		0xad, 0x8b, // DC-DC converter on
+		0xa1,       // segment remap
+		0xc8,       // COM scan direction
+		0xda, 0x12, // COM pins
+		0x81, 0x80, // contrast
+		0xd9, 0x22, // precharge period
+		0xdb, 0x35, // VCOM level
+		0xa4, // display from RAM
+		0xa6, // normal, not inverted
+		0xaf, // display on
+	},
+}
+
+// columnOffset is where the visible columns start in the controller RAM.
+// The SH1106 has 132 columns, of which modules show the middle 128.
+var columnOffset = map[string]int{SSD1306: 0, SH1106: 2}
+
+// oled is a 128x64 monochrome OLED module on an I2C bus
+type oled struct {
+	file   *os.File
+	offset int
+	// frame is the image shown
+	frame image
+}
+
+// openOLED opens the I2C bus device, such as /dev/i2c-1, and initializes the
+// controller at addr
+func openOLED(bus string, addr int, controller string) (*oled, error) {
+	commands, ok := initCommands[controller]
+	if !ok {
+		return nil, fmt.Errorf("unknown display controller %q", controller)
+	}
+	f, err := os.OpenFile(bus, os.O_RDWR, 0)
+	if err != nil {
+		return nil, err
+	}
+	if err := unix.IoctlSetInt(int(f.Fd()), i2cSlave, addr); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("I2C_SLAVE %s 0x%02x: %w", bus, addr, err)
+	}
+
+	d := &oled{file: f, offset: columnOffset[controller]}
+	if err := d.command(commands...); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("display at %s 0x%02x: %w", bus, addr, err)
+	}
+	return d, nil
+}
+
+// command sends controller commands
+func (d *oled) command(commands ...byte) error {
+	_, err := d.file.Write(append([]byte{controlCommand}, commands...))
+	return err
+}
+
+// show writes img to the display, page by page, skipping unchanged pages
+func (d *oled) show(img *image, force bool) error {
+	for page := range pages {
+		if !force && img[page] == d.frame[page] {
+			continue
+		}
+		column := d.offset
+		// Page address, then the low and high nibble of the column
+		if err := d.command(0xb0|byte(page), byte(column&0x0f), 0x10|byte(column>>4)); err != nil {
+			return err
+		}
+		if _, err := d.file.Write(append([]byte{controlData}, img[page][:]...)); err != nil {
+			return err
+		}
+		d.frame[page] = img[page]
+	}
+	return nil
+}
+
+// Close turns the display off and releases the bus
+func (d *oled) Close() error {
+	d.command(0xae)
+	return d.file.Close()
+}
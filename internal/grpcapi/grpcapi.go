@@ -0,0 +1,440 @@
+// Package grpcapi serves the gRPC monitoring and control API defined in
+// proto/gogpsdo/v1, for embedding the bridge in larger infrastructure:
+// status snapshots and streams, history queries and, when enabled, the
+// control RPCs of the control socket plus SCPI commands.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"net"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/bridge"
+	"github.com/karlcswanson/gogpsdo/gpsdo/scpi"
+	"github.com/karlcswanson/gogpsdo/internal/api"
+	"github.com/karlcswanson/gogpsdo/internal/config"
+	"github.com/karlcswanson/gogpsdo/internal/history"
+	pb "github.com/karlcswanson/gogpsdo/proto/gogpsdo/v1"
+)
+
+// Defaults and limits of the request fields
+const (
+	defaultWatchInterval = time.Second
+	minWatchInterval     = 100 * time.Millisecond
+	defaultSince         = time.Hour
+	defaultStep          = time.Minute
+)
+
+// transitionPoll is how often watched bridges are checked for a changed
+// lock state
+const transitionPoll = 100 * time.Millisecond
+
+// scpiTimeout bounds the wait for the SCPI port and its response
+const scpiTimeout = 15 * time.Second
+
+// Server implements the GPSDOService
+type Server struct {
+	pb.UnimplementedGPSDOServiceServer
+
+	control bool
+	bridges []*bridge.Bridge
+	history *history.Store
+	scpi    map[*bridge.Bridge]*scpi.Poller
+}
+
+// New creates the gRPC API for one or more bridges. The history and SCPI
+// ports are added with SetHistory and SetSCPI before serving.
+func New(cfg config.GRPC, bridges ...*bridge.Bridge) *Server {
+	return &Server{
+		control: cfg.Control,
+		bridges: bridges,
+		scpi:    make(map[*bridge.Bridge]*scpi.Poller),
+	}
+}
+
+// SetHistory serves QuerySamples from the history store
+func (s *Server) SetHistory(store *history.Store) {
+	s.history = store
+}
+
+// SetSCPI sends the SCPI commands for b to its poller
+func (s *Server) SetSCPI(b *bridge.Bridge, p *scpi.Poller) {
+	s.scpi[b] = p
+}
+
+// Serve answers RPCs on listener until ctx is cancelled
+func (s *Server) Serve(ctx context.Context, listener net.Listener) error {
+	server := grpc.NewServer()
+	pb.RegisterGPSDOServiceServer(server, s)
+	slog.Info("gRPC API listening", "addr", listener.Addr(), "control", s.control)
+
+	go func() {
+		<-ctx.Done()
+		// Not graceful, WatchStatus streams would keep it waiting
+		server.Stop()
+	}()
+	if err := server.Serve(listener); err != nil && ctx.Err() == nil {
+		return err
+	}
+	return nil
+}
+
+// selectBridges returns the bridge named device. An empty name selects the
+// only bridge, or every bridge if all is set.
+func (s *Server) selectBridges(device string, all bool) ([]*bridge.Bridge, error) {
+	if device == "" {
+		if all || len(s.bridges) == 1 {
+			return s.bridges, nil
+		}
+		return nil, status.Error(codes.InvalidArgument, "device is required with several devices")
+	}
+	for _, b := range s.bridges {
+		if b.Name() == device {
+			return []*bridge.Bridge{b}, nil
+		}
+	}
+	return nil, status.Errorf(codes.NotFound, "unknown device %q", device)
+}
+
+// selectBridge is selectBridges for RPCs on a single bridge
+func (s *Server) selectBridge(device string) (*bridge.Bridge, error) {
+	bridges, err := s.selectBridges(device, false)
+	if err != nil {
+		return nil, err
+	}
+	return bridges[0], nil
+}
+
+// checkControl refuses the control RPCs unless they are enabled
+func (s *Server) checkControl() error {
+	if !s.control {
+		return status.Error(codes.PermissionDenied, "control RPCs are disabled, set grpc.control")
+	}
+	return nil
+}
+
+// GetStatus returns the status of the selected bridges
+func (s *Server) GetStatus(ctx context.Context, req *pb.GetStatusRequest) (*pb.GetStatusResponse, error) {
+	bridges, err := s.selectBridges(req.GetDevice(), true)
+	if err != nil {
+		return nil, err
+	}
+	resp := &pb.GetStatusResponse{}
+	for _, b := range bridges {
+		resp.Devices = append(resp.Devices, newStatus(b))
+	}
+	return resp, nil
+}
+
+// WatchStatus streams the status of the selected bridges every interval and
+// on every lock state change
+func (s *Server) WatchStatus(req *pb.WatchStatusRequest, stream grpc.ServerStreamingServer[pb.WatchStatusResponse]) error {
+	bridges, err := s.selectBridges(req.GetDevice(), true)
+	if err != nil {
+		return err
+	}
+	interval := defaultWatchInterval
+	if req.Interval != nil {
+		interval = req.GetInterval().AsDuration()
+		if interval < minWatchInterval {
+			return status.Errorf(codes.InvalidArgument, "interval must be at least %s", minWatchInterval)
+		}
+	}
+
+	ticker := time.NewTicker(transitionPoll)
+	defer ticker.Stop()
+	sent := make([]gpsdo.Status, len(bridges))
+	var next time.Time
+	for {
+		now := time.Now()
+		due := !now.Before(next)
+		if due {
+			next = now.Add(interval)
+		}
+		for i, b := range bridges {
+			current := lockState(b)
+			if !due && current == sent[i] {
+				continue
+			}
+			sent[i] = current
+			if err := stream.Send(&pb.WatchStatusResponse{Status: newStatus(b)}); err != nil {
+				return err
+			}
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// lockState is the status of the last sample of b
+func lockState(b *bridge.Bridge) gpsdo.Status {
+	if data := b.Current(); data != nil {
+		return data.Status
+	}
+	return gpsdo.Unknown
+}
+
+// QuerySamples returns the stored samples of a bridge
+func (s *Server) QuerySamples(ctx context.Context, req *pb.QuerySamplesRequest) (*pb.QuerySamplesResponse, error) {
+	if s.history == nil {
+		return nil, status.Error(codes.FailedPrecondition, "history is not enabled")
+	}
+	b, err := s.selectBridge(req.GetDevice())
+	if err != nil {
+		return nil, err
+	}
+	since := time.Now().Add(-defaultSince)
+	if req.Since != nil {
+		since = req.GetSince().AsTime()
+	}
+	step := defaultStep
+	if req.Step != nil {
+		if step = req.GetStep().AsDuration(); step < time.Second {
+			return nil, status.Error(codes.InvalidArgument, "step must be at least 1s")
+		}
+	}
+
+	points, err := s.history.Samples(b.Name(), since, step)
+	if err != nil {
+		slog.Warn("History query failed", "error", err)
+		return nil, status.Error(codes.Internal, "history query failed")
+	}
+	resp := &pb.QuerySamplesResponse{}
+	for _, p := range points {
+		resp.Points = append(resp.Points, &pb.SamplePoint{
+			Time:    timestamppb.New(p.Time),
+			Offset:  p.Offset,
+			Min:     p.Min,
+			Max:     p.Max,
+			Samples: int64(p.Samples),
+			Locked:  p.Locked,
+		})
+	}
+	return resp, nil
+}
+
+// SetOffset changes the calibration offset of a bridge
+func (s *Server) SetOffset(ctx context.Context, req *pb.SetOffsetRequest) (*pb.SetOffsetResponse, error) {
+	if err := s.checkControl(); err != nil {
+		return nil, err
+	}
+	b, err := s.selectBridge(req.GetDevice())
+	if err != nil {
+		return nil, err
+	}
+	seconds := req.GetOffset()
+	if math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return nil, status.Error(codes.InvalidArgument, "offset is not a number of seconds")
+	}
+
+	previous := b.Offset()
+	offset := time.Duration(seconds * float64(time.Second))
+	b.SetOffset(offset)
+	slog.Info("Offset changed by gRPC request", "device", b.Name(), "from", previous, "to", offset)
+	return &pb.SetOffsetResponse{Previous: previous.Seconds(), Offset: offset.Seconds()}, nil
+}
+
+// ResetCounters zeroes the counters of the selected bridges
+func (s *Server) ResetCounters(ctx context.Context, req *pb.ResetCountersRequest) (*pb.ResetCountersResponse, error) {
+	if err := s.checkControl(); err != nil {
+		return nil, err
+	}
+	bridges, err := s.selectBridges(req.GetDevice(), true)
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range bridges {
+		b.ResetCounters()
+		slog.Info("Counters reset by gRPC request", "device", b.Name())
+	}
+	return &pb.ResetCountersResponse{}, nil
+}
+
+// SendSCPICommand sends a command to the SCPI port of a bridge
+func (s *Server) SendSCPICommand(ctx context.Context, req *pb.SendSCPICommandRequest) (*pb.SendSCPICommandResponse, error) {
+	if err := s.checkControl(); err != nil {
+		return nil, err
+	}
+	b, err := s.selectBridge(req.GetDevice())
+	if err != nil {
+		return nil, err
+	}
+	poller, ok := s.scpi[b]
+	if !ok {
+		return nil, status.Error(codes.FailedPrecondition, "no SCPI port is configured")
+	}
+	cmd := strings.TrimSpace(req.GetCommand())
+	if cmd == "" || strings.ContainsAny(cmd, "\r\n") {
+		return nil, status.Error(codes.InvalidArgument, "command must be a single line")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, scpiTimeout)
+	defer cancel()
+	slog.Info("SCPI command sent by gRPC request", "device", b.Name(), "command", cmd)
+	response, err := poller.Query(ctx, cmd)
+	// Commands other than queries don't answer, only the prompt follows
+	if errors.Is(err, scpi.ErrTimeout) && !strings.HasSuffix(cmd, "?") {
+		err = nil
+	}
+	if err != nil {
+		return nil, status.Error(codes.Unavailable, err.Error())
+	}
+	return &pb.SendSCPICommandResponse{Response: response}, nil
+}
+
+// StartSurvey starts a new position survey of a bridge's receiver
+func (s *Server) StartSurvey(ctx context.Context, req *pb.StartSurveyRequest) (*pb.StartSurveyResponse, error) {
+	if err := s.checkControl(); err != nil {
+		return nil, err
+	}
+	b, err := s.selectBridge(req.GetDevice())
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, scpiTimeout)
+	defer cancel()
+	if err := b.Survey(ctx); err != nil {
+		return nil, surveyError(err)
+	}
+	slog.Info("Position survey started by gRPC request", "device", b.Name())
+	return &pb.StartSurveyResponse{}, nil
+}
+
+// HoldPosition sets the position a bridge's receiver holds
+func (s *Server) HoldPosition(ctx context.Context, req *pb.HoldPositionRequest) (*pb.HoldPositionResponse, error) {
+	if err := s.checkControl(); err != nil {
+		return nil, err
+	}
+	b, err := s.selectBridge(req.GetDevice())
+	if err != nil {
+		return nil, err
+	}
+	p := gpsdo.Position{Latitude: req.GetLatitude(), Longitude: req.GetLongitude(), Altitude: req.GetAltitude()}
+	// Written to reject NaN
+	if !(p.Latitude >= -90 && p.Latitude <= 90 && p.Longitude >= -180 && p.Longitude <= 180) ||
+		math.IsNaN(p.Altitude) || math.IsInf(p.Altitude, 0) {
+		return nil, status.Error(codes.InvalidArgument, "position out of range")
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, scpiTimeout)
+	defer cancel()
+	if err := b.HoldPosition(ctx, p); err != nil {
+		return nil, surveyError(err)
+	}
+	slog.Info("Position hold set by gRPC request", "device", b.Name(),
+		"latitude", p.Latitude, "longitude", p.Longitude, "altitude", p.Altitude)
+	return &pb.HoldPositionResponse{}, nil
+}
+
+// surveyError converts an error of the survey commands
+func surveyError(err error) error {
+	if errors.Is(err, bridge.ErrNoSurvey) {
+		return status.Error(codes.FailedPrecondition, err.Error())
+	}
+	return status.Error(codes.Unavailable, err.Error())
+}
+
+// newStatus converts the HTTP API status of b
+func newStatus(b *bridge.Bridge) *pb.Status {
+	st := api.NewStatus(b)
+	out := &pb.Status{
+		Device:          st.Device,
+		Status:          st.Status,
+		Valid:           st.Valid,
+		Timestamp:       timestamp(st.Timestamp),
+		LeapSeconds:     int32(st.LeapSeconds),
+		Leap:            st.Leap,
+		SampleAge:       st.SampleAge,
+		LastUpdate:      timestamp(st.LastUpdate),
+		Offset:          b.Offset().Seconds(),
+		ClockOffset:     st.Clock.Offset,
+		InputConnected:  st.Input.Connected,
+		InputReconnects: st.Input.Reconnects,
+		Packets: &pb.Packets{
+			Total:          st.Packets.Total,
+			Valid:          st.Packets.Valid,
+			FramingErrors:  st.Packets.FramingErrors,
+			ChecksumErrors: st.Packets.ChecksumErrors,
+			RangeErrors:    st.Packets.RangeErrors,
+			Malformed:      st.Packets.Malformed,
+			Outliers:       st.Packets.Outliers,
+			Missed:         st.Packets.Missed,
+			Gaps:           st.Packets.Gaps,
+		},
+		SamplesSent:          st.Samples.Sent,
+		SamplesDropped:       st.Samples.Dropped,
+		PpsEdges:             st.PPS.Edges,
+		PpsPaired:            st.PPS.Paired,
+		HoldoverSince:        timestamp(st.Holdover.Since),
+		HoldoverRejected:     st.Holdover.Rejected,
+		HoldoverEpisodes:     st.Holdover.Episodes,
+		HoldoverLastDuration: st.Holdover.LastDuration,
+		HoldoverLongest:      st.Holdover.Longest,
+		PowerUpSince:         timestamp(st.Lock.PowerUpSince),
+		TimeToLock:           st.Lock.LastTimeToLock,
+	}
+	if id := st.Identity; id != nil {
+		out.Identity = &pb.Identity{
+			Manufacturer: id.Manufacturer,
+			Model:        id.Model,
+			Serial:       id.Serial,
+			Firmware:     id.Firmware,
+		}
+	}
+	if r := st.Receiver; r != nil {
+		out.Receiver = &pb.Receiver{
+			Satellites:         int32(r.Satellites),
+			Efc:                r.EFC,
+			HoldoverPrediction: r.HoldoverPrediction,
+			Antenna:            r.Antenna,
+			PhaseError:         r.PhaseError,
+			Discipline:         r.Discipline,
+			Updated:            timestamppb.New(r.Updated),
+			AntennaFaultSince:  timestamp(r.AntennaFaultSince),
+			AntennaFaults:      r.AntennaFaults,
+		}
+		for _, s := range r.Signals {
+			out.Receiver.Signals = append(out.Receiver.Signals, &pb.Signal{
+				Prn:       int32(s.PRN),
+				Elevation: int32(s.Elevation),
+				Azimuth:   int32(s.Azimuth),
+				Strength:  int32(s.Strength),
+			})
+		}
+		if p := r.Position; p != nil {
+			out.Receiver.Position = &pb.Position{
+				Latitude:  p.Latitude,
+				Longitude: p.Longitude,
+				Altitude:  p.Altitude,
+				Mode:      p.Mode,
+				Hold:      p.Hold,
+				Surveying: p.Surveying,
+				Progress:  p.Progress,
+			}
+		}
+	}
+	return out
+}
+
+// timestamp converts an optional time
+func timestamp(t *time.Time) *timestamppb.Timestamp {
+	if t == nil {
+		return nil
+	}
+	return timestamppb.New(*t)
+}
@@ -0,0 +1,308 @@
+// Package history persists samples, status transitions, statistics and
+// receiver diagnostics to an SQLite database, so the API and dashboard show
+// history across restarts.
+package history
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	// Registers the sqlite3 database/sql driver
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/bridge"
+	"github.com/karlcswanson/gogpsdo/internal/config"
+)
+
+const (
+	// flushInterval batches sample inserts to spare SD cards
+	flushInterval = 10 * time.Second
+	// pruneInterval is how often rows older than the retention are deleted
+	pruneInterval = time.Hour
+	// queueSize bounds the samples waiting to be written
+	queueSize = 1024
+)
+
+// errQueueFull is returned by Send when the database can't keep up
+var errQueueFull = errors.New("history queue full")
+
+const schema = `
+CREATE TABLE IF NOT EXISTS samples (
+	device TEXT NOT NULL,
+	time REAL NOT NULL,
+	receive_time REAL NOT NULL,
+	offset_seconds REAL NOT NULL,
+	status TEXT NOT NULL,
+	leap_seconds INTEGER NOT NULL
+);
+CREATE INDEX IF NOT EXISTS samples_device_time ON samples (device, time);
+
+CREATE TABLE IF NOT EXISTS transitions (
+	device TEXT NOT NULL,
+	time REAL NOT NULL,
+	from_status TEXT NOT NULL,
+	to_status TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS transitions_device_time ON transitions (device, time);
+
+CREATE TABLE IF NOT EXISTS statistics (
+	device TEXT NOT NULL,
+	time REAL NOT NULL,
+	packets_total INTEGER NOT NULL,
+	packets_valid INTEGER NOT NULL,
+	framing_errors INTEGER NOT NULL,
+	outliers INTEGER NOT NULL,
+	samples_sent INTEGER NOT NULL,
+	samples_dropped INTEGER NOT NULL,
+	holdover_rejected INTEGER NOT NULL,
+	offset_samples INTEGER NOT NULL,
+	offset_mean REAL,
+	offset_std_dev REAL,
+	offset_mad REAL,
+	adev_1 REAL,
+	adev_10 REAL,
+	adev_100 REAL,
+	adev_1000 REAL
+);
+CREATE INDEX IF NOT EXISTS statistics_device_time ON statistics (device, time);
+
+CREATE TABLE IF NOT EXISTS receiver (
+	device TEXT NOT NULL,
+	time REAL NOT NULL,
+	satellites INTEGER NOT NULL,
+	efc REAL NOT NULL,
+	holdover_prediction REAL NOT NULL,
+	signal_strength REAL
+);
+CREATE INDEX IF NOT EXISTS receiver_device_time ON receiver (device, time);
+`
+
+// sampleRow is a sample queued for insertion
+type sampleRow struct {
+	device string
+	sample gpsdo.Sample
+}
+
+// Store is the history database
+type Store struct {
+	cfg     config.History
+	db      *sql.DB
+	opened  time.Time
+	samples chan sampleRow
+}
+
+// Open opens or creates the database and its tables
+func Open(cfg config.History) (*Store, error) {
+	dsn := "file:" + cfg.Database + "?_journal_mode=WAL&_synchronous=NORMAL&_busy_timeout=5000"
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, err
+	}
+	// One connection, opened now, keeps working once privileges are dropped
+	// and serializes the writes
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(schema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("%s: %w", cfg.Database, err)
+	}
+	return &Store{
+		cfg:     cfg,
+		db:      db,
+		opened:  time.Now(),
+		samples: make(chan sampleRow, queueSize),
+	}, nil
+}
+
+// Close closes the database
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// Output returns the bridge output recording the samples of device
+func (s *Store) Output(device string) bridge.Output {
+	return &recorder{store: s, device: device}
+}
+
+// recorder queues samples for the store
+type recorder struct {
+	store  *Store
+	device string
+}
+
+func (r *recorder) Name() string {
+	return "History"
+}
+
+func (r *recorder) Send(data *gpsdo.Sample) error {
+	select {
+	case r.store.samples <- sampleRow{device: r.device, sample: *data}:
+		return nil
+	default:
+		return errQueueFull
+	}
+}
+
+// Run writes the queued samples, the transitions and periodic statistics of
+// the bridges and prunes old rows until ctx is cancelled
+func (s *Store) Run(ctx context.Context, bridges ...*bridge.Bridge) {
+	slog.Info("History store enabled", "database", s.cfg.Database, "retention", s.cfg.Retention)
+
+	// Only transitions of this process are recorded, earlier ones are stored
+	seen := make([]time.Time, len(bridges))
+	for i := range seen {
+		seen[i] = s.opened
+	}
+	// Diagnostics are stored once per update, not repeated while the
+	// receiver is silent
+	diagnosed := make([]time.Time, len(bridges))
+
+	flush := time.NewTicker(flushInterval)
+	defer flush.Stop()
+	stats := time.NewTicker(s.cfg.StatsInterval)
+	defer stats.Stop()
+	prune := time.NewTicker(pruneInterval)
+	defer prune.Stop()
+
+	s.prune()
+	for {
+		select {
+		case <-ctx.Done():
+			s.flush(bridges, seen)
+			return
+		case <-flush.C:
+			s.flush(bridges, seen)
+		case now := <-stats.C:
+			s.writeStatistics(bridges, diagnosed, now)
+		case <-prune.C:
+			s.prune()
+		}
+	}
+}
+
+// flush writes the queued samples and new transitions in one transaction
+func (s *Store) flush(bridges []*bridge.Bridge, seen []time.Time) {
+	err := s.transaction(func(tx *sql.Tx) error {
+		for done := false; !done; {
+			select {
+			case row := <-s.samples:
+				data := &row.sample
+				if _, err := tx.Exec(`INSERT INTO samples VALUES (?, ?, ?, ?, ?, ?)`,
+					row.device, unixSeconds(data.Timestamp), unixSeconds(data.ReceiveTime()),
+					data.SystemOffset().Seconds(), data.Status.String(), data.LeapSeconds); err != nil {
+					return err
+				}
+			default:
+				done = true
+			}
+		}
+
+		for i, b := range bridges {
+			for _, t := range b.Transitions() {
+				if !t.Time.After(seen[i]) {
+					continue
+				}
+				if _, err := tx.Exec(`INSERT INTO transitions VALUES (?, ?, ?, ?)`,
+					b.Name(), unixSeconds(t.Time), t.From.String(), t.To.String()); err != nil {
+					return err
+				}
+				seen[i] = t.Time
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Warn("History write failed", "error", err)
+	}
+}
+
+// writeStatistics writes a statistics snapshot of every bridge, and its
+// receiver diagnostics if they were updated since diagnosed
+func (s *Store) writeStatistics(bridges []*bridge.Bridge, diagnosed []time.Time, now time.Time) {
+	err := s.transaction(func(tx *sql.Tx) error {
+		for i, b := range bridges {
+			stats := b.Stats()
+			summary := b.Stability()
+
+			var mean, stdDev, mad sql.NullFloat64
+			if summary.Samples > 1 {
+				mean = sql.NullFloat64{Float64: summary.Mean, Valid: true}
+				stdDev = sql.NullFloat64{Float64: summary.StdDev, Valid: true}
+				mad = sql.NullFloat64{Float64: summary.MAD, Valid: true}
+			}
+			adev := make(map[time.Duration]sql.NullFloat64)
+			for _, dev := range summary.ADEV {
+				adev[dev.Tau] = sql.NullFloat64{Float64: dev.Deviation, Valid: true}
+			}
+
+			if _, err := tx.Exec(`INSERT INTO statistics VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+				b.Name(), unixSeconds(now), stats.TotalPackets, stats.ValidPackets, stats.FramingErrors,
+				stats.OutliersRejected, stats.SentSamples, stats.DroppedSamples, stats.HoldoverRejected,
+				summary.Samples, mean, stdDev, mad, adev[time.Second], adev[10*time.Second],
+				adev[100*time.Second], adev[1000*time.Second]); err != nil {
+				return err
+			}
+
+			diag := b.Diagnostics()
+			if !diag.Updated.After(diagnosed[i]) {
+				continue
+			}
+			var signal sql.NullFloat64
+			if len(diag.Signals) > 0 {
+				signal = sql.NullFloat64{Float64: gpsdo.MeanStrength(diag.Signals), Valid: true}
+			}
+			if _, err := tx.Exec(`INSERT INTO receiver VALUES (?, ?, ?, ?, ?, ?)`,
+				b.Name(), unixSeconds(diag.Updated), diag.Satellites, diag.EFC,
+				diag.HoldoverPrediction.Seconds(), signal); err != nil {
+				return err
+			}
+			diagnosed[i] = diag.Updated
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Warn("History statistics write failed", "error", err)
+	}
+}
+
+// prune deletes rows older than the retention
+func (s *Store) prune() {
+	cutoff := unixSeconds(time.Now().Add(-s.cfg.Retention))
+	err := s.transaction(func(tx *sql.Tx) error {
+		for _, table := range []string{"samples", "transitions", "statistics", "receiver"} {
+			if _, err := tx.Exec(`DELETE FROM `+table+` WHERE time < ?`, cutoff); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		slog.Warn("History prune failed", "error", err)
+	}
+}
+
+func (s *Store) transaction(fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// unixSeconds is the column encoding of times, so they work with the SQLite
+// date functions, e.g. datetime(time, 'unixepoch')
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / 1e9
+}
+
+func fromUnixSeconds(v float64) time.Time {
+	return time.Unix(0, int64(v*1e9)).UTC()
+}
@@ -0,0 +1,186 @@
+package history
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo/bridge"
+)
+
+// Point summarizes the samples of one time bucket
+type Point struct {
+	Time time.Time `json:"time"`
+	// Offset is the mean offset in seconds, Min and Max its range
+	Offset  float64 `json:"offset"`
+	Min     float64 `json:"min"`
+	Max     float64 `json:"max"`
+	Samples int     `json:"samples"`
+	// Locked is the fraction of samples reporting LOCKED
+	Locked float64 `json:"locked"`
+}
+
+// Statistics is a stored snapshot of the bridge counters and offset
+// statistics. Offset statistics are nil until enough samples were seen.
+type Statistics struct {
+	Time             time.Time `json:"time"`
+	TotalPackets     uint64    `json:"packets_total"`
+	ValidPackets     uint64    `json:"packets_valid"`
+	FramingErrors    uint64    `json:"framing_errors"`
+	OutliersRejected uint64    `json:"outliers_rejected"`
+	SentSamples      uint64    `json:"samples_sent"`
+	DroppedSamples   uint64    `json:"samples_dropped"`
+	HoldoverRejected uint64    `json:"holdover_rejected"`
+	Samples          int       `json:"offset_samples"`
+	Mean             *float64  `json:"offset_mean"`
+	StdDev           *float64  `json:"offset_std_dev"`
+	MAD              *float64  `json:"offset_mad"`
+	ADEV1            *float64  `json:"adev_1"`
+	ADEV10           *float64  `json:"adev_10"`
+	ADEV100          *float64  `json:"adev_100"`
+	ADEV1000         *float64  `json:"adev_1000"`
+}
+
+// ReceiverPoint summarizes the receiver diagnostics of one time bucket
+type ReceiverPoint struct {
+	Time time.Time `json:"time"`
+	// EFC is the mean oscillator EFC in percent of range, Min and Max its
+	// range
+	EFC    float64 `json:"efc"`
+	EFCMin float64 `json:"efc_min"`
+	EFCMax float64 `json:"efc_max"`
+	// Satellites is the mean number of tracked satellites
+	Satellites float64 `json:"satellites"`
+	// HoldoverPrediction is the mean predicted holdover uncertainty in
+	// seconds
+	HoldoverPrediction float64 `json:"holdover_prediction"`
+	// SignalStrength is the mean signal strength, nil if not reported
+	SignalStrength *float64 `json:"signal_strength"`
+}
+
+// Previous returns up to limit transitions of device stored before this
+// process started, oldest first
+func (s *Store) Previous(device string, limit int) ([]bridge.Transition, error) {
+	rows, err := s.db.Query(`SELECT time, from_status, to_status FROM transitions
+		WHERE device = ? AND time <= ? ORDER BY time DESC LIMIT ?`,
+		device, unixSeconds(s.opened), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var transitions []bridge.Transition
+	for rows.Next() {
+		var t float64
+		var from, to string
+		if err := rows.Scan(&t, &from, &to); err != nil {
+			return nil, err
+		}
+		transition := bridge.Transition{Time: fromUnixSeconds(t)}
+		transition.From.UnmarshalText([]byte(from))
+		transition.To.UnmarshalText([]byte(to))
+		transitions = append(transitions, transition)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(transitions)-1; i < j; i, j = i+1, j-1 {
+		transitions[i], transitions[j] = transitions[j], transitions[i]
+	}
+	return transitions, nil
+}
+
+// Samples returns the samples of device since the given time, averaged over
+// buckets of step, oldest first
+func (s *Store) Samples(device string, since time.Time, step time.Duration) ([]Point, error) {
+	width := step.Seconds()
+	if width <= 0 {
+		width = 1
+	}
+	rows, err := s.db.Query(`SELECT CAST(time / ? AS INTEGER) AS bucket, AVG(offset_seconds),
+		MIN(offset_seconds), MAX(offset_seconds), COUNT(*), AVG(status = 'LOCKED')
+		FROM samples WHERE device = ? AND time >= ? GROUP BY bucket ORDER BY bucket`,
+		width, device, unixSeconds(since))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := []Point{}
+	for rows.Next() {
+		var bucket int64
+		var p Point
+		if err := rows.Scan(&bucket, &p.Offset, &p.Min, &p.Max, &p.Samples, &p.Locked); err != nil {
+			return nil, err
+		}
+		p.Time = fromUnixSeconds(float64(bucket) * width)
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
+
+// Statistics returns the statistics snapshots of device since the given
+// time, oldest first
+func (s *Store) Statistics(device string, since time.Time) ([]Statistics, error) {
+	rows, err := s.db.Query(`SELECT time, packets_total, packets_valid, framing_errors, outliers,
+		samples_sent, samples_dropped, holdover_rejected, offset_samples, offset_mean, offset_std_dev,
+		offset_mad, adev_1, adev_10, adev_100, adev_1000
+		FROM statistics WHERE device = ? AND time >= ? ORDER BY time`,
+		device, unixSeconds(since))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stats := []Statistics{}
+	for rows.Next() {
+		var t float64
+		var st Statistics
+		var optional [7]sql.NullFloat64
+		if err := rows.Scan(&t, &st.TotalPackets, &st.ValidPackets, &st.FramingErrors, &st.OutliersRejected,
+			&st.SentSamples, &st.DroppedSamples, &st.HoldoverRejected, &st.Samples,
+			&optional[0], &optional[1], &optional[2], &optional[3], &optional[4], &optional[5], &optional[6]); err != nil {
+			return nil, err
+		}
+		st.Time = fromUnixSeconds(t)
+		for i, dst := range []**float64{&st.Mean, &st.StdDev, &st.MAD, &st.ADEV1, &st.ADEV10, &st.ADEV100, &st.ADEV1000} {
+			if optional[i].Valid {
+				*dst = &optional[i].Float64
+			}
+		}
+		stats = append(stats, st)
+	}
+	return stats, rows.Err()
+}
+
+// Receiver returns the receiver diagnostics of device since the given time,
+// averaged over buckets of step, oldest first
+func (s *Store) Receiver(device string, since time.Time, step time.Duration) ([]ReceiverPoint, error) {
+	width := step.Seconds()
+	if width <= 0 {
+		width = 1
+	}
+	rows, err := s.db.Query(`SELECT CAST(time / ? AS INTEGER) AS bucket, AVG(efc), MIN(efc), MAX(efc),
+		AVG(satellites), AVG(holdover_prediction), AVG(signal_strength)
+		FROM receiver WHERE device = ? AND time >= ? GROUP BY bucket ORDER BY bucket`,
+		width, device, unixSeconds(since))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	points := []ReceiverPoint{}
+	for rows.Next() {
+		var bucket int64
+		var p ReceiverPoint
+		var signal sql.NullFloat64
+		if err := rows.Scan(&bucket, &p.EFC, &p.EFCMin, &p.EFCMax, &p.Satellites, &p.HoldoverPrediction, &signal); err != nil {
+			return nil, err
+		}
+		p.Time = fromUnixSeconds(float64(bucket) * width)
+		if signal.Valid {
+			p.SignalStrength = &signal.Float64
+		}
+		points = append(points, p)
+	}
+	return points, rows.Err()
+}
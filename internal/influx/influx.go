@@ -0,0 +1,254 @@
+// Package influx writes bridge statistics to InfluxDB using the line
+// protocol over the v1 or v2 HTTP write API.
+package influx
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/bridge"
+	"github.com/karlcswanson/gogpsdo/internal/config"
+)
+
+// maxPending bounds the lines kept for retry while InfluxDB is unreachable
+const maxPending = 10000
+
+// Writer periodically writes one point per bridge
+type Writer struct {
+	cfg     config.InfluxDB
+	bridges []*bridge.Bridge
+	client  *http.Client
+	host    string
+	pending [][]byte
+}
+
+// New creates a writer for the bridges
+func New(cfg config.InfluxDB, bridges ...*bridge.Bridge) *Writer {
+	host, _ := os.Hostname()
+	return &Writer{
+		cfg:     cfg,
+		bridges: bridges,
+		client:  &http.Client{Timeout: 10 * time.Second},
+		host:    host,
+	}
+}
+
+// Run writes a point for every bridge each interval until ctx is cancelled.
+// Points that fail to write are retried with the next batch.
+func (w *Writer) Run(ctx context.Context) {
+	slog.Info("InfluxDB output enabled", "url", w.cfg.URL, "interval", w.cfg.Interval)
+
+	ticker := time.NewTicker(w.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, b := range w.bridges {
+				w.pending = append(w.pending, w.point(b, now))
+			}
+			if n := len(w.pending) - maxPending; n > 0 {
+				slog.Warn("InfluxDB backlog full, dropping oldest points", "dropped", n)
+				w.pending = w.pending[n:]
+			}
+
+			if err := w.write(ctx, bytes.Join(w.pending, []byte("\n"))); err != nil {
+				slog.Warn("InfluxDB write failed", "error", err, "pending", len(w.pending))
+				continue
+			}
+			w.pending = w.pending[:0]
+		}
+	}
+}
+
+// point encodes the current state of b as a line protocol point
+func (w *Writer) point(b *bridge.Bridge, now time.Time) []byte {
+	stats := b.Stats()
+	data := b.Current()
+
+	var line bytes.Buffer
+	line.WriteString(escape(w.cfg.Measurement, ", "))
+	line.WriteString(",host=" + escape(w.host, ",= "))
+	if b.Name() != "" {
+		line.WriteString(",device=" + escape(b.Name(), ",= "))
+	}
+
+	fields := []string{
+		"packets_total=" + uint64Field(stats.TotalPackets),
+		"packets_valid=" + uint64Field(stats.ValidPackets),
+		"framing_errors=" + uint64Field(stats.FramingErrors),
+		"checksum_errors=" + uint64Field(stats.ChecksumErrors),
+		"range_errors=" + uint64Field(stats.RangeErrors),
+		"packets_malformed=" + uint64Field(stats.MalformedPackets),
+		"outliers=" + uint64Field(stats.OutliersRejected),
+		"packets_missed=" + uint64Field(stats.MissedPackets),
+		"packet_gaps=" + uint64Field(stats.PacketGaps),
+		"samples_sent=" + uint64Field(stats.SentSamples),
+		"samples_dropped=" + uint64Field(stats.DroppedSamples),
+		"pps_edges=" + uint64Field(stats.PPSEdges),
+		"pps_paired=" + uint64Field(stats.PPSPaired),
+		"holdover_rejected=" + uint64Field(stats.HoldoverRejected),
+		"holdover_episodes=" + uint64Field(stats.HoldoverEpisodes),
+		"input_reconnects=" + uint64Field(stats.InputReconnects),
+		"clock_alarm=" + strconv.FormatBool(!stats.ClockAlarmSince.IsZero()),
+		"clock_alarms=" + uint64Field(stats.ClockAlarms),
+	}
+	if !stats.LastPacket.IsZero() {
+		fields = append(fields, "packet_age="+floatField(now.Sub(stats.LastPacket).Seconds()))
+	}
+	if data != nil {
+		fields = append(fields,
+			"status="+strconv.Quote(data.Status.String()),
+			"status_code="+strconv.Itoa(int(data.Status))+"i",
+			"valid="+strconv.FormatBool(data.Valid),
+			"leap_seconds="+strconv.Itoa(data.LeapSeconds)+"i",
+			"sample_age="+floatField(now.Sub(stats.LastUpdate).Seconds()))
+		if !data.PPS.IsZero() {
+			offset := data.Timestamp.Sub(data.PPS) + data.Offset
+			fields = append(fields, "offset="+floatField(offset.Seconds()))
+		}
+		if data.Valid {
+			fields = append(fields, "clock_offset="+floatField(data.SystemOffset().Seconds()))
+		}
+	}
+	if c := b.NTPCheck(); c != nil {
+		fields = append(fields,
+			"ntp_alarm="+strconv.FormatBool(!stats.NTPAlarmSince.IsZero()),
+			"ntp_alarms="+uint64Field(stats.NTPAlarms))
+		if c.Compared {
+			fields = append(fields, "ntp_divergence="+floatField(c.Divergence.Seconds()))
+		}
+	}
+	longest := stats.LongestHoldover
+	if !stats.HoldoverSince.IsZero() {
+		duration := now.Sub(stats.HoldoverSince)
+		longest = max(longest, duration)
+		fields = append(fields, "holdover_duration="+floatField(duration.Seconds()))
+	}
+	fields = append(fields, "holdover_longest="+floatField(longest.Seconds()))
+	if stats.LastHoldoverDuration > 0 {
+		fields = append(fields, "holdover_last="+floatField(stats.LastHoldoverDuration.Seconds()))
+	}
+	if !stats.PowerUpSince.IsZero() {
+		fields = append(fields, "power_up_duration="+floatField(now.Sub(stats.PowerUpSince).Seconds()))
+	}
+	if stats.LastTimeToLock > 0 {
+		fields = append(fields, "time_to_lock="+floatField(stats.LastTimeToLock.Seconds()))
+	}
+	if summary := b.Stability(); summary.Samples > 1 {
+		fields = append(fields,
+			"offset_mean="+floatField(summary.Mean),
+			"offset_std_dev="+floatField(summary.StdDev),
+			"offset_mad="+floatField(summary.MAD))
+		for _, dev := range summary.ADEV {
+			fields = append(fields, "adev_"+strconv.Itoa(int(dev.Tau/time.Second))+"s="+floatField(dev.Deviation))
+		}
+	}
+	if diag := b.Diagnostics(); !diag.Updated.IsZero() {
+		fields = append(fields,
+			"satellites="+strconv.Itoa(diag.Satellites)+"i",
+			"efc="+floatField(diag.EFC),
+			"holdover_prediction="+floatField(diag.HoldoverPrediction.Seconds()))
+		if diag.Discipline != "" {
+			fields = append(fields, "phase_error="+floatField(diag.PhaseError.Seconds()))
+		}
+		if diag.Antenna != "" {
+			fields = append(fields,
+				"antenna_fault="+strconv.FormatBool(!stats.AntennaFaultSince.IsZero()),
+				"antenna_faults="+uint64Field(stats.AntennaFaults))
+		}
+		if diag.Signals != nil {
+			fields = append(fields, "signal_strength="+floatField(gpsdo.MeanStrength(diag.Signals)))
+		}
+		if p := diag.Position; p != nil {
+			fields = append(fields,
+				"position_hold="+strconv.FormatBool(p.Hold),
+				"surveying="+strconv.FormatBool(p.Surveying))
+			if p.Surveying && p.Progress >= 0 {
+				fields = append(fields, "survey_progress="+floatField(p.Progress))
+			}
+		}
+	}
+
+	line.WriteByte(' ')
+	line.WriteString(strings.Join(fields, ","))
+	line.WriteByte(' ')
+	line.WriteString(strconv.FormatInt(now.UnixNano(), 10))
+	return line.Bytes()
+}
+
+// write posts a batch of lines to the v2 API when a bucket is configured
+// and the v1 API otherwise
+func (w *Writer) write(ctx context.Context, body []byte) error {
+	base, err := url.Parse(w.cfg.URL)
+	if err != nil {
+		return err
+	}
+
+	query := url.Values{"precision": {"ns"}}
+	if w.cfg.Bucket != "" {
+		base = base.JoinPath("api/v2/write")
+		query.Set("org", w.cfg.Org)
+		query.Set("bucket", w.cfg.Bucket)
+	} else {
+		base = base.JoinPath("write")
+		query.Set("db", w.cfg.Database)
+	}
+	base.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, base.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	if w.cfg.Token != "" {
+		req.Header.Set("Authorization", "Token "+w.cfg.Token)
+	} else if w.cfg.Username != "" {
+		req.SetBasicAuth(w.cfg.Username, w.cfg.Password)
+	}
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(msg))
+	}
+	return nil
+}
+
+// escape backslash escapes the characters special to a line protocol
+// measurement or tag
+func escape(s, special string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(special, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func uint64Field(v uint64) string {
+	return strconv.FormatUint(v, 10) + "i"
+}
+
+func floatField(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
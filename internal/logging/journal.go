@@ -0,0 +1,161 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/internal/systemd"
+)
+
+// journalHandler sends each record to the systemd journal with the priority
+// of its level and its attributes as fields, such as STATUS and OFFSET.
+// MESSAGE holds the text line without time and level, which the journal
+// keeps separately.
+type journalHandler struct {
+	journal *systemd.Journal
+	// attrs are those added by WithAttrs, with their group prefix
+	attrs []journalAttr
+	// prefix is the dotted group path of WithGroup
+	prefix string
+}
+
+// journalAttr is an attribute flattened to its dotted key
+type journalAttr struct {
+	key   string
+	value slog.Value
+}
+
+func (h *journalHandler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= level.Level()
+}
+
+func (h *journalHandler) Handle(_ context.Context, r slog.Record) error {
+	attrs := h.attrs
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = flatten(attrs, h.prefix, a)
+		return true
+	})
+
+	var msg strings.Builder
+	msg.WriteString(r.Message)
+	fields := []systemd.JournalField{
+		{Name: "PRIORITY", Value: strconv.Itoa(priority(r.Level))},
+		{Name: "SYSLOG_IDENTIFIER", Value: "gogpsdo"},
+	}
+	for _, a := range attrs {
+		text := formatValue(a.value)
+		fmt.Fprintf(&msg, " %s=%s", a.key, quote(text))
+		if name := fieldName(a.key); name != "" {
+			fields = append(fields, systemd.JournalField{Name: name, Value: text})
+		}
+	}
+	fields = append(fields, systemd.JournalField{Name: "MESSAGE", Value: msg.String()})
+
+	if err := h.journal.Send(fields); err != nil {
+		// stderr reaches the journal too, without the priority
+		fmt.Fprintln(os.Stderr, msg.String())
+		return err
+	}
+	return nil
+}
+
+func (h *journalHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.attrs = nil
+	for _, a := range attrs {
+		c.attrs = flatten(c.attrs, h.prefix, a)
+	}
+	c.attrs = append(h.attrs[:len(h.attrs):len(h.attrs)], c.attrs...)
+	return &c
+}
+
+func (h *journalHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.prefix = h.prefix + name + "."
+	return &c
+}
+
+// flatten appends a to attrs, with the attributes of groups under dotted
+// keys
+func flatten(attrs []journalAttr, prefix string, a slog.Attr) []journalAttr {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return attrs
+	}
+	if a.Value.Kind() != slog.KindGroup {
+		return append(attrs, journalAttr{key: prefix + a.Key, value: a.Value})
+	}
+	if a.Key != "" {
+		prefix += a.Key + "."
+	}
+	for _, g := range a.Value.Group() {
+		attrs = flatten(attrs, prefix, g)
+	}
+	return attrs
+}
+
+// priority maps a level to a syslog severity
+func priority(l slog.Level) int {
+	switch {
+	case l >= slog.LevelError:
+		return 3
+	case l >= slog.LevelWarn:
+		return 4
+	case l >= slog.LevelInfo:
+		return 6
+	}
+	return 7
+}
+
+// formatValue formats a value like the text handler
+func formatValue(v slog.Value) string {
+	if v.Kind() == slog.KindTime {
+		return v.Time().Format(time.RFC3339Nano)
+	}
+	return v.String()
+}
+
+// quote quotes s for the MESSAGE line if it isn't a plain word
+func quote(s string) string {
+	if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// journalFields are the fields with a meaning to the journal, which
+// attributes of the same name are renamed from
+var journalFields = []string{
+	"MESSAGE", "MESSAGE_ID", "PRIORITY", "CODE_FILE", "CODE_LINE", "CODE_FUNC", "ERRNO", "TID",
+	"DOCUMENTATION", "SYSLOG_FACILITY", "SYSLOG_IDENTIFIER", "SYSLOG_PID", "SYSLOG_TIMESTAMP", "SYSLOG_RAW",
+}
+
+// fieldName turns a key such as gps_time or counters.parse_errors into a
+// journal field name, GPS_TIME and COUNTERS_PARSE_ERRORS. Fields may not
+// start with an underscore or digit, which are trimmed, and a key such as
+// message that names a journal field gets a GOGPSDO_ prefix.
+func fieldName(key string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, key)
+	name = strings.TrimLeft(name, "_0123456789")
+	if slices.Contains(journalFields, name) {
+		name = "GOGPSDO_" + name
+	}
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
@@ -0,0 +1,168 @@
+// Package logging configures the process wide slog logger from the
+// gogpsdo logging config.
+package logging
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"sync"
+
+	"github.com/karlcswanson/gogpsdo/internal/config"
+	"github.com/karlcswanson/gogpsdo/internal/syslog"
+	"github.com/karlcswanson/gogpsdo/internal/systemd"
+)
+
+// level is shared by every handler so it can change while running
+var level slog.LevelVar
+
+// file is the log file of the installed logger, nil when logging elsewhere
+var file *rotatingFile
+
+// Setup installs the default slog logger. The returned closer releases the
+// log file, if any. created, if set, is called with the path of every log
+// file opened, such as to set its owner.
+func Setup(cfg config.Logging, created func(path string) error) (io.Closer, error) {
+	if err := SetLevel(cfg.Level); err != nil {
+		return nil, err
+	}
+
+	if cfg.Syslog.Enabled {
+		return setupSyslog(cfg)
+	}
+	if cfg.File == "" && (cfg.Journal == "always" || cfg.Journal == "auto" && systemd.JournalStream()) {
+		journal, err := systemd.OpenJournal()
+		if err == nil {
+			slog.SetDefault(slog.New(&journalHandler{journal: journal}))
+			return journal, nil
+		}
+		if cfg.Journal == "always" {
+			return nil, fmt.Errorf("journal: %w", err)
+		}
+	}
+
+	var out io.WriteCloser = nopCloser{os.Stderr}
+	if cfg.File != "" {
+		f, err := openRotating(cfg.File, int64(cfg.MaxSizeMB)<<20, cfg.RotateInterval, cfg.MaxFiles, created)
+		if err != nil {
+			return nil, fmt.Errorf("open log file: %w", err)
+		}
+		file = f
+		out = f
+	}
+
+	opts := &slog.HandlerOptions{Level: &level}
+	var handler slog.Handler = slog.NewTextHandler(out, opts)
+	if cfg.Format == "json" {
+		handler = slog.NewJSONHandler(out, opts)
+	}
+	slog.SetDefault(slog.New(handler))
+	return out, nil
+}
+
+// setupSyslog installs a logger sending each record to the local or remote
+// syslog server at the severity of its level
+func setupSyslog(cfg config.Logging) (io.Closer, error) {
+	// Validated by config.Parse
+	format, _ := syslog.ParseFormat(cfg.Syslog.Format)
+	facility, _ := syslog.ParseFacility(cfg.Syslog.Facility)
+	writer, err := syslog.Dial(syslog.Options{
+		Server:   cfg.Syslog.Server,
+		Format:   format,
+		Facility: facility,
+		Tag:      cfg.Syslog.Tag,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("syslog: %w", err)
+	}
+
+	out := &syslogOutput{writer: writer}
+	opts := &slog.HandlerOptions{
+		Level: &level,
+		// syslog adds its own timestamp
+		ReplaceAttr: func(groups []string, a slog.Attr) slog.Attr {
+			if len(groups) == 0 && a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}
+	var handler slog.Handler = slog.NewTextHandler(out, opts)
+	if cfg.Format == "json" {
+		handler = slog.NewJSONHandler(out, opts)
+	}
+	slog.SetDefault(slog.New(&syslogHandler{Handler: handler, out: out}))
+	return writer, nil
+}
+
+// syslogOutput writes each formatted record as one syslog message
+type syslogOutput struct {
+	// mutex holds level for the record being written
+	mutex  sync.Mutex
+	level  slog.Level
+	writer *syslog.Writer
+}
+
+func (o *syslogOutput) Write(p []byte) (int, error) {
+	severity := syslog.Debug
+	switch {
+	case o.level >= slog.LevelError:
+		severity = syslog.Error
+	case o.level >= slog.LevelWarn:
+		severity = syslog.Warning
+	case o.level >= slog.LevelInfo:
+		severity = syslog.Info
+	}
+	return len(p), o.writer.Write(severity, string(bytes.TrimSuffix(p, []byte("\n"))))
+}
+
+// syslogHandler passes the level of each record to its syslogOutput
+type syslogHandler struct {
+	slog.Handler
+	out *syslogOutput
+}
+
+func (h *syslogHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.out.mutex.Lock()
+	defer h.out.mutex.Unlock()
+	h.out.level = r.Level
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h *syslogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &syslogHandler{Handler: h.Handler.WithAttrs(attrs), out: h.out}
+}
+
+func (h *syslogHandler) WithGroup(name string) slog.Handler {
+	return &syslogHandler{Handler: h.Handler.WithGroup(name), out: h.out}
+}
+
+// Reopen reopens the log file after an external tool moved it away. It is a
+// no-op when not logging to a file.
+func Reopen() error {
+	if file == nil {
+		return nil
+	}
+	return file.reopen()
+}
+
+// SetLevel changes the level of the installed logger, e.g. on reload
+func SetLevel(name string) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(name)); err != nil {
+		return fmt.Errorf("log level %q: %w", name, err)
+	}
+	level.Set(l)
+	return nil
+}
+
+type nopCloser struct {
+	io.Writer
+}
+
+func (nopCloser) Close() error {
+	return nil
+}
@@ -0,0 +1,155 @@
+package logging
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// rotatingFile is a log file rotated by size and time. Rotated files are
+// named FILE.1 (newest) to FILE.MaxFiles, like the CSV output.
+type rotatingFile struct {
+	path string
+	// maxSize rotates the file before it would exceed this many bytes, 0 to
+	// never rotate by size
+	maxSize int64
+	// interval rotates the file at multiples of it, 0 to never rotate by
+	// time
+	interval time.Duration
+	maxFiles int
+	// created is called with the path every time the file is opened
+	created func(path string) error
+
+	mutex sync.Mutex
+	file  *os.File
+	size  int64
+	// window is the interval the file was last written in
+	window time.Time
+	// retry is when rotation is attempted again after it failed
+	retry time.Time
+}
+
+func openRotating(path string, maxSize int64, interval time.Duration, maxFiles int, created func(string) error) (*rotatingFile, error) {
+	r := &rotatingFile{path: path, maxSize: maxSize, interval: interval, maxFiles: maxFiles, created: created}
+	if err := r.open(); err != nil {
+		r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+// open opens the file for appending. The file stays open if only created
+// fails.
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	r.file = f
+	r.size = info.Size()
+	// The last write of a file kept from before a restart decides its
+	// interval, so a file from yesterday is rotated on the first write today.
+	// The modification time of a new file can lag the clock.
+	r.window = time.Now()
+	if r.size > 0 {
+		r.window = info.ModTime()
+	}
+	if r.interval > 0 {
+		r.window = r.window.Truncate(r.interval)
+	}
+	if r.created != nil {
+		return r.created(r.path)
+	}
+	return nil
+}
+
+// Write writes one record, rotating the file first if it is due. Rotation
+// errors go to stderr as there is nowhere else to log them.
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if r.file != nil && time.Now().After(r.retry) && r.due(len(p)) {
+		if err := r.rotate(); err != nil {
+			fmt.Fprintf(os.Stderr, "Log rotation error: %v\n", err)
+			r.retry = time.Now().Add(time.Minute)
+		}
+	}
+	if r.file == nil {
+		if err := r.open(); r.file == nil {
+			return 0, err
+		}
+	}
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// due reports whether writing n more bytes needs a new file
+func (r *rotatingFile) due(n int) bool {
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(n) > r.maxSize {
+		return true
+	}
+	if r.interval > 0 {
+		window := time.Now().Truncate(r.interval)
+		if !window.Equal(r.window) {
+			if r.size > 0 {
+				return true
+			}
+			r.window = window
+		}
+	}
+	return false
+}
+
+// rotate renames the file to FILE.1, shifting older files up and removing
+// the oldest, and starts a new file
+func (r *rotatingFile) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	r.file = nil
+
+	if r.maxFiles <= 0 {
+		os.Remove(r.path)
+	} else {
+		os.Remove(fmt.Sprintf("%s.%d", r.path, r.maxFiles))
+		for i := r.maxFiles - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+		}
+		if err := os.Rename(r.path, r.path+".1"); err != nil {
+			return err
+		}
+	}
+	return r.open()
+}
+
+// reopen closes the file and opens path again, after it was moved away by
+// an external tool such as logrotate
+func (r *rotatingFile) reopen() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.file != nil {
+		r.file.Close()
+		r.file = nil
+	}
+	return r.open()
+}
+
+func (r *rotatingFile) Close() error {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.file == nil {
+		return nil
+	}
+	err := r.file.Close()
+	r.file = nil
+	return err
+}
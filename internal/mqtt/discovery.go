@@ -0,0 +1,128 @@
+package mqtt
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo/bridge"
+)
+
+// entity is a Home Assistant sensor or binary sensor read from the status
+// topic
+type entity struct {
+	component string
+	id        string
+	name      string
+	template  string
+	unit      string
+	class     string
+	measured  bool
+}
+
+// entities are announced for every bridge
+var entities = []entity{
+	{component: "binary_sensor", id: "locked", name: "Locked",
+		template: "{{ 'ON' if value_json.status == 'LOCKED' else 'OFF' }}"},
+	{component: "sensor", id: "status", name: "Status",
+		template: "{{ value_json.status }}"},
+	{component: "sensor", id: "leap_seconds", name: "Leap seconds",
+		template: "{{ value_json.leap_seconds }}", unit: "s"},
+	{component: "sensor", id: "leap", name: "Leap indicator",
+		template: "{{ value_json.leap }}"},
+	{component: "sensor", id: "sample_age", name: "Sample age",
+		template: "{{ value_json.sample_age }}", unit: "s", class: "duration", measured: true},
+}
+
+// discoveryConfig is the payload of a discovery message, see
+// https://www.home-assistant.io/integrations/mqtt/#mqtt-discovery
+type discoveryConfig struct {
+	Name              string          `json:"name"`
+	UniqueID          string          `json:"unique_id"`
+	ObjectID          string          `json:"object_id"`
+	StateTopic        string          `json:"state_topic"`
+	ValueTemplate     string          `json:"value_template"`
+	AvailabilityTopic string          `json:"availability_topic"`
+	UnitOfMeasurement string          `json:"unit_of_measurement,omitempty"`
+	DeviceClass       string          `json:"device_class,omitempty"`
+	StateClass        string          `json:"state_class,omitempty"`
+	DisplayPrecision  *int            `json:"suggested_display_precision,omitempty"`
+	Device            discoveryDevice `json:"device"`
+}
+
+type discoveryDevice struct {
+	Identifiers  []string `json:"identifiers"`
+	Name         string   `json:"name"`
+	Manufacturer string   `json:"manufacturer"`
+}
+
+// subscribeBirth republishes the discovery messages when Home Assistant
+// comes online, as it may have lost them if the broker does not retain them
+func (p *Publisher) subscribeBirth(c paho.Client) {
+	topic := p.cfg.DiscoveryPrefix + "/status"
+	c.Subscribe(topic, byte(p.cfg.QoS), func(_ paho.Client, m paho.Message) {
+		if string(m.Payload()) == "online" {
+			slog.Debug("Home Assistant online, republishing MQTT discovery")
+			go p.publishDiscovery()
+		}
+	})
+}
+
+// publishDiscovery announces the entities of every bridge to Home Assistant
+func (p *Publisher) publishDiscovery() {
+	for _, b := range p.bridges {
+		node := nodeID(p.clientID, b.Name())
+		device := discoveryDevice{
+			Identifiers:  []string{node},
+			Name:         deviceName(b),
+			Manufacturer: "gogpsdo",
+		}
+		for _, e := range entities {
+			c := discoveryConfig{
+				Name:              e.name,
+				UniqueID:          node + "_" + e.id,
+				ObjectID:          node + "_" + e.id,
+				StateTopic:        p.topic(b.Name(), "status"),
+				ValueTemplate:     e.template,
+				AvailabilityTopic: p.topic("", "availability"),
+				UnitOfMeasurement: e.unit,
+				DeviceClass:       e.class,
+				Device:            device,
+			}
+			if e.measured {
+				precision := 1
+				c.StateClass = "measurement"
+				c.DisplayPrecision = &precision
+			}
+			topic := fmt.Sprintf("%s/%s/%s/%s/config", p.cfg.DiscoveryPrefix, e.component, node, e.id)
+			p.publishJSON(topic, true, c)
+		}
+	}
+}
+
+// nodeID identifies a bridge to Home Assistant, from the client id and the
+// device name, limited to the characters allowed in discovery topics
+func nodeID(clientID, device string) string {
+	id := clientID
+	if device != "" {
+		id += "_" + device
+	}
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, id)
+}
+
+// deviceName is the Home Assistant device name of a bridge
+func deviceName(b *bridge.Bridge) string {
+	if b.Name() == "" {
+		return "GPSDO"
+	}
+	return "GPSDO " + b.Name()
+}
@@ -0,0 +1,169 @@
+// Package mqtt publishes the bridge status and lock state transitions to an
+// MQTT broker.
+//
+// Topics, below the configured prefix and the device name if set:
+//
+//	availability  "online" or "offline", retained, with a last will
+//	status        the HTTP API status document, retained, every interval
+//	transition    each lock state transition as it happens
+//
+// With discovery enabled, Home Assistant discovery messages announce sensors
+// for the lock state, leap seconds and sample age read from the status topic.
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo/bridge"
+	"github.com/karlcswanson/gogpsdo/internal/api"
+	"github.com/karlcswanson/gogpsdo/internal/config"
+)
+
+// transitionPoll is how often the bridges are checked for new transitions
+const transitionPoll = time.Second
+
+// publishTimeout bounds the wait for a publish to be acknowledged
+const publishTimeout = 10 * time.Second
+
+// Publisher sends bridge state to an MQTT broker
+type Publisher struct {
+	cfg      config.MQTT
+	client   paho.Client
+	clientID string
+	bridges  []*bridge.Bridge
+	// seen is the time of the last published transition of each bridge
+	seen []time.Time
+}
+
+// New creates a publisher for the bridges. The connection is made by Run.
+func New(cfg config.MQTT, bridges ...*bridge.Bridge) *Publisher {
+	clientID := cfg.ClientID
+	if clientID == "" {
+		host, _ := os.Hostname()
+		clientID = "gogpsdo-" + host
+	}
+	p := &Publisher{
+		cfg:      cfg,
+		clientID: clientID,
+		bridges:  bridges,
+		seen:     make([]time.Time, len(bridges)),
+	}
+
+	opts := paho.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID(clientID).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetMaxReconnectInterval(time.Minute).
+		SetWill(p.topic("", "availability"), "offline", byte(cfg.QoS), true).
+		SetOnConnectHandler(func(c paho.Client) {
+			slog.Info("MQTT connected", "broker", cfg.Broker)
+			c.Publish(p.topic("", "availability"), byte(cfg.QoS), true, "online")
+			if cfg.Discovery {
+				// Runs in its own goroutine, so the publishes can be waited on
+				// The status right away, so the new sensors have a state
+				p.publishDiscovery()
+				p.subscribeBirth(c)
+				p.publishStatus()
+			}
+		}).
+		SetConnectionLostHandler(func(_ paho.Client, err error) {
+			slog.Warn("MQTT connection lost", "broker", cfg.Broker, "error", err)
+		})
+	p.client = paho.NewClient(opts)
+	return p
+}
+
+// Run publishes until ctx is cancelled, then marks the bridge offline and
+// disconnects
+func (p *Publisher) Run(ctx context.Context) {
+	slog.Info("MQTT publishing", "broker", p.cfg.Broker, "prefix", p.cfg.TopicPrefix)
+	p.client.Connect()
+	defer p.client.Disconnect(250)
+
+	// Transitions from before startup are history, not news
+	for i, b := range p.bridges {
+		if t := b.Transitions(); len(t) > 0 {
+			p.seen[i] = t[len(t)-1].Time
+		}
+	}
+
+	statusTicker := time.NewTicker(p.cfg.Interval)
+	defer statusTicker.Stop()
+	transitionTicker := time.NewTicker(transitionPoll)
+	defer transitionTicker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			p.publish(p.topic("", "availability"), true, "offline")
+			return
+		case <-statusTicker.C:
+			p.publishStatus()
+		case <-transitionTicker.C:
+			p.publishTransitions()
+		}
+	}
+}
+
+// publishStatus sends the status of every bridge
+func (p *Publisher) publishStatus() {
+	for _, b := range p.bridges {
+		p.publishJSON(p.topic(b.Name(), "status"), true, api.NewStatus(b))
+	}
+}
+
+// publishTransitions sends the transitions recorded since the last poll
+func (p *Publisher) publishTransitions() {
+	for i, b := range p.bridges {
+		for _, t := range b.Transitions() {
+			if !t.Time.After(p.seen[i]) {
+				continue
+			}
+			p.seen[i] = t.Time
+			p.publishJSON(p.topic(b.Name(), "transition"), false, t)
+		}
+	}
+}
+
+// topic joins the prefix, device name and leaf
+func (p *Publisher) topic(device, leaf string) string {
+	if device == "" {
+		return fmt.Sprintf("%s/%s", p.cfg.TopicPrefix, leaf)
+	}
+	return fmt.Sprintf("%s/%s/%s", p.cfg.TopicPrefix, device, leaf)
+}
+
+func (p *Publisher) publishJSON(topic string, retain bool, v any) {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		slog.Warn("MQTT encode failed", "topic", topic, "error", err)
+		return
+	}
+	p.publish(topic, retain, payload)
+}
+
+func (p *Publisher) publish(topic string, retain bool, payload any) {
+	if !p.client.IsConnectionOpen() {
+		slog.Debug("MQTT not connected, skipping publish", "topic", topic)
+		return
+	}
+
+	token := p.client.Publish(topic, byte(p.cfg.QoS), retain, payload)
+	if !token.WaitTimeout(publishTimeout) {
+		slog.Warn("MQTT publish timed out", "topic", topic)
+		return
+	}
+	if err := token.Error(); err != nil {
+		slog.Warn("MQTT publish failed", "topic", topic, "error", err)
+	}
+}
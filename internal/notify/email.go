@@ -0,0 +1,238 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"log/slog"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/bridge"
+	"github.com/karlcswanson/gogpsdo/internal/config"
+)
+
+// smtpTimeout bounds a complete mail delivery
+const smtpTimeout = 30 * time.Second
+
+// Email event names, used as template keys in the config
+const (
+	EmailLockLost = "lock_lost"
+	EmailHoldover = "holdover"
+	EmailStale    = "stale"
+	EmailClock    = "clock_alarm"
+	EmailNTP      = "ntp_alarm"
+	EmailAntenna  = "antenna_fault"
+)
+
+// defaultTemplates are used for events without a configured template
+var defaultTemplates = map[string]config.EmailTemplate{
+	EmailLockLost: {
+		Subject: "[gogpsdo] {{.Message}}",
+		Body:    "{{.Host}} lost GPS lock at {{.Time.UTC.Format \"2006-01-02 15:04:05\"}} UTC: {{.From}} -> {{.To}}.\n",
+	},
+	EmailHoldover: {
+		Subject: "[gogpsdo] {{.Message}}",
+		Body:    "{{.Host}}: {{.Message}}. Holdover began at {{.Status.Holdover.Since.UTC.Format \"2006-01-02 15:04:05\"}} UTC.\n",
+	},
+	EmailStale: {
+		Subject: "[gogpsdo] {{.Message}}",
+		Body:    "{{.Host}}: {{.Message}}. Last status {{.Status.Status}}, check the serial connection.\n",
+	},
+	EmailClock: {
+		Subject: "[gogpsdo] {{.Message}}",
+		Body:    "{{.Host}}: {{.Message}} by {{.Status.Clock.Offset}} seconds, check the serial framing and receiver.\n",
+	},
+	EmailNTP: {
+		Subject: "[gogpsdo] {{.Message}}",
+		Body:    "{{.Host}}: {{.Message}} by {{.Status.NTPCheck.Divergence}} seconds, check the receiver and antenna for a fault or GPS spoofing.\n",
+	},
+	EmailAntenna: {
+		Subject: "[gogpsdo] {{.Message}}",
+		Body:    "{{.Host}}: {{.Message}}, check the antenna, cable and lightning arrestor. The GPSDO will fall back to holdover.\n",
+	},
+}
+
+// emailData is passed to the templates
+type emailData struct {
+	Event
+	Host string
+}
+
+type emailTemplate struct {
+	subject *template.Template
+	body    *template.Template
+}
+
+// Email sends lock loss, long holdover, stale data, clock alarm, NTP alarm and
+// antenna fault alerts over SMTP
+type Email struct {
+	cfg       config.SMTP
+	watcher   *Watcher
+	templates map[string]emailTemplate
+	host      string
+}
+
+// NewEmail creates an email notifier for the bridges. It fails if a template
+// does not parse.
+func NewEmail(cfg config.SMTP, bridges ...*bridge.Bridge) (*Email, error) {
+	m := &Email{
+		cfg:       cfg,
+		watcher:   NewWatcher(cfg.StaleAfter, cfg.HoldoverAfter, bridges...),
+		templates: make(map[string]emailTemplate),
+	}
+	m.host, _ = os.Hostname()
+
+	for name, def := range defaultTemplates {
+		tmpl := cfg.Templates[name]
+		if tmpl.Subject == "" {
+			tmpl.Subject = def.Subject
+		}
+		if tmpl.Body == "" {
+			tmpl.Body = def.Body
+		}
+
+		subject, err := template.New(name).Parse(tmpl.Subject)
+		if err != nil {
+			return nil, fmt.Errorf("smtp template %s subject: %w", name, err)
+		}
+		body, err := template.New(name).Parse(tmpl.Body)
+		if err != nil {
+			return nil, fmt.Errorf("smtp template %s body: %w", name, err)
+		}
+		m.templates[name] = emailTemplate{subject: subject, body: body}
+	}
+	return m, nil
+}
+
+// Run watches the bridges and mails alerts until ctx is cancelled
+func (m *Email) Run(ctx context.Context) {
+	slog.Info("Email alerts enabled", "server", m.cfg.Server, "to", m.cfg.To)
+	filter := func(e Event) bool { return eventName(e) != "" }
+	run(ctx, "smtp", m.watcher, newThrottle(m.cfg.MinInterval), m.cfg.Retries, filter, m.send)
+}
+
+// eventName returns the template key for e, empty if e is not mailed
+func eventName(e Event) string {
+	switch e.Kind {
+	case Transition:
+		if e.From == gpsdo.Locked.String() {
+			return EmailLockLost
+		}
+	case Holdover:
+		return EmailHoldover
+	case Stale:
+		return EmailStale
+	case ClockAlarm:
+		return EmailClock
+	case NTPAlarm:
+		return EmailNTP
+	case AntennaFault:
+		return EmailAntenna
+	}
+	return ""
+}
+
+func (m *Email) send(ctx context.Context, e Event) error {
+	msg, err := m.message(e)
+	if err != nil {
+		return err
+	}
+
+	c, err := m.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+
+	if m.cfg.Username != "" {
+		host, _, _ := net.SplitHostPort(m.cfg.Server)
+		if err := c.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, host)); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(m.cfg.From); err != nil {
+		return err
+	}
+	for _, to := range m.cfg.To {
+		if err := c.Rcpt(to); err != nil {
+			return err
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// dial connects to the server, with implicit TLS if configured and STARTTLS
+// if the server offers it
+func (m *Email) dial(ctx context.Context) (*smtp.Client, error) {
+	host, _, err := net.SplitHostPort(m.cfg.Server)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig := &tls.Config{ServerName: host}
+
+	dialer := &net.Dialer{Timeout: smtpTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", m.cfg.Server)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(smtpTimeout))
+	if m.cfg.TLS {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	c, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if ok, _ := c.Extension("STARTTLS"); ok && !m.cfg.TLS {
+		if err := c.StartTLS(tlsConfig); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+// message renders the templates for e into an RFC 5322 message
+func (m *Email) message(e Event) ([]byte, error) {
+	tmpl := m.templates[eventName(e)]
+	data := emailData{Event: e, Host: m.host}
+
+	var subject, body bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.body.Execute(&body, data); err != nil {
+		return nil, err
+	}
+
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", m.cfg.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(m.cfg.To, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject.String())))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(strings.ReplaceAll(strings.ReplaceAll(body.String(), "\r\n", "\n"), "\n", "\r\n"))
+	return msg.Bytes(), nil
+}
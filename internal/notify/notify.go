@@ -0,0 +1,340 @@
+// Package notify detects GPSDO state changes worth alerting on, such as a lock
+// state transition, the input going stale, a clock or NTP alarm or an
+// antenna fault, and delivers them to a webhook or by email.
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo/bridge"
+	"github.com/karlcswanson/gogpsdo/internal/api"
+)
+
+// pollInterval is how often the bridges are checked for new events
+const pollInterval = time.Second
+
+// Kind is the type of an event
+type Kind string
+
+const (
+	// Transition is a change in the reported lock state
+	Transition Kind = "transition"
+	// Stale is raised when no sample has arrived for the stale timeout
+	Stale Kind = "stale"
+	// Recovered is raised when samples arrive again after Stale
+	Recovered Kind = "recovered"
+	// Holdover is raised once per holdover that lasts the holdover timeout
+	Holdover Kind = "holdover"
+	// ClockAlarm is raised when the samples differ from the system clock by
+	// more than the clock alarm threshold
+	ClockAlarm Kind = "clock_alarm"
+	// ClockRecovered is raised when the samples agree with the system clock
+	// again after ClockAlarm
+	ClockRecovered Kind = "clock_recovered"
+	// NTPAlarm is raised when the samples differ from the NTP reference
+	// servers by more than the ntp_check threshold
+	NTPAlarm Kind = "ntp_alarm"
+	// NTPRecovered is raised when the samples agree with the NTP reference
+	// servers again after NTPAlarm
+	NTPRecovered Kind = "ntp_recovered"
+	// AntennaFault is raised when the receiver reports an antenna fault,
+	// such as an open or shorted antenna line
+	AntennaFault Kind = "antenna_fault"
+	// AntennaRecovered is raised when the receiver reports the antenna OK
+	// again after AntennaFault
+	AntennaRecovered Kind = "antenna_recovered"
+)
+
+// queueSize bounds the events waiting for delivery
+const queueSize = 100
+
+// Event is a state change of one bridge
+type Event struct {
+	Device  string    `json:"device,omitempty"`
+	Kind    Kind      `json:"event"`
+	Time    time.Time `json:"time"`
+	From    string    `json:"from,omitempty"`
+	To      string    `json:"to,omitempty"`
+	Message string    `json:"message"`
+	// Suppressed counts events of the same kind dropped since the last one
+	// delivered because of the minimum re-notify interval
+	Suppressed int        `json:"suppressed,omitempty"`
+	Status     api.Status `json:"status"`
+}
+
+// key identifies events that are rate limited together
+func (e *Event) key() string {
+	return e.Device + "/" + string(e.Kind) + "/" + e.To
+}
+
+// Watcher turns bridge state into events
+type Watcher struct {
+	bridges       []*bridge.Bridge
+	staleAfter    time.Duration
+	holdoverAfter time.Duration
+	started       time.Time
+	// seen is the time of the last reported transition of each bridge
+	seen  []time.Time
+	stale []bool
+	// holdover is the start of the last holdover reported for each bridge
+	holdover []time.Time
+	// clock is the start of the clock alarm reported for each bridge, zero
+	// if none is
+	clock []time.Time
+	// ntp is the start of the NTP alarm reported for each bridge, zero if
+	// none is
+	ntp []time.Time
+	// antenna is the start of the antenna fault reported for each bridge,
+	// zero if none is
+	antenna []time.Time
+}
+
+// NewWatcher creates a watcher for the bridges. Transitions recorded before
+// now are not reported. A staleAfter or holdoverAfter of zero disables stale
+// or holdover events.
+func NewWatcher(staleAfter, holdoverAfter time.Duration, bridges ...*bridge.Bridge) *Watcher {
+	w := &Watcher{
+		bridges:       bridges,
+		staleAfter:    staleAfter,
+		holdoverAfter: holdoverAfter,
+		started:       time.Now(),
+		seen:          make([]time.Time, len(bridges)),
+		stale:         make([]bool, len(bridges)),
+		holdover:      make([]time.Time, len(bridges)),
+		clock:         make([]time.Time, len(bridges)),
+		ntp:           make([]time.Time, len(bridges)),
+		antenna:       make([]time.Time, len(bridges)),
+	}
+	for i, b := range bridges {
+		if t := b.Transitions(); len(t) > 0 {
+			w.seen[i] = t[len(t)-1].Time
+		}
+		// A holdover restored from before a restart was reported then
+		if since := b.Stats().HoldoverSince; holdoverAfter > 0 && !since.IsZero() &&
+			w.started.Sub(since) >= holdoverAfter {
+			w.holdover[i] = since
+		}
+	}
+	return w
+}
+
+// Poll returns the events since the previous call
+func (w *Watcher) Poll(now time.Time) []Event {
+	var events []Event
+	for i, b := range w.bridges {
+		for _, t := range b.Transitions() {
+			if !t.Time.After(w.seen[i]) {
+				continue
+			}
+			w.seen[i] = t.Time
+			events = append(events, w.event(b, Event{
+				Kind:    Transition,
+				Time:    t.Time,
+				From:    t.From.String(),
+				To:      t.To.String(),
+				Message: fmt.Sprintf("GPSDO %s -> %s", t.From, t.To),
+			}))
+		}
+
+		stats := b.Stats()
+		since := stats.HoldoverSince
+		if w.holdoverAfter > 0 && !since.IsZero() && !since.Equal(w.holdover[i]) &&
+			now.Sub(since) >= w.holdoverAfter {
+			w.holdover[i] = since
+			events = append(events, w.event(b, Event{
+				Kind:    Holdover,
+				Time:    now,
+				Message: fmt.Sprintf("GPSDO in holdover for %s", now.Sub(since).Truncate(time.Second)),
+			}))
+		}
+
+		switch alarm := stats.ClockAlarmSince; {
+		case !alarm.IsZero() && !alarm.Equal(w.clock[i]):
+			w.clock[i] = alarm
+			events = append(events, w.event(b, Event{
+				Kind:    ClockAlarm,
+				Time:    alarm,
+				Message: "GPSDO time disagrees with the system clock",
+			}))
+		case alarm.IsZero() && !w.clock[i].IsZero():
+			w.clock[i] = time.Time{}
+			events = append(events, w.event(b, Event{
+				Kind:    ClockRecovered,
+				Time:    now,
+				Message: "GPSDO time agrees with the system clock again",
+			}))
+		}
+
+		switch alarm := stats.NTPAlarmSince; {
+		case !alarm.IsZero() && !alarm.Equal(w.ntp[i]):
+			w.ntp[i] = alarm
+			events = append(events, w.event(b, Event{
+				Kind:    NTPAlarm,
+				Time:    alarm,
+				Message: "GPSDO time disagrees with the NTP reference servers",
+			}))
+		case alarm.IsZero() && !w.ntp[i].IsZero():
+			w.ntp[i] = time.Time{}
+			events = append(events, w.event(b, Event{
+				Kind:    NTPRecovered,
+				Time:    now,
+				Message: "GPSDO time agrees with the NTP reference servers again",
+			}))
+		}
+
+		switch fault := stats.AntennaFaultSince; {
+		case !fault.IsZero() && !fault.Equal(w.antenna[i]):
+			w.antenna[i] = fault
+			events = append(events, w.event(b, Event{
+				Kind:    AntennaFault,
+				Time:    fault,
+				Message: fmt.Sprintf("GPSDO antenna fault: %s", b.Diagnostics().Antenna),
+			}))
+		case fault.IsZero() && !w.antenna[i].IsZero():
+			w.antenna[i] = time.Time{}
+			events = append(events, w.event(b, Event{
+				Kind:    AntennaRecovered,
+				Time:    now,
+				Message: "GPSDO antenna OK again",
+			}))
+		}
+
+		if w.staleAfter <= 0 {
+			continue
+		}
+		last := stats.LastUpdate
+		if last.IsZero() {
+			last = w.started
+		}
+		switch age := now.Sub(last); {
+		case age > w.staleAfter && !w.stale[i]:
+			w.stale[i] = true
+			events = append(events, w.event(b, Event{
+				Kind:    Stale,
+				Time:    now,
+				Message: fmt.Sprintf("No GPSDO samples for %s", age.Truncate(time.Second)),
+			}))
+		case age <= w.staleAfter && w.stale[i]:
+			w.stale[i] = false
+			events = append(events, w.event(b, Event{
+				Kind:    Recovered,
+				Time:    now,
+				Message: "GPSDO samples resumed",
+			}))
+		}
+	}
+	return events
+}
+
+// event completes e with the device name and current status of b
+func (w *Watcher) event(b *bridge.Bridge, e Event) Event {
+	e.Device = b.Name()
+	if e.Device != "" {
+		e.Message = e.Device + ": " + e.Message
+	}
+	e.Status = api.NewStatus(b)
+	return e
+}
+
+// throttle enforces a minimum interval between events with the same key
+type throttle struct {
+	interval   time.Duration
+	last       map[string]time.Time
+	suppressed map[string]int
+}
+
+func newThrottle(interval time.Duration) *throttle {
+	return &throttle{
+		interval:   interval,
+		last:       make(map[string]time.Time),
+		suppressed: make(map[string]int),
+	}
+}
+
+// allow reports whether e may be delivered at now, recording the events it
+// suppresses in the next one allowed
+func (t *throttle) allow(e *Event, now time.Time) bool {
+	key := e.key()
+	if last, ok := t.last[key]; ok && now.Sub(last) < t.interval {
+		t.suppressed[key]++
+		return false
+	}
+	t.last[key] = now
+	e.Suppressed = t.suppressed[key]
+	delete(t.suppressed, key)
+	return true
+}
+
+// sender delivers one event, returning an error if it should be retried
+type sender func(ctx context.Context, e Event) error
+
+// run polls w until ctx is cancelled and delivers the events accepted by
+// filter, nil for all, in order through send. Failed deliveries are retried
+// with a doubling backoff.
+func run(ctx context.Context, name string, w *Watcher, t *throttle, retries int,
+	filter func(Event) bool, send sender) {
+	log := slog.With("notifier", name)
+
+	queue := make(chan Event, queueSize)
+	go func() {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case e := <-queue:
+				deliver(ctx, log, retries, e, send)
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			for _, e := range w.Poll(now) {
+				if filter != nil && !filter(e) {
+					continue
+				}
+				if !t.allow(&e, now) {
+					log.Debug("Notification suppressed", "event", e.Kind, "message", e.Message)
+					continue
+				}
+				select {
+				case queue <- e:
+				default:
+					log.Warn("Notification queue full, dropping event", "event", e.Kind, "message", e.Message)
+				}
+			}
+		}
+	}
+}
+
+func deliver(ctx context.Context, log *slog.Logger, retries int, e Event, send sender) {
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		err := send(ctx, e)
+		if err == nil {
+			log.Info("Notification sent", "event", e.Kind, "message", e.Message)
+			return
+		}
+		if attempt > retries {
+			log.Warn("Notification failed", "event", e.Kind, "error", err, "attempts", attempt)
+			return
+		}
+		log.Debug("Notification failed, retrying", "error", err, "backoff", backoff)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
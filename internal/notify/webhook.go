@@ -0,0 +1,61 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo/bridge"
+	"github.com/karlcswanson/gogpsdo/internal/config"
+)
+
+// Webhook POSTs each event as JSON to a URL
+type Webhook struct {
+	cfg     config.Webhook
+	watcher *Watcher
+	client  *http.Client
+}
+
+// NewWebhook creates a webhook notifier for the bridges
+func NewWebhook(cfg config.Webhook, bridges ...*bridge.Bridge) *Webhook {
+	return &Webhook{
+		cfg:     cfg,
+		watcher: NewWatcher(cfg.StaleAfter, 0, bridges...),
+		client:  &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Run watches the bridges and delivers events until ctx is cancelled
+func (h *Webhook) Run(ctx context.Context) {
+	slog.Info("Webhook notifications enabled", "url", h.cfg.URL)
+	run(ctx, "webhook", h.watcher, newThrottle(h.cfg.MinInterval), h.cfg.Retries, nil, h.post)
+}
+
+func (h *Webhook) post(ctx context.Context, e Event) error {
+	payload, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.cfg.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 256))
+		return fmt.Errorf("%s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+// Package privilege drops root privileges once the bridge has opened its
+// devices and sockets.
+package privilege
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"slices"
+	"strconv"
+	"syscall"
+)
+
+// Credentials are the ids the process switches to
+type Credentials struct {
+	UID    int
+	GID    int
+	Groups []int
+}
+
+// Lookup resolves userName and groupName, names or numeric ids, to
+// credentials. The group defaults to the primary group of the user, and the
+// supplementary groups are those of the user, such as dialout for serial
+// ports that are reopened later.
+func Lookup(userName, groupName string) (Credentials, error) {
+	u, err := lookupUser(userName)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	var creds Credentials
+	if creds.UID, err = strconv.Atoi(u.Uid); err != nil {
+		return Credentials{}, fmt.Errorf("user %s: uid %q is not numeric", userName, u.Uid)
+	}
+
+	if groupName == "" {
+		creds.GID, err = strconv.Atoi(u.Gid)
+	} else {
+		creds.GID, err = LookupGID(groupName)
+	}
+	if err != nil {
+		return Credentials{}, fmt.Errorf("group of %s: %w", userName, err)
+	}
+
+	ids, err := u.GroupIds()
+	if err != nil {
+		return Credentials{}, fmt.Errorf("user %s groups: %w", userName, err)
+	}
+	// Like initgroups(3), the primary group is a supplementary group too
+	creds.Groups = []int{creds.GID}
+	for _, id := range ids {
+		if n, err := strconv.Atoi(id); err == nil && !slices.Contains(creds.Groups, n) {
+			creds.Groups = append(creds.Groups, n)
+		}
+	}
+	return creds, nil
+}
+
+// LookupUID resolves a user name or numeric id to a uid
+func LookupUID(name string) (int, error) {
+	u, err := lookupUser(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(u.Uid)
+}
+
+// LookupGID resolves a group name or numeric id to a gid
+func LookupGID(name string) (int, error) {
+	g, err := user.LookupGroup(name)
+	if err != nil {
+		if g, err = user.LookupGroupId(name); err != nil {
+			return 0, fmt.Errorf("group %s: %w", name, err)
+		}
+	}
+	return strconv.Atoi(g.Gid)
+}
+
+func lookupUser(name string) (*user.User, error) {
+	u, err := user.Lookup(name)
+	if err != nil {
+		if u, err = user.LookupId(name); err != nil {
+			return nil, fmt.Errorf("user %s: %w", name, err)
+		}
+	}
+	return u, nil
+}
+
+// Chown sets the owner and group of path, names or numeric ids. Empty values
+// are left unchanged.
+func Chown(path, owner, group string) error {
+	uid, gid := -1, -1
+	var err error
+	if owner != "" {
+		if uid, err = LookupUID(owner); err != nil {
+			return err
+		}
+	}
+	if group != "" {
+		if gid, err = LookupGID(group); err != nil {
+			return err
+		}
+	}
+	if uid == -1 && gid == -1 {
+		return nil
+	}
+	return os.Lchown(path, uid, gid)
+}
+
+// Drop switches every thread of the process to creds and checks that root
+// cannot be regained
+func Drop(creds Credentials) error {
+	if os.Geteuid() != 0 {
+		return errors.New("dropping privileges requires starting as root")
+	}
+
+	if err := syscall.Setgroups(creds.Groups); err != nil {
+		return fmt.Errorf("setgroups: %w", err)
+	}
+	if err := syscall.Setgid(creds.GID); err != nil {
+		return fmt.Errorf("setgid %d: %w", creds.GID, err)
+	}
+	if err := syscall.Setuid(creds.UID); err != nil {
+		return fmt.Errorf("setuid %d: %w", creds.UID, err)
+	}
+
+	if creds.UID != 0 && syscall.Setuid(0) == nil {
+		return errors.New("root privileges could be regained")
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+// Package prometheus formats bridge statistics in the Prometheus text
+// exposition format, served on the HTTP API or written to a textfile for the
+// node_exporter textfile collector.
+package prometheus
+
+import (
+	"bufio"
+	"io"
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/bridge"
+)
+
+// ContentType is the media type of the exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// statuses are reported as a state set, one series per status
+var statuses = []gpsdo.Status{gpsdo.PowerUp, gpsdo.Holdover, gpsdo.Locked, gpsdo.Unknown}
+
+// family is a metric and its series
+type family struct {
+	name   string
+	kind   string
+	help   string
+	series []string
+}
+
+// metrics collects families in the order they are first added
+type metrics struct {
+	families []*family
+	index    map[string]*family
+}
+
+// add records one series. labels are name, value pairs.
+func (m *metrics) add(kind, name, help string, value float64, labels ...string) {
+	f, ok := m.index[name]
+	if !ok {
+		f = &family{name: name, kind: kind, help: help}
+		m.index[name] = f
+		m.families = append(m.families, f)
+	}
+
+	var line strings.Builder
+	line.WriteString(name)
+	for i := 0; i+1 < len(labels); i += 2 {
+		if labels[i+1] == "" {
+			continue
+		}
+		if line.Len() == len(name) {
+			line.WriteByte('{')
+		} else {
+			line.WriteByte(',')
+		}
+		line.WriteString(labels[i] + `="` + escape(labels[i+1]) + `"`)
+	}
+	if line.Len() > len(name) {
+		line.WriteByte('}')
+	}
+	line.WriteString(" " + strconv.FormatFloat(value, 'g', -1, 64))
+	f.series = append(f.series, line.String())
+}
+
+func (m *metrics) gauge(name, help string, value float64, labels ...string) {
+	m.add("gauge", name, help, value, labels...)
+}
+
+func (m *metrics) counter(name, help string, value uint64, labels ...string) {
+	m.add("counter", name, help, float64(value), labels...)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func escape(value string) string {
+	return labelEscaper.Replace(value)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// Write writes the metrics of the bridges to w. Series of a named bridge
+// carry a device label.
+func Write(w io.Writer, bridges ...*bridge.Bridge) error {
+	m := &metrics{index: make(map[string]*family)}
+	now := time.Now()
+	for _, b := range bridges {
+		collect(m, b, now)
+	}
+
+	out := bufio.NewWriter(w)
+	for _, f := range m.families {
+		out.WriteString("# HELP " + f.name + " " + f.help + "\n")
+		out.WriteString("# TYPE " + f.name + " " + f.kind + "\n")
+		for _, s := range f.series {
+			out.WriteString(s + "\n")
+		}
+	}
+	return out.Flush()
+}
+
+func collect(m *metrics, b *bridge.Bridge, now time.Time) {
+	device := b.Name()
+	stats := b.Stats()
+	data := b.Current()
+
+	status := gpsdo.Unknown
+	if data != nil {
+		status = data.Status
+	}
+	for _, s := range statuses {
+		m.gauge("gogpsdo_status", "Current GPSDO status, 1 for the reported status",
+			boolValue(s == status), "device", device, "status", s.String())
+	}
+	if data != nil {
+		m.gauge("gogpsdo_valid", "Whether the last sample was valid", boolValue(data.Valid), "device", device)
+		m.gauge("gogpsdo_leap_seconds", "GPS to UTC leap seconds", float64(data.LeapSeconds), "device", device)
+		if !data.PPS.IsZero() {
+			offset := data.Timestamp.Sub(data.PPS) + data.Offset
+			m.gauge("gogpsdo_offset_seconds", "Offset of the last PPS paired sample", offset.Seconds(), "device", device)
+		}
+		if data.Valid {
+			m.gauge("gogpsdo_clock_offset_seconds", "Time of the last valid sample minus the system clock",
+				data.SystemOffset().Seconds(), "device", device)
+		}
+	}
+	if !stats.LastUpdate.IsZero() {
+		m.gauge("gogpsdo_sample_age_seconds", "Seconds since the last sample", now.Sub(stats.LastUpdate).Seconds(), "device", device)
+	}
+	if !stats.LastPacket.IsZero() {
+		m.gauge("gogpsdo_packet_age_seconds", "Seconds since the last packet, valid or not", now.Sub(stats.LastPacket).Seconds(), "device", device)
+	}
+
+	m.gauge("gogpsdo_input_connected", "Whether the serial port is open", boolValue(stats.InputConnected), "device", device)
+	m.counter("gogpsdo_input_reconnects_total", "Serial port reopens", stats.InputReconnects, "device", device)
+	m.counter("gogpsdo_packets_total", "Packets received", stats.TotalPackets, "device", device)
+	m.counter("gogpsdo_packets_valid_total", "Packets parsed into samples", stats.ValidPackets, "device", device)
+	m.counter("gogpsdo_framing_errors_total", "Packets with framing errors", stats.FramingErrors, "device", device)
+	m.counter("gogpsdo_checksum_errors_total", "Packets rejected for a bad checksum", stats.ChecksumErrors, "device", device)
+	m.counter("gogpsdo_range_errors_total", "Packets rejected for an implausible field", stats.RangeErrors, "device", device)
+	m.counter("gogpsdo_packets_malformed_total", "Packets that could not be decoded otherwise", stats.MalformedPackets, "device", device)
+	m.counter("gogpsdo_packets_missed_total", "Packets expected but not received", stats.MissedPackets, "device", device)
+	m.counter("gogpsdo_packet_gaps_total", "Gaps of one or more missed packets", stats.PacketGaps, "device", device)
+	m.counter("gogpsdo_outliers_rejected_total", "Samples rejected by the outlier filter", stats.OutliersRejected, "device", device)
+	m.counter("gogpsdo_samples_sent_total", "Samples sent to the outputs", stats.SentSamples, "device", device)
+	m.counter("gogpsdo_samples_dropped_total", "Samples the outputs failed to send", stats.DroppedSamples, "device", device)
+	m.counter("gogpsdo_pps_edges_total", "Kernel PPS edges seen", stats.PPSEdges, "device", device)
+	m.counter("gogpsdo_pps_paired_total", "Samples paired with a PPS edge", stats.PPSPaired, "device", device)
+
+	var holdover float64
+	if !stats.HoldoverSince.IsZero() {
+		holdover = now.Sub(stats.HoldoverSince).Seconds()
+	}
+	m.gauge("gogpsdo_holdover_seconds", "Duration of the current holdover, 0 when not in holdover", holdover, "device", device)
+	m.counter("gogpsdo_holdover_rejected_total", "Samples not sent because of the holdover limit", stats.HoldoverRejected, "device", device)
+	m.counter("gogpsdo_holdover_episodes_total", "Holdovers entered", stats.HoldoverEpisodes, "device", device)
+	m.gauge("gogpsdo_holdover_last_seconds", "Duration of the last holdover to end, 0 if none has",
+		stats.LastHoldoverDuration.Seconds(), "device", device)
+	m.gauge("gogpsdo_holdover_longest_seconds", "Duration of the longest holdover, including the current one",
+		max(stats.LongestHoldover.Seconds(), holdover), "device", device)
+
+	var poweringUp float64
+	if !stats.PowerUpSince.IsZero() {
+		poweringUp = now.Sub(stats.PowerUpSince).Seconds()
+	}
+	m.gauge("gogpsdo_power_up_seconds", "Time since the GPSDO was first seen powering up, 0 once locked", poweringUp, "device", device)
+	if stats.LastTimeToLock > 0 {
+		m.gauge("gogpsdo_time_to_lock_seconds", "Time the last lock after power-up took", stats.LastTimeToLock.Seconds(), "device", device)
+	}
+
+	m.gauge("gogpsdo_clock_alarm", "Whether the samples differ from the system clock by more than the clock alarm threshold",
+		boolValue(!stats.ClockAlarmSince.IsZero()), "device", device)
+	m.counter("gogpsdo_clock_alarms_total", "Clock alarms raised", stats.ClockAlarms, "device", device)
+
+	if c := b.NTPCheck(); c != nil {
+		if c.Compared {
+			m.gauge("gogpsdo_ntp_divergence_seconds", "Time of the last valid sample minus the median of the NTP reference servers",
+				c.Divergence.Seconds(), "device", device)
+		}
+		m.gauge("gogpsdo_ntp_servers_answered", "NTP reference servers that answered the last query", float64(c.Answered), "device", device)
+		m.gauge("gogpsdo_ntp_alarm", "Whether the samples differ from the NTP reference servers by more than the threshold",
+			boolValue(!stats.NTPAlarmSince.IsZero()), "device", device)
+		m.counter("gogpsdo_ntp_alarms_total", "NTP alarms raised", stats.NTPAlarms, "device", device)
+	}
+
+	if summary := b.Stability(); summary.Samples > 1 {
+		m.gauge("gogpsdo_offset_mean_seconds", "Mean offset over the last hour", summary.Mean, "device", device)
+		m.gauge("gogpsdo_offset_std_dev_seconds", "Offset standard deviation over the last hour", summary.StdDev, "device", device)
+		m.gauge("gogpsdo_offset_mad_seconds", "Offset median absolute deviation over the last hour", summary.MAD, "device", device)
+		for _, dev := range summary.ADEV {
+			m.gauge("gogpsdo_allan_deviation", "Overlapping Allan deviation of the offset at tau seconds", dev.Deviation,
+				"device", device, "tau", strconv.Itoa(int(dev.Tau/time.Second)))
+		}
+	}
+
+	if id := b.Identity(); id != (gpsdo.Identity{}) {
+		m.gauge("gogpsdo_receiver_info", "The identity the receiver reported, always 1", 1, "device", device,
+			"manufacturer", id.Manufacturer, "model", id.Model, "serial", id.Serial, "firmware", id.Firmware)
+	}
+	if diag := b.Diagnostics(); !diag.Updated.IsZero() {
+		m.gauge("gogpsdo_satellites", "Satellites tracked by the receiver", float64(diag.Satellites), "device", device)
+		m.gauge("gogpsdo_efc_percent", "Oscillator EFC in percent of range", diag.EFC, "device", device)
+		m.gauge("gogpsdo_holdover_prediction_seconds", "Predicted time uncertainty after 24 hours of holdover",
+			diag.HoldoverPrediction.Seconds(), "device", device)
+		if diag.Discipline != "" {
+			m.gauge("gogpsdo_phase_error_seconds", "Oscillator PPS phase error reported by the receiver",
+				diag.PhaseError.Seconds(), "device", device)
+		}
+		if diag.Antenna != "" {
+			m.gauge("gogpsdo_antenna_fault", "Whether the receiver reports an antenna fault",
+				boolValue(!stats.AntennaFaultSince.IsZero()), "device", device)
+		}
+		m.counter("gogpsdo_antenna_faults_total", "Antenna faults reported by the receiver", stats.AntennaFaults, "device", device)
+		if diag.Signals != nil {
+			m.gauge("gogpsdo_signal_strength_mean", "Mean signal strength of the tracked satellites",
+				gpsdo.MeanStrength(diag.Signals), "device", device)
+		}
+		if p := diag.Position; p != nil {
+			m.gauge("gogpsdo_position_hold", "Whether the receiver holds its surveyed position",
+				boolValue(p.Hold), "device", device)
+			m.gauge("gogpsdo_position_surveying", "Whether the receiver is surveying its position",
+				boolValue(p.Surveying), "device", device)
+			if p.Surveying && p.Progress >= 0 {
+				m.gauge("gogpsdo_survey_progress_percent", "Progress of the position survey", p.Progress, "device", device)
+			}
+		}
+		for _, s := range diag.Signals {
+			m.gauge("gogpsdo_signal_strength", "Signal strength of a tracked satellite", float64(s.Strength),
+				"device", device, "prn", strconv.Itoa(s.PRN))
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(stats.Outputs)) {
+		out := stats.Outputs[name]
+		m.gauge("gogpsdo_output_connected", "Whether the output is connected", boolValue(out.Connected), "device", device, "output", name)
+		m.counter("gogpsdo_output_connects_total", "Output connections", out.Connects, "device", device, "output", name)
+		m.counter("gogpsdo_output_reconnects_total", "Output reconnections", out.Reconnects, "device", device, "output", name)
+		m.counter("gogpsdo_output_write_errors_total", "Output write errors", out.WriteErrors, "device", device, "output", name)
+	}
+}
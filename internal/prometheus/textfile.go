@@ -0,0 +1,88 @@
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo/bridge"
+	"github.com/karlcswanson/gogpsdo/internal/config"
+	"github.com/karlcswanson/gogpsdo/internal/privilege"
+)
+
+// defaultMode lets node_exporter, usually another user, read the textfile
+const defaultMode = 0o644
+
+// Textfile periodically writes the metrics to a file for the node_exporter
+// textfile collector
+type Textfile struct {
+	cfg     config.Prometheus
+	bridges []*bridge.Bridge
+}
+
+// NewTextfile creates a textfile writer for the bridges
+func NewTextfile(cfg config.Prometheus, bridges ...*bridge.Bridge) *Textfile {
+	return &Textfile{cfg: cfg, bridges: bridges}
+}
+
+// Run writes the textfile every interval until ctx is cancelled, then
+// removes it so node_exporter stops reporting stale values
+func (t *Textfile) Run(ctx context.Context) {
+	slog.Info("Prometheus textfile enabled", "file", t.cfg.Textfile, "interval", t.cfg.Interval)
+	defer os.Remove(t.cfg.Textfile)
+
+	ticker := time.NewTicker(t.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := t.write(); err != nil {
+			slog.Warn("Prometheus textfile write failed", "file", t.cfg.Textfile, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// write replaces the textfile atomically, so node_exporter never reads a
+// partial file
+func (t *Textfile) write() error {
+	var buf bytes.Buffer
+	if err := Write(&buf, t.bridges...); err != nil {
+		return err
+	}
+
+	mode := os.FileMode(defaultMode)
+	// Validated by config.Parse
+	if m, ok, _ := t.cfg.Permissions.FileMode(); ok {
+		mode = m
+	}
+
+	// node_exporter only reads *.prom, so the temporary file is ignored
+	tmp, err := os.CreateTemp(filepath.Dir(t.cfg.Textfile), "."+filepath.Base(t.cfg.Textfile)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(mode); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := privilege.Chown(tmp.Name(), t.cfg.Permissions.Owner, t.cfg.Permissions.Group); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), t.cfg.Textfile)
+}
@@ -0,0 +1,75 @@
+// Package simulate emits synthetic Z3805A time-of-day packets for testing
+// and demos without the real hardware.
+package simulate
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/z3805a"
+)
+
+// Config controls the simulated output
+type Config struct {
+	// Interval between packets, the Z3805A sends one every 2 seconds
+	Interval    time.Duration
+	Status      gpsdo.Status
+	LeapSeconds int
+	// Corrupt is the probability, 0 to 1, that a packet is damaged
+	Corrupt float64
+}
+
+// Run writes a packet for the current second to w every Interval until ctx
+// is cancelled or a write fails
+func Run(ctx context.Context, w io.Writer, cfg Config) error {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 2 * time.Second
+	}
+
+	// Align the packets to the second like the real receiver
+	now := time.Now()
+	timer := time.NewTimer(now.Truncate(time.Second).Add(time.Second).Sub(now))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case t := <-timer.C:
+			timer.Reset(cfg.Interval - time.Duration(t.Nanosecond()))
+
+			packet := z3805a.Encode(t.Truncate(time.Second), cfg.LeapSeconds, cfg.Status)
+			if rand.Float64() < cfg.Corrupt {
+				packet = corrupt(packet)
+			}
+			if _, err := w.Write(packet); err != nil {
+				return err
+			}
+			slog.Debug("Simulated packet", "packet", packet)
+		}
+	}
+}
+
+// corrupt damages a packet in one of the ways seen on a noisy serial line
+func corrupt(packet []byte) []byte {
+	i := rand.IntN(z3805a.PacketLen - 1)
+	switch rand.IntN(3) {
+	case 0:
+		// Dropped byte, a short packet
+		packet = append(packet[:i], packet[i+1:]...)
+		slog.Info("Simulating dropped byte", "offset", i)
+	case 1:
+		// Out of range BCD digit
+		packet[i] = 0x0F
+		slog.Info("Simulating bad digit", "offset", i)
+	default:
+		// Garbage before the packet
+		packet = append([]byte{0xAA, 0x55}, packet...)
+		slog.Info("Simulating line noise")
+	}
+	return packet
+}
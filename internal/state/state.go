@@ -0,0 +1,133 @@
+// Package state saves the bridge counters, last known status and calibration
+// to a file periodically and on shutdown, and restores them at startup, so
+// counters and dashboards don't start from zero after every restart.
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/karlcswanson/gogpsdo/gpsdo"
+	"github.com/karlcswanson/gogpsdo/gpsdo/bridge"
+	"github.com/karlcswanson/gogpsdo/internal/config"
+)
+
+// version is the file format version
+const version = 1
+
+// statusMaxAge is how old a saved status may be and still be restored. After
+// a longer outage the GPSDO may have been through anything.
+const statusMaxAge = 10 * time.Minute
+
+// File is the state of every device
+type File struct {
+	Version int       `json:"version"`
+	Saved   time.Time `json:"saved"`
+	// Devices are keyed by name, empty for a single unnamed device
+	Devices map[string]bridge.State `json:"devices"`
+}
+
+// Load reads the state file. A missing file is an empty state.
+func Load(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &File{Version: version}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f File
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("state file %s: %w", path, err)
+	}
+	if f.Version != version {
+		return nil, fmt.Errorf("state file %s: unsupported version %d", path, f.Version)
+	}
+	return &f, nil
+}
+
+// Restore applies the saved state to the bridges of the same name, before
+// they run. Devices that aren't configured any more are ignored.
+func (f *File) Restore(bridges ...*bridge.Bridge) {
+	for _, b := range bridges {
+		s, ok := f.Devices[b.Name()]
+		if !ok {
+			continue
+		}
+		if time.Since(f.Saved) > statusMaxAge {
+			s.Status = gpsdo.Unknown
+			s.HoldoverSince = time.Time{}
+			s.PowerUpSince = time.Time{}
+		}
+		offset := b.Offset()
+		b.Restore(s)
+		slog.Info("Restored state", "device", b.Name(), "saved", f.Saved.Format(time.DateTime),
+			"status", s.Status, "packets", s.TotalPackets)
+		if b.Offset() != offset {
+			slog.Info("Restored calibration offset", "device", b.Name(), "offset", b.Offset(), "configured", offset)
+		}
+	}
+}
+
+// Save writes the state of the bridges, replacing the file atomically so a
+// crash never leaves a partial file
+func Save(path string, bridges ...*bridge.Bridge) error {
+	f := File{Version: version, Saved: time.Now(), Devices: make(map[string]bridge.State)}
+	for _, b := range bridges {
+		f.Devices[b.Name()] = b.State()
+	}
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	// The state should survive a power cut right after the rename
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Run saves the state every interval until ctx is cancelled, and once more
+// then. Cancel ctx after the bridges stop, so the last counts are saved.
+func Run(ctx context.Context, cfg config.State, bridges ...*bridge.Bridge) {
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+		case <-ticker.C:
+		}
+		if err := Save(cfg.File, bridges...); err != nil {
+			slog.Warn("State save failed", "file", cfg.File, "error", err)
+		}
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
@@ -0,0 +1,238 @@
+// Package syslog sends messages to a local or remote syslog server in the
+// RFC 3164 (BSD) or RFC 5424 format, over a unix socket, UDP or TCP. Unlike
+// log/syslog it supports RFC 5424 and the framing of RFC 6587 over TCP.
+package syslog
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Severity is the syslog severity of a message
+type Severity int
+
+// Severities used by the logger
+const (
+	Error   Severity = 3
+	Warning Severity = 4
+	Info    Severity = 6
+	Debug   Severity = 7
+)
+
+// facilities are the facility codes of RFC 5424 by their usual names
+var facilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"ntp": 12, "security": 13, "console": 14, "solaris-cron": 15,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+// ParseFacility returns the code of a facility name such as daemon or local0
+func ParseFacility(name string) (int, error) {
+	code, ok := facilities[strings.ToLower(name)]
+	if !ok {
+		return 0, fmt.Errorf("unknown syslog facility %q", name)
+	}
+	return code, nil
+}
+
+// Format is the message format
+type Format int
+
+const (
+	// RFC3164 is the traditional BSD format most local daemons expect
+	RFC3164 Format = iota
+	// RFC5424 has a full timestamp with year and time zone
+	RFC5424
+)
+
+// ParseFormat parses rfc3164 or rfc5424
+func ParseFormat(name string) (Format, error) {
+	switch strings.ToLower(name) {
+	case "rfc3164":
+		return RFC3164, nil
+	case "rfc5424":
+		return RFC5424, nil
+	}
+	return 0, fmt.Errorf("unknown syslog format %q, expected rfc3164 or rfc5424", name)
+}
+
+// ParseAddress splits a server address into network and address. Empty is
+// the local syslog daemon, host:port or udp://host:port is UDP and
+// tcp://host:port is TCP. The port defaults to 514.
+func ParseAddress(server string) (network, addr string, err error) {
+	if server == "" {
+		return "", "", nil
+	}
+	network, addr, ok := strings.Cut(server, "://")
+	if !ok {
+		network, addr = "udp", server
+	}
+	if network != "udp" && network != "tcp" {
+		return "", "", fmt.Errorf("syslog server %q: network must be udp or tcp", server)
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, "514")
+	}
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		return "", "", fmt.Errorf("syslog server %q: %w", server, err)
+	}
+	return network, addr, nil
+}
+
+// localSockets are where syslog daemons listen on Linux and the BSDs
+var localSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// writeTimeout bounds a write to a stream socket, so a stalled server
+// doesn't block logging
+const writeTimeout = time.Second
+
+// redialDelay is how long messages are dropped after the server couldn't be
+// reached, rather than waiting for it on every message
+const redialDelay = 10 * time.Second
+
+// Options configure a Writer
+type Options struct {
+	// Server is the address as taken by ParseAddress, empty for the local
+	// syslog daemon
+	Server   string
+	Format   Format
+	Facility int
+	// Tag is the program name, the APP-NAME of RFC 5424
+	Tag string
+}
+
+// Writer sends messages to a syslog server. A broken connection is dialed
+// again on the next message.
+type Writer struct {
+	opts     Options
+	network  string
+	addr     string
+	hostname string
+	pid      string
+
+	mutex sync.Mutex
+	conn  net.Conn
+	// stream is set when conn needs framing
+	stream bool
+	// redial is when connecting is tried again after it failed
+	redial time.Time
+}
+
+// Dial connects to the server of opts. Only the local daemon has to be
+// reachable, a remote server is connected to again until it answers.
+func Dial(opts Options) (*Writer, error) {
+	network, addr, err := ParseAddress(opts.Server)
+	if err != nil {
+		return nil, err
+	}
+	hostname, _ := os.Hostname()
+	if hostname == "" {
+		hostname = "-"
+	}
+	w := &Writer{opts: opts, network: network, addr: addr, hostname: hostname, pid: strconv.Itoa(os.Getpid())}
+	if err := w.connect(); err != nil && network == "" {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) connect() error {
+	if time.Now().Before(w.redial) {
+		return errors.New("syslog server unreachable")
+	}
+	if w.network != "" {
+		conn, err := net.DialTimeout(w.network, w.addr, writeTimeout)
+		if err != nil {
+			w.redial = time.Now().Add(redialDelay)
+			return err
+		}
+		w.conn, w.stream = conn, w.network == "tcp"
+		return nil
+	}
+
+	for _, path := range localSockets {
+		for _, network := range []string{"unixgram", "unix"} {
+			if conn, err := net.Dial(network, path); err == nil {
+				w.conn, w.stream = conn, network == "unix"
+				return nil
+			}
+		}
+	}
+	w.redial = time.Now().Add(redialDelay)
+	return errors.New("no local syslog daemon found")
+}
+
+// Write sends msg with the severity. The message is dropped if the server
+// can't be reached.
+func (w *Writer) Write(severity Severity, msg string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	packet := w.format(severity, time.Now(), msg)
+	var err error
+	// One retry with a new connection, for servers that restarted
+	for range 2 {
+		if w.conn == nil {
+			if err = w.connect(); err != nil {
+				return err
+			}
+		}
+		if w.stream {
+			w.conn.SetWriteDeadline(time.Now().Add(writeTimeout))
+		}
+		if _, err = w.conn.Write(packet); err == nil {
+			return nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	return err
+}
+
+// format builds a message, framed for stream sockets
+func (w *Writer) format(severity Severity, t time.Time, msg string) []byte {
+	pri := w.opts.Facility*8 + int(severity)
+	var b strings.Builder
+	if w.opts.Format == RFC5424 {
+		// No MSGID or structured data
+		fmt.Fprintf(&b, "<%d>1 %s %s %s %s - - %s", pri, t.Format("2006-01-02T15:04:05.000000Z07:00"),
+			w.hostname, w.opts.Tag, w.pid, msg)
+	} else {
+		fmt.Fprintf(&b, "<%d>%s ", pri, t.Format(time.Stamp))
+		// Local daemons add the hostname themselves
+		if w.network != "" {
+			b.WriteString(w.hostname + " ")
+		}
+		fmt.Fprintf(&b, "%s[%s]: %s", w.opts.Tag, w.pid, msg)
+	}
+
+	if !w.stream {
+		return []byte(b.String())
+	}
+	if w.opts.Format == RFC5424 {
+		// Octet counting of RFC 6587
+		return []byte(strconv.Itoa(b.Len()) + " " + b.String())
+	}
+	// Non-transparent framing, as BSD daemons expect on stream sockets
+	return []byte(b.String() + "\n")
+}
+
+// Close closes the connection
+func (w *Writer) Close() error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
@@ -0,0 +1,109 @@
+package systemd
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// journalSocket is where journald receives native protocol entries
+const journalSocket = "/run/systemd/journal/socket"
+
+// JournalStream reports whether stderr is connected to the journal, as it
+// is for services started by systemd with the default StandardError=
+func JournalStream() bool {
+	stream := os.Getenv("JOURNAL_STREAM")
+	if stream == "" {
+		return false
+	}
+	var st unix.Stat_t
+	if err := unix.Fstat(int(os.Stderr.Fd()), &st); err != nil {
+		return false
+	}
+	return stream == fmt.Sprintf("%d:%d", st.Dev, st.Ino)
+}
+
+// JournalField is a field of a journal entry. Names are upper case letters,
+// digits and underscores.
+type JournalField struct {
+	Name  string
+	Value string
+}
+
+// Journal sends entries to journald with the native protocol, keeping their
+// priority and fields
+type Journal struct {
+	conn *net.UnixConn
+}
+
+// OpenJournal connects to the journal socket
+func OpenJournal() (*Journal, error) {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journalSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	return &Journal{conn: conn}, nil
+}
+
+// Send writes one entry
+func (j *Journal) Send(fields []JournalField) error {
+	var b bytes.Buffer
+	for _, f := range fields {
+		if !strings.Contains(f.Value, "\n") {
+			b.WriteString(f.Name + "=" + f.Value + "\n")
+			continue
+		}
+		// Values with newlines are written with their length
+		b.WriteString(f.Name + "\n")
+		binary.Write(&b, binary.LittleEndian, uint64(len(f.Value)))
+		b.WriteString(f.Value + "\n")
+	}
+
+	_, err := j.conn.Write(b.Bytes())
+	if errors.Is(err, syscall.EMSGSIZE) || errors.Is(err, syscall.ENOBUFS) {
+		return j.sendMemfd(b.Bytes())
+	}
+	return err
+}
+
+// sendMemfd passes an entry too large for a datagram in a sealed memfd
+func (j *Journal) sendMemfd(entry []byte) error {
+	fd, err := unix.MemfdCreate("journal", unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return err
+	}
+	file := os.NewFile(uintptr(fd), "journal")
+	defer file.Close()
+	if _, err := file.Write(entry); err != nil {
+		return err
+	}
+	seals := unix.F_SEAL_SHRINK | unix.F_SEAL_GROW | unix.F_SEAL_WRITE | unix.F_SEAL_SEAL
+	if _, err := unix.FcntlInt(uintptr(fd), unix.F_ADD_SEALS, seals); err != nil {
+		return err
+	}
+	// WriteMsgUnix refuses connected datagram sockets
+	raw, err := j.conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	werr := raw.Write(func(s uintptr) bool {
+		err = unix.Sendmsg(int(s), nil, unix.UnixRights(fd), nil, 0)
+		return err != unix.EAGAIN
+	})
+	if werr != nil {
+		return werr
+	}
+	return err
+}
+
+// Close closes the connection
+func (j *Journal) Close() error {
+	return j.conn.Close()
+}
@@ -0,0 +1,5 @@
+// Package gogpsdov1 is the generated client and server code of the gRPC
+// monitoring and control API, see gogpsdo.proto.
+package gogpsdov1
+
+//go:generate protoc -I ../.. --go_out=../.. --go_opt=paths=source_relative --go-grpc_out=../.. --go-grpc_opt=paths=source_relative gogpsdo/v1/gogpsdo.proto
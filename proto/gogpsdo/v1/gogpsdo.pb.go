@@ -0,0 +1,1864 @@
+// gRPC monitoring and control API of the gogpsdo bridge. Offsets and ages are
+// in seconds, like the HTTP status API.
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: gogpsdo/v1/gogpsdo.proto
+
+package gogpsdov1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Status is the state of one device.
+type Status struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Device is the device name, empty for a single unnamed device.
+	Device string `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
+	// Status is the lock state, e.g. LOCKED or HOLDOVER.
+	Status string `protobuf:"bytes,2,opt,name=status,proto3" json:"status,omitempty"`
+	Valid  bool   `protobuf:"varint,3,opt,name=valid,proto3" json:"valid,omitempty"`
+	// Timestamp is the time of the last sample, unset if none yet.
+	Timestamp   *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
+	LeapSeconds int32                  `protobuf:"varint,5,opt,name=leap_seconds,json=leapSeconds,proto3" json:"leap_seconds,omitempty"`
+	// Leap is the leap second warning, e.g. NONE or INSERT.
+	Leap string `protobuf:"bytes,6,opt,name=leap,proto3" json:"leap,omitempty"`
+	// SampleAge is the seconds since the last sample, -1 if none yet.
+	SampleAge  float64                `protobuf:"fixed64,7,opt,name=sample_age,json=sampleAge,proto3" json:"sample_age,omitempty"`
+	LastUpdate *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=last_update,json=lastUpdate,proto3" json:"last_update,omitempty"`
+	// Offset is the calibration offset.
+	Offset float64 `protobuf:"fixed64,9,opt,name=offset,proto3" json:"offset,omitempty"`
+	// ClockOffset is the sample time minus the system clock, unset without a
+	// valid sample.
+	ClockOffset     *float64 `protobuf:"fixed64,10,opt,name=clock_offset,json=clockOffset,proto3,oneof" json:"clock_offset,omitempty"`
+	InputConnected  bool     `protobuf:"varint,11,opt,name=input_connected,json=inputConnected,proto3" json:"input_connected,omitempty"`
+	InputReconnects uint64   `protobuf:"varint,12,opt,name=input_reconnects,json=inputReconnects,proto3" json:"input_reconnects,omitempty"`
+	Packets         *Packets `protobuf:"bytes,13,opt,name=packets,proto3" json:"packets,omitempty"`
+	SamplesSent     uint64   `protobuf:"varint,14,opt,name=samples_sent,json=samplesSent,proto3" json:"samples_sent,omitempty"`
+	SamplesDropped  uint64   `protobuf:"varint,15,opt,name=samples_dropped,json=samplesDropped,proto3" json:"samples_dropped,omitempty"`
+	PpsEdges        uint64   `protobuf:"varint,16,opt,name=pps_edges,json=ppsEdges,proto3" json:"pps_edges,omitempty"`
+	PpsPaired       uint64   `protobuf:"varint,17,opt,name=pps_paired,json=ppsPaired,proto3" json:"pps_paired,omitempty"`
+	// HoldoverSince is when holdover started, unset outside holdover.
+	HoldoverSince    *timestamppb.Timestamp `protobuf:"bytes,18,opt,name=holdover_since,json=holdoverSince,proto3" json:"holdover_since,omitempty"`
+	HoldoverRejected uint64                 `protobuf:"varint,19,opt,name=holdover_rejected,json=holdoverRejected,proto3" json:"holdover_rejected,omitempty"`
+	// Receiver holds the SCPI diagnostics, unset without them.
+	Receiver *Receiver `protobuf:"bytes,20,opt,name=receiver,proto3" json:"receiver,omitempty"`
+	// HoldoverEpisodes counts the holdovers entered.
+	HoldoverEpisodes uint64 `protobuf:"varint,21,opt,name=holdover_episodes,json=holdoverEpisodes,proto3" json:"holdover_episodes,omitempty"`
+	// HoldoverLastDuration is the seconds of the last holdover to end, unset
+	// if none has.
+	HoldoverLastDuration *float64 `protobuf:"fixed64,22,opt,name=holdover_last_duration,json=holdoverLastDuration,proto3,oneof" json:"holdover_last_duration,omitempty"`
+	// HoldoverLongest is the seconds of the longest holdover, including the
+	// current one.
+	HoldoverLongest float64 `protobuf:"fixed64,23,opt,name=holdover_longest,json=holdoverLongest,proto3" json:"holdover_longest,omitempty"`
+	// PowerUpSince is when the GPSDO was first seen powering up, unset once
+	// it locked.
+	PowerUpSince *timestamppb.Timestamp `protobuf:"bytes,24,opt,name=power_up_since,json=powerUpSince,proto3" json:"power_up_since,omitempty"`
+	// TimeToLock is the seconds the last lock after power-up took, unset if
+	// none was seen.
+	TimeToLock *float64 `protobuf:"fixed64,25,opt,name=time_to_lock,json=timeToLock,proto3,oneof" json:"time_to_lock,omitempty"`
+	// Identity is the make, model and firmware of the receiver, unset if it
+	// wasn't queried or didn't answer.
+	Identity      *Identity `protobuf:"bytes,26,opt,name=identity,proto3" json:"identity,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Status) Reset() {
+	*x = Status{}
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Status) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Status) ProtoMessage() {}
+
+func (x *Status) ProtoReflect() protoreflect.Message {
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Status.ProtoReflect.Descriptor instead.
+func (*Status) Descriptor() ([]byte, []int) {
+	return file_gogpsdo_v1_gogpsdo_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Status) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+func (x *Status) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Status) GetValid() bool {
+	if x != nil {
+		return x.Valid
+	}
+	return false
+}
+
+func (x *Status) GetTimestamp() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Timestamp
+	}
+	return nil
+}
+
+func (x *Status) GetLeapSeconds() int32 {
+	if x != nil {
+		return x.LeapSeconds
+	}
+	return 0
+}
+
+func (x *Status) GetLeap() string {
+	if x != nil {
+		return x.Leap
+	}
+	return ""
+}
+
+func (x *Status) GetSampleAge() float64 {
+	if x != nil {
+		return x.SampleAge
+	}
+	return 0
+}
+
+func (x *Status) GetLastUpdate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastUpdate
+	}
+	return nil
+}
+
+func (x *Status) GetOffset() float64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *Status) GetClockOffset() float64 {
+	if x != nil && x.ClockOffset != nil {
+		return *x.ClockOffset
+	}
+	return 0
+}
+
+func (x *Status) GetInputConnected() bool {
+	if x != nil {
+		return x.InputConnected
+	}
+	return false
+}
+
+func (x *Status) GetInputReconnects() uint64 {
+	if x != nil {
+		return x.InputReconnects
+	}
+	return 0
+}
+
+func (x *Status) GetPackets() *Packets {
+	if x != nil {
+		return x.Packets
+	}
+	return nil
+}
+
+func (x *Status) GetSamplesSent() uint64 {
+	if x != nil {
+		return x.SamplesSent
+	}
+	return 0
+}
+
+func (x *Status) GetSamplesDropped() uint64 {
+	if x != nil {
+		return x.SamplesDropped
+	}
+	return 0
+}
+
+func (x *Status) GetPpsEdges() uint64 {
+	if x != nil {
+		return x.PpsEdges
+	}
+	return 0
+}
+
+func (x *Status) GetPpsPaired() uint64 {
+	if x != nil {
+		return x.PpsPaired
+	}
+	return 0
+}
+
+func (x *Status) GetHoldoverSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.HoldoverSince
+	}
+	return nil
+}
+
+func (x *Status) GetHoldoverRejected() uint64 {
+	if x != nil {
+		return x.HoldoverRejected
+	}
+	return 0
+}
+
+func (x *Status) GetReceiver() *Receiver {
+	if x != nil {
+		return x.Receiver
+	}
+	return nil
+}
+
+func (x *Status) GetHoldoverEpisodes() uint64 {
+	if x != nil {
+		return x.HoldoverEpisodes
+	}
+	return 0
+}
+
+func (x *Status) GetHoldoverLastDuration() float64 {
+	if x != nil && x.HoldoverLastDuration != nil {
+		return *x.HoldoverLastDuration
+	}
+	return 0
+}
+
+func (x *Status) GetHoldoverLongest() float64 {
+	if x != nil {
+		return x.HoldoverLongest
+	}
+	return 0
+}
+
+func (x *Status) GetPowerUpSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.PowerUpSince
+	}
+	return nil
+}
+
+func (x *Status) GetTimeToLock() float64 {
+	if x != nil && x.TimeToLock != nil {
+		return *x.TimeToLock
+	}
+	return 0
+}
+
+func (x *Status) GetIdentity() *Identity {
+	if x != nil {
+		return x.Identity
+	}
+	return nil
+}
+
+// Packets are the input counters.
+type Packets struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Total         uint64                 `protobuf:"varint,1,opt,name=total,proto3" json:"total,omitempty"`
+	Valid         uint64                 `protobuf:"varint,2,opt,name=valid,proto3" json:"valid,omitempty"`
+	FramingErrors uint64                 `protobuf:"varint,3,opt,name=framing_errors,json=framingErrors,proto3" json:"framing_errors,omitempty"`
+	Outliers      uint64                 `protobuf:"varint,4,opt,name=outliers,proto3" json:"outliers,omitempty"`
+	Missed        uint64                 `protobuf:"varint,5,opt,name=missed,proto3" json:"missed,omitempty"`
+	Gaps          uint64                 `protobuf:"varint,6,opt,name=gaps,proto3" json:"gaps,omitempty"`
+	// Packets rejected for a bad checksum, an implausible field or otherwise.
+	ChecksumErrors uint64 `protobuf:"varint,7,opt,name=checksum_errors,json=checksumErrors,proto3" json:"checksum_errors,omitempty"`
+	RangeErrors    uint64 `protobuf:"varint,8,opt,name=range_errors,json=rangeErrors,proto3" json:"range_errors,omitempty"`
+	Malformed      uint64 `protobuf:"varint,9,opt,name=malformed,proto3" json:"malformed,omitempty"`
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *Packets) Reset() {
+	*x = Packets{}
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Packets) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Packets) ProtoMessage() {}
+
+func (x *Packets) ProtoReflect() protoreflect.Message {
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Packets.ProtoReflect.Descriptor instead.
+func (*Packets) Descriptor() ([]byte, []int) {
+	return file_gogpsdo_v1_gogpsdo_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Packets) GetTotal() uint64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *Packets) GetValid() uint64 {
+	if x != nil {
+		return x.Valid
+	}
+	return 0
+}
+
+func (x *Packets) GetFramingErrors() uint64 {
+	if x != nil {
+		return x.FramingErrors
+	}
+	return 0
+}
+
+func (x *Packets) GetOutliers() uint64 {
+	if x != nil {
+		return x.Outliers
+	}
+	return 0
+}
+
+func (x *Packets) GetMissed() uint64 {
+	if x != nil {
+		return x.Missed
+	}
+	return 0
+}
+
+func (x *Packets) GetGaps() uint64 {
+	if x != nil {
+		return x.Gaps
+	}
+	return 0
+}
+
+func (x *Packets) GetChecksumErrors() uint64 {
+	if x != nil {
+		return x.ChecksumErrors
+	}
+	return 0
+}
+
+func (x *Packets) GetRangeErrors() uint64 {
+	if x != nil {
+		return x.RangeErrors
+	}
+	return 0
+}
+
+func (x *Packets) GetMalformed() uint64 {
+	if x != nil {
+		return x.Malformed
+	}
+	return 0
+}
+
+// Identity is the make, model and firmware the receiver reported.
+type Identity struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Manufacturer  string                 `protobuf:"bytes,1,opt,name=manufacturer,proto3" json:"manufacturer,omitempty"`
+	Model         string                 `protobuf:"bytes,2,opt,name=model,proto3" json:"model,omitempty"`
+	Serial        string                 `protobuf:"bytes,3,opt,name=serial,proto3" json:"serial,omitempty"`
+	Firmware      string                 `protobuf:"bytes,4,opt,name=firmware,proto3" json:"firmware,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Identity) Reset() {
+	*x = Identity{}
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Identity) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Identity) ProtoMessage() {}
+
+func (x *Identity) ProtoReflect() protoreflect.Message {
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Identity.ProtoReflect.Descriptor instead.
+func (*Identity) Descriptor() ([]byte, []int) {
+	return file_gogpsdo_v1_gogpsdo_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Identity) GetManufacturer() string {
+	if x != nil {
+		return x.Manufacturer
+	}
+	return ""
+}
+
+func (x *Identity) GetModel() string {
+	if x != nil {
+		return x.Model
+	}
+	return ""
+}
+
+func (x *Identity) GetSerial() string {
+	if x != nil {
+		return x.Serial
+	}
+	return ""
+}
+
+func (x *Identity) GetFirmware() string {
+	if x != nil {
+		return x.Firmware
+	}
+	return ""
+}
+
+// Receiver holds diagnostics reported by the receiver outside the samples.
+type Receiver struct {
+	state              protoimpl.MessageState `protogen:"open.v1"`
+	Satellites         int32                  `protobuf:"varint,1,opt,name=satellites,proto3" json:"satellites,omitempty"`
+	Efc                float64                `protobuf:"fixed64,2,opt,name=efc,proto3" json:"efc,omitempty"`
+	HoldoverPrediction float64                `protobuf:"fixed64,3,opt,name=holdover_prediction,json=holdoverPrediction,proto3" json:"holdover_prediction,omitempty"`
+	Antenna            string                 `protobuf:"bytes,4,opt,name=antenna,proto3" json:"antenna,omitempty"`
+	PhaseError         float64                `protobuf:"fixed64,5,opt,name=phase_error,json=phaseError,proto3" json:"phase_error,omitempty"`
+	Discipline         string                 `protobuf:"bytes,6,opt,name=discipline,proto3" json:"discipline,omitempty"`
+	Updated            *timestamppb.Timestamp `protobuf:"bytes,7,opt,name=updated,proto3" json:"updated,omitempty"`
+	// Signals are the tracked satellites, empty if the receiver doesn't
+	// report them.
+	Signals []*Signal `protobuf:"bytes,8,rep,name=signals,proto3" json:"signals,omitempty"`
+	// AntennaFaultSince is when the receiver began to report an antenna
+	// fault, unset if the antenna is OK.
+	AntennaFaultSince *timestamppb.Timestamp `protobuf:"bytes,9,opt,name=antenna_fault_since,json=antennaFaultSince,proto3" json:"antenna_fault_since,omitempty"`
+	AntennaFaults     uint64                 `protobuf:"varint,10,opt,name=antenna_faults,json=antennaFaults,proto3" json:"antenna_faults,omitempty"`
+	// Position is the surveyed position, unset if the receiver doesn't
+	// report it.
+	Position      *Position `protobuf:"bytes,11,opt,name=position,proto3" json:"position,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Receiver) Reset() {
+	*x = Receiver{}
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Receiver) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Receiver) ProtoMessage() {}
+
+func (x *Receiver) ProtoReflect() protoreflect.Message {
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Receiver.ProtoReflect.Descriptor instead.
+func (*Receiver) Descriptor() ([]byte, []int) {
+	return file_gogpsdo_v1_gogpsdo_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Receiver) GetSatellites() int32 {
+	if x != nil {
+		return x.Satellites
+	}
+	return 0
+}
+
+func (x *Receiver) GetEfc() float64 {
+	if x != nil {
+		return x.Efc
+	}
+	return 0
+}
+
+func (x *Receiver) GetHoldoverPrediction() float64 {
+	if x != nil {
+		return x.HoldoverPrediction
+	}
+	return 0
+}
+
+func (x *Receiver) GetAntenna() string {
+	if x != nil {
+		return x.Antenna
+	}
+	return ""
+}
+
+func (x *Receiver) GetPhaseError() float64 {
+	if x != nil {
+		return x.PhaseError
+	}
+	return 0
+}
+
+func (x *Receiver) GetDiscipline() string {
+	if x != nil {
+		return x.Discipline
+	}
+	return ""
+}
+
+func (x *Receiver) GetUpdated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Updated
+	}
+	return nil
+}
+
+func (x *Receiver) GetSignals() []*Signal {
+	if x != nil {
+		return x.Signals
+	}
+	return nil
+}
+
+func (x *Receiver) GetAntennaFaultSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.AntennaFaultSince
+	}
+	return nil
+}
+
+func (x *Receiver) GetAntennaFaults() uint64 {
+	if x != nil {
+		return x.AntennaFaults
+	}
+	return 0
+}
+
+func (x *Receiver) GetPosition() *Position {
+	if x != nil {
+		return x.Position
+	}
+	return nil
+}
+
+// Position is the antenna position and survey state of the receiver.
+type Position struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Latitude and longitude are in degrees, altitude in meters.
+	Latitude  float64 `protobuf:"fixed64,1,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude float64 `protobuf:"fixed64,2,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	Altitude  float64 `protobuf:"fixed64,3,opt,name=altitude,proto3" json:"altitude,omitempty"`
+	Mode      string  `protobuf:"bytes,4,opt,name=mode,proto3" json:"mode,omitempty"`
+	// Hold is whether the receiver holds the surveyed position.
+	Hold      bool `protobuf:"varint,5,opt,name=hold,proto3" json:"hold,omitempty"`
+	Surveying bool `protobuf:"varint,6,opt,name=surveying,proto3" json:"surveying,omitempty"`
+	// Progress is the survey progress in percent, unset if unknown.
+	Progress      *float64 `protobuf:"fixed64,7,opt,name=progress,proto3,oneof" json:"progress,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Position) Reset() {
+	*x = Position{}
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Position) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Position) ProtoMessage() {}
+
+func (x *Position) ProtoReflect() protoreflect.Message {
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Position.ProtoReflect.Descriptor instead.
+func (*Position) Descriptor() ([]byte, []int) {
+	return file_gogpsdo_v1_gogpsdo_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Position) GetLatitude() float64 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+func (x *Position) GetLongitude() float64 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+func (x *Position) GetAltitude() float64 {
+	if x != nil {
+		return x.Altitude
+	}
+	return 0
+}
+
+func (x *Position) GetMode() string {
+	if x != nil {
+		return x.Mode
+	}
+	return ""
+}
+
+func (x *Position) GetHold() bool {
+	if x != nil {
+		return x.Hold
+	}
+	return false
+}
+
+func (x *Position) GetSurveying() bool {
+	if x != nil {
+		return x.Surveying
+	}
+	return false
+}
+
+func (x *Position) GetProgress() float64 {
+	if x != nil && x.Progress != nil {
+		return *x.Progress
+	}
+	return 0
+}
+
+// Signal is a tracked satellite with its signal strength.
+type Signal struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Prn   int32                  `protobuf:"varint,1,opt,name=prn,proto3" json:"prn,omitempty"`
+	// Elevation and azimuth are in degrees.
+	Elevation     int32 `protobuf:"varint,2,opt,name=elevation,proto3" json:"elevation,omitempty"`
+	Azimuth       int32 `protobuf:"varint,3,opt,name=azimuth,proto3" json:"azimuth,omitempty"`
+	Strength      int32 `protobuf:"varint,4,opt,name=strength,proto3" json:"strength,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Signal) Reset() {
+	*x = Signal{}
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Signal) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Signal) ProtoMessage() {}
+
+func (x *Signal) ProtoReflect() protoreflect.Message {
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Signal.ProtoReflect.Descriptor instead.
+func (*Signal) Descriptor() ([]byte, []int) {
+	return file_gogpsdo_v1_gogpsdo_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *Signal) GetPrn() int32 {
+	if x != nil {
+		return x.Prn
+	}
+	return 0
+}
+
+func (x *Signal) GetElevation() int32 {
+	if x != nil {
+		return x.Elevation
+	}
+	return 0
+}
+
+func (x *Signal) GetAzimuth() int32 {
+	if x != nil {
+		return x.Azimuth
+	}
+	return 0
+}
+
+func (x *Signal) GetStrength() int32 {
+	if x != nil {
+		return x.Strength
+	}
+	return 0
+}
+
+type GetStatusRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Device        string                 `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatusRequest) Reset() {
+	*x = GetStatusRequest{}
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusRequest) ProtoMessage() {}
+
+func (x *GetStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusRequest.ProtoReflect.Descriptor instead.
+func (*GetStatusRequest) Descriptor() ([]byte, []int) {
+	return file_gogpsdo_v1_gogpsdo_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *GetStatusRequest) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+type GetStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Devices       []*Status              `protobuf:"bytes,1,rep,name=devices,proto3" json:"devices,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetStatusResponse) Reset() {
+	*x = GetStatusResponse{}
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetStatusResponse) ProtoMessage() {}
+
+func (x *GetStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetStatusResponse.ProtoReflect.Descriptor instead.
+func (*GetStatusResponse) Descriptor() ([]byte, []int) {
+	return file_gogpsdo_v1_gogpsdo_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetStatusResponse) GetDevices() []*Status {
+	if x != nil {
+		return x.Devices
+	}
+	return nil
+}
+
+type WatchStatusRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Device string                 `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
+	// Interval between updates, 1 second if unset.
+	Interval      *durationpb.Duration `protobuf:"bytes,2,opt,name=interval,proto3" json:"interval,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchStatusRequest) Reset() {
+	*x = WatchStatusRequest{}
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchStatusRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchStatusRequest) ProtoMessage() {}
+
+func (x *WatchStatusRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchStatusRequest.ProtoReflect.Descriptor instead.
+func (*WatchStatusRequest) Descriptor() ([]byte, []int) {
+	return file_gogpsdo_v1_gogpsdo_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *WatchStatusRequest) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+func (x *WatchStatusRequest) GetInterval() *durationpb.Duration {
+	if x != nil {
+		return x.Interval
+	}
+	return nil
+}
+
+type WatchStatusResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Status        *Status                `protobuf:"bytes,1,opt,name=status,proto3" json:"status,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchStatusResponse) Reset() {
+	*x = WatchStatusResponse{}
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchStatusResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchStatusResponse) ProtoMessage() {}
+
+func (x *WatchStatusResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchStatusResponse.ProtoReflect.Descriptor instead.
+func (*WatchStatusResponse) Descriptor() ([]byte, []int) {
+	return file_gogpsdo_v1_gogpsdo_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *WatchStatusResponse) GetStatus() *Status {
+	if x != nil {
+		return x.Status
+	}
+	return nil
+}
+
+type QuerySamplesRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Device string                 `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
+	// Since is the start of the query, the last hour if unset.
+	Since *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=since,proto3" json:"since,omitempty"`
+	// Step is the bucket width, 1 minute if unset.
+	Step          *durationpb.Duration `protobuf:"bytes,3,opt,name=step,proto3" json:"step,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuerySamplesRequest) Reset() {
+	*x = QuerySamplesRequest{}
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuerySamplesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuerySamplesRequest) ProtoMessage() {}
+
+func (x *QuerySamplesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuerySamplesRequest.ProtoReflect.Descriptor instead.
+func (*QuerySamplesRequest) Descriptor() ([]byte, []int) {
+	return file_gogpsdo_v1_gogpsdo_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *QuerySamplesRequest) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+func (x *QuerySamplesRequest) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *QuerySamplesRequest) GetStep() *durationpb.Duration {
+	if x != nil {
+		return x.Step
+	}
+	return nil
+}
+
+type QuerySamplesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Points        []*SamplePoint         `protobuf:"bytes,1,rep,name=points,proto3" json:"points,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QuerySamplesResponse) Reset() {
+	*x = QuerySamplesResponse{}
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QuerySamplesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QuerySamplesResponse) ProtoMessage() {}
+
+func (x *QuerySamplesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QuerySamplesResponse.ProtoReflect.Descriptor instead.
+func (*QuerySamplesResponse) Descriptor() ([]byte, []int) {
+	return file_gogpsdo_v1_gogpsdo_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *QuerySamplesResponse) GetPoints() []*SamplePoint {
+	if x != nil {
+		return x.Points
+	}
+	return nil
+}
+
+// SamplePoint summarizes the samples of one bucket.
+type SamplePoint struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Time  *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=time,proto3" json:"time,omitempty"`
+	// Offset is the mean offset, min and max its range.
+	Offset  float64 `protobuf:"fixed64,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	Min     float64 `protobuf:"fixed64,3,opt,name=min,proto3" json:"min,omitempty"`
+	Max     float64 `protobuf:"fixed64,4,opt,name=max,proto3" json:"max,omitempty"`
+	Samples int64   `protobuf:"varint,5,opt,name=samples,proto3" json:"samples,omitempty"`
+	// Locked is the fraction of samples reporting LOCKED.
+	Locked        float64 `protobuf:"fixed64,6,opt,name=locked,proto3" json:"locked,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SamplePoint) Reset() {
+	*x = SamplePoint{}
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SamplePoint) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SamplePoint) ProtoMessage() {}
+
+func (x *SamplePoint) ProtoReflect() protoreflect.Message {
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SamplePoint.ProtoReflect.Descriptor instead.
+func (*SamplePoint) Descriptor() ([]byte, []int) {
+	return file_gogpsdo_v1_gogpsdo_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *SamplePoint) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *SamplePoint) GetOffset() float64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+func (x *SamplePoint) GetMin() float64 {
+	if x != nil {
+		return x.Min
+	}
+	return 0
+}
+
+func (x *SamplePoint) GetMax() float64 {
+	if x != nil {
+		return x.Max
+	}
+	return 0
+}
+
+func (x *SamplePoint) GetSamples() int64 {
+	if x != nil {
+		return x.Samples
+	}
+	return 0
+}
+
+func (x *SamplePoint) GetLocked() float64 {
+	if x != nil {
+		return x.Locked
+	}
+	return 0
+}
+
+type SetOffsetRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Device        string                 `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
+	Offset        float64                `protobuf:"fixed64,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetOffsetRequest) Reset() {
+	*x = SetOffsetRequest{}
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetOffsetRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetOffsetRequest) ProtoMessage() {}
+
+func (x *SetOffsetRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetOffsetRequest.ProtoReflect.Descriptor instead.
+func (*SetOffsetRequest) Descriptor() ([]byte, []int) {
+	return file_gogpsdo_v1_gogpsdo_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *SetOffsetRequest) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+func (x *SetOffsetRequest) GetOffset() float64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type SetOffsetResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Previous      float64                `protobuf:"fixed64,1,opt,name=previous,proto3" json:"previous,omitempty"`
+	Offset        float64                `protobuf:"fixed64,2,opt,name=offset,proto3" json:"offset,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SetOffsetResponse) Reset() {
+	*x = SetOffsetResponse{}
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SetOffsetResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SetOffsetResponse) ProtoMessage() {}
+
+func (x *SetOffsetResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SetOffsetResponse.ProtoReflect.Descriptor instead.
+func (*SetOffsetResponse) Descriptor() ([]byte, []int) {
+	return file_gogpsdo_v1_gogpsdo_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *SetOffsetResponse) GetPrevious() float64 {
+	if x != nil {
+		return x.Previous
+	}
+	return 0
+}
+
+func (x *SetOffsetResponse) GetOffset() float64 {
+	if x != nil {
+		return x.Offset
+	}
+	return 0
+}
+
+type ResetCountersRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Device        string                 `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResetCountersRequest) Reset() {
+	*x = ResetCountersRequest{}
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResetCountersRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResetCountersRequest) ProtoMessage() {}
+
+func (x *ResetCountersRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResetCountersRequest.ProtoReflect.Descriptor instead.
+func (*ResetCountersRequest) Descriptor() ([]byte, []int) {
+	return file_gogpsdo_v1_gogpsdo_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *ResetCountersRequest) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+type ResetCountersResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ResetCountersResponse) Reset() {
+	*x = ResetCountersResponse{}
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ResetCountersResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ResetCountersResponse) ProtoMessage() {}
+
+func (x *ResetCountersResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ResetCountersResponse.ProtoReflect.Descriptor instead.
+func (*ResetCountersResponse) Descriptor() ([]byte, []int) {
+	return file_gogpsdo_v1_gogpsdo_proto_rawDescGZIP(), []int{16}
+}
+
+type SendSCPICommandRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Device string                 `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
+	// Command is the SCPI command, e.g. :SYST:STAT?
+	Command       string `protobuf:"bytes,2,opt,name=command,proto3" json:"command,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendSCPICommandRequest) Reset() {
+	*x = SendSCPICommandRequest{}
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendSCPICommandRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendSCPICommandRequest) ProtoMessage() {}
+
+func (x *SendSCPICommandRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendSCPICommandRequest.ProtoReflect.Descriptor instead.
+func (*SendSCPICommandRequest) Descriptor() ([]byte, []int) {
+	return file_gogpsdo_v1_gogpsdo_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *SendSCPICommandRequest) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+func (x *SendSCPICommandRequest) GetCommand() string {
+	if x != nil {
+		return x.Command
+	}
+	return ""
+}
+
+type SendSCPICommandResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Response      string                 `protobuf:"bytes,1,opt,name=response,proto3" json:"response,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SendSCPICommandResponse) Reset() {
+	*x = SendSCPICommandResponse{}
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SendSCPICommandResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SendSCPICommandResponse) ProtoMessage() {}
+
+func (x *SendSCPICommandResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SendSCPICommandResponse.ProtoReflect.Descriptor instead.
+func (*SendSCPICommandResponse) Descriptor() ([]byte, []int) {
+	return file_gogpsdo_v1_gogpsdo_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *SendSCPICommandResponse) GetResponse() string {
+	if x != nil {
+		return x.Response
+	}
+	return ""
+}
+
+type StartSurveyRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Device        string                 `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartSurveyRequest) Reset() {
+	*x = StartSurveyRequest{}
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartSurveyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartSurveyRequest) ProtoMessage() {}
+
+func (x *StartSurveyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartSurveyRequest.ProtoReflect.Descriptor instead.
+func (*StartSurveyRequest) Descriptor() ([]byte, []int) {
+	return file_gogpsdo_v1_gogpsdo_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *StartSurveyRequest) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+type StartSurveyResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *StartSurveyResponse) Reset() {
+	*x = StartSurveyResponse{}
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *StartSurveyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*StartSurveyResponse) ProtoMessage() {}
+
+func (x *StartSurveyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use StartSurveyResponse.ProtoReflect.Descriptor instead.
+func (*StartSurveyResponse) Descriptor() ([]byte, []int) {
+	return file_gogpsdo_v1_gogpsdo_proto_rawDescGZIP(), []int{20}
+}
+
+type HoldPositionRequest struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	Device string                 `protobuf:"bytes,1,opt,name=device,proto3" json:"device,omitempty"`
+	// Latitude and longitude are in degrees, altitude in meters.
+	Latitude      float64 `protobuf:"fixed64,2,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude     float64 `protobuf:"fixed64,3,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	Altitude      float64 `protobuf:"fixed64,4,opt,name=altitude,proto3" json:"altitude,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HoldPositionRequest) Reset() {
+	*x = HoldPositionRequest{}
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HoldPositionRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HoldPositionRequest) ProtoMessage() {}
+
+func (x *HoldPositionRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HoldPositionRequest.ProtoReflect.Descriptor instead.
+func (*HoldPositionRequest) Descriptor() ([]byte, []int) {
+	return file_gogpsdo_v1_gogpsdo_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *HoldPositionRequest) GetDevice() string {
+	if x != nil {
+		return x.Device
+	}
+	return ""
+}
+
+func (x *HoldPositionRequest) GetLatitude() float64 {
+	if x != nil {
+		return x.Latitude
+	}
+	return 0
+}
+
+func (x *HoldPositionRequest) GetLongitude() float64 {
+	if x != nil {
+		return x.Longitude
+	}
+	return 0
+}
+
+func (x *HoldPositionRequest) GetAltitude() float64 {
+	if x != nil {
+		return x.Altitude
+	}
+	return 0
+}
+
+type HoldPositionResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HoldPositionResponse) Reset() {
+	*x = HoldPositionResponse{}
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[22]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HoldPositionResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HoldPositionResponse) ProtoMessage() {}
+
+func (x *HoldPositionResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_gogpsdo_v1_gogpsdo_proto_msgTypes[22]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HoldPositionResponse.ProtoReflect.Descriptor instead.
+func (*HoldPositionResponse) Descriptor() ([]byte, []int) {
+	return file_gogpsdo_v1_gogpsdo_proto_rawDescGZIP(), []int{22}
+}
+
+var File_gogpsdo_v1_gogpsdo_proto protoreflect.FileDescriptor
+
+const file_gogpsdo_v1_gogpsdo_proto_rawDesc = "" +
+	"\n" +
+	"\x18gogpsdo/v1/gogpsdo.proto\x12\n" +
+	"gogpsdo.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xf3\b\n" +
+	"\x06Status\x12\x16\n" +
+	"\x06device\x18\x01 \x01(\tR\x06device\x12\x16\n" +
+	"\x06status\x18\x02 \x01(\tR\x06status\x12\x14\n" +
+	"\x05valid\x18\x03 \x01(\bR\x05valid\x128\n" +
+	"\ttimestamp\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\ttimestamp\x12!\n" +
+	"\fleap_seconds\x18\x05 \x01(\x05R\vleapSeconds\x12\x12\n" +
+	"\x04leap\x18\x06 \x01(\tR\x04leap\x12\x1d\n" +
+	"\n" +
+	"sample_age\x18\a \x01(\x01R\tsampleAge\x12;\n" +
+	"\vlast_update\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"lastUpdate\x12\x16\n" +
+	"\x06offset\x18\t \x01(\x01R\x06offset\x12&\n" +
+	"\fclock_offset\x18\n" +
+	" \x01(\x01H\x00R\vclockOffset\x88\x01\x01\x12'\n" +
+	"\x0finput_connected\x18\v \x01(\bR\x0einputConnected\x12)\n" +
+	"\x10input_reconnects\x18\f \x01(\x04R\x0finputReconnects\x12-\n" +
+	"\apackets\x18\r \x01(\v2\x13.gogpsdo.v1.PacketsR\apackets\x12!\n" +
+	"\fsamples_sent\x18\x0e \x01(\x04R\vsamplesSent\x12'\n" +
+	"\x0fsamples_dropped\x18\x0f \x01(\x04R\x0esamplesDropped\x12\x1b\n" +
+	"\tpps_edges\x18\x10 \x01(\x04R\bppsEdges\x12\x1d\n" +
+	"\n" +
+	"pps_paired\x18\x11 \x01(\x04R\tppsPaired\x12A\n" +
+	"\x0eholdover_since\x18\x12 \x01(\v2\x1a.google.protobuf.TimestampR\rholdoverSince\x12+\n" +
+	"\x11holdover_rejected\x18\x13 \x01(\x04R\x10holdoverRejected\x120\n" +
+	"\breceiver\x18\x14 \x01(\v2\x14.gogpsdo.v1.ReceiverR\breceiver\x12+\n" +
+	"\x11holdover_episodes\x18\x15 \x01(\x04R\x10holdoverEpisodes\x129\n" +
+	"\x16holdover_last_duration\x18\x16 \x01(\x01H\x01R\x14holdoverLastDuration\x88\x01\x01\x12)\n" +
+	"\x10holdover_longest\x18\x17 \x01(\x01R\x0fholdoverLongest\x12@\n" +
+	"\x0epower_up_since\x18\x18 \x01(\v2\x1a.google.protobuf.TimestampR\fpowerUpSince\x12%\n" +
+	"\ftime_to_lock\x18\x19 \x01(\x01H\x02R\n" +
+	"timeToLock\x88\x01\x01\x120\n" +
+	"\bidentity\x18\x1a \x01(\v2\x14.gogpsdo.v1.IdentityR\bidentityB\x0f\n" +
+	"\r_clock_offsetB\x19\n" +
+	"\x17_holdover_last_durationB\x0f\n" +
+	"\r_time_to_lock\"\x8e\x02\n" +
+	"\aPackets\x12\x14\n" +
+	"\x05total\x18\x01 \x01(\x04R\x05total\x12\x14\n" +
+	"\x05valid\x18\x02 \x01(\x04R\x05valid\x12%\n" +
+	"\x0eframing_errors\x18\x03 \x01(\x04R\rframingErrors\x12\x1a\n" +
+	"\boutliers\x18\x04 \x01(\x04R\boutliers\x12\x16\n" +
+	"\x06missed\x18\x05 \x01(\x04R\x06missed\x12\x12\n" +
+	"\x04gaps\x18\x06 \x01(\x04R\x04gaps\x12'\n" +
+	"\x0fchecksum_errors\x18\a \x01(\x04R\x0echecksumErrors\x12!\n" +
+	"\frange_errors\x18\b \x01(\x04R\vrangeErrors\x12\x1c\n" +
+	"\tmalformed\x18\t \x01(\x04R\tmalformed\"x\n" +
+	"\bIdentity\x12\"\n" +
+	"\fmanufacturer\x18\x01 \x01(\tR\fmanufacturer\x12\x14\n" +
+	"\x05model\x18\x02 \x01(\tR\x05model\x12\x16\n" +
+	"\x06serial\x18\x03 \x01(\tR\x06serial\x12\x1a\n" +
+	"\bfirmware\x18\x04 \x01(\tR\bfirmware\"\xd1\x03\n" +
+	"\bReceiver\x12\x1e\n" +
+	"\n" +
+	"satellites\x18\x01 \x01(\x05R\n" +
+	"satellites\x12\x10\n" +
+	"\x03efc\x18\x02 \x01(\x01R\x03efc\x12/\n" +
+	"\x13holdover_prediction\x18\x03 \x01(\x01R\x12holdoverPrediction\x12\x18\n" +
+	"\aantenna\x18\x04 \x01(\tR\aantenna\x12\x1f\n" +
+	"\vphase_error\x18\x05 \x01(\x01R\n" +
+	"phaseError\x12\x1e\n" +
+	"\n" +
+	"discipline\x18\x06 \x01(\tR\n" +
+	"discipline\x124\n" +
+	"\aupdated\x18\a \x01(\v2\x1a.google.protobuf.TimestampR\aupdated\x12,\n" +
+	"\asignals\x18\b \x03(\v2\x12.gogpsdo.v1.SignalR\asignals\x12J\n" +
+	"\x13antenna_fault_since\x18\t \x01(\v2\x1a.google.protobuf.TimestampR\x11antennaFaultSince\x12%\n" +
+	"\x0eantenna_faults\x18\n" +
+	" \x01(\x04R\rantennaFaults\x120\n" +
+	"\bposition\x18\v \x01(\v2\x14.gogpsdo.v1.PositionR\bposition\"\xd4\x01\n" +
+	"\bPosition\x12\x1a\n" +
+	"\blatitude\x18\x01 \x01(\x01R\blatitude\x12\x1c\n" +
+	"\tlongitude\x18\x02 \x01(\x01R\tlongitude\x12\x1a\n" +
+	"\baltitude\x18\x03 \x01(\x01R\baltitude\x12\x12\n" +
+	"\x04mode\x18\x04 \x01(\tR\x04mode\x12\x12\n" +
+	"\x04hold\x18\x05 \x01(\bR\x04hold\x12\x1c\n" +
+	"\tsurveying\x18\x06 \x01(\bR\tsurveying\x12\x1f\n" +
+	"\bprogress\x18\a \x01(\x01H\x00R\bprogress\x88\x01\x01B\v\n" +
+	"\t_progress\"n\n" +
+	"\x06Signal\x12\x10\n" +
+	"\x03prn\x18\x01 \x01(\x05R\x03prn\x12\x1c\n" +
+	"\televation\x18\x02 \x01(\x05R\televation\x12\x18\n" +
+	"\aazimuth\x18\x03 \x01(\x05R\aazimuth\x12\x1a\n" +
+	"\bstrength\x18\x04 \x01(\x05R\bstrength\"*\n" +
+	"\x10GetStatusRequest\x12\x16\n" +
+	"\x06device\x18\x01 \x01(\tR\x06device\"A\n" +
+	"\x11GetStatusResponse\x12,\n" +
+	"\adevices\x18\x01 \x03(\v2\x12.gogpsdo.v1.StatusR\adevices\"c\n" +
+	"\x12WatchStatusRequest\x12\x16\n" +
+	"\x06device\x18\x01 \x01(\tR\x06device\x125\n" +
+	"\binterval\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\binterval\"A\n" +
+	"\x13WatchStatusResponse\x12*\n" +
+	"\x06status\x18\x01 \x01(\v2\x12.gogpsdo.v1.StatusR\x06status\"\x8e\x01\n" +
+	"\x13QuerySamplesRequest\x12\x16\n" +
+	"\x06device\x18\x01 \x01(\tR\x06device\x120\n" +
+	"\x05since\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x05since\x12-\n" +
+	"\x04step\x18\x03 \x01(\v2\x19.google.protobuf.DurationR\x04step\"G\n" +
+	"\x14QuerySamplesResponse\x12/\n" +
+	"\x06points\x18\x01 \x03(\v2\x17.gogpsdo.v1.SamplePointR\x06points\"\xab\x01\n" +
+	"\vSamplePoint\x12.\n" +
+	"\x04time\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x01R\x06offset\x12\x10\n" +
+	"\x03min\x18\x03 \x01(\x01R\x03min\x12\x10\n" +
+	"\x03max\x18\x04 \x01(\x01R\x03max\x12\x18\n" +
+	"\asamples\x18\x05 \x01(\x03R\asamples\x12\x16\n" +
+	"\x06locked\x18\x06 \x01(\x01R\x06locked\"B\n" +
+	"\x10SetOffsetRequest\x12\x16\n" +
+	"\x06device\x18\x01 \x01(\tR\x06device\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x01R\x06offset\"G\n" +
+	"\x11SetOffsetResponse\x12\x1a\n" +
+	"\bprevious\x18\x01 \x01(\x01R\bprevious\x12\x16\n" +
+	"\x06offset\x18\x02 \x01(\x01R\x06offset\".\n" +
+	"\x14ResetCountersRequest\x12\x16\n" +
+	"\x06device\x18\x01 \x01(\tR\x06device\"\x17\n" +
+	"\x15ResetCountersResponse\"J\n" +
+	"\x16SendSCPICommandRequest\x12\x16\n" +
+	"\x06device\x18\x01 \x01(\tR\x06device\x12\x18\n" +
+	"\acommand\x18\x02 \x01(\tR\acommand\"5\n" +
+	"\x17SendSCPICommandResponse\x12\x1a\n" +
+	"\bresponse\x18\x01 \x01(\tR\bresponse\",\n" +
+	"\x12StartSurveyRequest\x12\x16\n" +
+	"\x06device\x18\x01 \x01(\tR\x06device\"\x15\n" +
+	"\x13StartSurveyResponse\"\x83\x01\n" +
+	"\x13HoldPositionRequest\x12\x16\n" +
+	"\x06device\x18\x01 \x01(\tR\x06device\x12\x1a\n" +
+	"\blatitude\x18\x02 \x01(\x01R\blatitude\x12\x1c\n" +
+	"\tlongitude\x18\x03 \x01(\x01R\tlongitude\x12\x1a\n" +
+	"\baltitude\x18\x04 \x01(\x01R\baltitude\"\x16\n" +
+	"\x14HoldPositionResponse2\x9c\x05\n" +
+	"\fGPSDOService\x12H\n" +
+	"\tGetStatus\x12\x1c.gogpsdo.v1.GetStatusRequest\x1a\x1d.gogpsdo.v1.GetStatusResponse\x12P\n" +
+	"\vWatchStatus\x12\x1e.gogpsdo.v1.WatchStatusRequest\x1a\x1f.gogpsdo.v1.WatchStatusResponse0\x01\x12Q\n" +
+	"\fQuerySamples\x12\x1f.gogpsdo.v1.QuerySamplesRequest\x1a .gogpsdo.v1.QuerySamplesResponse\x12H\n" +
+	"\tSetOffset\x12\x1c.gogpsdo.v1.SetOffsetRequest\x1a\x1d.gogpsdo.v1.SetOffsetResponse\x12T\n" +
+	"\rResetCounters\x12 .gogpsdo.v1.ResetCountersRequest\x1a!.gogpsdo.v1.ResetCountersResponse\x12Z\n" +
+	"\x0fSendSCPICommand\x12\".gogpsdo.v1.SendSCPICommandRequest\x1a#.gogpsdo.v1.SendSCPICommandResponse\x12N\n" +
+	"\vStartSurvey\x12\x1e.gogpsdo.v1.StartSurveyRequest\x1a\x1f.gogpsdo.v1.StartSurveyResponse\x12Q\n" +
+	"\fHoldPosition\x12\x1f.gogpsdo.v1.HoldPositionRequest\x1a .gogpsdo.v1.HoldPositionResponseB<Z:github.com/karlcswanson/gogpsdo/proto/gogpsdo/v1;gogpsdov1b\x06proto3"
+
+var (
+	file_gogpsdo_v1_gogpsdo_proto_rawDescOnce sync.Once
+	file_gogpsdo_v1_gogpsdo_proto_rawDescData []byte
+)
+
+func file_gogpsdo_v1_gogpsdo_proto_rawDescGZIP() []byte {
+	file_gogpsdo_v1_gogpsdo_proto_rawDescOnce.Do(func() {
+		file_gogpsdo_v1_gogpsdo_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_gogpsdo_v1_gogpsdo_proto_rawDesc), len(file_gogpsdo_v1_gogpsdo_proto_rawDesc)))
+	})
+	return file_gogpsdo_v1_gogpsdo_proto_rawDescData
+}
+
+var file_gogpsdo_v1_gogpsdo_proto_msgTypes = make([]protoimpl.MessageInfo, 23)
+var file_gogpsdo_v1_gogpsdo_proto_goTypes = []any{
+	(*Status)(nil),                  // 0: gogpsdo.v1.Status
+	(*Packets)(nil),                 // 1: gogpsdo.v1.Packets
+	(*Identity)(nil),                // 2: gogpsdo.v1.Identity
+	(*Receiver)(nil),                // 3: gogpsdo.v1.Receiver
+	(*Position)(nil),                // 4: gogpsdo.v1.Position
+	(*Signal)(nil),                  // 5: gogpsdo.v1.Signal
+	(*GetStatusRequest)(nil),        // 6: gogpsdo.v1.GetStatusRequest
+	(*GetStatusResponse)(nil),       // 7: gogpsdo.v1.GetStatusResponse
+	(*WatchStatusRequest)(nil),      // 8: gogpsdo.v1.WatchStatusRequest
+	(*WatchStatusResponse)(nil),     // 9: gogpsdo.v1.WatchStatusResponse
+	(*QuerySamplesRequest)(nil),     // 10: gogpsdo.v1.QuerySamplesRequest
+	(*QuerySamplesResponse)(nil),    // 11: gogpsdo.v1.QuerySamplesResponse
+	(*SamplePoint)(nil),             // 12: gogpsdo.v1.SamplePoint
+	(*SetOffsetRequest)(nil),        // 13: gogpsdo.v1.SetOffsetRequest
+	(*SetOffsetResponse)(nil),       // 14: gogpsdo.v1.SetOffsetResponse
+	(*ResetCountersRequest)(nil),    // 15: gogpsdo.v1.ResetCountersRequest
+	(*ResetCountersResponse)(nil),   // 16: gogpsdo.v1.ResetCountersResponse
+	(*SendSCPICommandRequest)(nil),  // 17: gogpsdo.v1.SendSCPICommandRequest
+	(*SendSCPICommandResponse)(nil), // 18: gogpsdo.v1.SendSCPICommandResponse
+	(*StartSurveyRequest)(nil),      // 19: gogpsdo.v1.StartSurveyRequest
+	(*StartSurveyResponse)(nil),     // 20: gogpsdo.v1.StartSurveyResponse
+	(*HoldPositionRequest)(nil),     // 21: gogpsdo.v1.HoldPositionRequest
+	(*HoldPositionResponse)(nil),    // 22: gogpsdo.v1.HoldPositionResponse
+	(*timestamppb.Timestamp)(nil),   // 23: google.protobuf.Timestamp
+	(*durationpb.Duration)(nil),     // 24: google.protobuf.Duration
+}
+var file_gogpsdo_v1_gogpsdo_proto_depIdxs = []int32{
+	23, // 0: gogpsdo.v1.Status.timestamp:type_name -> google.protobuf.Timestamp
+	23, // 1: gogpsdo.v1.Status.last_update:type_name -> google.protobuf.Timestamp
+	1,  // 2: gogpsdo.v1.Status.packets:type_name -> gogpsdo.v1.Packets
+	23, // 3: gogpsdo.v1.Status.holdover_since:type_name -> google.protobuf.Timestamp
+	3,  // 4: gogpsdo.v1.Status.receiver:type_name -> gogpsdo.v1.Receiver
+	23, // 5: gogpsdo.v1.Status.power_up_since:type_name -> google.protobuf.Timestamp
+	2,  // 6: gogpsdo.v1.Status.identity:type_name -> gogpsdo.v1.Identity
+	23, // 7: gogpsdo.v1.Receiver.updated:type_name -> google.protobuf.Timestamp
+	5,  // 8: gogpsdo.v1.Receiver.signals:type_name -> gogpsdo.v1.Signal
+	23, // 9: gogpsdo.v1.Receiver.antenna_fault_since:type_name -> google.protobuf.Timestamp
+	4,  // 10: gogpsdo.v1.Receiver.position:type_name -> gogpsdo.v1.Position
+	0,  // 11: gogpsdo.v1.GetStatusResponse.devices:type_name -> gogpsdo.v1.Status
+	24, // 12: gogpsdo.v1.WatchStatusRequest.interval:type_name -> google.protobuf.Duration
+	0,  // 13: gogpsdo.v1.WatchStatusResponse.status:type_name -> gogpsdo.v1.Status
+	23, // 14: gogpsdo.v1.QuerySamplesRequest.since:type_name -> google.protobuf.Timestamp
+	24, // 15: gogpsdo.v1.QuerySamplesRequest.step:type_name -> google.protobuf.Duration
+	12, // 16: gogpsdo.v1.QuerySamplesResponse.points:type_name -> gogpsdo.v1.SamplePoint
+	23, // 17: gogpsdo.v1.SamplePoint.time:type_name -> google.protobuf.Timestamp
+	6,  // 18: gogpsdo.v1.GPSDOService.GetStatus:input_type -> gogpsdo.v1.GetStatusRequest
+	8,  // 19: gogpsdo.v1.GPSDOService.WatchStatus:input_type -> gogpsdo.v1.WatchStatusRequest
+	10, // 20: gogpsdo.v1.GPSDOService.QuerySamples:input_type -> gogpsdo.v1.QuerySamplesRequest
+	13, // 21: gogpsdo.v1.GPSDOService.SetOffset:input_type -> gogpsdo.v1.SetOffsetRequest
+	15, // 22: gogpsdo.v1.GPSDOService.ResetCounters:input_type -> gogpsdo.v1.ResetCountersRequest
+	17, // 23: gogpsdo.v1.GPSDOService.SendSCPICommand:input_type -> gogpsdo.v1.SendSCPICommandRequest
+	19, // 24: gogpsdo.v1.GPSDOService.StartSurvey:input_type -> gogpsdo.v1.StartSurveyRequest
+	21, // 25: gogpsdo.v1.GPSDOService.HoldPosition:input_type -> gogpsdo.v1.HoldPositionRequest
+	7,  // 26: gogpsdo.v1.GPSDOService.GetStatus:output_type -> gogpsdo.v1.GetStatusResponse
+	9,  // 27: gogpsdo.v1.GPSDOService.WatchStatus:output_type -> gogpsdo.v1.WatchStatusResponse
+	11, // 28: gogpsdo.v1.GPSDOService.QuerySamples:output_type -> gogpsdo.v1.QuerySamplesResponse
+	14, // 29: gogpsdo.v1.GPSDOService.SetOffset:output_type -> gogpsdo.v1.SetOffsetResponse
+	16, // 30: gogpsdo.v1.GPSDOService.ResetCounters:output_type -> gogpsdo.v1.ResetCountersResponse
+	18, // 31: gogpsdo.v1.GPSDOService.SendSCPICommand:output_type -> gogpsdo.v1.SendSCPICommandResponse
+	20, // 32: gogpsdo.v1.GPSDOService.StartSurvey:output_type -> gogpsdo.v1.StartSurveyResponse
+	22, // 33: gogpsdo.v1.GPSDOService.HoldPosition:output_type -> gogpsdo.v1.HoldPositionResponse
+	26, // [26:34] is the sub-list for method output_type
+	18, // [18:26] is the sub-list for method input_type
+	18, // [18:18] is the sub-list for extension type_name
+	18, // [18:18] is the sub-list for extension extendee
+	0,  // [0:18] is the sub-list for field type_name
+}
+
+func init() { file_gogpsdo_v1_gogpsdo_proto_init() }
+func file_gogpsdo_v1_gogpsdo_proto_init() {
+	if File_gogpsdo_v1_gogpsdo_proto != nil {
+		return
+	}
+	file_gogpsdo_v1_gogpsdo_proto_msgTypes[0].OneofWrappers = []any{}
+	file_gogpsdo_v1_gogpsdo_proto_msgTypes[4].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_gogpsdo_v1_gogpsdo_proto_rawDesc), len(file_gogpsdo_v1_gogpsdo_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   23,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_gogpsdo_v1_gogpsdo_proto_goTypes,
+		DependencyIndexes: file_gogpsdo_v1_gogpsdo_proto_depIdxs,
+		MessageInfos:      file_gogpsdo_v1_gogpsdo_proto_msgTypes,
+	}.Build()
+	File_gogpsdo_v1_gogpsdo_proto = out.File
+	file_gogpsdo_v1_gogpsdo_proto_goTypes = nil
+	file_gogpsdo_v1_gogpsdo_proto_depIdxs = nil
+}
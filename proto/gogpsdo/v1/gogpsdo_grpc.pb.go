@@ -0,0 +1,430 @@
+// gRPC monitoring and control API of the gogpsdo bridge. Offsets and ages are
+// in seconds, like the HTTP status API.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: gogpsdo/v1/gogpsdo.proto
+
+package gogpsdov1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	GPSDOService_GetStatus_FullMethodName       = "/gogpsdo.v1.GPSDOService/GetStatus"
+	GPSDOService_WatchStatus_FullMethodName     = "/gogpsdo.v1.GPSDOService/WatchStatus"
+	GPSDOService_QuerySamples_FullMethodName    = "/gogpsdo.v1.GPSDOService/QuerySamples"
+	GPSDOService_SetOffset_FullMethodName       = "/gogpsdo.v1.GPSDOService/SetOffset"
+	GPSDOService_ResetCounters_FullMethodName   = "/gogpsdo.v1.GPSDOService/ResetCounters"
+	GPSDOService_SendSCPICommand_FullMethodName = "/gogpsdo.v1.GPSDOService/SendSCPICommand"
+	GPSDOService_StartSurvey_FullMethodName     = "/gogpsdo.v1.GPSDOService/StartSurvey"
+	GPSDOService_HoldPosition_FullMethodName    = "/gogpsdo.v1.GPSDOService/HoldPosition"
+)
+
+// GPSDOServiceClient is the client API for GPSDOService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// GPSDOService monitors and controls the bridged GPSDOs. Requests select a
+// device by name; an empty name selects the only device, or every device
+// for GetStatus and ResetCounters.
+type GPSDOServiceClient interface {
+	// GetStatus returns the current status of the selected devices.
+	GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*GetStatusResponse, error)
+	// WatchStatus sends the status of the selected devices right away, then
+	// every interval and on every lock state transition.
+	WatchStatus(ctx context.Context, in *WatchStatusRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchStatusResponse], error)
+	// QuerySamples returns the stored samples of a device averaged over
+	// buckets. It needs the history database.
+	QuerySamples(ctx context.Context, in *QuerySamplesRequest, opts ...grpc.CallOption) (*QuerySamplesResponse, error)
+	// SetOffset changes the calibration offset of a device. Control RPCs are
+	// only served when grpc.control is set.
+	SetOffset(ctx context.Context, in *SetOffsetRequest, opts ...grpc.CallOption) (*SetOffsetResponse, error)
+	// ResetCounters zeroes the packet, sample and rejection counters.
+	ResetCounters(ctx context.Context, in *ResetCountersRequest, opts ...grpc.CallOption) (*ResetCountersResponse, error)
+	// SendSCPICommand sends a command to the SCPI port of a device and
+	// returns the response line. It needs scpi.port.
+	SendSCPICommand(ctx context.Context, in *SendSCPICommandRequest, opts ...grpc.CallOption) (*SendSCPICommandResponse, error)
+	// StartSurvey starts a new survey of the antenna position. It needs
+	// scpi.port or the tsip or ubx protocol.
+	StartSurvey(ctx context.Context, in *StartSurveyRequest, opts ...grpc.CallOption) (*StartSurveyResponse, error)
+	// HoldPosition sets the antenna position the receiver holds instead of
+	// surveying one, like StartSurvey.
+	HoldPosition(ctx context.Context, in *HoldPositionRequest, opts ...grpc.CallOption) (*HoldPositionResponse, error)
+}
+
+type gPSDOServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewGPSDOServiceClient(cc grpc.ClientConnInterface) GPSDOServiceClient {
+	return &gPSDOServiceClient{cc}
+}
+
+func (c *gPSDOServiceClient) GetStatus(ctx context.Context, in *GetStatusRequest, opts ...grpc.CallOption) (*GetStatusResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetStatusResponse)
+	err := c.cc.Invoke(ctx, GPSDOService_GetStatus_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gPSDOServiceClient) WatchStatus(ctx context.Context, in *WatchStatusRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchStatusResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &GPSDOService_ServiceDesc.Streams[0], GPSDOService_WatchStatus_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchStatusRequest, WatchStatusResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GPSDOService_WatchStatusClient = grpc.ServerStreamingClient[WatchStatusResponse]
+
+func (c *gPSDOServiceClient) QuerySamples(ctx context.Context, in *QuerySamplesRequest, opts ...grpc.CallOption) (*QuerySamplesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QuerySamplesResponse)
+	err := c.cc.Invoke(ctx, GPSDOService_QuerySamples_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gPSDOServiceClient) SetOffset(ctx context.Context, in *SetOffsetRequest, opts ...grpc.CallOption) (*SetOffsetResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SetOffsetResponse)
+	err := c.cc.Invoke(ctx, GPSDOService_SetOffset_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gPSDOServiceClient) ResetCounters(ctx context.Context, in *ResetCountersRequest, opts ...grpc.CallOption) (*ResetCountersResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ResetCountersResponse)
+	err := c.cc.Invoke(ctx, GPSDOService_ResetCounters_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gPSDOServiceClient) SendSCPICommand(ctx context.Context, in *SendSCPICommandRequest, opts ...grpc.CallOption) (*SendSCPICommandResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SendSCPICommandResponse)
+	err := c.cc.Invoke(ctx, GPSDOService_SendSCPICommand_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gPSDOServiceClient) StartSurvey(ctx context.Context, in *StartSurveyRequest, opts ...grpc.CallOption) (*StartSurveyResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(StartSurveyResponse)
+	err := c.cc.Invoke(ctx, GPSDOService_StartSurvey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *gPSDOServiceClient) HoldPosition(ctx context.Context, in *HoldPositionRequest, opts ...grpc.CallOption) (*HoldPositionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HoldPositionResponse)
+	err := c.cc.Invoke(ctx, GPSDOService_HoldPosition_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GPSDOServiceServer is the server API for GPSDOService service.
+// All implementations must embed UnimplementedGPSDOServiceServer
+// for forward compatibility.
+//
+// GPSDOService monitors and controls the bridged GPSDOs. Requests select a
+// device by name; an empty name selects the only device, or every device
+// for GetStatus and ResetCounters.
+type GPSDOServiceServer interface {
+	// GetStatus returns the current status of the selected devices.
+	GetStatus(context.Context, *GetStatusRequest) (*GetStatusResponse, error)
+	// WatchStatus sends the status of the selected devices right away, then
+	// every interval and on every lock state transition.
+	WatchStatus(*WatchStatusRequest, grpc.ServerStreamingServer[WatchStatusResponse]) error
+	// QuerySamples returns the stored samples of a device averaged over
+	// buckets. It needs the history database.
+	QuerySamples(context.Context, *QuerySamplesRequest) (*QuerySamplesResponse, error)
+	// SetOffset changes the calibration offset of a device. Control RPCs are
+	// only served when grpc.control is set.
+	SetOffset(context.Context, *SetOffsetRequest) (*SetOffsetResponse, error)
+	// ResetCounters zeroes the packet, sample and rejection counters.
+	ResetCounters(context.Context, *ResetCountersRequest) (*ResetCountersResponse, error)
+	// SendSCPICommand sends a command to the SCPI port of a device and
+	// returns the response line. It needs scpi.port.
+	SendSCPICommand(context.Context, *SendSCPICommandRequest) (*SendSCPICommandResponse, error)
+	// StartSurvey starts a new survey of the antenna position. It needs
+	// scpi.port or the tsip or ubx protocol.
+	StartSurvey(context.Context, *StartSurveyRequest) (*StartSurveyResponse, error)
+	// HoldPosition sets the antenna position the receiver holds instead of
+	// surveying one, like StartSurvey.
+	HoldPosition(context.Context, *HoldPositionRequest) (*HoldPositionResponse, error)
+	mustEmbedUnimplementedGPSDOServiceServer()
+}
+
+// UnimplementedGPSDOServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedGPSDOServiceServer struct{}
+
+func (UnimplementedGPSDOServiceServer) GetStatus(context.Context, *GetStatusRequest) (*GetStatusResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetStatus not implemented")
+}
+func (UnimplementedGPSDOServiceServer) WatchStatus(*WatchStatusRequest, grpc.ServerStreamingServer[WatchStatusResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchStatus not implemented")
+}
+func (UnimplementedGPSDOServiceServer) QuerySamples(context.Context, *QuerySamplesRequest) (*QuerySamplesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QuerySamples not implemented")
+}
+func (UnimplementedGPSDOServiceServer) SetOffset(context.Context, *SetOffsetRequest) (*SetOffsetResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SetOffset not implemented")
+}
+func (UnimplementedGPSDOServiceServer) ResetCounters(context.Context, *ResetCountersRequest) (*ResetCountersResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResetCounters not implemented")
+}
+func (UnimplementedGPSDOServiceServer) SendSCPICommand(context.Context, *SendSCPICommandRequest) (*SendSCPICommandResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SendSCPICommand not implemented")
+}
+func (UnimplementedGPSDOServiceServer) StartSurvey(context.Context, *StartSurveyRequest) (*StartSurveyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartSurvey not implemented")
+}
+func (UnimplementedGPSDOServiceServer) HoldPosition(context.Context, *HoldPositionRequest) (*HoldPositionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HoldPosition not implemented")
+}
+func (UnimplementedGPSDOServiceServer) mustEmbedUnimplementedGPSDOServiceServer() {}
+func (UnimplementedGPSDOServiceServer) testEmbeddedByValue()                      {}
+
+// UnsafeGPSDOServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to GPSDOServiceServer will
+// result in compilation errors.
+type UnsafeGPSDOServiceServer interface {
+	mustEmbedUnimplementedGPSDOServiceServer()
+}
+
+func RegisterGPSDOServiceServer(s grpc.ServiceRegistrar, srv GPSDOServiceServer) {
+	// If the following call pancis, it indicates UnimplementedGPSDOServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&GPSDOService_ServiceDesc, srv)
+}
+
+func _GPSDOService_GetStatus_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStatusRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GPSDOServiceServer).GetStatus(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GPSDOService_GetStatus_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GPSDOServiceServer).GetStatus(ctx, req.(*GetStatusRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GPSDOService_WatchStatus_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchStatusRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GPSDOServiceServer).WatchStatus(m, &grpc.GenericServerStream[WatchStatusRequest, WatchStatusResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GPSDOService_WatchStatusServer = grpc.ServerStreamingServer[WatchStatusResponse]
+
+func _GPSDOService_QuerySamples_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QuerySamplesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GPSDOServiceServer).QuerySamples(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GPSDOService_QuerySamples_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GPSDOServiceServer).QuerySamples(ctx, req.(*QuerySamplesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GPSDOService_SetOffset_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SetOffsetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GPSDOServiceServer).SetOffset(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GPSDOService_SetOffset_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GPSDOServiceServer).SetOffset(ctx, req.(*SetOffsetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GPSDOService_ResetCounters_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetCountersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GPSDOServiceServer).ResetCounters(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GPSDOService_ResetCounters_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GPSDOServiceServer).ResetCounters(ctx, req.(*ResetCountersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GPSDOService_SendSCPICommand_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SendSCPICommandRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GPSDOServiceServer).SendSCPICommand(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GPSDOService_SendSCPICommand_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GPSDOServiceServer).SendSCPICommand(ctx, req.(*SendSCPICommandRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GPSDOService_StartSurvey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartSurveyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GPSDOServiceServer).StartSurvey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GPSDOService_StartSurvey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GPSDOServiceServer).StartSurvey(ctx, req.(*StartSurveyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GPSDOService_HoldPosition_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HoldPositionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GPSDOServiceServer).HoldPosition(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GPSDOService_HoldPosition_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GPSDOServiceServer).HoldPosition(ctx, req.(*HoldPositionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// GPSDOService_ServiceDesc is the grpc.ServiceDesc for GPSDOService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var GPSDOService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "gogpsdo.v1.GPSDOService",
+	HandlerType: (*GPSDOServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetStatus",
+			Handler:    _GPSDOService_GetStatus_Handler,
+		},
+		{
+			MethodName: "QuerySamples",
+			Handler:    _GPSDOService_QuerySamples_Handler,
+		},
+		{
+			MethodName: "SetOffset",
+			Handler:    _GPSDOService_SetOffset_Handler,
+		},
+		{
+			MethodName: "ResetCounters",
+			Handler:    _GPSDOService_ResetCounters_Handler,
+		},
+		{
+			MethodName: "SendSCPICommand",
+			Handler:    _GPSDOService_SendSCPICommand_Handler,
+		},
+		{
+			MethodName: "StartSurvey",
+			Handler:    _GPSDOService_StartSurvey_Handler,
+		},
+		{
+			MethodName: "HoldPosition",
+			Handler:    _GPSDOService_HoldPosition_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchStatus",
+			Handler:       _GPSDOService_WatchStatus_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "gogpsdo/v1/gogpsdo.proto",
+}